@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	dbvars "github.com/flashbots/relayscan/database/vars"
+	"github.com/flashbots/relayscan/services/privatetx"
+	"github.com/flashbots/relayscan/vars"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mempoolFiles     []string
+	privateTxRecheck bool
+	privateTxHistory time.Duration
+	privateTxLimit   uint64
+
+	errNoMempoolFiles = errors.New("no mempool dataset files")
+)
+
+func init() {
+	analyzePrivateTxCmd.Flags().StringSliceVar(&mempoolFiles, "mempool-file", nil, "mempool dataset CSV files with hash and timestamp_ms columns (can be gzipped, can be repeated)")
+	analyzePrivateTxCmd.Flags().DurationVar(&privateTxHistory, "min-history", time.Hour, "only analyze blocks with at least this much mempool data before them (without gaps)")
+	analyzePrivateTxCmd.Flags().BoolVar(&privateTxRecheck, "recheck", false, "also analyze blocks that already have a private tx share")
+	analyzePrivateTxCmd.Flags().Uint64Var(&privateTxLimit, "limit", 0, "max number of blocks (0 for all covered by the dataset)")
+	analyzePrivateTxCmd.Flags().Uint64Var(&numThreads, "threads", 10, "how many threads")
+	analyzePrivateTxCmd.Flags().StringSliceVar(&ethNodeURIs, "eth-node", vars.DefaultEthNodeURIs, "eth node URIs, in order of preference (i.e. Infura)")
+	analyzePrivateTxCmd.Flags().StringVar(&ethNodeBackupURI, "eth-node-backup", vars.DefaultEthBackupNodeURI, "eth node backup URI (deprecated, use several --eth-node)")
+}
+
+var analyzePrivateTxCmd = &cobra.Command{
+	Use:   "analyze-private-tx",
+	Short: "Compute the share of transactions not seen in the public mempool for delivered payloads",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(mempoolFiles) == 0 {
+			log.WithError(errNoMempoolFiles).Fatal("--mempool-file is required")
+		}
+
+		log.Infof("loading mempool dataset from %d files ...", len(mempoolFiles))
+		dataset, err := privatetx.LoadMempoolDataset(mempoolFiles...)
+		if err != nil {
+			log.WithError(err).Fatal("failed to load mempool dataset")
+		}
+		log.Infof("loaded %d transactions, seen between %s and %s", dataset.Len(), dataset.Start().Format(time.RFC3339), dataset.End().Format(time.RFC3339))
+		for _, gap := range dataset.Gaps() {
+			log.Warnf("no mempool data between %s and %s, blocks in this range are skipped", gap.Start.Format(time.RFC3339), gap.End.Format(time.RFC3339))
+		}
+
+		ethNode, err := common.ConnectEthNode(log, append(ethNodeURIs, ethNodeBackupURI)...)
+		if err != nil {
+			log.WithError(err).Fatal("failed to connect to eth nodes")
+		}
+
+		db := database.MustConnectPostgres(log, vars.DefaultPostgresDSN)
+
+		opts := AnalyzePrivateTxOpts{
+			MinHistory: privateTxHistory,
+			Recheck:    privateTxRecheck,
+			Limit:      privateTxLimit,
+			NumThreads: numThreads,
+		}
+		numUpdated, err := RunAnalyzePrivateTx(db, ethNode, dataset, opts)
+		if err != nil {
+			log.WithError(err).Fatal("analyze private tx failed")
+		}
+		log.Infof("analyzed %d blocks", numUpdated)
+	},
+}
+
+// AnalyzePrivateTxOpts contains options for the private transaction analysis
+type AnalyzePrivateTxOpts struct {
+	MinHistory time.Duration // how long the dataset needs to cover before a block
+	Recheck    bool          // also analyze blocks that were analyzed before
+	Limit      uint64
+	NumThreads uint64
+}
+
+// PrivateTxEthClient is the execution client access needed for the private transaction analysis (i.e. common.EthNode)
+type PrivateTxEthClient interface {
+	BlockByHash(ctx context.Context, hash ethcommon.Hash) (*types.Block, error)
+}
+
+// privateTxBlock is a delivered block to analyze
+type privateTxBlock struct {
+	Slot      uint64 `db:"slot"`
+	BlockHash string `db:"block_hash"`
+}
+
+// RunAnalyzePrivateTx computes the share of transactions not seen in the public mempool for the checked, on-chain
+// payloads covered by the dataset, and returns the number of analyzed blocks
+func RunAnalyzePrivateTx(db *database.DatabaseService, eth PrivateTxEthClient, dataset *privatetx.MempoolDataset, opts AnalyzePrivateTxOpts) (int, error) {
+	startTime := time.Now().UTC()
+
+	query := `SELECT DISTINCT slot, block_hash FROM ` + dbvars.TableDataAPIPayloadDelivered + `
+		WHERE value_check_ok IS NOT NULL AND found_onchain=true AND slot_missed IS NOT true
+		AND block_timestamp >= $1 AND block_timestamp <= $2`
+	if !opts.Recheck {
+		query += ` AND block_private_tx_share IS NULL`
+	}
+	query += ` ORDER BY slot, block_hash`
+	if opts.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
+	}
+
+	blocks := []*privateTxBlock{}
+	err := db.DB.Select(&blocks, query, dataset.Start().Add(opts.MinHistory), dataset.End())
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get blocks: %w", err)
+	}
+	log.Infof("analyzing %d blocks", len(blocks))
+	if len(blocks) == 0 {
+		return 0, nil
+	}
+
+	threads := max(opts.NumThreads, 1)
+	var numUpdated atomic.Int64
+	var firstErr error
+	var errOnce sync.Once
+	wg := new(sync.WaitGroup)
+	blockC := make(chan *privateTxBlock, threads)
+	for i := 0; i < int(threads); i++ { //nolint:gosec,intrange
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for block := range blockC {
+				updated, err := analyzePrivateTxBlock(db, eth, dataset, opts.MinHistory, block)
+				if err != nil {
+					errOnce.Do(func() { firstErr = err })
+					continue
+				}
+				if updated {
+					numUpdated.Add(1)
+				}
+			}
+		}()
+	}
+
+	for _, block := range blocks {
+		blockC <- block
+	}
+	close(blockC)
+	wg.Wait()
+
+	log.WithField("timeNeeded", time.Since(startTime)).Info("Analyze private tx done!")
+	return int(numUpdated.Load()), firstErr
+}
+
+// analyzePrivateTxBlock analyzes a block and saves the result for all payloads that delivered it. Returns false if
+// the block isn't covered by the dataset.
+func analyzePrivateTxBlock(db *database.DatabaseService, eth PrivateTxEthClient, dataset *privatetx.MempoolDataset, minHistory time.Duration, entry *privateTxBlock) (bool, error) {
+	blockHash := entry.BlockHash
+	_log := log.WithField("slot", entry.Slot).WithField("blockHash", blockHash)
+	block, err := eth.BlockByHash(context.Background(), ethcommon.HexToHash(blockHash))
+	if err != nil {
+		_log.WithError(err).Error("failed to get block")
+		return false, fmt.Errorf("failed to get block %s: %w", blockHash, err)
+	}
+
+	// the query only checks the start and end of the dataset (and block_timestamp could be off for rows from an older
+	// check), blocks in a gap of the dataset are skipped here
+	if !dataset.Covers(time.Unix(int64(block.Time()), 0), minHistory) { //nolint:gosec
+		_log.Debug("block not covered by the mempool dataset")
+		return false, nil
+	}
+
+	result := privatetx.AnalyzeBlock(block, dataset)
+	_log.WithField("numTx", result.NumTx).WithField("numPrivateTx", result.NumPrivateTx).Debug("analyzed block")
+
+	query := `UPDATE ` + dbvars.TableDataAPIPayloadDelivered + ` SET
+		block_num_tx=$1,
+		block_num_private_tx=$2,
+		block_private_tx_share=$3
+		WHERE slot=$4 AND block_hash=$5`
+	_, err = db.DB.Exec(query, result.NumTx, result.NumPrivateTx, fmt.Sprintf("%.4f", result.Share), entry.Slot, blockHash)
+	if err != nil {
+		_log.WithError(err).Error("failed to save private tx share")
+		return false, fmt.Errorf("failed to save block %s: %w", blockHash, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	dbvars "github.com/flashbots/relayscan/database/vars"
+	"github.com/flashbots/relayscan/services/privatetx"
+	"github.com/flashbots/relayscan/testutil/ethchain"
+	"github.com/flashbots/relayscan/testutil/testdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAnalyzePrivateTx(t *testing.T) {
+	db := testdb.New(t)
+
+	sender := newTestAccount(t)
+	txPublic := ethchain.NewTransfer(sender.key, 0, ethcommon.Address{0x01}, big.NewInt(1))
+	txPrivate := ethchain.NewTransfer(sender.key, 1, ethcommon.Address{0x01}, big.NewInt(1))
+	txPrivate2 := ethchain.NewTransfer(sender.key, 2, ethcommon.Address{0x01}, big.NewInt(1))
+
+	chain := ethchain.New()
+	block := ethchain.NewBlock(100, ethcommon.Hash{0x01}, ethcommon.Address{}, nil, txPublic, txPrivate, txPrivate2)
+	chain.AddBlock(block)
+	blockTime := time.Unix(int64(block.Time()), 0) //nolint:gosec
+
+	// two relays delivered the block, one a block before the dataset
+	_, err := db.SaveDataAPIPayloadDeliveredBatch([]*database.DataAPIPayloadDeliveredEntry{
+		{Relay: "a", Slot: 10, ParentHash: "0x01", BlockHash: block.Hash().Hex(), ValueClaimedWei: "1"},
+		{Relay: "b", Slot: 10, ParentHash: "0x01", BlockHash: block.Hash().Hex(), ValueClaimedWei: "1"},
+		{Relay: "c", Slot: 5, ParentHash: "0x01", BlockHash: ethcommon.Hash{0x02}.Hex(), ValueClaimedWei: "1"},
+	})
+	require.NoError(t, err)
+	_, err = db.DB.Exec(`UPDATE `+dbvars.TableDataAPIPayloadDelivered+` SET value_check_ok=true, found_onchain=true,
+		block_timestamp=CASE WHEN slot=10 THEN $1::timestamp ELSE $2::timestamp END`, blockTime.UTC(), blockTime.Add(-time.Hour).UTC())
+	require.NoError(t, err)
+
+	dataset := privatetx.NewMempoolDataset()
+	dataset.Add(ethcommon.Hash{0x03}, blockTime.Add(-90*time.Minute).UnixMilli())
+	dataset.Add(txPublic.Hash(), blockTime.Add(-time.Second).UnixMilli())
+	dataset.Add(txPrivate.Hash(), blockTime.Add(time.Second).UnixMilli())
+	dataset.AddCoverage(blockTime.Add(-90*time.Minute), blockTime.Add(time.Second))
+
+	eth := common.NewEthNodeFromClients(log, []common.EthClient{chain}, nil)
+	opts := AnalyzePrivateTxOpts{MinHistory: time.Hour, NumThreads: 2}
+	numUpdated, err := RunAnalyzePrivateTx(db, eth, dataset, opts)
+	require.NoError(t, err)
+	require.Equal(t, 1, numUpdated)
+
+	rows := []*database.DataAPIPayloadDeliveredEntry{}
+	err = db.DB.Select(&rows, `SELECT relay, block_num_tx, block_num_private_tx, block_private_tx_share FROM `+dbvars.TableDataAPIPayloadDelivered+` ORDER BY relay`)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	for _, row := range rows[:2] {
+		require.Equal(t, int64(3), row.BlockNumTx.Int64)
+		require.Equal(t, int64(2), row.BlockNumPrivateTx.Int64)
+		require.Equal(t, "0.6667", row.BlockPrivateTxShare.String)
+	}
+	require.False(t, rows[2].BlockPrivateTxShare.Valid)
+
+	// analyzed blocks are skipped unless rechecked
+	numUpdated, err = RunAnalyzePrivateTx(db, eth, dataset, opts)
+	require.NoError(t, err)
+	require.Equal(t, 0, numUpdated)
+	opts.Recheck = true
+	numUpdated, err = RunAnalyzePrivateTx(db, eth, dataset, opts)
+	require.NoError(t, err)
+	require.Equal(t, 1, numUpdated)
+
+	// per builder aggregate
+	_, err = db.DB.Exec(`UPDATE ` + dbvars.TableDataAPIPayloadDelivered + ` SET extra_data='builder'`)
+	require.NoError(t, err)
+	entry, err := db.GetBuilderPrivateTxShare([]string{"builder"}, time.Unix(1606824023, 0), time.Now()) // since genesis
+	require.NoError(t, err)
+	require.Equal(t, &database.BuilderPrivateTxEntry{NumBlocks: 1, NumTx: 3, NumPrivateTx: 2, PrivateTxShare: "0.6667", AvgBlockShare: "0.6667"}, entry)
+}
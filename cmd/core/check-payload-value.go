@@ -2,15 +2,16 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/flashbots/relayscan/common"
 	"github.com/flashbots/relayscan/database"
 	dbvars "github.com/flashbots/relayscan/database/vars"
@@ -23,12 +24,14 @@ var (
 	limit              uint64
 	slotMax            uint64
 	slotMin            uint64
-	ethNodeURI         string
+	ethNodeURIs        []string
 	ethNodeBackupURI   string
 	checkIncorrectOnly bool
 	checkMissedOnly    bool
 	checkTx            bool
 	checkAll           bool
+
+	errInvalidClaimedValue = errors.New("invalid claimed value")
 )
 
 func init() {
@@ -37,8 +40,8 @@ func init() {
 	checkPayloadValueCmd.Flags().Uint64Var(&slotMin, "slot-min", 0, "only check slots after this one")
 	checkPayloadValueCmd.Flags().Uint64Var(&limit, "limit", 1000, "how many payloads")
 	checkPayloadValueCmd.Flags().Uint64Var(&numThreads, "threads", 10, "how many threads")
-	checkPayloadValueCmd.Flags().StringVar(&ethNodeURI, "eth-node", vars.DefaultEthNodeURI, "eth node URI (i.e. Infura)")
-	checkPayloadValueCmd.Flags().StringVar(&ethNodeBackupURI, "eth-node-backup", vars.DefaultEthBackupNodeURI, "eth node backup URI (i.e. Infura)")
+	checkPayloadValueCmd.Flags().StringSliceVar(&ethNodeURIs, "eth-node", vars.DefaultEthNodeURIs, "eth node URIs, in order of preference (i.e. Infura)")
+	checkPayloadValueCmd.Flags().StringVar(&ethNodeBackupURI, "eth-node-backup", vars.DefaultEthBackupNodeURI, "eth node backup URI (deprecated, use several --eth-node)")
 	checkPayloadValueCmd.Flags().BoolVar(&checkIncorrectOnly, "check-incorrect", false, "whether to double-check incorrect values only")
 	checkPayloadValueCmd.Flags().BoolVar(&checkMissedOnly, "check-missed", false, "whether to double-check missed slots only")
 	checkPayloadValueCmd.Flags().BoolVar(&checkTx, "check-tx", false, "whether to check for tx from/to proposer feeRecipient")
@@ -49,19 +52,9 @@ var checkPayloadValueCmd = &cobra.Command{
 	Use:   "check-payload-value",
 	Short: "Check payload value for delivered payloads",
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := ethclient.Dial(ethNodeURI)
+		ethNode, err := common.ConnectEthNode(log, append(ethNodeURIs, ethNodeBackupURI)...)
 		if err != nil {
-			log.Fatalf("Failed to create RPC client for '%s'", ethNodeURI)
-		}
-		log.Infof("Using eth node: %s", ethNodeURI)
-
-		client2 := client
-		if ethNodeBackupURI != "" {
-			client2, err = ethclient.Dial(ethNodeBackupURI)
-			if err != nil {
-				log.Fatalf("Failed to create backup RPC client for '%s'", ethNodeBackupURI)
-			}
-			log.Infof("Using eth backup node: %s", ethNodeBackupURI)
+			log.WithError(err).Fatal("failed to connect to eth nodes")
 		}
 
 		// Connect to Postgres
@@ -79,7 +72,7 @@ var checkPayloadValueCmd = &cobra.Command{
 			CheckAll:           checkAll,
 		}
 
-		err = RunCheckPayloadValue(db, client, client2, opts)
+		err = RunCheckPayloadValue(db, ethNode, opts)
 		if err != nil {
 			log.WithError(err).Fatal("check payload value failed")
 		}
@@ -100,7 +93,7 @@ type CheckPayloadValueOpts struct {
 }
 
 // RunCheckPayloadValue checks payload values for delivered payloads
-func RunCheckPayloadValue(db *database.DatabaseService, client, client2 *ethclient.Client, opts CheckPayloadValueOpts) error {
+func RunCheckPayloadValue(db *database.DatabaseService, eth PayloadValueEthClient, opts CheckPayloadValueOpts) error {
 	startTime := time.Now().UTC()
 
 	entries := []database.DataAPIPayloadDeliveredEntry{}
@@ -145,27 +138,27 @@ func RunCheckPayloadValue(db *database.DatabaseService, client, client2 *ethclie
 		return nil
 	}
 
+	// Entries of the same slot are checked together (by one worker), so the block data is fetched only once
+	slots := groupEntriesBySlot(entries, opts.SlotMin)
+	log.Infof("checking %d slots", len(slots))
+
+	checker := &payloadValueChecker{eth: eth, checkTx: opts.CheckTx}
 	wg := new(sync.WaitGroup)
-	entryC := make(chan database.DataAPIPayloadDeliveredEntry)
 	threads := opts.NumThreads
 	if opts.Slot != 0 {
 		threads = 1
 	}
+	slotC := make(chan []*database.DataAPIPayloadDeliveredEntry, threads)
 	for i := 0; i < int(threads); i++ { //nolint:gosec,intrange
 		log.Infof("starting worker %d", i+1)
 		wg.Add(1)
-		go startUpdateWorker(wg, db, client, client2, entryC)
+		go startUpdateWorker(wg, db, checker, slotC)
 	}
 
-	for _, entry := range entries {
-		// possibly skip
-		if opts.SlotMin != 0 && entry.Slot < opts.SlotMin {
-			continue
-		}
-
-		entryC <- entry
+	for _, slotEntries := range slots {
+		slotC <- slotEntries
 	}
-	close(entryC)
+	close(slotC)
 	wg.Wait()
 
 	timeNeeded := time.Since(startTime)
@@ -173,271 +166,392 @@ func RunCheckPayloadValue(db *database.DatabaseService, client, client2 *ethclie
 	return nil
 }
 
-func _getBalanceDiff(ethClient *ethclient.Client, address ethcommon.Address, blockNumber *big.Int) (*big.Int, error) {
-	blockNumberMinusOne := new(big.Int).Sub(blockNumber, big.NewInt(1))
+// groupEntriesBySlot groups the entries by slot (keeping the order of the first entry of each slot), skipping slots
+// before minSlot
+func groupEntriesBySlot(entries []database.DataAPIPayloadDeliveredEntry, minSlot uint64) (slots [][]*database.DataAPIPayloadDeliveredEntry) {
+	slotIndex := make(map[uint64]int)
+	for i := range entries {
+		entry := &entries[i]
+		if minSlot != 0 && entry.Slot < minSlot {
+			continue
+		}
+		index, found := slotIndex[entry.Slot]
+		if !found {
+			index = len(slots)
+			slotIndex[entry.Slot] = index
+			slots = append(slots, nil)
+		}
+		slots[index] = append(slots[index], entry)
+	}
+	return slots
+}
+
+// PayloadValueEthClient is the execution client access needed for the payload value check (i.e. common.EthNode)
+type PayloadValueEthClient interface {
+	BlockByHash(ctx context.Context, hash ethcommon.Hash) (*types.Block, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BalancesAt(ctx context.Context, queries []common.BalanceQuery) ([]*big.Int, error)
+	ReceiptsByBlockHash(ctx context.Context, hash ethcommon.Hash) ([]*types.Receipt, error)
+}
+
+// payloadValueChecker checks the delivered value of payloads on-chain
+type payloadValueChecker struct {
+	eth     PayloadValueEthClient
+	checkTx bool
+}
+
+// payloadBlock is the on-chain data for a block hash, shared by all entries (relays) that delivered it
+type payloadBlock struct {
+	block        *types.Block // nil if not found on-chain
+	slotMissed   bool         // another block with the same number is canonical
+	balanceDiffs map[ethcommon.Address]*big.Int
+	fees         *blockFees // nil if the receipts don't match the block
+}
+
+// blockFees is the on-chain composition of the block value
+type blockFees struct {
+	burned            *big.Int // base fees and blob fees
+	priorityFees      *big.Int // tips paid to the coinbase
+	coinbaseTransfers *big.Int // direct payments to the coinbase, including internal calls
+}
+
+// getBlock fetches a block, and the balance diffs of the given addresses and the coinbase (plus the coinbase nonce) in
+// one batched request
+func (c *payloadValueChecker) getBlock(_log *logrus.Entry, blockHash string, addresses []ethcommon.Address) (*payloadBlock, error) {
+	// query block by hash
+	block, err := c.eth.BlockByHash(context.Background(), ethcommon.HexToHash(blockHash))
+	if errors.Is(err, ethereum.NotFound) {
+		_log.WithError(err).Warnf("block by hash not found: %s", blockHash)
+		return &payloadBlock{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error querying block by hash %s: %w", blockHash, err)
+	}
+
+	// query block by number to ensure that's what landed on-chain
+	//
+	// TODO: This reports "slot is missed" when actually an EL block with that number is there, but the hash is different.
+	//       Should refactor this to instead say elBlockHashMismatch (and save both hashes)
+	blockByNum, err := c.eth.HeaderByNumber(context.Background(), block.Number())
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get block by number %d: %w", block.NumberU64(), err)
+	} else if blockByNum.Hash() != block.Hash() {
+		_log.Warnf("block hash mismatch when checking by number. probably missed slot! entry hash: %s / by number: %s", blockHash, blockByNum.Hash().Hex())
+		return &payloadBlock{block: block, slotMissed: true}, nil
+	}
 
-	balanceBefore, err := ethClient.BalanceAt(context.TODO(), address, blockNumberMinusOne)
+	// Balances before and after the block, for all addresses in a single batch
+	addresses = append(addresses, block.Coinbase())
+	queries := []common.BalanceQuery{}
+	balanceDiffs := make(map[ethcommon.Address]*big.Int)
+	blockNumberMinusOne := new(big.Int).Sub(block.Number(), big.NewInt(1))
+	for _, address := range addresses {
+		if balanceDiffs[address] != nil {
+			continue
+		}
+		balanceDiffs[address] = new(big.Int)
+		queries = append(queries, common.BalanceQuery{Address: address, BlockNumber: blockNumberMinusOne}, common.BalanceQuery{Address: address, BlockNumber: block.Number()})
+	}
+	// The coinbase nonce diff is the number of transactions sent by the coinbase, so senders are only recovered if needed
+	queries = append(queries, common.BalanceQuery{Address: block.Coinbase(), BlockNumber: blockNumberMinusOne, Nonce: true}, common.BalanceQuery{Address: block.Coinbase(), BlockNumber: block.Number(), Nonce: true})
+	balances, err := c.eth.BalancesAt(context.Background(), queries)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't get balance for %s @ %d", address, blockNumberMinusOne) //nolint
+		return nil, fmt.Errorf("couldn't get balance diffs: %w", err)
+	}
+	for i := 0; i < len(queries)-2; i += 2 {
+		balanceDiffs[queries[i].Address] = new(big.Int).Sub(balances[i+1], balances[i])
 	}
+	numCoinbaseTxs := new(big.Int).Sub(balances[len(balances)-1], balances[len(balances)-2]).Uint64()
 
-	balanceAfter, err := ethClient.BalanceAt(context.TODO(), address, blockNumber)
+	receipts, err := c.eth.ReceiptsByBlockHash(context.Background(), block.Hash())
 	if err != nil {
-		return nil, fmt.Errorf("couldn't get balance for %s @ %d", address, blockNumber) //nolint
+		return nil, fmt.Errorf("couldn't get receipts: %w", err)
 	}
-	balanceDiff := new(big.Int).Sub(balanceAfter, balanceBefore)
-	return balanceDiff, nil
+	if len(receipts) != len(block.Transactions()) {
+		_log.Warnf("got %d receipts for %d transactions, skipping block fees", len(receipts), len(block.Transactions()))
+		return &payloadBlock{block: block, balanceDiffs: balanceDiffs}, nil
+	}
+	fees := computeBlockFees(block, receipts, balanceDiffs[block.Coinbase()], numCoinbaseTxs)
+	return &payloadBlock{block: block, balanceDiffs: balanceDiffs, fees: fees}, nil
 }
 
-// func startUpdateWorker(wg *sync.WaitGroup, db *database.DatabaseService, client, client2 *flashbotsrpc.FlashbotsRPC, entryC chan database.DataAPIPayloadDeliveredEntry, bn *beaconclient.ProdBeaconInstance) {
-func startUpdateWorker(wg *sync.WaitGroup, db *database.DatabaseService, client, client2 *ethclient.Client, entryC chan database.DataAPIPayloadDeliveredEntry) {
-	defer wg.Done()
+// computeBlockFees returns the burned fees and priority fees of all transactions, and the direct transfers to the
+// coinbase. Because transfers by internal calls aren't visible in the transactions, they are derived from the coinbase
+// balance diff: what's left after the priority fees, the transactions sent by the coinbase (i.e. the proposer payment)
+// and withdrawals to the coinbase. numCoinbaseTxs is the nonce diff of the coinbase: senders are recovered from the end of
+// the block (where the proposer payment is) until all transactions of the coinbase are found.
+func computeBlockFees(block *types.Block, receipts []*types.Receipt, coinbaseBalanceDiff *big.Int, numCoinbaseTxs uint64) *blockFees {
+	baseFee := big.NewInt(0)
+	if block.BaseFee() != nil {
+		baseFee = block.BaseFee()
+	}
 
-	getBalanceDiff := func(address ethcommon.Address, blockNumber *big.Int) (*big.Int, error) {
-		r, err := _getBalanceDiff(client, address, blockNumber)
-		if err != nil {
-			r, err = _getBalanceDiff(client2, address, blockNumber)
+	fees := &blockFees{burned: new(big.Int), priorityFees: new(big.Int)}
+	coinbaseSpent := new(big.Int)
+	txs := block.Transactions()
+	for i := len(txs) - 1; i >= 0; i-- {
+		tx, receipt := txs[i], receipts[i]
+		gasUsed := new(big.Int).SetUint64(receipt.GasUsed)
+		tip := new(big.Int).Sub(receipt.EffectiveGasPrice, baseFee)
+		fees.priorityFees.Add(fees.priorityFees, tip.Mul(tip, gasUsed))
+		fees.burned.Add(fees.burned, new(big.Int).Mul(baseFee, gasUsed))
+
+		blobFee := new(big.Int)
+		if receipt.BlobGasPrice != nil {
+			blobFee.Mul(new(big.Int).SetUint64(receipt.BlobGasUsed), receipt.BlobGasPrice)
+			fees.burned.Add(fees.burned, blobFee)
 		}
-		return r, err
-	}
 
-	getBlockByHash := func(blockHashHex string) (*types.Block, error) {
-		blockHash := ethcommon.HexToHash(blockHashHex)
-		block, err := client.BlockByHash(context.Background(), blockHash)
-		if err != nil || block == nil {
-			block, err = client2.BlockByHash(context.Background(), blockHash)
+		if numCoinbaseTxs == 0 {
+			continue
+		}
+		txFrom, err := common.TxSender(tx)
+		if err != nil || txFrom != block.Coinbase() {
+			continue
+		}
+		numCoinbaseTxs--
+		coinbaseSpent.Add(coinbaseSpent, new(big.Int).Mul(gasUsed, receipt.EffectiveGasPrice))
+		coinbaseSpent.Add(coinbaseSpent, blobFee)
+		if receipt.Status == types.ReceiptStatusSuccessful {
+			coinbaseSpent.Add(coinbaseSpent, tx.Value())
 		}
-		return block, err
 	}
 
-	getHeaderByNumber := func(blockNumber *big.Int) (*types.Header, error) {
-		block, err := client.HeaderByNumber(context.Background(), blockNumber)
-		if err != nil || block == nil {
-			block, err = client2.HeaderByNumber(context.Background(), blockNumber)
-		}
-		return block, err
-	}
-
-	saveEntry := func(_log *logrus.Entry, entry database.DataAPIPayloadDeliveredEntry) {
-		query := `UPDATE ` + dbvars.TableDataAPIPayloadDelivered + ` SET
-				block_number=:block_number,
-				extra_data=:extra_data,
-				slot_missed=:slot_missed,
-				value_check_ok=:value_check_ok,
-				value_check_method=:value_check_method,
-				value_delivered_wei=:value_delivered_wei,
-				value_delivered_eth=:value_delivered_eth,
-				value_delivered_diff_wei=:value_delivered_diff_wei,
-				value_delivered_diff_eth=:value_delivered_diff_eth,
-				block_coinbase_addr=:block_coinbase_addr,
-				block_coinbase_is_proposer=:block_coinbase_is_proposer,
-				coinbase_diff_wei=:coinbase_diff_wei,
-				coinbase_diff_eth=:coinbase_diff_eth,
-				found_onchain=:found_onchain, -- should rename field, because getBlockByHash might succeed even though this slot was missed
-				num_blob_txs=:num_blob_txs,
-				num_blobs=:num_blobs,
-				block_timestamp=:block_timestamp
-				WHERE slot=:slot`
-		_, err := db.DB.NamedExec(query, entry)
-		if err != nil {
-			_log.WithError(err).Fatalf("failed to save entry")
+	withdrawn := new(big.Int)
+	for _, withdrawal := range block.Withdrawals() {
+		if withdrawal.Address == block.Coinbase() {
+			withdrawn.Add(withdrawn, new(big.Int).Mul(new(big.Int).SetUint64(withdrawal.Amount), big.NewInt(1e9))) // gwei
 		}
 	}
 
-	var err error
-	var block *types.Block
-	for entry := range entryC {
-		_log := log.WithFields(logrus.Fields{
-			"slot":        entry.Slot,
-			"blockNumber": entry.BlockNumber.Int64,
-			"blockHash":   entry.BlockHash,
-			"relay":       entry.Relay,
+	fees.coinbaseTransfers = new(big.Int).Sub(coinbaseBalanceDiff, fees.priorityFees)
+	fees.coinbaseTransfers.Add(fees.coinbaseTransfers, coinbaseSpent)
+	fees.coinbaseTransfers.Sub(fees.coinbaseTransfers, withdrawn)
+	return fees
+}
+
+// checkSlot checks all entries of a slot. The data of each distinct block is fetched only once.
+func (c *payloadValueChecker) checkSlot(_log *logrus.Entry, entries []*database.DataAPIPayloadDeliveredEntry) error {
+	feeRecipients := make(map[string][]ethcommon.Address) // map[blockHash]addresses
+	for _, entry := range entries {
+		feeRecipients[entry.BlockHash] = append(feeRecipients[entry.BlockHash], ethcommon.HexToAddress(entry.ProposerFeeRecipient))
+	}
+
+	blocks := make(map[string]*payloadBlock) // map[blockHash]block
+	for _, entry := range entries {
+		_log := _log.WithFields(logrus.Fields{
+			"blockHash": entry.BlockHash,
+			"relay":     entry.Relay,
 		})
-		_log.Infof("checking slot %d ...", entry.Slot)
-		claimedProposerValue, ok := new(big.Int).SetString(entry.ValueClaimedWei, 10)
-		if !ok {
-			_log.Fatalf("couldn't convert claimed value to big.Int: %s", entry.ValueClaimedWei)
+
+		block, found := blocks[entry.BlockHash]
+		if !found {
+			var err error
+			block, err = c.getBlock(_log, entry.BlockHash, feeRecipients[entry.BlockHash])
+			if err != nil {
+				return err
+			}
+			blocks[entry.BlockHash] = block
 		}
 
-		// // Check if slot was delivered
-		// _log.Infof("%d - %d = %d", headSlot, entry.Slot, headSlot-entry.Slot)
-		// if headSlot-entry.Slot < 30_000 { // before, my BN always returns the error
-		// 	_, err := bn.GetHeaderForSlot(entry.Slot)
-		// 	entry.SlotWasMissed = database.NewNullBool(false)
-		// 	if err != nil {
-		// 		if strings.Contains(err.Error(), "Could not find requested block") {
-		// 			entry.SlotWasMissed = database.NewNullBool(true)
-		// 			_log.Warn("couldn't find block in beacon node, probably missed the proposal!")
-		// 		} else {
-		// 			_log.WithError(err).Fatalf("couldn't get slot from BN")
-		// 		}
-		// 	}
-		// }
-
-		// query block by hash
-		block, err = getBlockByHash(entry.BlockHash)
+		err := c.checkEntry(_log, entry, block)
 		if err != nil {
-			if err.Error() == "not found" {
-				_log.WithError(err).Warnf("block by hash not found: %s", entry.BlockHash)
-				_log.WithError(err).Warnf("block not found: %s", entry.BlockHash)
-				entry.FoundOnChain = database.NewNullBool(false)
-				saveEntry(_log, entry)
-				continue
-			} else {
-				_log.WithError(err).Fatalf("error querying block by hash: %s", entry.BlockHash)
-			}
+			return err
 		}
+	}
+	return nil
+}
+
+// saveCheckedSlot saves the check results of all rows of a slot in a single transaction. Rows are updated by id, because
+// relays might have delivered different blocks (or claimed different values) for the same slot.
+func saveCheckedSlot(db *database.DatabaseService, entries []*database.DataAPIPayloadDeliveredEntry) error {
+	query := `UPDATE ` + dbvars.TableDataAPIPayloadDelivered + ` SET
+			block_number=:block_number,
+			extra_data=:extra_data,
+			slot_missed=:slot_missed,
+			value_check_ok=:value_check_ok,
+			value_check_method=:value_check_method,
+			value_delivered_wei=:value_delivered_wei,
+			value_delivered_eth=:value_delivered_eth,
+			value_delivered_diff_wei=:value_delivered_diff_wei,
+			value_delivered_diff_eth=:value_delivered_diff_eth,
+			block_coinbase_addr=:block_coinbase_addr,
+			block_coinbase_is_proposer=:block_coinbase_is_proposer,
+			coinbase_diff_wei=:coinbase_diff_wei,
+			coinbase_diff_eth=:coinbase_diff_eth,
+			found_onchain=:found_onchain, -- should rename field, because getBlockByHash might succeed even though this slot was missed
+			num_blob_txs=:num_blob_txs,
+			num_blobs=:num_blobs,
+			block_timestamp=:block_timestamp,
+			block_gas_used=:block_gas_used,
+			block_base_fee=:block_base_fee,
+			block_num_tx=:block_num_tx,
+			block_burned_wei=:block_burned_wei,
+			block_burned_eth=:block_burned_eth,
+			block_priority_fees_wei=:block_priority_fees_wei,
+			block_priority_fees_eth=:block_priority_fees_eth,
+			block_coinbase_transfers_wei=:block_coinbase_transfers_wei,
+			block_coinbase_transfers_eth=:block_coinbase_transfers_eth
+			WHERE id=:id`
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
 
-		// We found this block by hash, it's on chain
-		entry.FoundOnChain = database.NewNullBool(true)
+	stmt, err := tx.PrepareNamed(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
 
-		if !entry.BlockNumber.Valid {
-			entry.BlockNumber = database.NewNullInt64(block.Number().Int64())
+	for _, entry := range entries {
+		_, err = stmt.Exec(entry)
+		if err != nil {
+			return fmt.Errorf("couldn't update entry %d (relay %s): %w", entry.ID, entry.Relay, err)
 		}
+	}
+	return tx.Commit()
+}
 
-		entry.BlockCoinbaseAddress = database.NewNullString(block.Coinbase().Hex())
-		coinbaseIsProposer := strings.EqualFold(block.Coinbase().Hex(), entry.ProposerFeeRecipient)
-		entry.BlockCoinbaseIsProposer = database.NewNullBool(coinbaseIsProposer)
+func startUpdateWorker(wg *sync.WaitGroup, db *database.DatabaseService, checker *payloadValueChecker, slotC chan []*database.DataAPIPayloadDeliveredEntry) {
+	defer wg.Done()
 
-		entryBlockHash := ethcommon.HexToHash(entry.BlockHash)
+	for entries := range slotC {
+		slot := entries[0].Slot
+		_log := log.WithFields(logrus.Fields{
+			"slot":       slot,
+			"numEntries": len(entries),
+		})
+		_log.Infof("checking slot %d ...", slot)
 
-		// query block by number to ensure that's what landed on-chain
-		//
-		// TODO: This reports "slot is missed" when actually an EL block with that number is there, but the hash is different.
-		//       Should refactor this to instead say elBlockHashMismatch (and save both hashes)
-		blockByNum, err := getHeaderByNumber(block.Number())
+		err := checker.checkSlot(_log, entries)
 		if err != nil {
-			_log.WithError(err).Fatalf("couldn't get block by number %d", block.NumberU64())
-		} else if blockByNum == nil {
-			_log.WithError(err).Warnf("block by number not found: %d", block.NumberU64())
-			continue
-		} else if blockByNum.Hash() != entryBlockHash {
-			_log.Warnf("block hash mismatch when checking by number. probably missed slot! entry hash: %s / by number: %s", entry.BlockHash, blockByNum.Hash().Hex())
-			entry.SlotWasMissed = database.NewNullBool(true)
-			saveEntry(_log, entry)
-			continue
+			_log.WithError(err).Fatal("failed to check payload value")
 		}
 
-		// Block was found on chain and is same for this blocknumber. Now check the payment!
-		checkMethod := "balanceDiff"
-		proposerFeeRecipientAddr := ethcommon.HexToAddress(entry.ProposerFeeRecipient)
-		proposerBalanceDiffWei, err := getBalanceDiff(proposerFeeRecipientAddr, block.Number())
+		err = saveCheckedSlot(db, entries)
 		if err != nil {
-			_log.WithError(err).Fatalf("couldn't get balance diff")
+			_log.WithError(err).Fatalf("failed to save entries")
 		}
+	}
+}
 
-		txs := block.Transactions()
-
-		proposerValueDiffFromClaim := new(big.Int).Sub(claimedProposerValue, proposerBalanceDiffWei)
-		if proposerValueDiffFromClaim.String() != "0" {
-			// Value delivered is off. Might be due to a forwarder contract... Checking payment tx...
-			checkMethod = "balanceDiff+txValue"
-			isDeliveredValueIncorrect := true
-			if len(txs) > 0 {
-				paymentTx := txs[len(txs)-1]
-				if paymentTx.To().Hex() == entry.ProposerFeeRecipient {
-					proposerValueDiffFromClaim = new(big.Int).Sub(claimedProposerValue, paymentTx.Value())
-					if proposerValueDiffFromClaim.String() == "0" {
-						_log.Debug("all good, payment is in last tx but was probably forwarded through smart contract")
-						isDeliveredValueIncorrect = false
-					}
-				}
-			}
+// checkEntry checks the payment of the entry in the block, and updates the entry
+func (c *payloadValueChecker) checkEntry(_log *logrus.Entry, entry *database.DataAPIPayloadDeliveredEntry, data *payloadBlock) error {
+	claimedProposerValue, ok := new(big.Int).SetString(entry.ValueClaimedWei, 10)
+	if !ok {
+		return fmt.Errorf("%w: %s", errInvalidClaimedValue, entry.ValueClaimedWei)
+	}
 
-			if isDeliveredValueIncorrect {
-				_log.Warnf("Value delivered to %s diffs by %s from claim. delivered: %s - claim: %s - relay: %s - slot: %d / block: %d", entry.ProposerFeeRecipient, proposerValueDiffFromClaim.String(), proposerBalanceDiffWei, entry.ValueClaimedWei, entry.Relay, entry.Slot, block.NumberU64())
-			}
-		}
+	if data.block == nil {
+		entry.FoundOnChain = database.NewNullBool(false)
+		return nil
+	}
 
-		// check for transactions to/from proposer feeRecipient
-		if checkTx {
-			log.Infof("checking %d tx...", len(txs))
-
-			for i, tx := range txs {
-				if tx.ChainId().Uint64() == 0 {
-					continue
-				}
-				txFrom, _ := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
-				if txFrom.Hex() == entry.ProposerFeeRecipient {
-					_log.Infof("- tx %d from feeRecipient with value %s", i, tx.Value().String())
-					proposerValueDiffFromClaim = new(big.Int).Add(proposerValueDiffFromClaim, tx.Value())
-				} else if tx.To().Hex() == entry.ProposerFeeRecipient {
-					_log.Infof("- tx %d to feeRecipient with value %s", i, tx.Value().String())
-				}
-			}
-		}
+	// We found this block by hash, it's on chain
+	block := data.block
+	entry.FoundOnChain = database.NewNullBool(true)
 
-		// find number of blob transactions
-		numBlobTxs := 0
-		numBlobs := 0
-		for _, tx := range txs {
-			if tx.Type() == types.BlobTxType {
-				numBlobTxs++
-				numBlobs += len(tx.BlobHashes())
-			}
-		}
-		entry.NumBlobTxs = database.NewNullInt64(int64(numBlobTxs))
-		entry.NumBlobs = database.NewNullInt64(int64(numBlobs))
-
-		entry.ExtraData = database.ExtraDataToUtf8Str(block.Extra())
-		entry.ValueCheckOk = database.NewNullBool(proposerValueDiffFromClaim.String() == "0")
-		entry.ValueCheckMethod = database.NewNullString(checkMethod)
-		entry.ValueDeliveredWei = database.NewNullString(proposerBalanceDiffWei.String())
-		entry.ValueDeliveredEth = database.NewNullString(common.WeiToEth(proposerBalanceDiffWei).String())
-		entry.ValueDeliveredDiffWei = database.NewNullString(proposerValueDiffFromClaim.String())
-		entry.ValueDeliveredDiffEth = database.NewNullString(common.WeiToEth(proposerValueDiffFromClaim).String())
-
-		// set block timestamp
-		blockTime := time.Unix(int64(block.Time()), 0).UTC() //nolint:gosec
-		entry.BlockTimestamp = database.NewNullTime(blockTime)
-
-		log.WithFields(logrus.Fields{
-			"coinbaseIsProposer": coinbaseIsProposer,
-			// "coinbase":                 block.Miner,
-			// "proposerFeeRec(reported)": entry.ProposerFeeRecipient,
-			"valueCheckOk":     entry.ValueCheckOk.Bool,
-			"valueCheckMethod": entry.ValueCheckMethod.String,
-			// "valueDeliveredWei":       entry.ValueDeliveredWei,
-			"valueDeliveredEth": entry.ValueDeliveredEth.String,
-			// "valueDeliveredDiffWei":   entry.ValueDeliveredDiffWei,
-			"valueDeliveredDiffEth": entry.ValueDeliveredDiffEth.String,
-			"numBlobTxs":            numBlobTxs,
-			"numBlobs":              numBlobs,
-		}).Info("value check done")
-
-		if !coinbaseIsProposer {
-			// Get builder profit/subsidy, taking into account possible tx from coinbase to builder-owned address
-			// First, get the overall balance diff
-			builderBalanceDiffWei, err := getBalanceDiff(block.Coinbase(), block.Number())
-			if err != nil {
-				_log.WithError(err).Fatalf("couldn't get balance diff")
-			}
+	if !entry.BlockNumber.Valid {
+		entry.BlockNumber = database.NewNullInt64(block.Number().Int64())
+	}
+
+	entry.BlockCoinbaseAddress = database.NewNullString(block.Coinbase().Hex())
+	coinbaseIsProposer := strings.EqualFold(block.Coinbase().Hex(), entry.ProposerFeeRecipient)
+	entry.BlockCoinbaseIsProposer = database.NewNullBool(coinbaseIsProposer)
 
-			// Second, adjust for any tx from coinbase to builder-owned address.
-			builderOwnedAddresses := vars.BuilderAddresses[strings.ToLower(block.Coinbase().Hex())]
-			_log.Infof("builderOwnedAddresses for %s: %+v (slot %d)", block.Coinbase().Hex(), builderOwnedAddresses, entry.Slot)
-			for _, tx := range txs {
-				if tx.ChainId().Uint64() == 0 {
-					continue
-				}
-
-				txFrom, _ := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
-				isFromBuilderCoinbase := txFrom.Hex() == block.Coinbase().Hex()
-				isToBuilderOwnedAddress := false
-				if builderOwnedAddresses != nil && tx.To() != nil && builderOwnedAddresses[strings.ToLower(tx.To().Hex())] {
-					isToBuilderOwnedAddress = true
-				}
-
-				if isFromBuilderCoinbase && isToBuilderOwnedAddress {
-					_log.Infof("- Tx from coinbase to builder address found: %s -- adjusting value by +%s ETH", tx.Hash().Hex(), common.WeiToEth(tx.Value()).String())
-					builderBalanceDiffWei = new(big.Int).Add(builderBalanceDiffWei, tx.Value())
-				}
+	if data.slotMissed {
+		entry.SlotWasMissed = database.NewNullBool(true)
+		return nil
+	}
+
+	// Block was found on chain and is same for this blocknumber. Now check the payment!
+	feeRecipient := ethcommon.HexToAddress(entry.ProposerFeeRecipient)
+	proposerBalanceDiffWei := data.balanceDiffs[feeRecipient]
+	payment := common.CheckProposerPayment(block, feeRecipient, proposerBalanceDiffWei, claimedProposerValue)
+	checkMethod := payment.Method
+	proposerValueDiffFromClaim := payment.DiffWei
+	if payment.Ok() && checkMethod == common.PaymentCheckMethodBalanceDiffTxValue {
+		_log.Debug("all good, payment is in last tx but was probably forwarded through smart contract")
+	} else if !payment.Ok() {
+		_log.Warnf("Value delivered to %s diffs by %s from claim. delivered: %s - claim: %s - relay: %s - slot: %d / block: %d", entry.ProposerFeeRecipient, proposerValueDiffFromClaim.String(), proposerBalanceDiffWei, entry.ValueClaimedWei, entry.Relay, entry.Slot, block.NumberU64())
+	}
+
+	// check for transactions to/from proposer feeRecipient
+	txs := block.Transactions()
+	if c.checkTx {
+		_log.Infof("checking %d tx...", len(txs))
+
+		for i, tx := range txs {
+			txFrom, _ := common.TxSender(tx)
+			if txFrom == feeRecipient {
+				_log.Infof("- tx %d from feeRecipient with value %s", i, tx.Value().String())
+				proposerValueDiffFromClaim = new(big.Int).Add(proposerValueDiffFromClaim, tx.Value())
+			} else if tx.To() != nil && *tx.To() == feeRecipient {
+				_log.Infof("- tx %d to feeRecipient with value %s", i, tx.Value().String())
 			}
+		}
+	}
 
-			// save
-			entry.CoinbaseDiffWei = database.NewNullString(builderBalanceDiffWei.String())
-			entry.CoinbaseDiffEth = database.NewNullString(common.WeiToEth(builderBalanceDiffWei).String())
+	// find number of blob transactions
+	numBlobTxs := 0
+	numBlobs := 0
+	for _, tx := range txs {
+		if tx.Type() == types.BlobTxType {
+			numBlobTxs++
+			numBlobs += len(tx.BlobHashes())
 		}
-		saveEntry(_log, entry)
 	}
+	entry.NumBlobTxs = database.NewNullInt64(int64(numBlobTxs))
+	entry.NumBlobs = database.NewNullInt64(int64(numBlobs))
+
+	entry.ExtraData = database.ExtraDataToUtf8Str(block.Extra())
+	entry.ValueCheckOk = database.NewNullBool(proposerValueDiffFromClaim.String() == "0")
+	entry.ValueCheckMethod = database.NewNullString(checkMethod)
+	entry.ValueDeliveredWei = database.NewNullString(proposerBalanceDiffWei.String())
+	entry.ValueDeliveredEth = database.NewNullString(common.WeiToEth(proposerBalanceDiffWei).String())
+	entry.ValueDeliveredDiffWei = database.NewNullString(proposerValueDiffFromClaim.String())
+	entry.ValueDeliveredDiffEth = database.NewNullString(common.WeiToEth(proposerValueDiffFromClaim).String())
+
+	// set block timestamp
+	blockTime := time.Unix(int64(block.Time()), 0).UTC() //nolint:gosec
+	entry.BlockTimestamp = database.NewNullTime(blockTime)
+	entry.BlockGasUsed = database.NewNullInt64(int64(block.GasUsed())) //nolint:gosec
+	if block.BaseFee() != nil {
+		entry.BlockBaseFee = database.NewNullString(block.BaseFee().String())
+	}
+	entry.BlockNumTx = database.NewNullInt64(int64(len(txs)))
+	if data.fees != nil {
+		entry.BlockBurnedWei = database.NewNullString(data.fees.burned.String())
+		entry.BlockBurnedEth = database.NewNullString(common.WeiToEth(data.fees.burned).String())
+		entry.BlockPriorityFeesWei = database.NewNullString(data.fees.priorityFees.String())
+		entry.BlockPriorityFeesEth = database.NewNullString(common.WeiToEth(data.fees.priorityFees).String())
+		entry.BlockCoinbaseTransfersWei = database.NewNullString(data.fees.coinbaseTransfers.String())
+		entry.BlockCoinbaseTransfersEth = database.NewNullString(common.WeiToEth(data.fees.coinbaseTransfers).String())
+	}
+
+	_log.WithFields(logrus.Fields{
+		"coinbaseIsProposer": coinbaseIsProposer,
+		// "coinbase":                 block.Miner,
+		// "proposerFeeRec(reported)": entry.ProposerFeeRecipient,
+		"valueCheckOk":     entry.ValueCheckOk.Bool,
+		"valueCheckMethod": entry.ValueCheckMethod.String,
+		// "valueDeliveredWei":       entry.ValueDeliveredWei,
+		"valueDeliveredEth": entry.ValueDeliveredEth.String,
+		// "valueDeliveredDiffWei":   entry.ValueDeliveredDiffWei,
+		"valueDeliveredDiffEth": entry.ValueDeliveredDiffEth.String,
+		"numBlobTxs":            numBlobTxs,
+		"numBlobs":              numBlobs,
+	}).Info("value check done")
+
+	if !coinbaseIsProposer {
+		// Get builder profit/subsidy, taking into account possible tx from coinbase to builder-owned address
+		builderBalanceDiffWei := common.BuilderProfit(block, data.balanceDiffs[block.Coinbase()])
+
+		// save
+		entry.CoinbaseDiffWei = database.NewNullString(builderBalanceDiffWei.String())
+		entry.CoinbaseDiffEth = database.NewNullString(common.WeiToEth(builderBalanceDiffWei).String())
+	}
+	return nil
 }
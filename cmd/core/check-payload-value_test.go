@@ -0,0 +1,396 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	dbvars "github.com/flashbots/relayscan/database/vars"
+	"github.com/flashbots/relayscan/testutil/ethchain"
+	"github.com/flashbots/relayscan/testutil/testdb"
+	"github.com/flashbots/relayscan/vars"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestEthNode = errors.New("eth node error")
+
+type testAccount struct {
+	key     *ecdsa.PrivateKey
+	address ethcommon.Address
+}
+
+func newTestAccount(t *testing.T) testAccount {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return testAccount{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+func TestCheckPayloadValue(t *testing.T) {
+	builder := newTestAccount(t)
+	proposer := newTestAccount(t)
+	user := newTestAccount(t)
+	builderOwned := newTestAccount(t)
+
+	const blockNumber = 100
+
+	// the block depends on the test case, the balances are the same for all:
+	// the proposer receives 1000 wei (or 900 with forwarding), the builder coinbase 300 wei
+	payment := ethchain.NewTransfer(builder.key, 0, proposer.address, big.NewInt(1000))
+	transfer := ethchain.NewTransfer(user.key, 0, user.address, big.NewInt(1))
+
+	testCases := []struct {
+		name            string
+		claim           string
+		coinbase        ethcommon.Address
+		txs             []*types.Transaction
+		proposerDiff    int64
+		notFound        bool // the block isn't known at all
+		missed          bool // the block is known by hash, but another one is canonical
+		failFirstClient bool
+
+		expectFoundOnChain bool
+		expectSlotMissed   bool
+		expectValueCheckOk bool
+		expectMethod       string
+		expectDiffWei      string
+		expectCoinbaseDiff string // empty if the coinbase is the proposer
+		expectNumBlobTxs   int64
+		expectNumBlobs     int64
+	}{
+		{
+			name:               "balance diff matches claim",
+			claim:              "1000",
+			coinbase:           builder.address,
+			txs:                []*types.Transaction{transfer, payment},
+			proposerDiff:       1000,
+			expectFoundOnChain: true,
+			expectValueCheckOk: true,
+			expectMethod:       "balanceDiff",
+			expectDiffWei:      "0",
+			expectCoinbaseDiff: "300",
+		},
+		{
+			name:               "balance diff lower, payment in last tx",
+			claim:              "1000",
+			coinbase:           builder.address,
+			txs:                []*types.Transaction{transfer, payment},
+			proposerDiff:       900,
+			expectFoundOnChain: true,
+			expectValueCheckOk: true,
+			expectMethod:       "balanceDiff+txValue",
+			expectDiffWei:      "0",
+			expectCoinbaseDiff: "300",
+		},
+		{
+			name:               "balance diff lower, no payment tx",
+			claim:              "1000",
+			coinbase:           builder.address,
+			txs:                []*types.Transaction{payment, transfer},
+			proposerDiff:       900,
+			expectFoundOnChain: true,
+			expectValueCheckOk: false,
+			expectMethod:       "balanceDiff+txValue",
+			expectDiffWei:      "100",
+			expectCoinbaseDiff: "300",
+		},
+		{
+			name:               "claim higher than payment tx",
+			claim:              "1200",
+			coinbase:           builder.address,
+			txs:                []*types.Transaction{transfer, payment},
+			proposerDiff:       1000,
+			expectFoundOnChain: true,
+			expectValueCheckOk: false,
+			expectMethod:       "balanceDiff+txValue",
+			expectDiffWei:      "200",
+			expectCoinbaseDiff: "300",
+		},
+		{
+			name:               "coinbase is proposer",
+			claim:              "1000",
+			coinbase:           proposer.address,
+			txs:                []*types.Transaction{transfer},
+			proposerDiff:       1000,
+			expectFoundOnChain: true,
+			expectValueCheckOk: true,
+			expectMethod:       "balanceDiff",
+			expectDiffWei:      "0",
+		},
+		{
+			name:               "tx from coinbase to builder-owned address",
+			claim:              "1000",
+			coinbase:           builder.address,
+			txs:                []*types.Transaction{ethchain.NewTransfer(builder.key, 1, builderOwned.address, big.NewInt(200)), payment},
+			proposerDiff:       1000,
+			expectFoundOnChain: true,
+			expectValueCheckOk: true,
+			expectMethod:       "balanceDiff",
+			expectDiffWei:      "0",
+			expectCoinbaseDiff: "500",
+		},
+		{
+			name:     "blob transactions",
+			claim:    "1000",
+			coinbase: builder.address,
+			txs: []*types.Transaction{
+				ethchain.NewBlobTx(user.key, 0, user.address, 1),
+				ethchain.NewBlobTx(user.key, 1, user.address, 3),
+				payment,
+			},
+			proposerDiff:       1000,
+			expectFoundOnChain: true,
+			expectValueCheckOk: true,
+			expectMethod:       "balanceDiff",
+			expectDiffWei:      "0",
+			expectCoinbaseDiff: "300",
+			expectNumBlobTxs:   2,
+			expectNumBlobs:     4,
+		},
+		{
+			name:               "block not found",
+			claim:              "1000",
+			coinbase:           builder.address,
+			notFound:           true,
+			expectFoundOnChain: false,
+		},
+		{
+			name:               "missed slot",
+			claim:              "1000",
+			coinbase:           builder.address,
+			txs:                []*types.Transaction{payment},
+			missed:             true,
+			expectFoundOnChain: true,
+			expectSlotMissed:   true,
+		},
+		{
+			name:               "failover to the second client",
+			claim:              "1000",
+			coinbase:           builder.address,
+			txs:                []*types.Transaction{payment},
+			proposerDiff:       1000,
+			failFirstClient:    true,
+			expectFoundOnChain: true,
+			expectValueCheckOk: true,
+			expectMethod:       "balanceDiff",
+			expectDiffWei:      "0",
+			expectCoinbaseDiff: "300",
+		},
+	}
+
+	coinbaseKey := strings.ToLower(builder.address.Hex())
+	vars.BuilderAddresses[coinbaseKey] = map[string]bool{strings.ToLower(builderOwned.address.Hex()): true}
+	defer delete(vars.BuilderAddresses, coinbaseKey)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chain := ethchain.New()
+			block := ethchain.NewBlock(blockNumber, ethcommon.Hash{0x01}, tc.coinbase, []byte("builder"), tc.txs...)
+			if tc.missed {
+				chain.AddOrphanedBlock(block)
+				chain.AddBlock(ethchain.NewBlock(blockNumber, ethcommon.Hash{0x02}, tc.coinbase, nil))
+			} else if !tc.notFound {
+				chain.AddBlock(block)
+			}
+			chain.SetBalance(proposer.address, blockNumber-1, big.NewInt(10_000))
+			chain.SetBalance(proposer.address, blockNumber, big.NewInt(10_000+tc.proposerDiff))
+			chain.SetBalance(builder.address, blockNumber-1, big.NewInt(5_000))
+			chain.SetBalance(builder.address, blockNumber, big.NewInt(5_300))
+
+			clients := []common.EthClient{chain}
+			if tc.failFirstClient {
+				failing := ethchain.New()
+				failing.SetError(errTestEthNode)
+				clients = []common.EthClient{failing, chain}
+			}
+			checker := &payloadValueChecker{eth: common.NewEthNodeFromClients(log, clients, nil)}
+
+			entry := &database.DataAPIPayloadDeliveredEntry{
+				Slot:                 1234,
+				BlockHash:            block.Hash().Hex(),
+				ProposerFeeRecipient: proposer.address.Hex(),
+				ValueClaimedWei:      tc.claim,
+			}
+			err := checker.checkSlot(log, []*database.DataAPIPayloadDeliveredEntry{entry})
+			require.NoError(t, err)
+			require.Equal(t, database.NewNullBool(tc.expectFoundOnChain), entry.FoundOnChain)
+			if tc.notFound {
+				return
+			}
+			require.Equal(t, tc.expectSlotMissed, entry.SlotWasMissed.Bool)
+			if tc.missed {
+				require.False(t, entry.ValueCheckOk.Valid)
+				return
+			}
+
+			require.Equal(t, int64(blockNumber), entry.BlockNumber.Int64)
+			require.Equal(t, "builder", entry.ExtraData)
+			require.Equal(t, database.NewNullBool(tc.expectValueCheckOk), entry.ValueCheckOk)
+			require.Equal(t, tc.expectMethod, entry.ValueCheckMethod.String)
+			require.Equal(t, tc.expectDiffWei, entry.ValueDeliveredDiffWei.String)
+			require.Equal(t, big.NewInt(tc.proposerDiff).String(), entry.ValueDeliveredWei.String)
+			require.Equal(t, tc.expectCoinbaseDiff, entry.CoinbaseDiffWei.String)
+			require.Equal(t, tc.expectCoinbaseDiff != "", entry.CoinbaseDiffWei.Valid)
+			require.Equal(t, tc.coinbase == proposer.address, entry.BlockCoinbaseIsProposer.Bool)
+			require.Equal(t, tc.expectNumBlobTxs, entry.NumBlobTxs.Int64)
+			require.Equal(t, tc.expectNumBlobs, entry.NumBlobs.Int64)
+			require.True(t, entry.BlockTimestamp.Valid)
+			require.True(t, entry.BlockGasUsed.Valid)
+			require.Equal(t, "7", entry.BlockBaseFee.String)
+		})
+	}
+}
+
+func TestCheckPayloadValueErrors(t *testing.T) {
+	failing := ethchain.New()
+	failing.SetError(errTestEthNode)
+	checker := &payloadValueChecker{eth: common.NewEthNodeFromClients(log, []common.EthClient{failing, failing}, nil)}
+
+	// errors other than "not found" are returned
+	entry := &database.DataAPIPayloadDeliveredEntry{BlockHash: ethcommon.Hash{0x01}.Hex(), ValueClaimedWei: "1000"}
+	err := checker.checkSlot(log, []*database.DataAPIPayloadDeliveredEntry{entry})
+	require.ErrorIs(t, err, errTestEthNode)
+
+	entry.ValueClaimedWei = "abc"
+	err = checker.checkEntry(log, entry, &payloadBlock{})
+	require.ErrorIs(t, err, errInvalidClaimedValue)
+}
+
+func TestCheckPayloadValueSharedBlock(t *testing.T) {
+	builder := newTestAccount(t)
+	proposer := newTestAccount(t)
+
+	chain := ethchain.New()
+	block := ethchain.NewBlock(100, ethcommon.Hash{0x01}, builder.address, nil, ethchain.NewTransfer(builder.key, 0, proposer.address, big.NewInt(1000)))
+	chain.AddBlock(block)
+	chain.SetBalance(proposer.address, 100, big.NewInt(1000))
+	chain.SetBalance(builder.address, 100, big.NewInt(300))
+	checker := &payloadValueChecker{eth: common.NewEthNodeFromClients(log, []common.EthClient{chain}, nil)}
+
+	// two relays delivered the same block, one of them with a wrong claim
+	entries := []*database.DataAPIPayloadDeliveredEntry{
+		{Relay: "a", Slot: 1234, BlockHash: block.Hash().Hex(), ProposerFeeRecipient: proposer.address.Hex(), ValueClaimedWei: "1000"},
+		{Relay: "b", Slot: 1234, BlockHash: block.Hash().Hex(), ProposerFeeRecipient: proposer.address.Hex(), ValueClaimedWei: "1100"},
+	}
+	err := checker.checkSlot(log, entries)
+	require.NoError(t, err)
+	require.True(t, entries[0].ValueCheckOk.Bool)
+	require.False(t, entries[1].ValueCheckOk.Bool)
+	require.Equal(t, "100", entries[1].ValueDeliveredDiffWei.String)
+	require.Equal(t, "300", entries[1].CoinbaseDiffWei.String)
+
+	// the payment tx uses 21000 gas at base fee 7 and tip 1. The coinbase received 300 in total, after paying 1000 and
+	// the fees for the payment, so 148300 came from direct transfers.
+	for _, entry := range entries {
+		require.Equal(t, int64(1), entry.BlockNumTx.Int64)
+		require.Equal(t, "147000", entry.BlockBurnedWei.String)
+		require.Equal(t, "21000", entry.BlockPriorityFeesWei.String)
+		require.Equal(t, "148300", entry.BlockCoinbaseTransfersWei.String)
+	}
+
+	// the block data is fetched once: BlockByHash, HeaderByNumber, one batch with all balances and the receipts
+	require.Equal(t, 4, chain.NumCalls())
+}
+
+func TestGroupEntriesBySlot(t *testing.T) {
+	entries := []database.DataAPIPayloadDeliveredEntry{
+		{Slot: 3, Relay: "a"},
+		{Slot: 2, Relay: "a"},
+		{Slot: 3, Relay: "b"},
+		{Slot: 1, Relay: "a"},
+	}
+	slots := groupEntriesBySlot(entries, 0)
+	require.Len(t, slots, 3)
+	require.Len(t, slots[0], 2)
+	require.Equal(t, "b", slots[0][1].Relay)
+	require.Equal(t, uint64(2), slots[1][0].Slot)
+
+	slots = groupEntriesBySlot(entries, 2)
+	require.Len(t, slots, 2)
+}
+
+func TestRunCheckPayloadValueConflictingRelays(t *testing.T) {
+	db := testdb.New(t)
+
+	builder := newTestAccount(t)
+	proposer := newTestAccount(t)
+
+	// relay a delivered the canonical block, relay b a block that isn't on chain
+	chain := ethchain.New()
+	block := ethchain.NewBlock(100, ethcommon.Hash{0x01}, builder.address, nil, ethchain.NewTransfer(builder.key, 0, proposer.address, big.NewInt(1000)))
+	chain.AddBlock(block)
+	chain.SetBalance(proposer.address, 100, big.NewInt(1000))
+
+	const slot = 1234
+	_, err := db.SaveDataAPIPayloadDeliveredBatch([]*database.DataAPIPayloadDeliveredEntry{
+		{Relay: "a", Slot: slot, ParentHash: "0x01", BlockHash: block.Hash().Hex(), ProposerFeeRecipient: proposer.address.Hex(), ValueClaimedWei: "1000"},
+		{Relay: "b", Slot: slot, ParentHash: "0x01", BlockHash: ethcommon.Hash{0x02}.Hex(), ProposerFeeRecipient: proposer.address.Hex(), ValueClaimedWei: "2000"},
+	})
+	require.NoError(t, err)
+
+	eth := common.NewEthNodeFromClients(log, []common.EthClient{chain}, nil)
+	err = RunCheckPayloadValue(db, eth, CheckPayloadValueOpts{Slot: slot, Limit: 10})
+	require.NoError(t, err)
+
+	// each row has its own results
+	rows := []*database.DataAPIPayloadDeliveredEntry{}
+	err = db.DB.Select(&rows, `SELECT relay, found_onchain, value_check_ok, block_number, block_coinbase_addr FROM `+dbvars.TableDataAPIPayloadDelivered+` ORDER BY relay`)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	require.True(t, rows[0].FoundOnChain.Bool)
+	require.True(t, rows[0].ValueCheckOk.Bool)
+	require.Equal(t, int64(100), rows[0].BlockNumber.Int64)
+	require.Equal(t, builder.address.Hex(), rows[0].BlockCoinbaseAddress.String)
+
+	require.True(t, rows[1].FoundOnChain.Valid)
+	require.False(t, rows[1].FoundOnChain.Bool)
+	require.False(t, rows[1].ValueCheckOk.Valid)
+	require.False(t, rows[1].BlockCoinbaseAddress.Valid)
+}
+
+func TestComputeBlockFees(t *testing.T) {
+	builder := newTestAccount(t)
+	user := newTestAccount(t)
+
+	// user pays the coinbase 500 and sends a blob tx, the builder's payment to the user reverts
+	txs := []*types.Transaction{
+		ethchain.NewTransfer(user.key, 0, builder.address, big.NewInt(500)),
+		ethchain.NewBlobTx(user.key, 1, user.address, 1),
+		ethchain.NewTransfer(builder.key, 0, user.address, big.NewInt(1000)),
+	}
+	header := &types.Header{Number: big.NewInt(100), Coinbase: builder.address, BaseFee: big.NewInt(7)}
+	withdrawals := []*types.Withdrawal{{Address: builder.address, Amount: 2}, {Address: user.address, Amount: 5}}
+	block := types.NewBlock(header, &types.Body{Transactions: txs, Withdrawals: withdrawals}, nil, trie.NewStackTrie(nil))
+
+	receipts := []*types.Receipt{
+		{Status: types.ReceiptStatusSuccessful, GasUsed: 21_000, EffectiveGasPrice: big.NewInt(9)},
+		{Status: types.ReceiptStatusSuccessful, GasUsed: 21_000, EffectiveGasPrice: big.NewInt(8), BlobGasUsed: 131072, BlobGasPrice: big.NewInt(2)},
+		{Status: types.ReceiptStatusFailed, GasUsed: 30_000, EffectiveGasPrice: big.NewInt(8)},
+	}
+
+	// coinbase: +500 transfer, +3*21000 tips, +30000 own tip, -30000*8 own gas, +2 gwei withdrawal
+	balanceDiff := big.NewInt(500 + 3*21_000 + 30_000 - 30_000*8 + 2_000_000_000)
+	fees := computeBlockFees(block, receipts, balanceDiff, 1)
+	require.Equal(t, "766144", fees.burned.String()) // 72000 gas at 7, and 131072 blob gas at 2
+	require.Equal(t, "93000", fees.priorityFees.String())
+	require.Equal(t, "500", fees.coinbaseTransfers.String())
+
+	// unprotected legacy payment from the coinbase (chain id 0)
+	legacyTx, err := types.SignTx(types.NewTransaction(1, user.address, big.NewInt(1000), 21_000, big.NewInt(8), nil), types.HomesteadSigner{}, builder.key)
+	require.NoError(t, err)
+	block = types.NewBlock(header, &types.Body{Transactions: []*types.Transaction{legacyTx}}, nil, trie.NewStackTrie(nil))
+	receipts = []*types.Receipt{{Status: types.ReceiptStatusSuccessful, GasUsed: 21_000, EffectiveGasPrice: big.NewInt(8)}}
+	balanceDiff = big.NewInt(21_000 - 21_000*8 - 1000) // own tip, gas and payment
+	fees = computeBlockFees(block, receipts, balanceDiff, 1)
+	require.Equal(t, "21000", fees.priorityFees.String())
+	require.Equal(t, "0", fees.coinbaseTransfers.String())
+}
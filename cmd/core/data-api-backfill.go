@@ -21,12 +21,15 @@ var (
 	minSlot    int64
 	initCursor uint64
 	pageLimit  = 100 // 100 is max on bloxroute
+
+	backfillBeaconCheckURIs []string // optional, to sanity-check the slot computed from the genesis time
 )
 
 func init() {
 	backfillDataAPICmd.Flags().StringVar(&cliRelay, "relay", "", "specific relay only")
 	backfillDataAPICmd.Flags().Uint64Var(&initCursor, "cursor", 0, "initial cursor")
 	backfillDataAPICmd.Flags().Int64Var(&minSlot, "min-slot", 0, "minimum slot (if unset, backfill until the merge, negative number for that number of slots before latest)")
+	backfillDataAPICmd.Flags().StringSliceVar(&backfillBeaconCheckURIs, "beacon-check-uri", nil, "beacon node to sanity-check the slot computed from the genesis time (optional, can be repeated)")
 }
 
 var backfillDataAPICmd = &cobra.Command{
@@ -62,8 +65,13 @@ var backfillDataAPICmd = &cobra.Command{
 		// Connect to Postgres
 		db := database.MustConnectPostgres(log, vars.DefaultPostgresDSN)
 
+		slotClock, err := common.NewSlotClock(log, backfillBeaconCheckURIs)
+		if err != nil {
+			log.WithError(err).Fatal("failed to create slot clock")
+		}
+
 		// Run backfill
-		err = RunBackfill(db, relays, initCursor, minSlot)
+		err = RunBackfill(db, relays, initCursor, minSlot, slotClock)
 		if err != nil {
 			log.WithError(err).Fatal("backfill failed")
 		}
@@ -71,7 +79,7 @@ var backfillDataAPICmd = &cobra.Command{
 }
 
 // RunBackfill runs the data API backfill for all given relays
-func RunBackfill(db *database.DatabaseService, relays []common.RelayEntry, initCursor uint64, minSlot int64) error {
+func RunBackfill(db *database.DatabaseService, relays []common.RelayEntry, initCursor uint64, minSlot int64, slotClock common.SlotClock) error {
 	startTime := time.Now().UTC()
 
 	log.Infof("Using %d relays", len(relays))
@@ -81,10 +89,9 @@ func RunBackfill(db *database.DatabaseService, relays []common.RelayEntry, initC
 
 	// If needed, get latest slot (i.e. if min-slot is negative)
 	if minSlot < 0 {
-		log.Infof("Getting latest slot from beaconcha.in for offset %d", minSlot)
-		latestSlotOnBeaconChain := common.MustGetLatestSlot()
-		log.Infof("Latest slot from beaconcha.in: %d", latestSlotOnBeaconChain)
-		minSlot = int64(latestSlotOnBeaconChain) + minSlot
+		latestSlot := slotClock.CurrentSlot()
+		log.Infof("Latest slot: %d (offset %d)", latestSlot, minSlot)
+		minSlot = int64(latestSlot) + minSlot //nolint:gosec
 	}
 
 	if minSlot != 0 {
@@ -94,10 +101,27 @@ func RunBackfill(db *database.DatabaseService, relays []common.RelayEntry, initC
 	for _, relay := range relays {
 		log.Infof("Starting backfilling for relay %s ...", relay.Hostname())
 		backfiller := newBackfiller(db, relay, initCursor, uint64(minSlot))
+		relayStartTime := time.Now()
 		err := backfiller.backfillPayloadsDelivered()
 		if err != nil {
 			log.WithError(err).WithField("relay", relay).Error("backfill failed")
 		}
+
+		// Remember the data API availability (shown on the relay page)
+		status := &database.DataAPIBackfillStatusEntry{
+			Relay:       relay.Hostname(),
+			Available:   err == nil,
+			NumRequests: backfiller.numRequests,
+			NumPayloads: backfiller.numPayloads,
+			DurationMS:  time.Since(relayStartTime).Milliseconds(),
+		}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		err = db.SaveDataAPIBackfillStatus(status)
+		if err != nil {
+			log.WithError(err).WithField("relay", relay).Error("failed to save backfill status")
+		}
 	}
 
 	timeNeeded := time.Since(startTime)
@@ -110,6 +134,10 @@ type backfiller struct {
 	db         *database.DatabaseService
 	cursorSlot uint64
 	minSlot    uint64
+
+	// stats of the last backfillPayloadsDelivered call
+	numRequests uint64
+	numPayloads uint64
 }
 
 func newBackfiller(db *database.DatabaseService, relay common.RelayEntry, cursorSlot, minSlot uint64) *backfiller {
@@ -136,6 +164,9 @@ func (bf *backfiller) backfillPayloadsDelivered() error {
 	}
 	_log.Infof("Latest payload in DB for slot: %d", latestSlotInDB)
 
+	bf.numRequests = 0
+	bf.numPayloads = 0
+
 	// 2. backfill until latest DB entry is reached
 	baseURL := bf.relay.GetURI("/relay/v1/data/bidtraces/proposer_payload_delivered")
 	cursorSlot := bf.cursorSlot
@@ -150,6 +181,7 @@ func (bf *backfiller) backfillPayloadsDelivered() error {
 		}
 		_log.WithField("url: ", url).Info("Fetching payloads...")
 		var data []relaycommon.BidTraceV2JSON
+		bf.numRequests++
 		_, err = common.SendHTTPRequest(context.Background(), *http.DefaultClient, http.MethodGet, url, nil, &data)
 		if err != nil {
 			return err
@@ -195,6 +227,7 @@ func (bf *backfiller) backfillPayloadsDelivered() error {
 			_log.WithError(err).Fatal("failed to save entries")
 			return err
 		}
+		bf.numPayloads += uint64(newEntries) //nolint:gosec
 
 		_log.WithFields(logrus.Fields{
 			"newEntries": newEntries,
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	"github.com/flashbots/relayscan/database/vars"
+	"github.com/flashbots/relayscan/testutil/mockrelay"
+	"github.com/flashbots/relayscan/testutil/testdb"
+	"github.com/stretchr/testify/require"
+)
+
+func countPayloadsDelivered(t *testing.T, db *database.DatabaseService) (count int) {
+	t.Helper()
+	require.NoError(t, db.DB.Get(&count, `SELECT COUNT(*) FROM `+vars.TableDataAPIPayloadDelivered))
+	return count
+}
+
+func TestBackfillPayloadsDelivered(t *testing.T) {
+	db := testdb.New(t)
+	relay, err := mockrelay.New()
+	require.NoError(t, err)
+	defer relay.Close()
+
+	// 250 payloads are backfilled in pages of 100 (the cursor is inclusive, so one more request is needed)
+	relay.SetPayloadsDelivered(mockrelay.GeneratePayloadsDelivered(1000, 250))
+	err = newBackfiller(db, relay.RelayEntry(), 0, 0).backfillPayloadsDelivered()
+	require.NoError(t, err)
+	require.Equal(t, 250, countPayloadsDelivered(t, db))
+	require.Equal(t, 4, relay.NumRequests(mockrelay.PathPayloadDelivered))
+
+	relayEntry := relay.RelayEntry()
+	latest, err := db.GetDataAPILatestPayloadDelivered(relayEntry.Hostname())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1249), latest.Slot)
+
+	// the next run stops at the latest slot in the database
+	relay.SetPayloadsDelivered(mockrelay.GeneratePayloadsDelivered(1000, 400))
+	err = newBackfiller(db, relay.RelayEntry(), 0, 0).backfillPayloadsDelivered()
+	require.NoError(t, err)
+	require.Equal(t, 400, countPayloadsDelivered(t, db))
+	require.Equal(t, 6, relay.NumRequests(mockrelay.PathPayloadDelivered))
+
+	// relay errors are returned
+	relay.InjectError(mockrelay.PathPayloadDelivered, http.StatusInternalServerError, "oops", 1)
+	err = newBackfiller(db, relay.RelayEntry(), 0, 0).backfillPayloadsDelivered()
+	require.Error(t, err)
+}
+
+func TestRunBackfillFixtures(t *testing.T) {
+	db := testdb.New(t)
+	relay, err := mockrelay.New()
+	require.NoError(t, err)
+	defer relay.Close()
+
+	payloads, err := mockrelay.FixturePayloadsDelivered()
+	require.NoError(t, err)
+	relay.SetPayloadsDelivered(payloads)
+
+	// a rate-limited relay doesn't stop the backfill of the others
+	rateLimited, err := mockrelay.New()
+	require.NoError(t, err)
+	defer rateLimited.Close()
+	rateLimited.InjectRateLimit(mockrelay.PathPayloadDelivered, -1)
+
+	clock := common.NewFakeSlotClockAtSlot(payloads[0].Slot + 10)
+	err = RunBackfill(db, []common.RelayEntry{rateLimited.RelayEntry(), relay.RelayEntry()}, 0, -100, clock)
+	require.NoError(t, err)
+
+	entries, err := db.GetDeliveredPayloadsForSlot(payloads[0].Slot)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, payloads[0].BlockHash, entries[0].BlockHash)
+	require.Equal(t, payloads[0].Value, entries[0].ValueClaimedWei)
+	require.Equal(t, len(payloads), countPayloadsDelivered(t, db))
+
+	// the data API availability is saved for each relay
+	relayEntry, rateLimitedEntry := relay.RelayEntry(), rateLimited.RelayEntry()
+	status, err := db.GetDataAPIBackfillStatus(relayEntry.Hostname(), 10)
+	require.NoError(t, err)
+	require.Len(t, status, 1)
+	require.True(t, status[0].Available)
+	require.Equal(t, uint64(len(payloads)), status[0].NumPayloads)
+
+	status, err = db.GetDataAPIBackfillStatus(rateLimitedEntry.Hostname(), 10)
+	require.NoError(t, err)
+	require.Len(t, status, 1)
+	require.False(t, status[0].Available)
+	require.NotEmpty(t, status[0].Error)
+}
@@ -6,31 +6,43 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/flashbots/relayscan/cmd/core"
 	"github.com/flashbots/relayscan/common"
 	"github.com/flashbots/relayscan/database"
+	"github.com/flashbots/relayscan/services/alerting"
 	"github.com/flashbots/relayscan/vars"
 	"github.com/spf13/cobra"
 )
 
 var (
-	runnerInterval       time.Duration
-	runnerEthNodeURI     string
-	runnerEthBackupURI   string
-	runnerLimit          uint64
-	runnerNumThreads     uint64
-	runnerRunOnce        bool
-	runnerSkipBackfill   bool
-	runnerSkipCheckValue bool
-	runnerRelay          string
-	runnerMinSlot        int64
+	runnerInterval        time.Duration
+	runnerEthNodeURIs     []string
+	runnerEthBackupURI    string
+	runnerLimit           uint64
+	runnerNumThreads      uint64
+	runnerRunOnce         bool
+	runnerSkipBackfill    bool
+	runnerSkipCheckValue  bool
+	runnerRelay           string
+	runnerMinSlot         int64
+	runnerBeaconCheckURIs []string
+
+	// alerting
+	alertWebhookURL            string
+	alertSlackWebhookURL       string
+	alertPushoverToken         string
+	alertPushoverUser          string
+	alertValueCheckWindow      time.Duration
+	alertMaxFailedValueChecks  uint64
+	alertDataAPIMaxEmptyRuns   int
+	alertCheckValueMaxLagSlots uint64
+	alertBidCollectMaxSilence  time.Duration
 )
 
 func init() {
 	backfillRunnerCmd.Flags().DurationVar(&runnerInterval, "interval", time.Duration(vars.DefaultBackfillRunnerInterval)*time.Minute, "interval between runs")
-	backfillRunnerCmd.Flags().StringVar(&runnerEthNodeURI, "eth-node", vars.DefaultEthNodeURI, "eth node URI")
-	backfillRunnerCmd.Flags().StringVar(&runnerEthBackupURI, "eth-node-backup", vars.DefaultEthBackupNodeURI, "eth backup node URI")
+	backfillRunnerCmd.Flags().StringSliceVar(&runnerEthNodeURIs, "eth-node", vars.DefaultEthNodeURIs, "eth node URIs, in order of preference")
+	backfillRunnerCmd.Flags().StringVar(&runnerEthBackupURI, "eth-node-backup", vars.DefaultEthBackupNodeURI, "eth backup node URI (deprecated, use several --eth-node)")
 	backfillRunnerCmd.Flags().Uint64Var(&runnerLimit, "limit", 1000, "limit for check-payload-value")
 	backfillRunnerCmd.Flags().Uint64Var(&runnerNumThreads, "threads", uint64(vars.DefaultBackfillRunnerNumThreads), "number of threads for check-payload-value")
 	backfillRunnerCmd.Flags().BoolVar(&runnerRunOnce, "once", false, "run once and exit")
@@ -38,6 +50,18 @@ func init() {
 	backfillRunnerCmd.Flags().BoolVar(&runnerSkipCheckValue, "skip-check-value", false, "skip check-payload-value step")
 	backfillRunnerCmd.Flags().StringVar(&runnerRelay, "relay", "", "specific relay only (e.g. 'fb', 'us', or full URL)")
 	backfillRunnerCmd.Flags().Int64Var(&runnerMinSlot, "min-slot", 0, "minimum slot (negative for offset from latest)")
+	backfillRunnerCmd.Flags().StringSliceVar(&runnerBeaconCheckURIs, "beacon-check-uri", nil, "beacon node to sanity-check the slot computed from the genesis time (optional, can be repeated)")
+
+	// alerting (enabled if at least one notifier is configured)
+	backfillRunnerCmd.Flags().StringVar(&alertWebhookURL, "alert-webhook", vars.DefaultAlertWebhookURL, "generic webhook URL for alerts (JSON POST)")
+	backfillRunnerCmd.Flags().StringVar(&alertSlackWebhookURL, "alert-slack-webhook", vars.DefaultAlertSlackWebhookURL, "Slack-compatible incoming webhook URL for alerts")
+	backfillRunnerCmd.Flags().StringVar(&alertPushoverToken, "alert-pushover-token", vars.DefaultPushoverAppToken, "Pushover application token for alerts")
+	backfillRunnerCmd.Flags().StringVar(&alertPushoverUser, "alert-pushover-user", vars.DefaultPushoverUserKey, "Pushover user key for alerts")
+	backfillRunnerCmd.Flags().DurationVar(&alertValueCheckWindow, "alert-value-check-window", 6*time.Hour, "time window for failed payload value checks (0 to disable)")
+	backfillRunnerCmd.Flags().Uint64Var(&alertMaxFailedValueChecks, "alert-max-failed-value-checks", 0, "alert if a relay has more failed payload value checks within the window")
+	backfillRunnerCmd.Flags().IntVar(&alertDataAPIMaxEmptyRuns, "alert-data-api-empty-runs", 12, "alert if a relay data API returned no new payloads in this many backfill runs (0 to disable)")
+	backfillRunnerCmd.Flags().Uint64Var(&alertCheckValueMaxLagSlots, "alert-check-value-lag", 300, "alert if check-payload-value is more than this many slots behind head (0 to disable)")
+	backfillRunnerCmd.Flags().DurationVar(&alertBidCollectMaxSilence, "alert-bidcollect-silence", 10*time.Minute, "alert if a bidcollect source received no bids for this long, requires bidcollect --save-source-status (0 to disable)")
 }
 
 var backfillRunnerCmd = &cobra.Command{
@@ -80,28 +104,20 @@ var backfillRunnerCmd = &cobra.Command{
 			log.Infof("Using min-slot: %d", runnerMinSlot)
 		}
 
+		slotClock, err := common.NewSlotClock(log, runnerBeaconCheckURIs)
+		if err != nil {
+			log.WithError(err).Fatal("failed to create slot clock")
+		}
+
 		// Connect to Postgres
 		db := database.MustConnectPostgres(log, vars.DefaultPostgresDSN)
 
 		// Connect to eth nodes
-		var ethClient, ethClient2 *ethclient.Client
+		var ethNode *common.EthNode
 		if !runnerSkipCheckValue {
-			if runnerEthNodeURI == "" {
-				log.Fatal("eth-node is required for check-payload-value")
-			}
-			ethClient, err = ethclient.Dial(runnerEthNodeURI)
+			ethNode, err = common.ConnectEthNode(log, append(runnerEthNodeURIs, runnerEthBackupURI)...)
 			if err != nil {
-				log.WithError(err).Fatalf("failed to connect to eth node: %s", runnerEthNodeURI)
-			}
-			log.Infof("Connected to eth node: %s", runnerEthNodeURI)
-
-			ethClient2 = ethClient
-			if runnerEthBackupURI != "" {
-				ethClient2, err = ethclient.Dial(runnerEthBackupURI)
-				if err != nil {
-					log.WithError(err).Fatalf("failed to connect to backup eth node: %s", runnerEthBackupURI)
-				}
-				log.Infof("Connected to backup eth node: %s", runnerEthBackupURI)
+				log.WithError(err).Fatal("failed to connect to eth nodes (required for check-payload-value)")
 			}
 		}
 
@@ -111,6 +127,38 @@ var backfillRunnerCmd = &cobra.Command{
 			NumThreads: runnerNumThreads,
 		}
 
+		// Set up alerting
+		var alerter *alerting.Alerter
+		notifiers := []alerting.Notifier{}
+		if alertWebhookURL != "" {
+			notifiers = append(notifiers, alerting.NewWebhookNotifier(alertWebhookURL))
+		}
+		if alertSlackWebhookURL != "" {
+			notifiers = append(notifiers, alerting.NewSlackNotifier(alertSlackWebhookURL))
+		}
+		if alertPushoverToken != "" && alertPushoverUser != "" {
+			notifiers = append(notifiers, alerting.NewPushoverNotifier(alertPushoverToken, alertPushoverUser))
+		}
+		if len(notifiers) > 0 {
+			for _, notifier := range notifiers {
+				log.Infof("Alerting via %s", notifier.Name())
+			}
+			alerter = alerting.NewAlerter(&alerting.AlerterOpts{
+				Log:                   log,
+				DB:                    db,
+				Notifiers:             notifiers,
+				Relays:                relays,
+				Clock:                 slotClock,
+				ValueCheckWindow:      alertValueCheckWindow,
+				MaxFailedValueChecks:  alertMaxFailedValueChecks,
+				DataAPIMaxEmptyRuns:   alertDataAPIMaxEmptyRuns,
+				CheckValueMaxLagSlots: alertCheckValueMaxLagSlots,
+				BidCollectMaxSilence:  alertBidCollectMaxSilence,
+			})
+		} else {
+			log.Info("Alerting disabled (no notifier configured)")
+		}
+
 		// Run function
 		runBackfillCycle := func() {
 			log.Info("Starting backfill cycle...")
@@ -118,7 +166,7 @@ var backfillRunnerCmd = &cobra.Command{
 			// Step 1: data-api-backfill
 			if !runnerSkipBackfill {
 				log.Info("Running data-api-backfill...")
-				err := core.RunBackfill(db, relays, 0, runnerMinSlot)
+				err := core.RunBackfill(db, relays, 0, runnerMinSlot, slotClock)
 				if err != nil {
 					log.WithError(err).Error("data-api-backfill failed")
 				}
@@ -127,12 +175,17 @@ var backfillRunnerCmd = &cobra.Command{
 			// Step 2: check-payload-value
 			if !runnerSkipCheckValue {
 				log.Info("Running check-payload-value...")
-				err := core.RunCheckPayloadValue(db, ethClient, ethClient2, checkOpts)
+				err := core.RunCheckPayloadValue(db, ethNode, checkOpts)
 				if err != nil {
 					log.WithError(err).Error("check-payload-value failed")
 				}
 			}
 
+			// Step 3: alerts
+			if alerter != nil {
+				alerter.Check()
+			}
+
 			log.Info("Backfill cycle complete")
 		}
 
@@ -6,7 +6,9 @@ package service
 
 import (
 	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
 	"github.com/flashbots/relayscan/services/bidcollect"
+	"github.com/flashbots/relayscan/services/bidcollect/objectstore"
 	"github.com/flashbots/relayscan/services/bidcollect/webserver"
 	"github.com/flashbots/relayscan/services/bidcollect/website"
 	"github.com/flashbots/relayscan/vars"
@@ -20,12 +22,21 @@ var (
 	collectDataAPI          bool
 	useAllRelays            bool
 
+	dataAPISchedules []string // per-relay data API poll offsets (i.e. relay.ultrasound.money=-4s,-2s,0s,2s)
+	saveSignedBids   bool     // store the full signed getHeader bids in the database
+	getHeaderOffsets string   // getHeader poll offsets relative to slot start (i.e. 0s,500ms,1s,2s)
+
+	saveSourceStatus bool // store the last received bid per source in the database (for backfill-runner alerts)
+
 	outDir    string
 	outputTSV bool   // by default: CSV, but can be changed to TSV with this setting
 	uid       string // used in output filenames, to avoid collissions between multiple collector instances
 
-	useRedis  bool
-	redisAddr string
+	outputObservations bool // record every observation of a bid (for latency analysis)
+
+	useRedis      bool
+	useRedisDedup bool
+	redisAddr     string
 
 	runDevServerOnly    bool // used to play with file listing website
 	devServerListenAddr string
@@ -34,6 +45,8 @@ var (
 	buildWebsiteUpload bool
 	buildWebsiteOutDir string
 
+	archiveStores []string // object stores for the bid archive (i.e. s3://bucket?endpoint=...&profile=r2)
+
 	runWebserverOnly    bool // provides a SSE stream of new bids
 	WebserverListenAddr string
 )
@@ -44,18 +57,28 @@ func init() {
 	bidCollectCmd.Flags().BoolVar(&collectDataAPI, "data-api", false, "use data API")
 	bidCollectCmd.Flags().BoolVar(&useAllRelays, "all-relays", false, "use all relays")
 
+	// for data API
+	bidCollectCmd.Flags().StringArrayVar(&dataAPISchedules, "data-api-schedule", nil, "data API poll offsets per relay, i.e. 'relay.ultrasound.money=-4s,-2s,-1s,0s,1s,2s' or 'default=...' (can be repeated)")
+
 	// for getHeader
-	bidCollectCmd.Flags().StringVar(&beaconNodeURI, "beacon-uri", vars.DefaultBeaconURI, "beacon endpoint")
+	bidCollectCmd.Flags().StringSliceVar(&beaconNodeURIs, "beacon-uri", vars.DefaultBeaconURIs, "beacon endpoint (can be repeated or comma-separated, for failover)")
+	bidCollectCmd.Flags().StringVar(&getHeaderOffsets, "get-header-offsets", "0s,500ms,1s,2s", "getHeader poll offsets relative to slot start")
+	bidCollectCmd.Flags().BoolVar(&saveSignedBids, "save-signed-bids", false, "store the full signed getHeader bids (incl. signature status) in the database (POSTGRES_DSN)")
+
+	// for alerting
+	bidCollectCmd.Flags().BoolVar(&saveSourceStatus, "save-source-status", false, "store the last received bid per source in the database (POSTGRES_DSN), used by backfill-runner alerts")
 
 	// for saving to file
 	bidCollectCmd.Flags().StringVar(&outDir, "out", "csv", "output directory for CSV/TSV")
 	bidCollectCmd.Flags().BoolVar(&outputTSV, "out-tsv", false, "output as TSV (instead of CSV)")
+	bidCollectCmd.Flags().BoolVar(&outputObservations, "observations", false, "also write every observation of a bid (source, relay, received_at) to obs_*.csv")
 
 	// utils
 	bidCollectCmd.Flags().StringVar(&uid, "uid", "", "unique identifier for output files (to avoid collisions)")
 
 	// Redis for pushing bids to
 	bidCollectCmd.Flags().BoolVar(&useRedis, "redis", false, "Publish bids to Redis")
+	bidCollectCmd.Flags().BoolVar(&useRedisDedup, "redis-dedup", false, "Share bid deduplication and top bids with other collector instances via Redis (implies --observations)")
 	bidCollectCmd.Flags().StringVar(&redisAddr, "redis-addr", "localhost:6379", "Redis address for publishing bids (optional)")
 
 	// Webserver mode
@@ -70,6 +93,9 @@ func init() {
 	bidCollectCmd.Flags().BoolVar(&buildWebsite, "build-website", false, "build file listing website")
 	bidCollectCmd.Flags().BoolVar(&buildWebsiteUpload, "build-website-upload", false, "upload after building")
 	bidCollectCmd.Flags().StringVar(&buildWebsiteOutDir, "build-website-out", "build", "output directory for website")
+	bidCollectCmd.Flags().StringSliceVar(&archiveStores, "store", vars.DefaultBidArchiveStores, "bid archive object store (i.e. file:///path or s3://bucket?endpoint=...&profile=...), the first one is used for the website")
+
+	bidCollectCmd.AddCommand(bidCollectArchiveCmd)
 }
 
 var bidCollectCmd = &cobra.Command{
@@ -93,7 +119,11 @@ var bidCollectCmd = &cobra.Command{
 
 		if buildWebsite {
 			log.Infof("Bidcollect %s building website (output: %s) ...", vars.Version, buildWebsiteOutDir)
-			website.BuildProdWebsite(log, buildWebsiteOutDir, buildWebsiteUpload)
+			if len(archiveStores) == 0 {
+				log.Fatal("--store is required to build the website")
+			}
+			store := objectstore.MustNewMany(archiveStores)[0]
+			website.BuildProdWebsite(log, store, buildWebsiteOutDir, buildWebsiteUpload)
 			return
 		}
 
@@ -117,6 +147,32 @@ var bidCollectCmd = &cobra.Command{
 			log.Infof("- relay #%d: %s", index+1, relay.Hostname())
 		}
 
+		dataAPIPollSchedules, err := bidcollect.ParseDataAPIPollSchedules(dataAPISchedules)
+		if err != nil {
+			log.WithError(err).Fatal("failed to parse data API poll schedules")
+		}
+
+		getHeaderPollOffsets, err := bidcollect.ParseGetHeaderPollOffsets(getHeaderOffsets)
+		if err != nil {
+			log.WithError(err).Fatal("failed to parse getHeader poll offsets")
+		}
+
+		builderDomain, err := bidcollect.ComputeBuilderDomain(vars.GenesisForkVersion)
+		if err != nil {
+			log.WithError(err).Fatal("invalid GENESIS_FORK_VERSION")
+		}
+
+		var db, signedBidsDB, sourceStatusDB *database.DatabaseService
+		if saveSignedBids || saveSourceStatus {
+			db = database.MustConnectPostgres(log, vars.DefaultPostgresDSN)
+		}
+		if saveSignedBids {
+			signedBidsDB = db
+		}
+		if saveSourceStatus {
+			sourceStatusDB = db
+		}
+
 		opts := bidcollect.BidCollectorOpts{
 			Log:                     log,
 			UID:                     uid,
@@ -124,11 +180,18 @@ var bidCollectCmd = &cobra.Command{
 			CollectUltrasoundStream: collectUltrasoundStream,
 			CollectGetHeader:        collectGetHeader,
 			CollectDataAPI:          collectDataAPI,
-			BeaconNodeURI:           beaconNodeURI,
+			BeaconNodeURIs:          beaconNodeURIs,
+			DataAPIPollSchedules:    dataAPIPollSchedules,
+			DB:                      signedBidsDB,
+			SourceStatusDB:          sourceStatusDB,
+			GetHeaderPollOffsets:    getHeaderPollOffsets,
+			BuilderDomain:           builderDomain,
 			OutDir:                  outDir,
 			OutputTSV:               outputTSV,
+			OutputObservations:      outputObservations,
 			RedisAddr:               redisAddr,
 			UseRedis:                useRedis,
+			UseRedisDedup:           useRedisDedup,
 		}
 
 		bidCollector, err := bidcollect.NewBidCollector(&opts)
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/flashbots/relayscan/services/bidcollect/archive"
+	"github.com/flashbots/relayscan/services/bidcollect/objectstore"
+	"github.com/flashbots/relayscan/vars"
+	"github.com/spf13/cobra"
+)
+
+var (
+	archiveDate            string
+	archiveDeleteInput     bool
+	archiveRecordInstances bool
+)
+
+func init() {
+	bidCollectArchiveCmd.Flags().StringVar(&archiveDate, "date", "", "date to archive (yyyy-mm-dd, default: yesterday)")
+	bidCollectArchiveCmd.Flags().StringVar(&outDir, "out", "csv", "bidcollect output directory (containing the date directories)")
+	bidCollectArchiveCmd.Flags().StringSliceVar(&archiveStores, "store", vars.DefaultBidArchiveStores, "object store to upload to, can be repeated (i.e. file:///path or s3://bucket?endpoint=...&profile=...)")
+	bidCollectArchiveCmd.Flags().BoolVar(&archiveDeleteInput, "delete", false, "delete input files after archiving")
+	bidCollectArchiveCmd.Flags().BoolVar(&archiveRecordInstances, "instances", false, "also write <date>_instances.csv, with the collector instances that saw each bid")
+}
+
+var bidCollectArchiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Combine the bids of a day (from all collector instances) into the daily archive, and upload it",
+	Run: func(cmd *cobra.Command, args []string) {
+		if archiveDate == "" {
+			archiveDate = time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+		}
+
+		stores := objectstore.MustNewMany(archiveStores)
+		log.Infof("Bidcollect %s archiving %s (stores: %d) ...", vars.Version, archiveDate, len(stores))
+		for _, store := range stores {
+			log.Infof("- store: %s", store.String())
+		}
+
+		archiver, err := archive.NewArchiver(&archive.ArchiverOpts{
+			Log:             log,
+			Date:            archiveDate,
+			OutDir:          outDir,
+			Stores:          stores,
+			DeleteInput:     archiveDeleteInput,
+			RecordInstances: archiveRecordInstances,
+		})
+		if err != nil {
+			log.WithError(err).Fatal("failed to create archiver")
+		}
+
+		manifest, err := archiver.Run(context.Background())
+		if err != nil {
+			log.WithError(err).Fatal("failed to archive bids")
+		}
+
+		log.Infof("Archive complete: %d bids (%d top bids, %d duplicates removed)", manifest.NumBidsAll, manifest.NumBidsTop, manifest.NumBidsDuplicates)
+		for sourceType, count := range manifest.SourceTypesAll {
+			log.Infof("- source type %d: %d bids", sourceType, count)
+		}
+	},
+}
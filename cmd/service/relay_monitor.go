@@ -0,0 +1,84 @@
+package service
+
+import (
+	"time"
+
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	"github.com/flashbots/relayscan/services/relaymonitor"
+	"github.com/flashbots/relayscan/vars"
+	"github.com/spf13/cobra"
+)
+
+var (
+	monitorInterval  time.Duration
+	monitorTimeout   time.Duration
+	monitorRetention time.Duration
+	monitorRelays    []string
+	monitorRunOnce   bool
+)
+
+func init() {
+	relayMonitorCmd.Flags().DurationVar(&monitorInterval, "interval", 30*time.Second, "interval between checks")
+	relayMonitorCmd.Flags().DurationVar(&monitorTimeout, "timeout", 5*time.Second, "timeout per request")
+	relayMonitorCmd.Flags().DurationVar(&monitorRetention, "retention", 30*24*time.Hour, "delete checks older than this (0 to keep all)")
+	relayMonitorCmd.Flags().StringSliceVar(&monitorRelays, "relay", nil, "relay URLs to check (default: all relays)")
+	relayMonitorCmd.Flags().BoolVar(&monitorRunOnce, "once", false, "check once and exit")
+}
+
+var relayMonitorCmd = &cobra.Command{
+	Use:   "relay-monitor",
+	Short: "Continuously check the relay status and data API endpoints, and store availability and latency",
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		var relays []common.RelayEntry
+
+		log.Infof("Relayscan relay-monitor %s starting...", vars.Version)
+
+		if monitorInterval <= 0 {
+			log.Fatalf("--interval must be positive (got %s)", monitorInterval)
+		}
+		if monitorTimeout <= 0 {
+			log.Fatalf("--timeout must be positive (got %s), requests would never time out", monitorTimeout)
+		}
+		if monitorRetention < 0 {
+			log.Fatalf("--retention must not be negative (got %s, use 0 to keep all checks)", monitorRetention)
+		}
+
+		if len(monitorRelays) > 0 {
+			for _, relayURL := range monitorRelays {
+				relayEntry, err := common.NewRelayEntry(relayURL, false)
+				if err != nil {
+					log.WithField("relay", relayURL).WithError(err).Fatal("failed to decode relay")
+				}
+				relays = append(relays, relayEntry)
+			}
+		} else {
+			relays, err = common.GetRelays()
+			if err != nil {
+				log.WithError(err).Fatal("failed to get relays")
+			}
+		}
+		log.Infof("Checking %d relays every %s", len(relays), monitorInterval)
+		for i, relay := range relays {
+			log.Infof("- relay #%d: %s", i+1, relay.Hostname())
+		}
+
+		db := database.MustConnectPostgres(log, vars.DefaultPostgresDSN)
+
+		monitor := relaymonitor.NewRelayMonitor(&relaymonitor.RelayMonitorOpts{
+			Log:       log,
+			DB:        db,
+			Relays:    relays,
+			Interval:  monitorInterval,
+			Timeout:   monitorTimeout,
+			Retention: monitorRetention,
+		})
+
+		if monitorRunOnce {
+			monitor.CheckAndSave()
+			return
+		}
+		monitor.Start()
+	},
+}
@@ -7,8 +7,8 @@ import (
 )
 
 var (
-	log           = common.Logger
-	beaconNodeURI string
+	log            = common.Logger
+	beaconNodeURIs []string
 )
 
 var ServiceCmd = &cobra.Command{
@@ -23,4 +23,5 @@ func init() {
 	ServiceCmd.AddCommand(websiteCmd)
 	ServiceCmd.AddCommand(bidCollectCmd)
 	ServiceCmd.AddCommand(backfillRunnerCmd)
+	ServiceCmd.AddCommand(relayMonitorCmd)
 }
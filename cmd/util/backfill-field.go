@@ -0,0 +1,311 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	dbvars "github.com/flashbots/relayscan/database/vars"
+	"github.com/flashbots/relayscan/vars"
+	"github.com/spf13/cobra"
+)
+
+var (
+	errUnknownBackfillField = errors.New("unknown field")
+
+	backfillFieldName      string
+	backfillFieldMinSlot   uint64
+	backfillFieldMaxSlot   uint64
+	backfillFieldBatchSize int
+	backfillFieldLimit     int
+)
+
+// onchainField is a column (or several) of the delivered payloads table that is filled in from on-chain block data
+type onchainField struct {
+	columns  []string                        // updated columns
+	missing  string                          // SQL condition for rows that need to be filled in (unless backfilled before)
+	needsTxs bool                            // needs the block transactions, not only the header
+	values   func(block *onchainBlock) []any // values for the columns
+}
+
+// onchainBlock is the on-chain data of a block. Transactions are only fetched for fields that need them.
+type onchainBlock struct {
+	header *types.Header
+	txs    types.Transactions
+}
+
+var onchainFields = map[string]*onchainField{
+	"extra_data": {
+		columns: []string{"extra_data"},
+		missing: "extra_data = ''",
+		values: func(block *onchainBlock) []any {
+			return []any{database.ExtraDataToUtf8Str(block.header.Extra)}
+		},
+	},
+	"block_timestamp": {
+		columns: []string{"block_timestamp"},
+		missing: "block_timestamp IS NULL",
+		values: func(block *onchainBlock) []any {
+			return []any{time.Unix(int64(block.header.Time), 0).UTC()} //nolint:gosec
+		},
+	},
+	"num_blobs": {
+		columns:  []string{"num_blob_txs", "num_blobs"},
+		missing:  "num_blobs IS NULL",
+		needsTxs: true,
+		values: func(block *onchainBlock) []any {
+			numBlobTxs, numBlobs := 0, 0
+			for _, tx := range block.txs {
+				if tx.Type() == types.BlobTxType {
+					numBlobTxs++
+					numBlobs += len(tx.BlobHashes())
+				}
+			}
+			return []any{numBlobTxs, numBlobs}
+		},
+	},
+	"num_tx": {
+		columns:  []string{"block_num_tx"},
+		missing:  "block_num_tx IS NULL",
+		needsTxs: true,
+		values: func(block *onchainBlock) []any {
+			return []any{len(block.txs)}
+		},
+	},
+	"gas_used": {
+		columns: []string{"block_gas_used"},
+		missing: "block_gas_used IS NULL",
+		values: func(block *onchainBlock) []any {
+			return []any{int64(block.header.GasUsed)} //nolint:gosec
+		},
+	},
+	"base_fee": {
+		columns: []string{"block_base_fee"},
+		missing: "block_base_fee IS NULL",
+		values: func(block *onchainBlock) []any {
+			if block.header.BaseFee == nil {
+				return []any{nil}
+			}
+			return []any{block.header.BaseFee.String()}
+		},
+	},
+}
+
+func onchainFieldNames() []string {
+	names := make([]string, 0, len(onchainFields))
+	for name := range onchainFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	backfillFieldCmd.Flags().StringSliceVar(&ethNodeURIs, "eth-node", vars.DefaultEthNodeURIs, "eth node URIs, in order of preference (i.e. Infura)")
+	backfillFieldCmd.Flags().StringVar(&ethNodeBackupURI, "eth-node-backup", vars.DefaultEthBackupNodeURI, "eth node backup URI (deprecated, use several --eth-node)")
+	backfillFieldCmd.Flags().StringVar(&backfillFieldName, "field", "", "field to fill in: "+strings.Join(onchainFieldNames(), ", "))
+	backfillFieldCmd.Flags().Uint64Var(&backfillFieldMinSlot, "min-slot", 0, "only rows from this slot on")
+	backfillFieldCmd.Flags().Uint64Var(&backfillFieldMaxSlot, "max-slot", 0, "only rows up to this slot (0 for no limit)")
+	backfillFieldCmd.Flags().IntVar(&backfillFieldBatchSize, "batch-size", 100, "rows per batch (block data of a batch is fetched together)")
+	backfillFieldCmd.Flags().IntVar(&backfillFieldLimit, "limit", 0, "max number of rows to process (0 for no limit)")
+	backfillFieldCmd.Flags().Uint64Var(&numThreads, "threads", numThreads, "concurrent block requests, for fields that need the transactions")
+	_ = backfillFieldCmd.MarkFlagRequired("field")
+}
+
+var backfillFieldCmd = &cobra.Command{
+	Use:   "backfill-field",
+	Short: "Fill in a missing on-chain field of the delivered payloads",
+	Run: func(cmd *cobra.Command, args []string) {
+		ethNode, err := common.ConnectEthNode(log, append(ethNodeURIs, ethNodeBackupURI)...)
+		if err != nil {
+			log.WithError(err).Fatal("failed to connect to eth nodes")
+		}
+		db := database.MustConnectPostgres(log, vars.DefaultPostgresDSN)
+
+		numUpdated, err := RunBackfillField(db, ethNode, BackfillFieldOpts{
+			Field:      backfillFieldName,
+			MinSlot:    backfillFieldMinSlot,
+			MaxSlot:    backfillFieldMaxSlot,
+			BatchSize:  backfillFieldBatchSize,
+			Limit:      backfillFieldLimit,
+			NumThreads: int(numThreads), //nolint:gosec
+		})
+		if err != nil {
+			log.WithError(err).Fatal("backfill failed")
+		}
+		log.Infof("backfill of %s done, %d rows updated", backfillFieldName, numUpdated)
+	},
+}
+
+// BackfillFieldEthClient is the execution client access needed for the backfill (i.e. common.EthNode)
+type BackfillFieldEthClient interface {
+	BlockByHash(ctx context.Context, hash ethcommon.Hash) (*types.Block, error)
+	HeadersByHash(ctx context.Context, hashes []ethcommon.Hash) ([]*types.Header, error)
+}
+
+type BackfillFieldOpts struct {
+	Field      string
+	MinSlot    uint64
+	MaxSlot    uint64 // 0 for no limit
+	BatchSize  int
+	Limit      int // max number of rows, 0 for no limit
+	NumThreads int // concurrent block requests, for fields that need the transactions
+}
+
+type backfillFieldRow struct {
+	ID        int64  `db:"id"`
+	BlockHash string `db:"block_hash"`
+}
+
+// RunBackfillField fills in a field for all delivered payloads where it's missing, in batches ordered by id. Rows of
+// blocks that aren't on-chain are skipped. Updated rows are marked in backfilled_fields, so rows with an empty on-chain
+// value aren't fetched again. Returns the number of updated rows.
+func RunBackfillField(db *database.DatabaseService, eth BackfillFieldEthClient, opts BackfillFieldOpts) (numUpdated int, err error) {
+	field, found := onchainFields[opts.Field]
+	if !found {
+		return 0, fmt.Errorf("%w: %s (available: %s)", errUnknownBackfillField, opts.Field, strings.Join(onchainFieldNames(), ", "))
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+
+	selectQuery := `SELECT id, block_hash FROM ` + dbvars.TableDataAPIPayloadDelivered + ` WHERE ` + field.missing + `
+		AND NOT ($5 = ANY(backfilled_fields))
+		AND found_onchain IS NOT false AND id > $1 AND slot >= $2 AND ($3::bigint = 0 OR slot <= $3)
+		ORDER BY id ASC LIMIT $4`
+
+	setColumns := make([]string, len(field.columns))
+	for i, column := range field.columns {
+		setColumns[i] = fmt.Sprintf("%s=$%d", column, i+1)
+	}
+	numColumns := len(field.columns)
+	setColumns = append(setColumns, fmt.Sprintf("backfilled_fields=array_append(backfilled_fields, $%d)", numColumns+1))
+	updateQuery := `UPDATE ` + dbvars.TableDataAPIPayloadDelivered + ` SET ` + strings.Join(setColumns, ", ") + fmt.Sprintf(` WHERE id=$%d`, numColumns+2)
+
+	lastID := int64(0)
+	numRows := 0
+	for opts.Limit == 0 || numRows < opts.Limit {
+		batchSize := opts.BatchSize
+		if opts.Limit > 0 && opts.Limit-numRows < batchSize {
+			batchSize = opts.Limit - numRows
+		}
+
+		rows := []*backfillFieldRow{}
+		err = db.DB.Select(&rows, selectQuery, lastID, opts.MinSlot, opts.MaxSlot, batchSize, opts.Field)
+		if err != nil {
+			return numUpdated, fmt.Errorf("failed to get rows: %w", err)
+		} else if len(rows) == 0 {
+			break
+		}
+		lastID = rows[len(rows)-1].ID
+		numRows += len(rows)
+
+		// each block once, even if several relays delivered it
+		hashes := []ethcommon.Hash{}
+		seen := make(map[ethcommon.Hash]bool)
+		for _, row := range rows {
+			hash := ethcommon.HexToHash(row.BlockHash)
+			if !seen[hash] {
+				seen[hash] = true
+				hashes = append(hashes, hash)
+			}
+		}
+		blocks, err := fetchOnchainBlocks(context.Background(), eth, hashes, field.needsTxs, opts.NumThreads)
+		if err != nil {
+			return numUpdated, err
+		}
+
+		n, err := saveBackfilledField(db, updateQuery, opts.Field, rows, blocks)
+		numUpdated += n
+		if err != nil {
+			return numUpdated, err
+		}
+		log.Infof("backfill %s: %d rows updated in batch up to id %d (%d / %d blocks found on-chain)", opts.Field, n, lastID, len(blocks), len(hashes))
+	}
+	return numUpdated, nil
+}
+
+// fetchOnchainBlocks returns the data of all blocks that are found on-chain. Headers are fetched in a single batched
+// request, full blocks (if transactions are needed) with numThreads concurrent requests.
+func fetchOnchainBlocks(ctx context.Context, eth BackfillFieldEthClient, hashes []ethcommon.Hash, needsTxs bool, numThreads int) (map[ethcommon.Hash]*onchainBlock, error) {
+	blocks := make(map[ethcommon.Hash]*onchainBlock)
+	if !needsTxs {
+		headers, err := eth.HeadersByHash(ctx, hashes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get headers: %w", err)
+		}
+		for i, header := range headers {
+			if header == nil {
+				log.Warnf("block not found: %s", hashes[i].Hex())
+				continue
+			}
+			blocks[hashes[i]] = &onchainBlock{header: header}
+		}
+		return blocks, nil
+	}
+
+	if numThreads <= 0 {
+		numThreads = 1
+	}
+	var lock sync.Mutex
+	var firstErr error
+	wg := new(sync.WaitGroup)
+	hashC := make(chan ethcommon.Hash)
+	for range numThreads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for hash := range hashC {
+				block, err := eth.BlockByHash(ctx, hash)
+				lock.Lock()
+				if errors.Is(err, ethereum.NotFound) {
+					log.Warnf("block not found: %s", hash.Hex())
+				} else if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("failed to get block %s: %w", hash.Hex(), err)
+				} else if err == nil {
+					blocks[hash] = &onchainBlock{header: block.Header(), txs: block.Transactions()}
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	for _, hash := range hashes {
+		hashC <- hash
+	}
+	close(hashC)
+	wg.Wait()
+	return blocks, firstErr
+}
+
+// saveBackfilledField updates the rows of a batch in a single transaction
+func saveBackfilledField(db *database.DatabaseService, updateQuery, fieldName string, rows []*backfillFieldRow, blocks map[ethcommon.Hash]*onchainBlock) (numUpdated int, err error) {
+	field := onchainFields[fieldName]
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, row := range rows {
+		block, found := blocks[ethcommon.HexToHash(row.BlockHash)]
+		if !found {
+			continue
+		}
+		args := append(field.values(block), fieldName, row.ID)
+		_, err = tx.Exec(updateQuery, args...)
+		if err != nil {
+			return 0, fmt.Errorf("couldn't update row %d: %w", row.ID, err)
+		}
+		numUpdated++
+	}
+	return numUpdated, tx.Commit()
+}
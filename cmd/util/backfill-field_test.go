@@ -0,0 +1,124 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	dbvars "github.com/flashbots/relayscan/database/vars"
+	"github.com/flashbots/relayscan/testutil/ethchain"
+	"github.com/flashbots/relayscan/testutil/testdb"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestEthNode = errors.New("eth node error")
+
+func TestOnchainFieldValues(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	to := ethcommon.Address{0x01}
+
+	block := ethchain.NewBlock(100, ethcommon.Hash{}, to, []byte("builder"),
+		ethchain.NewTransfer(key, 0, to, big.NewInt(1)),
+		ethchain.NewBlobTx(key, 1, to, 2),
+		ethchain.NewBlobTx(key, 2, to, 3),
+	)
+	data := &onchainBlock{header: block.Header(), txs: block.Transactions()}
+
+	require.Equal(t, []any{"builder"}, onchainFields["extra_data"].values(data))
+	require.Equal(t, []any{time.Unix(1_700_000_000+100*12, 0).UTC()}, onchainFields["block_timestamp"].values(data))
+	require.Equal(t, []any{2, 5}, onchainFields["num_blobs"].values(data))
+	require.Equal(t, []any{3}, onchainFields["num_tx"].values(data))
+	require.Equal(t, []any{int64(block.GasUsed())}, onchainFields["gas_used"].values(data)) //nolint:gosec
+	require.Equal(t, []any{"7"}, onchainFields["base_fee"].values(data))
+
+	for name, field := range onchainFields {
+		require.Len(t, field.values(data), len(field.columns), name)
+	}
+}
+
+func TestFetchOnchainBlocks(t *testing.T) {
+	chain := ethchain.New()
+	block1 := ethchain.NewBlock(1, ethcommon.Hash{}, ethcommon.Address{}, nil)
+	block2 := ethchain.NewBlock(2, block1.Hash(), ethcommon.Address{}, nil)
+	chain.AddBlock(block1)
+	chain.AddBlock(block2)
+	hashes := []ethcommon.Hash{block1.Hash(), {0x01}, block2.Hash()}
+
+	// headers only: a single batched request
+	blocks, err := fetchOnchainBlocks(context.Background(), chain, hashes, false, 2)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	require.Nil(t, blocks[block1.Hash()].txs)
+	require.Equal(t, 1, chain.NumCalls())
+
+	// with transactions: a request per block
+	blocks, err = fetchOnchainBlocks(context.Background(), chain, hashes, true, 2)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	require.Equal(t, uint64(2), blocks[block2.Hash()].header.Number.Uint64())
+	require.Equal(t, 4, chain.NumCalls())
+
+	chain.SetError(errTestEthNode)
+	_, err = fetchOnchainBlocks(context.Background(), chain, hashes, true, 2)
+	require.ErrorIs(t, err, errTestEthNode)
+	_, err = fetchOnchainBlocks(context.Background(), chain, hashes, false, 2)
+	require.ErrorIs(t, err, errTestEthNode)
+}
+
+func TestRunBackfillField(t *testing.T) {
+	_, err := RunBackfillField(nil, nil, BackfillFieldOpts{Field: "foo"})
+	require.ErrorIs(t, err, errUnknownBackfillField)
+
+	db := testdb.New(t)
+
+	chain := ethchain.New()
+	block := ethchain.NewBlock(100, ethcommon.Hash{}, ethcommon.Address{}, []byte("builder"))
+	chain.AddBlock(block)
+	emptyBlock := ethchain.NewBlock(101, block.Hash(), ethcommon.Address{}, nil)
+	chain.AddBlock(emptyBlock)
+
+	// two relays delivered the block, one a block that isn't on-chain, one is out of the slot range, one delivered a
+	// block without extra_data
+	_, err = db.SaveDataAPIPayloadDeliveredBatch([]*database.DataAPIPayloadDeliveredEntry{
+		{Relay: "a", Slot: 10, ParentHash: "0x01", BlockHash: block.Hash().Hex(), ValueClaimedWei: "1"},
+		{Relay: "b", Slot: 10, ParentHash: "0x01", BlockHash: block.Hash().Hex(), ValueClaimedWei: "1"},
+		{Relay: "c", Slot: 10, ParentHash: "0x01", BlockHash: ethcommon.Hash{0x02}.Hex(), ValueClaimedWei: "1"},
+		{Relay: "d", Slot: 20, ParentHash: "0x01", BlockHash: block.Hash().Hex(), ValueClaimedWei: "1"},
+		{Relay: "e", Slot: 11, ParentHash: "0x01", BlockHash: emptyBlock.Hash().Hex(), ValueClaimedWei: "1"},
+	})
+	require.NoError(t, err)
+
+	eth := common.NewEthNodeFromClients(log, []common.EthClient{chain}, nil)
+	numUpdated, err := RunBackfillField(db, eth, BackfillFieldOpts{Field: "extra_data", MaxSlot: 15, BatchSize: 2})
+	require.NoError(t, err)
+	require.Equal(t, 3, numUpdated)
+
+	// a finished backfill doesn't fetch the rows again, even if the on-chain value is empty
+	numUpdated, err = RunBackfillField(db, eth, BackfillFieldOpts{Field: "extra_data", MaxSlot: 15, BatchSize: 2})
+	require.NoError(t, err)
+	require.Equal(t, 0, numUpdated)
+
+	numUpdated, err = RunBackfillField(db, eth, BackfillFieldOpts{Field: "num_blobs", BatchSize: 2, NumThreads: 2})
+	require.NoError(t, err)
+	require.Equal(t, 4, numUpdated)
+
+	rows := []*database.DataAPIPayloadDeliveredEntry{}
+	err = db.DB.Select(&rows, `SELECT relay, extra_data, num_blobs FROM `+dbvars.TableDataAPIPayloadDelivered+` ORDER BY relay`)
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+	require.Equal(t, "builder", rows[0].ExtraData)
+	require.Equal(t, "builder", rows[1].ExtraData)
+	require.Empty(t, rows[2].ExtraData)
+	require.False(t, rows[2].NumBlobs.Valid)
+	require.Empty(t, rows[3].ExtraData)
+	require.True(t, rows[3].NumBlobs.Valid)
+	require.Empty(t, rows[4].ExtraData)
+	require.True(t, rows[4].NumBlobs.Valid)
+}
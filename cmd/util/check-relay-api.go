@@ -0,0 +1,84 @@
+package util
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	"github.com/flashbots/relayscan/services/relaycheck"
+	"github.com/flashbots/relayscan/vars"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkRelayAPISave    bool
+	checkRelayAPIJSON    bool
+	checkRelayAPITimeout time.Duration
+	checkRelayAPISlots   int
+)
+
+func init() {
+	checkRelayAPICmd.Flags().BoolVar(&checkRelayAPISave, "save", false, "store the report in the database (POSTGRES_DSN)")
+	checkRelayAPICmd.Flags().BoolVar(&checkRelayAPIJSON, "json", false, "print the report as JSON")
+	checkRelayAPICmd.Flags().DurationVar(&checkRelayAPITimeout, "timeout", 10*time.Second, "timeout per request")
+	checkRelayAPICmd.Flags().IntVar(&checkRelayAPISlots, "slots", 5, "number of recent delivered payloads to compare with builder_blocks_received")
+}
+
+var checkRelayAPICmd = &cobra.Command{
+	Use:   "check-relay-api <relay>",
+	Short: "Check the data API and builder API of a relay for conformance with the relay-specs",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		relay, err := common.NewRelayEntry(args[0], false)
+		if err != nil {
+			log.WithField("relay", args[0]).WithError(err).Fatal("failed to decode relay")
+		}
+
+		log.Infof("Checking the API of relay %s ...", relay.Hostname())
+		checker := relaycheck.NewChecker(&relaycheck.CheckerOpts{
+			Log:                 log,
+			Relay:               relay,
+			Timeout:             checkRelayAPITimeout,
+			NumConsistencySlots: checkRelayAPISlots,
+		})
+		report := checker.Run()
+
+		if checkRelayAPIJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				log.WithError(err).Fatal("failed to encode report")
+			}
+		} else {
+			report.Print(os.Stdout)
+		}
+
+		numFail := report.Count(relaycheck.ResultFail)
+		log.Infof("%d checks passed, %d warnings, %d failed, %d skipped", report.Count(relaycheck.ResultPass), report.Count(relaycheck.ResultWarn), numFail, report.Count(relaycheck.ResultSkip))
+
+		if checkRelayAPISave {
+			results, err := json.Marshal(report.Results)
+			if err != nil {
+				log.WithError(err).Fatal("failed to encode results")
+			}
+			db := database.MustConnectPostgres(log, vars.DefaultPostgresDSN)
+			err = db.SaveRelayAPIConformance(&database.RelayAPIConformanceEntry{
+				Relay:   report.Relay,
+				NumPass: report.Count(relaycheck.ResultPass),
+				NumWarn: report.Count(relaycheck.ResultWarn),
+				NumFail: numFail,
+				Results: string(results),
+			})
+			if err != nil {
+				log.WithError(err).Fatal("failed to save report")
+			}
+			log.Info("Report saved to the database")
+		}
+
+		if numFail > 0 {
+			os.Exit(1)
+		}
+	},
+}
@@ -13,7 +13,7 @@ var (
 	// Printer for pretty printing numbers
 	// printer = message.NewPrinter(language.English)
 
-	ethNodeURI       string
+	ethNodeURIs      []string
 	ethNodeBackupURI string
 )
 
@@ -26,5 +26,7 @@ var UtilCmd = &cobra.Command{
 }
 
 func init() {
-	UtilCmd.AddCommand(backfillExtradataCmd)
+	UtilCmd.AddCommand(backfillFieldCmd)
+	UtilCmd.AddCommand(checkRelayAPICmd)
+	UtilCmd.AddCommand(inspectBlockCmd)
 }
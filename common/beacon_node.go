@@ -0,0 +1,276 @@
+package common
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/beaconclient"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrBeaconNodesUnavailable = errors.New("all beacon nodes are unavailable")
+	ErrBeaconNodesSyncing     = errors.New("all beacon nodes are syncing")
+)
+
+// BeaconNode is the part of the beacon API used by relayscan (implemented by beaconclient.ProdBeaconInstance)
+type BeaconNode interface {
+	GetURI() string
+	SyncStatus() (*beaconclient.SyncStatusPayloadData, error)
+	GetProposerDuties(epoch uint64) (*beaconclient.ProposerDutiesResponse, error)
+	GetBlock(blockID string) (*beaconclient.GetBlockResponse, error)
+	SubscribeToHeadEvents(slotC chan beaconclient.HeadEventData)
+	SubscribeToPayloadAttributesEvents(payloadAttributesC chan beaconclient.PayloadAttributesEvent)
+}
+
+// BeaconNodeStatus is the result of the last health check of a beacon node
+type BeaconNodeStatus struct {
+	URI       string
+	Healthy   bool // responded to the last sync status request (or a later request)
+	IsSyncing bool
+	HeadSlot  uint64
+	LastError error
+	LastCheck time.Time
+}
+
+// MultiBeaconClient uses several beacon nodes. Requests go to the best node first (synced, with the highest head slot),
+// and fail over to the next ones on errors. Events are subscribed to on all nodes.
+type MultiBeaconClient struct {
+	log   *logrus.Entry
+	nodes []BeaconNode
+
+	statusLock sync.RWMutex
+	status     []BeaconNodeStatus // same order as nodes
+
+	eventLock             sync.Mutex
+	lastHeadSlot          uint64
+	lastPayloadAttributes map[uint64]string // map[slot]parentHash, of forwarded payload_attributes events
+}
+
+func NewMultiBeaconClient(log *logrus.Entry, nodes []BeaconNode) *MultiBeaconClient {
+	status := make([]BeaconNodeStatus, len(nodes))
+	for i, node := range nodes {
+		// assume healthy until the first check
+		status[i] = BeaconNodeStatus{URI: node.GetURI(), Healthy: true}
+	}
+
+	return &MultiBeaconClient{
+		log:                   log,
+		nodes:                 nodes,
+		status:                status,
+		lastPayloadAttributes: make(map[uint64]string),
+	}
+}
+
+// NewMultiBeaconClientFromURIs creates a client for the given beacon node URIs
+func NewMultiBeaconClientFromURIs(log *logrus.Entry, uris []string) (*MultiBeaconClient, error) {
+	if len(uris) == 0 {
+		return nil, ErrURLEmpty
+	}
+	nodes := make([]BeaconNode, len(uris))
+	for i, uri := range uris {
+		if uri == "" {
+			return nil, ErrURLEmpty
+		}
+		nodes[i] = beaconclient.NewProdBeaconInstance(log, uri)
+	}
+	return NewMultiBeaconClient(log, nodes), nil
+}
+
+// CheckHealth requests the sync status of all nodes (in parallel), and returns the updated status
+func (c *MultiBeaconClient) CheckHealth() []BeaconNodeStatus {
+	var wg sync.WaitGroup
+	for i, node := range c.nodes {
+		wg.Add(1)
+		go func(i int, node BeaconNode) {
+			defer wg.Done()
+			syncStatus, err := node.SyncStatus()
+
+			c.statusLock.Lock()
+			defer c.statusLock.Unlock()
+			status := &c.status[i]
+			status.LastCheck = time.Now().UTC()
+			status.LastError = err
+			status.Healthy = err == nil
+			if err == nil {
+				status.IsSyncing = syncStatus.IsSyncing
+				status.HeadSlot = syncStatus.HeadSlot
+			}
+		}(i, node)
+	}
+	wg.Wait()
+	return c.Status()
+}
+
+// StartHealthChecks checks the health of all nodes in the given interval, waiting on the clock (blocking)
+func (c *MultiBeaconClient) StartHealthChecks(clock SlotClock, interval time.Duration) {
+	for {
+		for _, status := range c.CheckHealth() {
+			log := c.log.WithFields(logrus.Fields{"uri": status.URI, "headSlot": status.HeadSlot, "isSyncing": status.IsSyncing})
+			if !status.Healthy {
+				log.WithError(status.LastError).Warn("[beacon] node is unhealthy")
+			} else if status.IsSyncing {
+				log.Warn("[beacon] node is syncing")
+			}
+		}
+		clock.Sleep(interval)
+	}
+}
+
+// Status returns the last known status of all nodes
+func (c *MultiBeaconClient) Status() []BeaconNodeStatus {
+	c.statusLock.RLock()
+	defer c.statusLock.RUnlock()
+	status := make([]BeaconNodeStatus, len(c.status))
+	copy(status, c.status)
+	return status
+}
+
+// BestSyncStatus checks the health of all nodes, and returns the sync status of the best node
+func (c *MultiBeaconClient) BestSyncStatus(allowSyncing bool) (*beaconclient.SyncStatusPayloadData, error) {
+	if len(c.nodes) == 0 {
+		return nil, ErrBeaconNodesUnavailable
+	}
+	c.CheckHealth()
+	status := c.Status()[c.nodeIndicesByHealth()[0]]
+	if !status.Healthy {
+		return nil, ErrBeaconNodesUnavailable
+	}
+	if status.IsSyncing && !allowSyncing {
+		return nil, ErrBeaconNodesSyncing
+	}
+	return &beaconclient.SyncStatusPayloadData{HeadSlot: status.HeadSlot, IsSyncing: status.IsSyncing}, nil
+}
+
+// nodeIndicesByHealth returns the node indices ordered by preference: healthy before unhealthy, synced before syncing,
+// then by highest head slot (and otherwise in the configured order)
+func (c *MultiBeaconClient) nodeIndicesByHealth() []int {
+	status := c.Status()
+	indices := make([]int, len(status))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(a, b int) bool {
+		sa, sb := status[indices[a]], status[indices[b]]
+		if sa.Healthy != sb.Healthy {
+			return sa.Healthy
+		}
+		if sa.IsSyncing != sb.IsSyncing {
+			return !sa.IsSyncing
+		}
+		return sa.HeadSlot > sb.HeadSlot
+	})
+	return indices
+}
+
+// markUnhealthy is called when a request to a node failed, so the next requests go to other nodes first
+func (c *MultiBeaconClient) markUnhealthy(index int, err error) {
+	c.statusLock.Lock()
+	defer c.statusLock.Unlock()
+	c.status[index].Healthy = false
+	c.status[index].LastError = err
+}
+
+// request calls fn on the nodes in order of preference, until one succeeds
+func (c *MultiBeaconClient) request(name string, fn func(node BeaconNode) error) (err error) {
+	for _, i := range c.nodeIndicesByHealth() {
+		err = fn(c.nodes[i])
+		if err == nil {
+			return nil
+		}
+		c.log.WithField("uri", c.nodes[i].GetURI()).WithError(err).Warnf("[beacon] %s failed", name)
+		c.markUnhealthy(i, err)
+	}
+	return errors.Join(ErrBeaconNodesUnavailable, err)
+}
+
+func (c *MultiBeaconClient) GetProposerDuties(epoch uint64) (duties *beaconclient.ProposerDutiesResponse, err error) {
+	err = c.request("GetProposerDuties", func(node BeaconNode) (err error) {
+		duties, err = node.GetProposerDuties(epoch)
+		return err
+	})
+	return duties, err
+}
+
+func (c *MultiBeaconClient) GetBlock(blockID string) (block *beaconclient.GetBlockResponse, err error) {
+	err = c.request("GetBlock", func(node BeaconNode) (err error) {
+		block, err = node.GetBlock(blockID)
+		return err
+	})
+	return block, err
+}
+
+// SubscribeToHeadEvents subscribes to head events on all nodes. Only the first event of each new slot is forwarded.
+func (c *MultiBeaconClient) SubscribeToHeadEvents(slotC chan beaconclient.HeadEventData) {
+	c.subscribeAll(func(node BeaconNode) {
+		nodeC := make(chan beaconclient.HeadEventData)
+		go node.SubscribeToHeadEvents(nodeC)
+		for event := range nodeC {
+			if c.shouldForwardHeadEvent(event) {
+				slotC <- event
+			}
+		}
+	})
+}
+
+// SubscribeToPayloadAttributesEvents subscribes to payload_attributes events on all nodes. The first event for a new
+// slot is forwarded from any node (the fastest one wins). Further events for the same slot with a different parent
+// (reorgs) are only forwarded from the best node, so a lagging node can't cause flip-flopping between parents.
+func (c *MultiBeaconClient) SubscribeToPayloadAttributesEvents(payloadAttributesC chan beaconclient.PayloadAttributesEvent) {
+	c.subscribeAll(func(node BeaconNode) {
+		nodeC := make(chan beaconclient.PayloadAttributesEvent)
+		go node.SubscribeToPayloadAttributesEvents(nodeC)
+		for event := range nodeC {
+			if c.shouldForwardPayloadAttributes(node.GetURI(), event) {
+				payloadAttributesC <- event
+			}
+		}
+	})
+}
+
+func (c *MultiBeaconClient) subscribeAll(subscribe func(node BeaconNode)) {
+	for _, node := range c.nodes {
+		go subscribe(node)
+	}
+}
+
+func (c *MultiBeaconClient) shouldForwardHeadEvent(event beaconclient.HeadEventData) bool {
+	c.eventLock.Lock()
+	defer c.eventLock.Unlock()
+	if event.Slot <= c.lastHeadSlot {
+		return false
+	}
+	c.lastHeadSlot = event.Slot
+	return true
+}
+
+func (c *MultiBeaconClient) shouldForwardPayloadAttributes(uri string, event beaconclient.PayloadAttributesEvent) bool {
+	bestURI := c.nodes[c.nodeIndicesByHealth()[0]].GetURI()
+
+	c.eventLock.Lock()
+	defer c.eventLock.Unlock()
+
+	slot, parentHash := event.Data.ProposalSlot, event.Data.ParentBlockHash
+	lastParentHash, seen := c.lastPayloadAttributes[slot]
+	switch {
+	case !seen:
+		// only new slots (not older ones)
+		for seenSlot := range c.lastPayloadAttributes {
+			if seenSlot > slot {
+				return false
+			}
+		}
+	case lastParentHash == parentHash || uri != bestURI:
+		return false
+	}
+
+	c.lastPayloadAttributes[slot] = parentHash
+	for seenSlot := range c.lastPayloadAttributes {
+		if seenSlot < slot {
+			delete(c.lastPayloadAttributes, seenSlot)
+		}
+	}
+	return true
+}
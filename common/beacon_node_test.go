@@ -0,0 +1,147 @@
+package common
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/beaconclient"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestBeaconNode = errors.New("beacon node error")
+
+type testBeaconNode struct {
+	uri        string
+	syncStatus *beaconclient.SyncStatusPayloadData
+	err        error
+	requests   int
+
+	syncStatusRequests int
+}
+
+func (n *testBeaconNode) GetURI() string { return n.uri }
+
+func (n *testBeaconNode) SyncStatus() (*beaconclient.SyncStatusPayloadData, error) {
+	n.syncStatusRequests++
+	return n.syncStatus, n.err
+}
+
+func (n *testBeaconNode) GetProposerDuties(epoch uint64) (*beaconclient.ProposerDutiesResponse, error) {
+	n.requests++
+	if n.err != nil {
+		return nil, n.err
+	}
+	return &beaconclient.ProposerDutiesResponse{Data: []beaconclient.ProposerDutiesResponseData{{Slot: epoch * 32, Pubkey: n.uri}}}, nil
+}
+
+func (n *testBeaconNode) GetBlock(blockID string) (*beaconclient.GetBlockResponse, error) {
+	n.requests++
+	return nil, n.err
+}
+
+func (n *testBeaconNode) SubscribeToHeadEvents(slotC chan beaconclient.HeadEventData) {}
+
+func (n *testBeaconNode) SubscribeToPayloadAttributesEvents(payloadAttributesC chan beaconclient.PayloadAttributesEvent) {
+}
+
+func newTestBeaconNode(uri string, headSlot uint64, isSyncing bool) *testBeaconNode {
+	return &testBeaconNode{uri: uri, syncStatus: &beaconclient.SyncStatusPayloadData{HeadSlot: headSlot, IsSyncing: isSyncing}}
+}
+
+func TestMultiBeaconClientBestNode(t *testing.T) {
+	syncing := newTestBeaconNode("syncing", 200, true)
+	behind := newTestBeaconNode("behind", 99, false)
+	best := newTestBeaconNode("best", 100, false)
+	down := newTestBeaconNode("down", 0, false)
+	down.err = errTestBeaconNode
+
+	bn := NewMultiBeaconClient(Logger, []BeaconNode{down, syncing, behind, best})
+	syncStatus, err := bn.BestSyncStatus(false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), syncStatus.HeadSlot)
+	require.Equal(t, []int{3, 2, 1, 0}, bn.nodeIndicesByHealth())
+
+	// requests go to the best node
+	duties, err := bn.GetProposerDuties(1)
+	require.NoError(t, err)
+	require.Equal(t, "best", duties.Data[0].Pubkey)
+
+	// failover on errors, and the failed node isn't tried first anymore
+	best.err = errTestBeaconNode
+	duties, err = bn.GetProposerDuties(1)
+	require.NoError(t, err)
+	require.Equal(t, "behind", duties.Data[0].Pubkey)
+	require.Equal(t, 2, best.requests)
+	_, err = bn.GetProposerDuties(1)
+	require.NoError(t, err)
+	require.Equal(t, 2, best.requests)
+
+	// all nodes failing
+	behind.err = errTestBeaconNode
+	syncing.err = errTestBeaconNode
+	_, err = bn.GetBlock("head")
+	require.ErrorIs(t, err, ErrBeaconNodesUnavailable)
+	_, err = bn.BestSyncStatus(false)
+	require.ErrorIs(t, err, ErrBeaconNodesUnavailable)
+}
+
+func TestMultiBeaconClientSyncing(t *testing.T) {
+	bn := NewMultiBeaconClient(Logger, []BeaconNode{newTestBeaconNode("syncing", 200, true)})
+	_, err := bn.BestSyncStatus(false)
+	require.ErrorIs(t, err, ErrBeaconNodesSyncing)
+
+	syncStatus, err := bn.BestSyncStatus(true)
+	require.NoError(t, err)
+	require.True(t, syncStatus.IsSyncing)
+
+	_, err = NewMultiBeaconClientFromURIs(Logger, nil)
+	require.ErrorIs(t, err, ErrURLEmpty)
+}
+
+func TestMultiBeaconClientHealthChecks(t *testing.T) {
+	node := newTestBeaconNode("node", 100, false)
+	bn := NewMultiBeaconClient(Logger, []BeaconNode{node})
+	clock := NewFakeSlotClockAtSlot(100)
+	go bn.StartHealthChecks(clock, 12*time.Second)
+
+	clock.WaitForWaiters(1)
+	require.Equal(t, 1, node.syncStatusRequests)
+
+	node.err = errTestBeaconNode
+	clock.Advance(12 * time.Second)
+	clock.WaitForWaiters(1)
+	require.Equal(t, 2, node.syncStatusRequests)
+	require.False(t, bn.Status()[0].Healthy)
+}
+
+func TestMultiBeaconClientPayloadAttributesForwarding(t *testing.T) {
+	best := newTestBeaconNode("best", 100, false)
+	lagging := newTestBeaconNode("lagging", 99, false)
+	bn := NewMultiBeaconClient(Logger, []BeaconNode{lagging, best})
+	bn.CheckHealth()
+
+	event := func(slot uint64, parentHash string) beaconclient.PayloadAttributesEvent {
+		e := beaconclient.PayloadAttributesEvent{}
+		e.Data.ProposalSlot = slot
+		e.Data.ParentBlockHash = parentHash
+		return e
+	}
+
+	// a new slot is forwarded from any node, duplicates are not
+	require.True(t, bn.shouldForwardPayloadAttributes("lagging", event(101, "0x1")))
+	require.False(t, bn.shouldForwardPayloadAttributes("best", event(101, "0x1")))
+
+	// a new parent for the same slot only from the best node
+	require.False(t, bn.shouldForwardPayloadAttributes("lagging", event(101, "0x2")))
+	require.True(t, bn.shouldForwardPayloadAttributes("best", event(101, "0x2")))
+
+	// older slots are ignored
+	require.True(t, bn.shouldForwardPayloadAttributes("best", event(102, "0x3")))
+	require.False(t, bn.shouldForwardPayloadAttributes("best", event(101, "0x4")))
+
+	// head events are deduplicated by slot
+	require.True(t, bn.shouldForwardHeadEvent(beaconclient.HeadEventData{Slot: 100}))
+	require.False(t, bn.shouldForwardHeadEvent(beaconclient.HeadEventData{Slot: 100}))
+	require.False(t, bn.shouldForwardHeadEvent(beaconclient.HeadEventData{Slot: 99}))
+}
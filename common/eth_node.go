@@ -2,68 +2,340 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
 )
 
+var (
+	ErrEthNodesUnavailable = errors.New("all eth nodes are unavailable")
+
+	// DefaultEthNodeRequestTimeout is the timeout of a single request to one eth node
+	DefaultEthNodeRequestTimeout = 10 * time.Second
+)
+
+// BalanceQuery is the balance of an address at a block number
+type BalanceQuery struct {
+	Address     ethcommon.Address
+	BlockNumber *big.Int
+	Nonce       bool // query the transaction count instead of the balance (in the same batch)
+}
+
+// EthClient is the part of the execution JSON-RPC API used by relayscan (implemented by EthRPCClient)
+type EthClient interface {
+	BlockByHash(ctx context.Context, hash ethcommon.Hash) (*types.Block, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BalanceAt(ctx context.Context, account ethcommon.Address, blockNumber *big.Int) (*big.Int, error)
+
+	// BalancesAt returns several balances (or nonces) with a single request (a JSON-RPC batch)
+	BalancesAt(ctx context.Context, queries []BalanceQuery) ([]*big.Int, error)
+
+	// HeadersByHash returns several headers with a single request (a JSON-RPC batch), nil for unknown blocks
+	HeadersByHash(ctx context.Context, hashes []ethcommon.Hash) ([]*types.Header, error)
+
+	// ReceiptsByBlockHash returns the receipts of all transactions of a block (eth_getBlockReceipts)
+	ReceiptsByBlockHash(ctx context.Context, hash ethcommon.Hash) ([]*types.Receipt, error)
+}
+
+// EthRPCClient is an ethclient.Client that can also batch balance requests
+type EthRPCClient struct {
+	*ethclient.Client
+	rpc *rpc.Client
+}
+
+func DialEthRPCClient(uri string) (*EthRPCClient, error) {
+	rpcClient, err := rpc.Dial(uri)
+	if err != nil {
+		return nil, err
+	}
+	return &EthRPCClient{Client: ethclient.NewClient(rpcClient), rpc: rpcClient}, nil
+}
+
+func (c *EthRPCClient) BalancesAt(ctx context.Context, queries []BalanceQuery) ([]*big.Int, error) {
+	results := make([]hexutil.Big, len(queries))
+	batch := make([]rpc.BatchElem, len(queries))
+	for i, query := range queries {
+		method := "eth_getBalance"
+		if query.Nonce {
+			method = "eth_getTransactionCount"
+		}
+		batch[i] = rpc.BatchElem{
+			Method: method,
+			Args:   []any{query.Address, hexutil.EncodeBig(query.BlockNumber)},
+			Result: &results[i],
+		}
+	}
+	err := c.rpc.BatchCallContext(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]*big.Int, len(queries))
+	for i, elem := range batch {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("%s for %s @ %d: %w", elem.Method, queries[i].Address, queries[i].BlockNumber, elem.Error)
+		}
+		balances[i] = results[i].ToInt()
+	}
+	return balances, nil
+}
+
+func (c *EthRPCClient) HeadersByHash(ctx context.Context, hashes []ethcommon.Hash) ([]*types.Header, error) {
+	headers := make([]*types.Header, len(hashes))
+	batch := make([]rpc.BatchElem, len(hashes))
+	for i, hash := range hashes {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getBlockByHash",
+			Args:   []any{hash, false},
+			Result: &headers[i],
+		}
+	}
+	err := c.rpc.BatchCallContext(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, elem := range batch {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("eth_getBlockByHash for %s: %w", hashes[i], elem.Error)
+		}
+	}
+	return headers, nil
+}
+
+func (c *EthRPCClient) ReceiptsByBlockHash(ctx context.Context, hash ethcommon.Hash) ([]*types.Receipt, error) {
+	return c.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(hash, false))
+}
+
+// EthNodeStatus is the health of an eth node, as seen by the last request to it
+type EthNodeStatus struct {
+	URI         string
+	Healthy     bool
+	NumFailures int // consecutive failed requests
+	LastError   error
+	LastFailure time.Time
+}
+
+// EthNode uses any number of execution clients. Requests go to the healthy clients first (in the configured order),
+// and fail over to the next ones on errors and timeouts.
 type EthNode struct {
-	Clients []*ethclient.Client
+	log     *logrus.Entry
+	clients []EthClient
+	timeout time.Duration
+
+	statusLock sync.RWMutex
+	status     []EthNodeStatus // same order as clients
+}
+
+// NewEthNodeFromClients creates an eth node for the given clients (uris are only used for logging and may be nil)
+func NewEthNodeFromClients(log *logrus.Entry, clients []EthClient, uris []string) *EthNode {
+	status := make([]EthNodeStatus, len(clients))
+	for i := range clients {
+		status[i] = EthNodeStatus{Healthy: true}
+		if i < len(uris) {
+			status[i].URI = uris[i]
+		}
+	}
+
+	return &EthNode{
+		log:     log,
+		clients: clients,
+		timeout: DefaultEthNodeRequestTimeout,
+		status:  status,
+	}
 }
 
-func NewEthNode(uris ...string) (*EthNode, error) {
+// NewEthNode connects to the given eth node URIs
+func NewEthNode(log *logrus.Entry, uris ...string) (*EthNode, error) {
 	if len(uris) == 0 {
 		return nil, ErrURLEmpty
 	}
-	node := &EthNode{} //nolint:exhaustruct
-	for _, uri := range uris {
-		client, err := ethclient.Dial(uri)
+	clients := make([]EthClient, len(uris))
+	for i, uri := range uris {
+		if uri == "" {
+			return nil, ErrURLEmpty
+		}
+		client, err := DialEthRPCClient(uri)
 		if err != nil {
-			fmt.Println("Error connecting to eth node", uri, err)
-			return nil, err
+			return nil, fmt.Errorf("error connecting to eth node %s: %w", uri, err)
 		}
-		node.Clients = append(node.Clients, client)
+		clients[i] = client
 	}
-	return node, nil
+	return NewEthNodeFromClients(log, clients, uris), nil
 }
 
-func (n *EthNode) BlockByNumber(blockNumber int64) (block *types.Block, err error) {
-	for _, client := range n.Clients {
-		block, err = client.BlockByNumber(context.Background(), big.NewInt(blockNumber))
-		if err == nil {
-			return block, nil
+// ConnectEthNode connects to the given eth node URIs in order of preference (empty URIs are skipped)
+func ConnectEthNode(log *logrus.Entry, uris ...string) (*EthNode, error) {
+	nonEmpty := []string{}
+	for _, uri := range uris {
+		if uri != "" {
+			nonEmpty = append(nonEmpty, uri)
 		}
 	}
-	return nil, err
+	node, err := NewEthNode(log, nonEmpty...)
+	if err != nil {
+		return nil, err
+	}
+	for i, uri := range nonEmpty {
+		log.Infof("Using eth node #%d: %s", i+1, uri)
+	}
+	return node, nil
 }
 
-func (n *EthNode) BlockByHash(blockHash string) (block *types.Block, err error) {
-	for _, client := range n.Clients {
-		block, err = client.BlockByHash(context.Background(), ethcommon.HexToHash(blockHash))
-		if err == nil {
-			return block, nil
-		}
-	}
-	return nil, err
+// SetRequestTimeout sets the timeout for each request to a single client (DefaultEthNodeRequestTimeout by default)
+func (n *EthNode) SetRequestTimeout(timeout time.Duration) {
+	n.timeout = timeout
 }
 
-func (n *EthNode) GetBalanceDiff(address string, blockNumber int64) (diff *big.Int, err error) {
-	for _, client := range n.Clients {
-		balanceBefore, err := client.BalanceAt(context.Background(), ethcommon.HexToAddress(address), big.NewInt(blockNumber-1))
-		if err != nil {
-			continue
+// Status returns the last known status of all clients
+func (n *EthNode) Status() []EthNodeStatus {
+	n.statusLock.RLock()
+	defer n.statusLock.RUnlock()
+	status := make([]EthNodeStatus, len(n.status))
+	copy(status, n.status)
+	return status
+}
+
+// clientIndicesByHealth returns the client indices ordered by preference: healthy before unhealthy, then by the number
+// of consecutive failures (and otherwise in the configured order)
+func (n *EthNode) clientIndicesByHealth() []int {
+	status := n.Status()
+	indices := make([]int, len(status))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(a, b int) bool {
+		sa, sb := status[indices[a]], status[indices[b]]
+		if sa.Healthy != sb.Healthy {
+			return sa.Healthy
 		}
+		return sa.NumFailures < sb.NumFailures
+	})
+	return indices
+}
 
-		balanceAfter, err := client.BalanceAt(context.Background(), ethcommon.HexToAddress(address), big.NewInt(blockNumber))
-		if err != nil {
+func (n *EthNode) markHealthy(index int) {
+	n.statusLock.Lock()
+	defer n.statusLock.Unlock()
+	n.status[index].Healthy = true
+	n.status[index].NumFailures = 0
+}
+
+func (n *EthNode) markUnhealthy(index int, err error) {
+	n.statusLock.Lock()
+	defer n.statusLock.Unlock()
+	n.status[index].Healthy = false
+	n.status[index].NumFailures++
+	n.status[index].LastError = err
+	n.status[index].LastFailure = time.Now().UTC()
+}
+
+// request calls fn on the clients in order of preference, until one succeeds. "not found" responses don't mark a
+// client as unhealthy (it might just lag behind), and are returned as ethereum.NotFound if no client has the data.
+func (n *EthNode) request(ctx context.Context, name string, fn func(ctx context.Context, client EthClient) error) error {
+	var lastErr error
+	for _, i := range n.clientIndicesByHealth() {
+		reqCtx, cancel := context.WithTimeout(ctx, n.timeout)
+		err := fn(reqCtx, n.clients[i])
+		cancel()
+		if err == nil {
+			n.markHealthy(i)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ethereum.NotFound) {
 			continue
 		}
+		n.log.WithField("uri", n.status[i].URI).WithError(err).Warnf("[eth] %s failed", name)
+		n.markUnhealthy(i, err)
+		lastErr = err
+	}
+	if len(n.clients) > 0 && lastErr == nil {
+		return ethereum.NotFound
+	}
+	return errors.Join(ErrEthNodesUnavailable, lastErr)
+}
+
+func (n *EthNode) BlockByHash(ctx context.Context, hash ethcommon.Hash) (block *types.Block, err error) {
+	err = n.request(ctx, "BlockByHash", func(ctx context.Context, client EthClient) (err error) {
+		block, err = client.BlockByHash(ctx, hash)
+		return err
+	})
+	return block, err
+}
+
+func (n *EthNode) BlockByNumber(ctx context.Context, number *big.Int) (block *types.Block, err error) {
+	err = n.request(ctx, "BlockByNumber", func(ctx context.Context, client EthClient) (err error) {
+		block, err = client.BlockByNumber(ctx, number)
+		return err
+	})
+	return block, err
+}
+
+func (n *EthNode) HeaderByNumber(ctx context.Context, number *big.Int) (header *types.Header, err error) {
+	err = n.request(ctx, "HeaderByNumber", func(ctx context.Context, client EthClient) (err error) {
+		header, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+func (n *EthNode) BalanceAt(ctx context.Context, account ethcommon.Address, blockNumber *big.Int) (balance *big.Int, err error) {
+	err = n.request(ctx, "BalanceAt", func(ctx context.Context, client EthClient) (err error) {
+		balance, err = client.BalanceAt(ctx, account, blockNumber)
+		return err
+	})
+	return balance, err
+}
+
+// BalancesAt returns the balances for all queries, from a single client with one batched request
+func (n *EthNode) BalancesAt(ctx context.Context, queries []BalanceQuery) (balances []*big.Int, err error) {
+	err = n.request(ctx, "BalancesAt", func(ctx context.Context, client EthClient) (err error) {
+		balances, err = client.BalancesAt(ctx, queries)
+		return err
+	})
+	return balances, err
+}
+
+// HeadersByHash returns the headers for all hashes (nil for unknown blocks), from a single client with one batched request
+func (n *EthNode) HeadersByHash(ctx context.Context, hashes []ethcommon.Hash) (headers []*types.Header, err error) {
+	err = n.request(ctx, "HeadersByHash", func(ctx context.Context, client EthClient) (err error) {
+		headers, err = client.HeadersByHash(ctx, hashes)
+		return err
+	})
+	return headers, err
+}
+
+func (n *EthNode) ReceiptsByBlockHash(ctx context.Context, hash ethcommon.Hash) (receipts []*types.Receipt, err error) {
+	err = n.request(ctx, "ReceiptsByBlockHash", func(ctx context.Context, client EthClient) (err error) {
+		receipts, err = client.ReceiptsByBlockHash(ctx, hash)
+		return err
+	})
+	return receipts, err
+}
 
-		balanceDiff := new(big.Int).Sub(balanceAfter, balanceBefore)
-		return balanceDiff, nil
+// BalanceDiff returns how much the balance of an address changed in a block (both balances with one batched request)
+func (n *EthNode) BalanceDiff(ctx context.Context, address ethcommon.Address, blockNumber *big.Int) (*big.Int, error) {
+	balances, err := n.BalancesAt(ctx, []BalanceQuery{
+		{Address: address, BlockNumber: new(big.Int).Sub(blockNumber, big.NewInt(1))},
+		{Address: address, BlockNumber: blockNumber},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get balance diff for %s @ %d: %w", address, blockNumber, err)
 	}
-	return nil, err
+	return new(big.Int).Sub(balances[1], balances[0]), nil
 }
@@ -0,0 +1,266 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestEthNode = errors.New("eth node error")
+
+// testEthClient returns err for all requests (or blocks until the context is done if hang is set), and otherwise
+// a balance of 100 * block number
+type testEthClient struct {
+	err      error
+	hang     bool
+	numCalls atomic.Int64
+}
+
+func (c *testEthClient) call(ctx context.Context) error {
+	c.numCalls.Add(1)
+	if c.hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return c.err
+}
+
+func (c *testEthClient) BlockByHash(ctx context.Context, hash ethcommon.Hash) (*types.Block, error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+	return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}), nil
+}
+
+func (c *testEthClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+	return types.NewBlockWithHeader(&types.Header{Number: number}), nil
+}
+
+func (c *testEthClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+	return &types.Header{Number: number}, nil
+}
+
+func (c *testEthClient) BalanceAt(ctx context.Context, account ethcommon.Address, blockNumber *big.Int) (*big.Int, error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+	return new(big.Int).Mul(blockNumber, big.NewInt(100)), nil
+}
+
+func (c *testEthClient) BalancesAt(ctx context.Context, queries []BalanceQuery) ([]*big.Int, error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+	balances := make([]*big.Int, len(queries))
+	for i, query := range queries {
+		balances[i] = new(big.Int).Mul(query.BlockNumber, big.NewInt(100))
+	}
+	return balances, nil
+}
+
+func (c *testEthClient) HeadersByHash(ctx context.Context, hashes []ethcommon.Hash) ([]*types.Header, error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+	headers := make([]*types.Header, len(hashes))
+	for i := range hashes {
+		headers[i] = &types.Header{Number: big.NewInt(int64(i))}
+	}
+	return headers, nil
+}
+
+func (c *testEthClient) ReceiptsByBlockHash(ctx context.Context, hash ethcommon.Hash) ([]*types.Receipt, error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+	return []*types.Receipt{}, nil
+}
+
+func TestEthNodeFailover(t *testing.T) {
+	failing := &testEthClient{err: errTestEthNode}
+	ok := &testEthClient{}
+	node := NewEthNodeFromClients(Logger, []EthClient{failing, ok}, []string{"failing", "ok"})
+
+	header, err := node.HeaderByNumber(context.Background(), big.NewInt(5))
+	require.NoError(t, err)
+	require.Equal(t, int64(5), header.Number.Int64())
+	require.Equal(t, int64(1), failing.numCalls.Load())
+
+	// the failed client is tried last from now on
+	_, err = node.BlockByNumber(context.Background(), big.NewInt(5))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), failing.numCalls.Load())
+	status := node.Status()
+	require.False(t, status[0].Healthy)
+	require.Equal(t, 1, status[0].NumFailures)
+	require.ErrorIs(t, status[0].LastError, errTestEthNode)
+	require.True(t, status[1].Healthy)
+
+	// when all clients fail, the error is returned
+	ok.err = errTestEthNode
+	_, err = node.BlockByHash(context.Background(), ethcommon.Hash{})
+	require.ErrorIs(t, err, ErrEthNodesUnavailable)
+	require.ErrorIs(t, err, errTestEthNode)
+
+	// a successful request makes a client healthy again
+	failing.err = nil
+	_, err = node.BalanceAt(context.Background(), ethcommon.Address{}, big.NewInt(1))
+	require.NoError(t, err)
+	require.True(t, node.Status()[0].Healthy)
+	require.Equal(t, []int{0, 1}, node.clientIndicesByHealth())
+}
+
+func TestEthNodeNotFound(t *testing.T) {
+	lagging := &testEthClient{err: ethereum.NotFound}
+	ok := &testEthClient{}
+	node := NewEthNodeFromClients(Logger, []EthClient{lagging, ok}, nil)
+
+	// not found on one client, found on the other
+	_, err := node.BlockByHash(context.Background(), ethcommon.Hash{})
+	require.NoError(t, err)
+	require.True(t, node.Status()[0].Healthy)
+
+	// not found on all clients
+	ok.err = ethereum.NotFound
+	_, err = node.BlockByHash(context.Background(), ethcommon.Hash{})
+	require.ErrorIs(t, err, ethereum.NotFound)
+	require.NotErrorIs(t, err, ErrEthNodesUnavailable)
+
+	// not found on one, and failed on the other: unknown
+	ok.err = errTestEthNode
+	_, err = node.BlockByHash(context.Background(), ethcommon.Hash{})
+	require.ErrorIs(t, err, ErrEthNodesUnavailable)
+	require.NotErrorIs(t, err, ethereum.NotFound)
+}
+
+func TestEthNodeTimeout(t *testing.T) {
+	hanging := &testEthClient{hang: true}
+	ok := &testEthClient{}
+	node := NewEthNodeFromClients(Logger, []EthClient{hanging, ok}, nil)
+	node.SetRequestTimeout(10 * time.Millisecond)
+
+	diff, err := node.BalanceDiff(context.Background(), ethcommon.Address{}, big.NewInt(10))
+	require.NoError(t, err)
+	require.Equal(t, int64(100), diff.Int64())
+	require.ErrorIs(t, node.Status()[0].LastError, context.DeadlineExceeded)
+
+	// a cancelled parent context isn't a node failure
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ok.hang = true
+	_, err = node.BalanceAt(ctx, ethcommon.Address{}, big.NewInt(10))
+	require.ErrorIs(t, err, context.Canceled)
+	require.True(t, node.Status()[1].Healthy)
+}
+
+func TestEthRPCClientBalancesAt(t *testing.T) {
+	type rpcRequest struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params []string        `json:"params"`
+	}
+
+	numHTTPRequests := new(atomic.Int64)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		numHTTPRequests.Add(1)
+		var batch []rpcRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+
+		resp := []map[string]any{}
+		for _, req := range batch {
+			require.Contains(t, []string{"eth_getBalance", "eth_getTransactionCount"}, req.Method)
+			require.Len(t, req.Params, 2)
+			// balance is 100 * block number, nonce is the block number
+			blockNumber, ok := new(big.Int).SetString(req.Params[1][2:], 16)
+			require.True(t, ok)
+			result := fmt.Sprintf("0x%x", new(big.Int).Mul(blockNumber, big.NewInt(100)))
+			if req.Method == "eth_getTransactionCount" {
+				result = fmt.Sprintf("0x%x", blockNumber)
+			}
+			resp = append(resp, map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	node, err := ConnectEthNode(Logger, srv.URL, "")
+	require.NoError(t, err)
+	require.Len(t, node.Status(), 1)
+
+	diff, err := node.BalanceDiff(context.Background(), ethcommon.Address{0x01}, big.NewInt(20))
+	require.NoError(t, err)
+	require.Equal(t, int64(100), diff.Int64())
+	require.Equal(t, int64(1), numHTTPRequests.Load())
+
+	// balances and nonces in the same batch
+	results, err := node.BalancesAt(context.Background(), []BalanceQuery{
+		{Address: ethcommon.Address{0x01}, BlockNumber: big.NewInt(20)},
+		{Address: ethcommon.Address{0x01}, BlockNumber: big.NewInt(20), Nonce: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []*big.Int{big.NewInt(2000), big.NewInt(20)}, results)
+	require.Equal(t, int64(2), numHTTPRequests.Load())
+}
+
+func TestEthRPCClientHeadersByHash(t *testing.T) {
+	type rpcRequest struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params []any           `json:"params"`
+	}
+
+	known := &types.Header{Number: big.NewInt(7), Difficulty: big.NewInt(0), Extra: []byte("builder")}
+	numHTTPRequests := new(atomic.Int64)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		numHTTPRequests.Add(1)
+		var batch []rpcRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+
+		resp := []map[string]any{}
+		for _, req := range batch {
+			require.Equal(t, "eth_getBlockByHash", req.Method)
+			fullTxs, ok := req.Params[1].(bool)
+			require.True(t, ok)
+			require.False(t, fullTxs) // header only
+			// null for unknown blocks
+			var result any
+			if req.Params[0] == known.Hash().Hex() {
+				result = known
+			}
+			resp = append(resp, map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	node, err := ConnectEthNode(Logger, srv.URL, "")
+	require.NoError(t, err)
+
+	headers, err := node.HeadersByHash(context.Background(), []ethcommon.Hash{known.Hash(), {0x01}})
+	require.NoError(t, err)
+	require.Len(t, headers, 2)
+	require.Equal(t, known.Hash(), headers[0].Hash())
+	require.Equal(t, []byte("builder"), headers[0].Extra)
+	require.Nil(t, headers[1])
+	require.Equal(t, int64(1), numHTTPRequests.Load())
+}
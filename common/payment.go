@@ -0,0 +1,80 @@
+package common
+
+import (
+	"math/big"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/flashbots/relayscan/vars"
+)
+
+const (
+	PaymentCheckMethodBalanceDiff        = "balanceDiff"
+	PaymentCheckMethodBalanceDiffTxValue = "balanceDiff+txValue"
+)
+
+// ProposerPaymentCheck is the payment to the proposer fee recipient, compared to the claimed value
+type ProposerPaymentCheck struct {
+	Method    string
+	DiffWei   *big.Int           // claimed - delivered
+	PaymentTx *types.Transaction // last tx of the block, if it's sent to the fee recipient
+}
+
+func (p *ProposerPaymentCheck) Ok() bool {
+	return p.DiffWei.Sign() == 0
+}
+
+// CheckProposerPayment compares the claimed value with the balance diff of the fee recipient. If they differ, the
+// payment might have been forwarded through a smart contract wallet, and the value of the last transaction is compared
+// instead if it's sent to the fee recipient.
+func CheckProposerPayment(block *types.Block, feeRecipient ethcommon.Address, balanceDiff, claimedValue *big.Int) *ProposerPaymentCheck {
+	check := &ProposerPaymentCheck{
+		Method:  PaymentCheckMethodBalanceDiff,
+		DiffWei: new(big.Int).Sub(claimedValue, balanceDiff),
+	}
+
+	txs := block.Transactions()
+	if len(txs) > 0 {
+		lastTx := txs[len(txs)-1]
+		if lastTx.To() != nil && *lastTx.To() == feeRecipient {
+			check.PaymentTx = lastTx
+		}
+	}
+
+	if check.DiffWei.Sign() != 0 {
+		check.Method = PaymentCheckMethodBalanceDiffTxValue
+		if check.PaymentTx != nil {
+			check.DiffWei = new(big.Int).Sub(claimedValue, check.PaymentTx.Value())
+		}
+	}
+	return check
+}
+
+// BuilderProfit returns the coinbase balance diff, adjusted for transfers from the coinbase to builder-owned addresses
+// (vars.BuilderAddresses). Only the senders of transactions to builder-owned addresses are recovered.
+func BuilderProfit(block *types.Block, coinbaseBalanceDiff *big.Int) *big.Int {
+	profit := new(big.Int).Set(coinbaseBalanceDiff)
+	builderOwnedAddresses := vars.BuilderAddresses[strings.ToLower(block.Coinbase().Hex())]
+	if len(builderOwnedAddresses) == 0 {
+		return profit
+	}
+	for _, tx := range block.Transactions() {
+		if tx.To() == nil || !builderOwnedAddresses[strings.ToLower(tx.To().Hex())] {
+			continue
+		}
+		if from, err := TxSender(tx); err == nil && from == block.Coinbase() {
+			profit.Add(profit, tx.Value())
+		}
+	}
+	return profit
+}
+
+// TxSender recovers the sender of a transaction, including unprotected legacy transactions (chain id 0)
+func TxSender(tx *types.Transaction) (ethcommon.Address, error) {
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.ChainId().Sign() != 0 {
+		signer = types.LatestSignerForChainID(tx.ChainId())
+	}
+	return types.Sender(signer, tx)
+}
@@ -0,0 +1,193 @@
+package common
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SlotClock provides the current time and slot, and waiting (so scheduling can be tested with a fake clock)
+type SlotClock interface {
+	Now() time.Time
+	CurrentSlot() uint64
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemSlotClock computes the current slot from the system time and the genesis time (vars.Genesis)
+type SystemSlotClock struct{}
+
+func NewSystemSlotClock() *SystemSlotClock {
+	return &SystemSlotClock{}
+}
+
+func (c *SystemSlotClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+func (c *SystemSlotClock) CurrentSlot() uint64 {
+	return TimeToSlot(c.Now())
+}
+
+func (c *SystemSlotClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (c *SystemSlotClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// BeaconCheckedSlotClock computes the current slot from the system time like SystemSlotClock, and uses the beacon node
+// only as a sanity check: its head slot is compared at most once per beaconCheckInterval, and a large difference is
+// logged, as it means the system clock or the beacon node is off. The head slot isn't used as current slot, because it
+// lags behind when slots are missed.
+type BeaconCheckedSlotClock struct {
+	SystemSlotClock
+	log *logrus.Entry
+	bn  *MultiBeaconClient
+
+	checkLock   sync.Mutex
+	lastCheck   time.Time
+	lastHeadLag int64 // wall-clock slot minus beacon head slot at the last check
+}
+
+const (
+	beaconCheckInterval   = time.Minute
+	beaconCheckMaxHeadLag = 4 // slots
+)
+
+func NewBeaconCheckedSlotClock(log *logrus.Entry, bn *MultiBeaconClient) *BeaconCheckedSlotClock {
+	return &BeaconCheckedSlotClock{log: log, bn: bn}
+}
+
+func (c *BeaconCheckedSlotClock) CurrentSlot() uint64 {
+	slot := c.SystemSlotClock.CurrentSlot()
+	c.checkHeadSlot(slot)
+	return slot
+}
+
+// checkHeadSlot compares the wall-clock slot to the head slot of the beacon node, if the last check is old enough
+func (c *BeaconCheckedSlotClock) checkHeadSlot(slot uint64) {
+	c.checkLock.Lock()
+	defer c.checkLock.Unlock()
+	now := c.Now()
+	if !c.lastCheck.IsZero() && now.Sub(c.lastCheck) < beaconCheckInterval {
+		return
+	}
+	c.lastCheck = now
+
+	syncStatus, err := c.bn.BestSyncStatus(false)
+	if err != nil {
+		c.log.WithError(err).Warn("failed to get head slot from beacon node to check the system clock")
+		return
+	}
+	c.lastHeadLag = int64(slot) - int64(syncStatus.HeadSlot) //nolint:gosec
+	if c.lastHeadLag > beaconCheckMaxHeadLag || c.lastHeadLag < -1 {
+		c.log.WithFields(logrus.Fields{
+			"slot":     slot,
+			"headSlot": syncStatus.HeadSlot,
+		}).Warn("beacon node head slot differs from the wall-clock slot, check the system clock and the beacon node")
+	}
+}
+
+// NewSlotClock returns a BeaconCheckedSlotClock if beacon node URIs are given, and a SystemSlotClock otherwise
+func NewSlotClock(log *logrus.Entry, beaconCheckURIs []string) (SlotClock, error) {
+	if len(beaconCheckURIs) == 0 {
+		return NewSystemSlotClock(), nil
+	}
+	bn, err := NewMultiBeaconClientFromURIs(log, beaconCheckURIs)
+	if err != nil {
+		return nil, err
+	}
+	return NewBeaconCheckedSlotClock(log, bn), nil
+}
+
+// FakeSlotClock is a SlotClock for tests, which only changes when set or advanced. Sleep and After wait until the
+// clock is advanced past their deadline.
+type FakeSlotClock struct {
+	lock    sync.Mutex
+	now     time.Time
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	until time.Time
+	c     chan time.Time
+}
+
+func NewFakeSlotClock(now time.Time) *FakeSlotClock {
+	return &FakeSlotClock{now: now.UTC()}
+}
+
+// NewFakeSlotClockAtSlot returns a fake clock at the start of the given slot
+func NewFakeSlotClockAtSlot(slot uint64) *FakeSlotClock {
+	return NewFakeSlotClock(SlotToTime(slot))
+}
+
+func (c *FakeSlotClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *FakeSlotClock) CurrentSlot() uint64 {
+	return TimeToSlot(c.Now())
+}
+
+func (c *FakeSlotClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+func (c *FakeSlotClock) After(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeClockWaiter{until: c.now.Add(d), c: ch})
+	return ch
+}
+
+func (c *FakeSlotClock) Set(now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = now.UTC()
+	c.fireWaiters()
+}
+
+func (c *FakeSlotClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	c.fireWaiters()
+}
+
+// NumWaiters returns the number of pending Sleep and After calls
+func (c *FakeSlotClock) NumWaiters() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.waiters)
+}
+
+// WaitForWaiters blocks until there are at least n pending Sleep and After calls (i.e. goroutines reached their wait)
+func (c *FakeSlotClock) WaitForWaiters(n int) {
+	for c.NumWaiters() < n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// fireWaiters wakes up all waiters whose deadline has passed (must be called with the lock held)
+func (c *FakeSlotClock) fireWaiters() {
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if !w.until.After(c.now) {
+			w.c <- c.now
+		} else {
+			pending = append(pending, w)
+		}
+	}
+	c.waiters = pending
+}
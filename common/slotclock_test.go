@@ -0,0 +1,78 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeSlotClock(t *testing.T) {
+	clock := NewFakeSlotClockAtSlot(6591598)
+	require.Equal(t, uint64(6591598), clock.CurrentSlot())
+	require.Equal(t, SlotToTime(6591598), clock.Now())
+
+	clock.Advance(11 * time.Second)
+	require.Equal(t, uint64(6591598), clock.CurrentSlot())
+	clock.Advance(1 * time.Second)
+	require.Equal(t, uint64(6591599), clock.CurrentSlot())
+
+	clock.Set(SlotToTime(100))
+	require.Equal(t, uint64(100), clock.CurrentSlot())
+}
+
+func TestFakeSlotClockWaiters(t *testing.T) {
+	clock := NewFakeSlotClockAtSlot(100)
+
+	// non-positive durations return immediately
+	<-clock.After(0)
+	clock.Sleep(-time.Second)
+	require.Equal(t, 0, clock.NumWaiters())
+
+	done := make(chan bool)
+	go func() {
+		clock.Sleep(2 * time.Second)
+		done <- true
+	}()
+	after := clock.After(time.Second)
+	clock.WaitForWaiters(2)
+
+	clock.Advance(time.Second)
+	require.Equal(t, SlotToTime(100).Add(time.Second), <-after)
+	require.Equal(t, 1, clock.NumWaiters())
+
+	clock.Advance(time.Second)
+	<-done
+	require.Equal(t, 0, clock.NumWaiters())
+}
+
+func TestNewSlotClock(t *testing.T) {
+	clock, err := NewSlotClock(Logger, nil)
+	require.NoError(t, err)
+	require.IsType(t, &SystemSlotClock{}, clock)
+	require.Equal(t, TimeToSlot(time.Now().UTC()), clock.CurrentSlot())
+
+	clock, err = NewSlotClock(Logger, []string{"http://localhost:3500"})
+	require.NoError(t, err)
+	require.IsType(t, &BeaconCheckedSlotClock{}, clock)
+}
+
+func TestBeaconCheckedSlotClock(t *testing.T) {
+	// the head slot lags behind when slots are missed, the current slot is always the wall-clock slot
+	node := newTestBeaconNode("bn", 1234, false)
+	clock := NewBeaconCheckedSlotClock(Logger, NewMultiBeaconClient(Logger, []BeaconNode{node}))
+	slot := clock.CurrentSlot()
+	require.InDelta(t, TimeToSlot(time.Now().UTC()), slot, 1)
+	require.Equal(t, int64(slot)-1234, clock.lastHeadLag) //nolint:gosec
+
+	// the beacon node check is cached
+	numRequests := node.syncStatusRequests
+	clock.CurrentSlot()
+	require.Equal(t, numRequests, node.syncStatusRequests)
+
+	// beacon node errors don't affect the slot
+	node.err = errTestBeaconNode
+	clock.lastCheck = time.Time{}
+	require.InDelta(t, TimeToSlot(time.Now().UTC()), clock.CurrentSlot(), 1)
+	require.Equal(t, numRequests+1, node.syncStatusRequests)
+}
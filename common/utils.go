@@ -2,9 +2,7 @@
 package common
 
 import (
-	"encoding/json"
 	"math/big"
-	"net/http"
 	"net/url"
 	"runtime"
 	"strings"
@@ -12,9 +10,7 @@ import (
 
 	"github.com/dustin/go-humanize"
 	"github.com/ethereum/go-ethereum/params"
-	"github.com/flashbots/mev-boost-relay/beaconclient"
 	"github.com/flashbots/relayscan/vars"
-	"github.com/sirupsen/logrus"
 )
 
 func Check(err error) {
@@ -116,16 +112,6 @@ func BeginningOfDay(t time.Time) time.Time {
 	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
 }
 
-func MustConnectBeaconNode(log *logrus.Entry, beaconNodeURI string, allowSyncing bool) (bn *beaconclient.ProdBeaconInstance, headSlot uint64) {
-	bn = beaconclient.NewProdBeaconInstance(log, beaconNodeURI)
-	syncStatus, err := bn.SyncStatus()
-	Check(err)
-	if syncStatus.IsSyncing && !allowSyncing {
-		panic("beacon node is syncing")
-	}
-	return bn, syncStatus.HeadSlot
-}
-
 func ReverseBytes(src []byte) []byte {
 	dst := make([]byte, len(src))
 	copy(dst, src)
@@ -150,24 +136,3 @@ func HumanBytes(n uint64) string {
 	s = strings.Replace(s, "GiB", "GB", 1)
 	return s
 }
-
-func getJSON(url string, target interface{}) error {
-	r, err := http.DefaultClient.Get(url)
-	if err != nil {
-		return err
-	}
-	defer r.Body.Close() //nolint:errcheck
-	return json.NewDecoder(r.Body).Decode(target)
-}
-
-func MustGetLatestSlot() uint64 {
-	url := "https://beaconcha.in/api/v1/slot/latest"
-	var c struct {
-		Data struct {
-			Slot uint64 `json:"slot"`
-		}
-	}
-	err := getJSON(url, &c)
-	Check(err)
-	return c.Data.Slot
-}
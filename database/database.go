@@ -2,6 +2,8 @@
 package database
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"time"
 
@@ -12,6 +14,14 @@ import (
 	migrate "github.com/rubenv/sql-migrate"
 )
 
+var ErrInvalidGroupBy = errors.New("invalid group by")
+
+// Columns the value claim accuracy can be grouped by
+const (
+	GroupByRelay     = "relay"
+	GroupByExtraData = "extra_data"
+)
+
 type DatabaseService struct {
 	DB *sqlx.DB
 }
@@ -45,8 +55,8 @@ func (s *DatabaseService) Close() error {
 
 func (s *DatabaseService) SaveSignedBuilderBid(entry SignedBuilderBidEntry) error {
 	query := `INSERT INTO ` + vars.TableSignedBuilderBid + `
-		(relay, requested_at, received_at, duration_ms, slot, parent_hash, proposer_pubkey, pubkey, signature, value, fee_recipient, block_hash, block_number, gas_limit, gas_used, extra_data, epoch, timestamp, prev_randao) VALUES
-		(:relay, :requested_at, :received_at, :duration_ms, :slot, :parent_hash, :proposer_pubkey, :pubkey, :signature, :value, :fee_recipient, :block_hash, :block_number, :gas_limit, :gas_used, :extra_data, :epoch, :timestamp, :prev_randao)
+		(relay, requested_at, received_at, duration_ms, slot, parent_hash, proposer_pubkey, pubkey, signature, value, fee_recipient, block_hash, block_number, gas_limit, gas_used, extra_data, epoch, timestamp, prev_randao, version, encoding, num_blobs, signature_status, signed_bid) VALUES
+		(:relay, :requested_at, :received_at, :duration_ms, :slot, :parent_hash, :proposer_pubkey, :pubkey, :signature, :value, :fee_recipient, :block_hash, :block_number, :gas_limit, :gas_used, :extra_data, :epoch, :timestamp, :prev_randao, :version, :encoding, :num_blobs, :signature_status, :signed_bid)
 		ON CONFLICT DO NOTHING`
 	_, err := s.DB.NamedExec(query, entry)
 	return err
@@ -206,6 +216,15 @@ func (s *DatabaseService) GetDeliveredPayloadsForSlot(slot uint64) (res []*DataA
 	return res, err
 }
 
+// GetDeliveredPayloadsForBlockHash returns the payloads of all relays that delivered a block (uses the block_hash index)
+func (s *DatabaseService) GetDeliveredPayloadsForBlockHash(blockHash string) (res []*DataAPIPayloadDeliveredEntry, err error) {
+	query := `SELECT
+		id, inserted_at, relay, epoch, slot, parent_hash, block_hash, builder_pubkey, proposer_pubkey, proposer_fee_recipient, gas_limit, gas_used, value_claimed_wei, value_claimed_eth, num_tx, block_number
+	FROM ` + vars.TableDataAPIPayloadDelivered + ` WHERE block_hash=$1;`
+	err = s.DB.Select(&res, query, blockHash)
+	return res, err
+}
+
 func (s *DatabaseService) GetLatestDeliveredPayload() (*DataAPIPayloadDeliveredEntry, error) {
 	query := `SELECT
 		id, inserted_at, relay, epoch, slot, parent_hash, block_hash, builder_pubkey, proposer_pubkey, proposer_fee_recipient, gas_limit, gas_used, value_claimed_wei, value_claimed_eth, num_tx, block_number
@@ -225,12 +244,20 @@ func (s *DatabaseService) GetDeliveredPayloadsForSlots(slotStart, slotEnd uint64
 
 func (s *DatabaseService) GetSignedBuilderBidsForSlot(slot uint64) (res []*SignedBuilderBidEntry, err error) {
 	query := `SELECT
-		id, relay, requested_at, received_at, duration_ms, slot, parent_hash, proposer_pubkey, pubkey, signature, value, fee_recipient, block_hash, block_number, gas_limit, gas_used, extra_data, epoch, timestamp, prev_randao
+		id, relay, requested_at, received_at, duration_ms, slot, parent_hash, proposer_pubkey, pubkey, signature, value, fee_recipient, block_hash, block_number, gas_limit, gas_used, extra_data, epoch, timestamp, prev_randao, version, encoding, num_blobs, signature_status, signed_bid
 	FROM ` + vars.TableSignedBuilderBid + ` WHERE slot=$1;`
 	err = s.DB.Select(&res, query, slot)
 	return res, err
 }
 
+func (s *DatabaseService) GetDataAPIBidsForSlot(slot uint64) (res []*DataAPIBuilderBidEntry, err error) {
+	query := `SELECT
+		id, inserted_at, relay, epoch, slot, parent_hash, block_hash, builder_pubkey, proposer_pubkey, proposer_fee_recipient, gas_limit, gas_used, value, num_tx, block_number, timestamp
+	FROM ` + vars.TableDataAPIBuilderBid + ` WHERE slot=$1;`
+	err = s.DB.Select(&res, query, slot)
+	return res, err
+}
+
 func (s *DatabaseService) SaveBuilderStats(entries []*BuilderStatsEntry) error {
 	if len(entries) == 0 {
 		return nil
@@ -271,3 +298,320 @@ func (s *DatabaseService) GetRecentPayloadsForExtraData(extraData []string, limi
 	err = s.DB.Select(&resp, query, args...)
 	return resp, err
 }
+
+func (s *DatabaseService) SaveDataAPIBackfillStatus(entry *DataAPIBackfillStatusEntry) error {
+	query := `INSERT INTO ` + vars.TableDataAPIBackfillStatus + `
+		(relay, available, error, num_requests, num_payloads, duration_ms) VALUES
+		(:relay, :available, :error, :num_requests, :num_payloads, :duration_ms)`
+	_, err := s.DB.NamedExec(query, entry)
+	return err
+}
+
+// GetDataAPIBackfillStatus returns the latest backfill results for a relay (newest first)
+func (s *DatabaseService) GetDataAPIBackfillStatus(relay string, limit int) (res []*DataAPIBackfillStatusEntry, err error) {
+	query := `SELECT id, inserted_at, relay, available, error, num_requests, num_payloads, duration_ms FROM ` + vars.TableDataAPIBackfillStatus + ` WHERE relay=$1 ORDER BY inserted_at DESC LIMIT $2;`
+	err = s.DB.Select(&res, query, relay, limit)
+	return res, err
+}
+
+// GetLatestCheckedSlot returns the highest slot with a payload value check result
+func (s *DatabaseService) GetLatestCheckedSlot() (slot uint64, err error) {
+	query := `SELECT COALESCE(max(slot), 0) FROM ` + vars.TableDataAPIPayloadDelivered + ` WHERE value_check_ok IS NOT NULL;`
+	err = s.DB.Get(&slot, query)
+	return slot, err
+}
+
+// SaveBidCollectSourceStatus upserts the per-source receive status of a bidcollect instance
+func (s *DatabaseService) SaveBidCollectSourceStatus(entries []*BidCollectSourceStatusEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	query := `INSERT INTO ` + vars.TableBidCollectSourceStatus + `
+		(uid, source_type, relay, updated_at, last_bid_at, num_bids) VALUES
+		(:uid, :source_type, :relay, :updated_at, :last_bid_at, :num_bids)
+		ON CONFLICT (uid, source_type, relay) DO UPDATE SET
+			updated_at = EXCLUDED.updated_at,
+			last_bid_at = EXCLUDED.last_bid_at,
+			num_bids = EXCLUDED.num_bids;`
+	_, err := s.DB.NamedExec(query, entries)
+	return err
+}
+
+// GetBidCollectSourceStatus returns the source status of all bidcollect instances that reported since the given time
+func (s *DatabaseService) GetBidCollectSourceStatus(since time.Time) (res []*BidCollectSourceStatusEntry, err error) {
+	query := `SELECT uid, source_type, relay, updated_at, last_bid_at, num_bids FROM ` + vars.TableBidCollectSourceStatus + `
+		WHERE updated_at >= $1 ORDER BY uid, source_type, relay;`
+	err = s.DB.Select(&res, query, since.UTC())
+	return res, err
+}
+
+func (s *DatabaseService) SaveRelayAPIChecks(entries []*RelayAPICheckEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	query := `INSERT INTO ` + vars.TableRelayAPICheck + `
+		(checked_at, relay, endpoint, ok, status_code, latency_ms, error) VALUES
+		(:checked_at, :relay, :endpoint, :ok, :status_code, :latency_ms, :error)`
+	_, err := s.DB.NamedExec(query, entries)
+	return err
+}
+
+// DeleteRelayAPIChecksBefore deletes the relay API checks before the given time (retention of the relay-monitor)
+func (s *DatabaseService) DeleteRelayAPIChecksBefore(before time.Time) (rowsDeleted int64, err error) {
+	query := `DELETE FROM ` + vars.TableRelayAPICheck + ` WHERE checked_at < $1`
+	r, err := s.DB.Exec(query, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return r.RowsAffected()
+}
+
+// GetRelayAPIUptime returns the uptime and latency percentiles per relay and endpoint (all relays if relay is empty)
+func (s *DatabaseService) GetRelayAPIUptime(since, until time.Time, relay string) (res []*RelayAPIUptimeEntry, err error) {
+	query := `SELECT relay, endpoint,
+		count(*) AS checks,
+		count(*) FILTER (WHERE ok) AS ok_checks,
+		COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY latency_ms) FILTER (WHERE ok), 0) AS latency_p50,
+		COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY latency_ms) FILTER (WHERE ok), 0) AS latency_p90,
+		COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY latency_ms) FILTER (WHERE ok), 0) AS latency_p99,
+		COALESCE((array_agg(error ORDER BY checked_at DESC) FILTER (WHERE NOT ok))[1], '') AS last_error
+	FROM ` + vars.TableRelayAPICheck + ` WHERE checked_at >= $1 AND checked_at <= $2 AND ($3::text = '' OR relay = $3)
+	GROUP BY relay, endpoint ORDER BY relay, endpoint;`
+	err = s.DB.Select(&res, query, since.UTC(), until.UTC(), relay)
+	return res, err
+}
+
+func (s *DatabaseService) SaveRelayAPIConformance(entry *RelayAPIConformanceEntry) error {
+	query := `INSERT INTO ` + vars.TableRelayAPIConformance + `
+		(relay, num_pass, num_warn, num_fail, results) VALUES
+		(:relay, :num_pass, :num_warn, :num_fail, :results)`
+	_, err := s.DB.NamedExec(query, entry)
+	return err
+}
+
+// GetRelayPayloadsOverTime returns the number of payloads delivered by a relay, in buckets of slotsPerBucket slots
+func (s *DatabaseService) GetRelayPayloadsOverTime(relay string, since, until time.Time, slotsPerBucket uint64) (res []*RelayPayloadsBucketEntry, err error) {
+	startSlot := timeToSlot(since)
+	endSlot := timeToSlot(until)
+
+	query := `SELECT (slot / $1) * $1 AS slot_start, count(DISTINCT slot) AS payloads FROM ` + vars.TableDataAPIPayloadDelivered + `
+		WHERE relay=$2 AND slot >= $3 AND slot <= $4
+		GROUP BY slot_start ORDER BY slot_start ASC;`
+	err = s.DB.Select(&res, query, slotsPerBucket, relay, startSlot, endSlot)
+	return res, err
+}
+
+// GetRelayValueCheckStats returns the number of payloads of a relay, and the results of their value checks
+func (s *DatabaseService) GetRelayValueCheckStats(relay string, since, until time.Time) (*RelayValueCheckStatsEntry, error) {
+	startSlot := timeToSlot(since)
+	endSlot := timeToSlot(until)
+
+	// Counted by slot like GetRelayPayloadsOverTime, a relay can have several rows for a slot (different blocks). The
+	// values are summed once per block, a block can have several rows too (e.g. with different proposer fields).
+	query := `WITH payloads AS (
+		SELECT slot, block_hash, value_check_ok, slot_missed, value_claimed_eth, value_delivered_eth
+		FROM ` + vars.TableDataAPIPayloadDelivered + ` WHERE relay=$1 AND slot >= $2 AND slot <= $3
+	), failed AS (
+		SELECT DISTINCT ON (slot, block_hash) value_claimed_eth, value_delivered_eth
+		FROM payloads WHERE value_check_ok=false ORDER BY slot, block_hash
+	)
+	SELECT
+		count(DISTINCT slot) AS payloads,
+		count(DISTINCT slot) FILTER (WHERE value_check_ok IS NOT NULL) AS checked,
+		count(DISTINCT slot) FILTER (WHERE value_check_ok=false) AS failed,
+		count(DISTINCT slot) FILTER (WHERE slot_missed=true) AS missed,
+		(SELECT COALESCE(sum(value_claimed_eth), 0) FROM failed)::text AS failed_claimed_eth,
+		(SELECT COALESCE(sum(value_delivered_eth), 0) FROM failed)::text AS failed_delivered_eth
+	FROM payloads;`
+	entry := new(RelayValueCheckStatsEntry)
+	err := s.DB.Get(entry, query, relay, startSlot, endSlot)
+	return entry, err
+}
+
+// GetRelayIncorrectClaims returns the latest payloads of a relay that failed the value check
+func (s *DatabaseService) GetRelayIncorrectClaims(relay string, since, until time.Time, limit int) (res []*RelayPayloadCheckEntry, err error) {
+	return s.getRelayPayloadChecks(relay, "value_check_ok=false", since, until, limit)
+}
+
+// GetRelayMissedSlots returns the latest payloads of a relay that were delivered, but the slot was missed
+func (s *DatabaseService) GetRelayMissedSlots(relay string, since, until time.Time, limit int) (res []*RelayPayloadCheckEntry, err error) {
+	return s.getRelayPayloadChecks(relay, "slot_missed=true", since, until, limit)
+}
+
+func (s *DatabaseService) getRelayPayloadChecks(relay, condition string, since, until time.Time, limit int) (res []*RelayPayloadCheckEntry, err error) {
+	startSlot := timeToSlot(since)
+	endSlot := timeToSlot(until)
+
+	query := `SELECT slot, block_hash, extra_data, value_claimed_eth::text AS value_claimed_eth, COALESCE(value_delivered_eth::text, '') AS value_delivered_eth
+	FROM ` + vars.TableDataAPIPayloadDelivered + ` WHERE relay=$1 AND ` + condition + ` AND slot >= $2 AND slot <= $3
+	ORDER BY slot DESC LIMIT $4;`
+	err = s.DB.Select(&res, query, relay, startSlot, endSlot, limit)
+	return res, err
+}
+
+// GetBuilderStatsOverTime returns the blocks and profits of the builder with the given extra_data values, in buckets
+// of slotsPerBucket slots (together with the number of blocks of all builders)
+func (s *DatabaseService) GetBuilderStatsOverTime(extraData []string, since, until time.Time, slotsPerBucket uint64) (res []*BuilderStatsBucketEntry, err error) {
+	startSlot := timeToSlot(since)
+	endSlot := timeToSlot(until)
+
+	query := `SELECT
+		(slot / ?) * ? AS slot_start,
+		count(*) FILTER (WHERE is_builder) AS blocks,
+		count(*) AS blocks_total,
+		COALESCE(round(sum(coinbase_diff_eth) FILTER (WHERE is_builder), 4), 0)::text AS total_profit,
+		COALESCE(round(abs(sum(coinbase_diff_eth) FILTER (WHERE is_builder AND coinbase_diff_eth < 0)), 4), 0)::text AS total_subsidies
+	FROM (
+		SELECT DISTINCT ON (slot) slot, extra_data IN (?) AS is_builder, coinbase_diff_eth FROM ` + vars.TableDataAPIPayloadDelivered + `
+		WHERE value_check_ok IS NOT NULL AND slot >= ? AND slot <= ?
+	) AS x
+	GROUP BY slot_start ORDER BY slot_start ASC;`
+
+	query, args, err := sqlx.In(query, slotsPerBucket, slotsPerBucket, extraData, startSlot, endSlot)
+	if err != nil {
+		return nil, err
+	}
+	err = s.DB.Select(&res, s.DB.Rebind(query), args...)
+	return res, err
+}
+
+// GetBuilderPubkeys returns the pubkeys used for blocks with the given extra_data values
+func (s *DatabaseService) GetBuilderPubkeys(extraData []string, since, until time.Time) (res []*BuilderPubkeyEntry, err error) {
+	startSlot := timeToSlot(since)
+	endSlot := timeToSlot(until)
+
+	query := `SELECT p.builder_pubkey, COALESCE(b.description, '') AS description, count(DISTINCT p.slot) AS blocks
+	FROM ` + vars.TableDataAPIPayloadDelivered + ` AS p LEFT JOIN ` + vars.TableBlockBuilder + ` AS b ON p.builder_pubkey = b.builder_pubkey
+	WHERE p.value_check_ok IS NOT NULL AND p.extra_data IN (?) AND p.slot >= ? AND p.slot <= ?
+	GROUP BY p.builder_pubkey, b.description ORDER BY blocks DESC;`
+
+	query, args, err := sqlx.In(query, extraData, startSlot, endSlot)
+	if err != nil {
+		return nil, err
+	}
+	err = s.DB.Select(&res, s.DB.Rebind(query), args...)
+	return res, err
+}
+
+// GetBuilderRelays returns how many blocks with the given extra_data values each relay delivered
+func (s *DatabaseService) GetBuilderRelays(extraData []string, since, until time.Time) (res []*TopRelayEntry, err error) {
+	startSlot := timeToSlot(since)
+	endSlot := timeToSlot(until)
+
+	query := `SELECT relay, count(relay) AS payloads FROM ` + vars.TableDataAPIPayloadDelivered + `
+	WHERE value_check_ok IS NOT NULL AND extra_data IN (?) AND slot >= ? AND slot <= ?
+	GROUP BY relay ORDER BY payloads DESC;`
+
+	query, args, err := sqlx.In(query, extraData, startSlot, endSlot)
+	if err != nil {
+		return nil, err
+	}
+	err = s.DB.Select(&res, s.DB.Rebind(query), args...) //nolint:musttag
+	return res, err
+}
+
+// GetBuilderRecentBlocks returns the latest blocks with the given extra_data values in the time range
+func (s *DatabaseService) GetBuilderRecentBlocks(extraData []string, since, until time.Time, limit int) (res []*BuilderBlockEntry, err error) {
+	startSlot := timeToSlot(since)
+	endSlot := timeToSlot(until)
+
+	query := `SELECT
+		slot,
+		COALESCE(max(block_number), 0) AS block_number,
+		block_hash,
+		max(extra_data) AS extra_data,
+		max(builder_pubkey) AS builder_pubkey,
+		max(value_claimed_eth)::text AS value_claimed_eth,
+		COALESCE(max(coinbase_diff_eth)::text, '') AS coinbase_diff_eth,
+		string_agg(relay, ',' ORDER BY relay) AS relays,
+		COALESCE(max(block_priority_fees_eth)::text, '') AS priority_fees_eth,
+		COALESCE(max(block_coinbase_transfers_eth)::text, '') AS coinbase_transfers_eth
+	FROM ` + vars.TableDataAPIPayloadDelivered + `
+	WHERE extra_data IN (?) AND slot >= ? AND slot <= ?
+	GROUP BY slot, block_hash ORDER BY slot DESC LIMIT ?;`
+
+	query, args, err := sqlx.In(query, extraData, startSlot, endSlot, limit)
+	if err != nil {
+		return nil, err
+	}
+	err = s.DB.Select(&res, s.DB.Rebind(query), args...)
+	return res, err
+}
+
+// GetExtraDataValues returns all distinct extra_data values of delivered payloads, to resolve builder names
+// independently of a time range
+func (s *DatabaseService) GetExtraDataValues() (res []string, err error) {
+	query := `SELECT DISTINCT extra_data FROM ` + vars.TableDataAPIPayloadDelivered + ` WHERE value_check_ok IS NOT NULL;`
+	err = s.DB.Select(&res, query)
+	return res, err
+}
+
+// GetBuilderPrivateTxShare returns the share of transactions not seen in the public mempool, over the blocks with the
+// given extra_data values that were analyzed with analyze-private-tx
+func (s *DatabaseService) GetBuilderPrivateTxShare(extraData []string, since, until time.Time) (*BuilderPrivateTxEntry, error) {
+	startSlot := timeToSlot(since)
+	endSlot := timeToSlot(until)
+
+	query := `SELECT
+		count(*) AS blocks,
+		COALESCE(sum(block_num_tx), 0) AS num_tx,
+		COALESCE(sum(block_num_private_tx), 0) AS num_private_tx,
+		COALESCE(round(sum(block_num_private_tx)::numeric / NULLIF(sum(block_num_tx), 0), 4), 0)::text AS private_tx_share,
+		COALESCE(round(avg(block_private_tx_share), 4), 0)::text AS avg_block_share
+	FROM (
+		SELECT DISTINCT ON (slot) slot, block_num_tx, block_num_private_tx, block_private_tx_share
+		FROM ` + vars.TableDataAPIPayloadDelivered + `
+		WHERE block_private_tx_share IS NOT NULL AND extra_data IN (?) AND slot >= ? AND slot <= ?
+		ORDER BY slot
+	) AS blocks;`
+
+	query, args, err := sqlx.In(query, extraData, startSlot, endSlot)
+	if err != nil {
+		return nil, err
+	}
+	entry := new(BuilderPrivateTxEntry)
+	err = s.DB.Get(entry, s.DB.Rebind(query), args...)
+	return entry, err
+}
+
+// GetValueClaimAccuracy returns the value claim accuracy of the checked payloads, grouped by relay or extra_data
+func (s *DatabaseService) GetValueClaimAccuracy(since, until time.Time, groupBy string) (res []*ValueClaimAccuracyEntry, err error) {
+	if groupBy != GroupByRelay && groupBy != GroupByExtraData {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidGroupBy, groupBy)
+	}
+
+	startSlot := timeToSlot(since)
+	endSlot := timeToSlot(until)
+
+	query := `SELECT
+		` + groupBy + ` AS name,
+		count(*) AS payloads,
+		count(*) FILTER (WHERE value_delivered_diff_wei > 0) AS overclaims,
+		count(*) FILTER (WHERE value_delivered_diff_wei < 0) AS underclaims,
+		COALESCE(sum(value_delivered_diff_wei) FILTER (WHERE value_delivered_diff_wei > 0), 0)::text AS shortfall_total_wei,
+		COALESCE(max(value_delivered_diff_wei) FILTER (WHERE value_delivered_diff_wei > 0), 0)::text AS shortfall_max_wei,
+		COALESCE(-sum(value_delivered_diff_wei) FILTER (WHERE value_delivered_diff_wei < 0), 0)::text AS excess_total_wei,
+		count(*) FILTER (WHERE value_delivered_diff_wei > 0 AND value_delivered_diff_wei <= 1e15) AS shortfall_lte_0_001,
+		count(*) FILTER (WHERE value_delivered_diff_wei > 1e15 AND value_delivered_diff_wei <= 1e16) AS shortfall_lte_0_01,
+		count(*) FILTER (WHERE value_delivered_diff_wei > 1e16 AND value_delivered_diff_wei <= 1e17) AS shortfall_lte_0_1,
+		count(*) FILTER (WHERE value_delivered_diff_wei > 1e17 AND value_delivered_diff_wei <= 1e18) AS shortfall_lte_1,
+		count(*) FILTER (WHERE value_delivered_diff_wei > 1e18) AS shortfall_gt_1
+	FROM ` + vars.TableDataAPIPayloadDelivered + `
+	WHERE value_check_ok IS NOT NULL AND value_delivered_diff_wei IS NOT NULL AND slot >= $1 AND slot <= $2
+	GROUP BY ` + groupBy + ` ORDER BY payloads DESC;`
+	err = s.DB.Select(&res, query, startSlot, endSlot)
+	return res, err
+}
+
+// GetValueOverclaims returns the payloads that delivered less than claimed, with the largest shortfall first
+func (s *DatabaseService) GetValueOverclaims(since, until time.Time, limit int) (res []*ValueOverclaimEntry, err error) {
+	startSlot := timeToSlot(since)
+	endSlot := timeToSlot(until)
+
+	query := `SELECT slot, relay, block_hash, extra_data, value_claimed_eth::text AS value_claimed_eth, COALESCE(value_delivered_eth::text, '') AS value_delivered_eth, value_delivered_diff_eth::text AS shortfall_eth
+	FROM ` + vars.TableDataAPIPayloadDelivered + `
+	WHERE value_delivered_diff_wei > 0 AND slot >= $1 AND slot <= $2
+	ORDER BY value_delivered_diff_wei DESC, slot DESC LIMIT $3;`
+	err = s.DB.Select(&res, query, startSlot, endSlot, limit)
+	return res, err
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var migration005SQL = `
+	ALTER TABLE ` + vars.TableSignedBuilderBid + ` ADD version text NOT NULL DEFAULT '';
+	ALTER TABLE ` + vars.TableSignedBuilderBid + ` ADD signature_status text NOT NULL DEFAULT '';
+	ALTER TABLE ` + vars.TableSignedBuilderBid + ` ADD signed_bid jsonb DEFAULT NULL;
+
+	CREATE INDEX IF NOT EXISTS ` + vars.TableSignedBuilderBid + `_signature_status_idx ON ` + vars.TableSignedBuilderBid + `("signature_status");
+`
+
+var Migration005AddSignedBuilderBidFields = &migrate.Migration{
+	Id: "005-add-signed-builder-bid-fields",
+	Up: []string{migration005SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var migration006SQL = `
+	ALTER TABLE ` + vars.TableSignedBuilderBid + ` ADD encoding text NOT NULL DEFAULT '';
+	ALTER TABLE ` + vars.TableSignedBuilderBid + ` ADD num_blobs bigint NOT NULL DEFAULT 0;
+`
+
+var Migration006AddSignedBuilderBidBlobs = &migrate.Migration{
+	Id: "006-add-signed-builder-bid-blobs",
+	Up: []string{migration006SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
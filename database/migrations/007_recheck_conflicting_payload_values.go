@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// check-payload-value used to save the results by slot, so if relays delivered different blocks (or claimed different
+// values) for a slot, all rows got the results of one of them. These slots are queued for a recheck.
+var migration007SQL = `
+	UPDATE ` + vars.TableDataAPIPayloadDelivered + ` SET value_check_ok=NULL WHERE slot IN (
+		SELECT slot FROM ` + vars.TableDataAPIPayloadDelivered + ` GROUP BY slot HAVING
+			count(DISTINCT block_hash) > 1 OR
+			count(DISTINCT value_claimed_wei) > 1 OR
+			count(DISTINCT lower(proposer_fee_recipient)) > 1
+	);
+`
+
+var Migration007RecheckConflictingPayloadValues = &migrate.Migration{
+	Id: "007-recheck-conflicting-payload-values",
+	Up: []string{migration007SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
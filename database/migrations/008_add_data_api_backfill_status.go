@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var migration008SQL = `
+CREATE TABLE IF NOT EXISTS ` + vars.TableDataAPIBackfillStatus + ` (
+	id          bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	inserted_at timestamp NOT NULL default current_timestamp,
+	relay       text NOT NULL,
+
+	available     boolean NOT NULL, -- whether the data API responded to all requests
+	error         text NOT NULL,
+	num_requests  bigint NOT NULL,
+	num_payloads  bigint NOT NULL,  -- new payloads saved to the database
+	duration_ms   bigint NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS ` + vars.TableDataAPIBackfillStatus + `_relay_insertedat_idx ON ` + vars.TableDataAPIBackfillStatus + `("relay", "inserted_at");
+`
+
+var Migration008AddDataAPIBackfillStatus = &migrate.Migration{
+	Id: "008-add-data-api-backfill-status",
+	Up: []string{migration008SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
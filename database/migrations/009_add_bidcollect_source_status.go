@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var migration009SQL = `
+CREATE TABLE IF NOT EXISTS ` + vars.TableBidCollectSourceStatus + ` (
+	uid         text NOT NULL,  -- bidcollect instance
+	source_type integer NOT NULL,
+	relay       text NOT NULL,
+
+	updated_at  timestamp NOT NULL default current_timestamp,
+	last_bid_at timestamp NOT NULL,
+	num_bids    bigint NOT NULL, -- bids received since the instance started
+
+	PRIMARY KEY (uid, source_type, relay)
+);
+`
+
+var Migration009AddBidCollectSourceStatus = &migrate.Migration{
+	Id: "009-add-bidcollect-source-status",
+	Up: []string{migration009SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var migration010SQL = `
+CREATE TABLE IF NOT EXISTS ` + vars.TableRelayAPICheck + ` (
+	id          bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	checked_at  timestamp NOT NULL,
+	relay       text NOT NULL,
+	endpoint    text NOT NULL, -- status or data_api
+
+	ok          boolean NOT NULL, -- status code 200 and no error
+	status_code integer NOT NULL, -- 0 if there was no response
+	latency_ms  bigint NOT NULL,
+	error       text NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS ` + vars.TableRelayAPICheck + `_checkedat_relay_idx ON ` + vars.TableRelayAPICheck + `("checked_at", "relay");
+`
+
+var Migration010AddRelayAPICheck = &migrate.Migration{
+	Id: "010-add-relay-api-check",
+	Up: []string{migration010SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
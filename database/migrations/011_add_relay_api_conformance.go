@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var migration011SQL = `
+CREATE TABLE IF NOT EXISTS ` + vars.TableRelayAPIConformance + ` (
+	id          bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	inserted_at timestamp NOT NULL default current_timestamp,
+	relay       text NOT NULL,
+
+	num_pass    integer NOT NULL,
+	num_warn    integer NOT NULL,
+	num_fail    integer NOT NULL,
+	results     jsonb NOT NULL -- list of {name, result, details}
+);
+
+CREATE INDEX IF NOT EXISTS ` + vars.TableRelayAPIConformance + `_relay_insertedat_idx ON ` + vars.TableRelayAPIConformance + `("relay", "inserted_at");
+`
+
+var Migration011AddRelayAPIConformance = &migrate.Migration{
+	Id: "011-add-relay-api-conformance",
+	Up: []string{migration011SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// gas_used is the value reported by the relay, block_gas_used the on-chain one
+var migration012SQL = `
+	ALTER TABLE ` + vars.TableDataAPIPayloadDelivered + ` ADD block_gas_used bigint DEFAULT NULL;
+	ALTER TABLE ` + vars.TableDataAPIPayloadDelivered + ` ADD block_base_fee NUMERIC(48, 0) DEFAULT NULL;
+`
+
+var Migration012AddBlockGasAndBaseFee = &migrate.Migration{
+	Id: "012-add-block-gas-and-base-fee",
+	Up: []string{migration012SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// On-chain composition of the block value: burned base (and blob) fees, priority fees to the coinbase, and direct
+// transfers to the coinbase. num_tx is the relay's claim, block_num_tx the on-chain one.
+var migration013SQL = `
+	ALTER TABLE ` + vars.TableDataAPIPayloadDelivered + ` ADD block_num_tx int DEFAULT NULL;
+	ALTER TABLE ` + vars.TableDataAPIPayloadDelivered + ` ADD block_burned_wei NUMERIC(48, 0) DEFAULT NULL;
+	ALTER TABLE ` + vars.TableDataAPIPayloadDelivered + ` ADD block_burned_eth NUMERIC(16, 8) DEFAULT NULL;
+	ALTER TABLE ` + vars.TableDataAPIPayloadDelivered + ` ADD block_priority_fees_wei NUMERIC(48, 0) DEFAULT NULL;
+	ALTER TABLE ` + vars.TableDataAPIPayloadDelivered + ` ADD block_priority_fees_eth NUMERIC(16, 8) DEFAULT NULL;
+	ALTER TABLE ` + vars.TableDataAPIPayloadDelivered + ` ADD block_coinbase_transfers_wei NUMERIC(48, 0) DEFAULT NULL;
+	ALTER TABLE ` + vars.TableDataAPIPayloadDelivered + ` ADD block_coinbase_transfers_eth NUMERIC(16, 8) DEFAULT NULL;
+`
+
+var Migration013AddBlockFees = &migrate.Migration{
+	Id: "013-add-block-fees",
+	Up: []string{migration013SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
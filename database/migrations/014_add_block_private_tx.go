@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// Transactions of the block that weren't seen in the public mempool before the slot, from the analyze-private-tx
+// command (only for blocks covered by a mempool dataset).
+var migration014SQL = `
+	ALTER TABLE ` + vars.TableDataAPIPayloadDelivered + ` ADD block_num_private_tx int DEFAULT NULL;
+	ALTER TABLE ` + vars.TableDataAPIPayloadDelivered + ` ADD block_private_tx_share NUMERIC(5, 4) DEFAULT NULL;
+`
+
+var Migration014AddBlockPrivateTx = &migrate.Migration{
+	Id: "014-add-block-private-tx",
+	Up: []string{migration014SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// block_hash is only the last column of the unique index, lookups by block hash (inspect-block) need their own index
+var migration015SQL = `
+	CREATE INDEX IF NOT EXISTS ` + vars.TableDataAPIPayloadDelivered + `_block_hash_idx ON ` + vars.TableDataAPIPayloadDelivered + `("block_hash");
+`
+
+var Migration015AddBlockHashIndex = &migrate.Migration{
+	Id: "015-add-block-hash-index",
+	Up: []string{migration015SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// The builder page looks up payloads by extra_data (latest slots first), and resolves builder names from the distinct
+// extra_data values
+var migration016SQL = `
+	CREATE INDEX IF NOT EXISTS ` + vars.TableDataAPIPayloadDelivered + `_extra_data_slot_idx ON ` + vars.TableDataAPIPayloadDelivered + `("extra_data", "slot");
+`
+
+var Migration016AddExtraDataIndex = &migrate.Migration{
+	Id: "016-add-extra-data-index",
+	Up: []string{migration016SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
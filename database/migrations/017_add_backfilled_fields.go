@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"github.com/flashbots/relayscan/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// Fields that backfill-field already filled in from the on-chain block. Some on-chain values can be empty (a block
+// without extra_data), so the missing value alone doesn't tell if a row still needs to be backfilled.
+var migration017SQL = `
+	ALTER TABLE ` + vars.TableDataAPIPayloadDelivered + ` ADD backfilled_fields text[] NOT NULL DEFAULT '{}';
+`
+
+var Migration017AddBackfilledFields = &migrate.Migration{
+	Id: "017-add-backfilled-fields",
+	Up: []string{migration017SQL},
+
+	DisableTransactionUp:   false,
+	DisableTransactionDown: true,
+}
@@ -11,5 +11,18 @@ var Migrations = migrate.MemoryMigrationSource{
 		Migration002AddBlobCount,
 		Migration003AddBlobIndexes,
 		Migration004AddBlockTimestamp,
+		Migration005AddSignedBuilderBidFields,
+		Migration006AddSignedBuilderBidBlobs,
+		Migration007RecheckConflictingPayloadValues,
+		Migration008AddDataAPIBackfillStatus,
+		Migration009AddBidCollectSourceStatus,
+		Migration010AddRelayAPICheck,
+		Migration011AddRelayAPIConformance,
+		Migration012AddBlockGasAndBaseFee,
+		Migration013AddBlockFees,
+		Migration014AddBlockPrivateTx,
+		Migration015AddBlockHashIndex,
+		Migration016AddExtraDataIndex,
+		Migration017AddBackfilledFields,
 	},
 }
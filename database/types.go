@@ -81,6 +81,23 @@ type DataAPIPayloadDeliveredEntry struct {
 
 	// Block time added 2024-07-26
 	BlockTimestamp sql.NullTime `db:"block_timestamp"`
+
+	// On-chain gas used and base fee (gas_used is the relay's claim)
+	BlockGasUsed sql.NullInt64  `db:"block_gas_used"`
+	BlockBaseFee sql.NullString `db:"block_base_fee"`
+
+	// On-chain composition of the block value (coinbase transfers include internal calls, derived from the balance diff)
+	BlockNumTx                sql.NullInt64  `db:"block_num_tx"`
+	BlockBurnedWei            sql.NullString `db:"block_burned_wei"`
+	BlockBurnedEth            sql.NullString `db:"block_burned_eth"`
+	BlockPriorityFeesWei      sql.NullString `db:"block_priority_fees_wei"`
+	BlockPriorityFeesEth      sql.NullString `db:"block_priority_fees_eth"`
+	BlockCoinbaseTransfersWei sql.NullString `db:"block_coinbase_transfers_wei"`
+	BlockCoinbaseTransfersEth sql.NullString `db:"block_coinbase_transfers_eth"`
+
+	// Transactions not seen in the public mempool before the slot (exclusive order flow)
+	BlockNumPrivateTx   sql.NullInt64  `db:"block_num_private_tx"`
+	BlockPrivateTxShare sql.NullString `db:"block_private_tx_share"`
 }
 
 type DataAPIBuilderBidEntry struct {
@@ -130,6 +147,12 @@ type SignedBuilderBidEntry struct {
 	PrevRandao   string `db:"prev_randao"`
 	Timestamp    uint64 `db:"timestamp"`
 	Epoch        uint64 `db:"epoch"`
+
+	Version         string         `db:"version"`          // fork of the bid, i.e. deneb
+	Encoding        string         `db:"encoding"`         // json or ssz
+	NumBlobs        uint64         `db:"num_blobs"`        // number of blob KZG commitments
+	SignatureStatus string         `db:"signature_status"` // valid, invalid or pubkey_mismatch
+	SignedBid       sql.NullString `db:"signed_bid"`       // full signed bid as JSON (optional)
 }
 
 type BlockBuilderEntry struct {
@@ -189,6 +212,164 @@ type BuilderStatsEntry struct {
 	BuilderPubkeys string `db:"builder_pubkeys" json:"builder_pubkeys"`
 	BlocksIncluded int    `db:"blocks_included" json:"blocks_included"`
 }
+
+// DataAPIBackfillStatusEntry is the result of one backfill run of a relay data API
+type DataAPIBackfillStatusEntry struct {
+	ID         int64     `db:"id" json:"-"`
+	InsertedAt time.Time `db:"inserted_at" json:"time"`
+	Relay      string    `db:"relay" json:"relay"`
+
+	Available   bool   `db:"available" json:"available"`
+	Error       string `db:"error" json:"error,omitempty"`
+	NumRequests uint64 `db:"num_requests" json:"num_requests"`
+	NumPayloads uint64 `db:"num_payloads" json:"num_payloads"` // new payloads saved
+	DurationMS  int64  `db:"duration_ms" json:"duration_ms"`
+}
+
+// RelayPayloadsBucketEntry is the number of payloads a relay delivered in a range of slots
+type RelayPayloadsBucketEntry struct {
+	SlotStart   uint64 `db:"slot_start" json:"slot_start"`
+	NumPayloads uint64 `db:"payloads" json:"num_payloads"`
+}
+
+// RelayValueCheckStatsEntry summarizes the payload value checks of a relay
+type RelayValueCheckStatsEntry struct {
+	NumPayloads        uint64 `db:"payloads" json:"num_payloads"`
+	NumChecked         uint64 `db:"checked" json:"num_checked"`
+	NumFailed          uint64 `db:"failed" json:"num_failed"` // value_check_ok=false
+	NumMissed          uint64 `db:"missed" json:"num_missed"`
+	FailedClaimedEth   string `db:"failed_claimed_eth" json:"failed_claimed_eth"`     // claimed value of the failed checks
+	FailedDeliveredEth string `db:"failed_delivered_eth" json:"failed_delivered_eth"` // delivered value of the failed checks
+}
+
+// RelayPayloadCheckEntry is a payload of a relay with an incorrect claim or a missed slot
+type RelayPayloadCheckEntry struct {
+	Slot              uint64 `db:"slot" json:"slot"`
+	BlockHash         string `db:"block_hash" json:"block_hash"`
+	ExtraData         string `db:"extra_data" json:"extra_data"`
+	ValueClaimedEth   string `db:"value_claimed_eth" json:"value_claimed_eth"`
+	ValueDeliveredEth string `db:"value_delivered_eth" json:"value_delivered_eth"`
+}
+
+// BuilderStatsBucketEntry is the number of blocks and the profit of a builder in a range of slots
+type BuilderStatsBucketEntry struct {
+	SlotStart      uint64 `db:"slot_start"`
+	NumBlocks      uint64 `db:"blocks"`
+	NumBlocksTotal uint64 `db:"blocks_total"` // blocks of all builders
+	ProfitTotal    string `db:"total_profit"`
+	SubsidiesTotal string `db:"total_subsidies"`
+}
+
+// BuilderPubkeyEntry is a pubkey used by a builder
+type BuilderPubkeyEntry struct {
+	BuilderPubkey string `db:"builder_pubkey" json:"builder_pubkey"`
+	Description   string `db:"description" json:"description,omitempty"`
+	NumBlocks     uint64 `db:"blocks" json:"num_blocks"`
+}
+
+// BuilderBlockEntry is a block of a builder, with all relays that delivered it
+type BuilderBlockEntry struct {
+	Slot            uint64 `db:"slot" json:"slot"`
+	BlockNumber     uint64 `db:"block_number" json:"block_number"`
+	BlockHash       string `db:"block_hash" json:"block_hash"`
+	ExtraData       string `db:"extra_data" json:"extra_data"`
+	BuilderPubkey   string `db:"builder_pubkey" json:"builder_pubkey"`
+	ValueClaimedEth string `db:"value_claimed_eth" json:"value_claimed_eth"`
+	CoinbaseDiffEth string `db:"coinbase_diff_eth" json:"coinbase_diff_eth"`
+	Relays          string `db:"relays" json:"relays"` // comma separated
+
+	PriorityFeesEth      string `db:"priority_fees_eth" json:"priority_fees_eth"`           // empty if not checked yet
+	CoinbaseTransfersEth string `db:"coinbase_transfers_eth" json:"coinbase_transfers_eth"` // empty if not checked yet
+}
+
+// BuilderPrivateTxEntry is the share of a builder's transactions that weren't seen in the public mempool, over the
+// blocks analyzed with a mempool dataset
+type BuilderPrivateTxEntry struct {
+	NumBlocks      uint64 `db:"blocks" json:"num_blocks"`
+	NumTx          uint64 `db:"num_tx" json:"num_tx"`
+	NumPrivateTx   uint64 `db:"num_private_tx" json:"num_private_tx"`
+	PrivateTxShare string `db:"private_tx_share" json:"private_tx_share"` // all transactions of all blocks
+	AvgBlockShare  string `db:"avg_block_share" json:"avg_block_share"`   // average of the per-block shares
+}
+
+// ValueClaimAccuracyEntry compares the claimed and delivered values of the checked payloads of a relay or an
+// extra_data value. The shortfall is value_delivered_diff_wei (claimed minus delivered) where positive.
+type ValueClaimAccuracyEntry struct {
+	Name              string `db:"name"` // relay or extra_data
+	NumPayloads       uint64 `db:"payloads"`
+	NumOverclaims     uint64 `db:"overclaims"`  // delivered less than claimed
+	NumUnderclaims    uint64 `db:"underclaims"` // delivered more than claimed
+	ShortfallTotalWei string `db:"shortfall_total_wei"`
+	ShortfallMaxWei   string `db:"shortfall_max_wei"`
+	ExcessTotalWei    string `db:"excess_total_wei"`
+
+	// Number of overclaims by shortfall
+	NumShortfallLte0001 uint64 `db:"shortfall_lte_0_001"` // <= 0.001 ETH
+	NumShortfallLte001  uint64 `db:"shortfall_lte_0_01"`  // <= 0.01 ETH
+	NumShortfallLte01   uint64 `db:"shortfall_lte_0_1"`   // <= 0.1 ETH
+	NumShortfallLte1    uint64 `db:"shortfall_lte_1"`     // <= 1 ETH
+	NumShortfallGt1     uint64 `db:"shortfall_gt_1"`      // > 1 ETH
+}
+
+// ValueOverclaimEntry is a payload that delivered less than the relay claimed
+type ValueOverclaimEntry struct {
+	Slot              uint64 `db:"slot" json:"slot"`
+	Relay             string `db:"relay" json:"relay"`
+	BlockHash         string `db:"block_hash" json:"block_hash"`
+	ExtraData         string `db:"extra_data" json:"extra_data"`
+	ValueClaimedEth   string `db:"value_claimed_eth" json:"value_claimed_eth"`
+	ValueDeliveredEth string `db:"value_delivered_eth" json:"value_delivered_eth"`
+	ShortfallEth      string `db:"shortfall_eth" json:"shortfall_eth"`
+}
+
+// BidCollectSourceStatusEntry is the last time a bidcollect instance received a bid from a source
+type BidCollectSourceStatusEntry struct {
+	UID        string `db:"uid" json:"uid"`
+	SourceType int    `db:"source_type" json:"source_type"`
+	Relay      string `db:"relay" json:"relay"`
+
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	LastBidAt time.Time `db:"last_bid_at" json:"last_bid_at"`
+	NumBids   uint64    `db:"num_bids" json:"num_bids"`
+}
+
+// RelayAPICheckEntry is the result of one request of the relay monitor to a relay API endpoint
+type RelayAPICheckEntry struct {
+	ID        int64     `db:"id" json:"-"`
+	CheckedAt time.Time `db:"checked_at" json:"checked_at"`
+	Relay     string    `db:"relay" json:"relay"`
+	Endpoint  string    `db:"endpoint" json:"endpoint"`
+
+	OK         bool   `db:"ok" json:"ok"`
+	StatusCode int    `db:"status_code" json:"status_code"`
+	LatencyMS  int64  `db:"latency_ms" json:"latency_ms"`
+	Error      string `db:"error" json:"error,omitempty"`
+}
+
+// RelayAPIUptimeEntry summarizes the relay monitor checks of one relay API endpoint
+type RelayAPIUptimeEntry struct {
+	Relay        string  `db:"relay"`
+	Endpoint     string  `db:"endpoint"`
+	NumChecks    uint64  `db:"checks"`
+	NumOK        uint64  `db:"ok_checks"`
+	LatencyP50MS float64 `db:"latency_p50"` // of the successful checks
+	LatencyP90MS float64 `db:"latency_p90"`
+	LatencyP99MS float64 `db:"latency_p99"`
+	LastError    string  `db:"last_error"`
+}
+
+// RelayAPIConformanceEntry is a conformance report of the relay API (see util check-relay-api)
+type RelayAPIConformanceEntry struct {
+	ID         int64     `db:"id"`
+	InsertedAt time.Time `db:"inserted_at"`
+	Relay      string    `db:"relay"`
+
+	NumPass int    `db:"num_pass"`
+	NumWarn int    `db:"num_warn"`
+	NumFail int    `db:"num_fail"`
+	Results string `db:"results"` // JSON
+}
+
 type TmpPayloadsForExtraDataEntry struct {
 	Slot           uint64       `db:"slot"`
 	ExtraData      string       `db:"extra_data"`
@@ -1,15 +1,26 @@
 package database
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
 	"time"
 	"unicode/utf8"
 
+	builderspec "github.com/attestantio/go-builder-client/spec"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	consensusbellatrix "github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/flashbots/go-boost-utils/types"
 	relaycommon "github.com/flashbots/mev-boost-relay/common"
 	"github.com/flashbots/relayscan/common"
 )
 
+var (
+	ErrEmptyBid              = errors.New("empty bid")
+	ErrUnsupportedBidVersion = errors.New("unsupported bid version")
+)
+
 func BidTraceV2JSONToPayloadDeliveredEntry(relay string, entry relaycommon.BidTraceV2JSON) DataAPIPayloadDeliveredEntry {
 	wei, ok := new(big.Int).SetString(entry.Value, 10)
 	if !ok {
@@ -83,7 +94,25 @@ func ExtraDataToUtf8Str(extraData types.ExtraData) string {
 	return string(extraData)
 }
 
-func SignedBuilderBidToEntry(relay string, slot uint64, parentHash, proposerPubkey string, timeRequestStart, timeRequestEnd time.Time, bid *types.SignedBuilderBid) SignedBuilderBidEntry {
+// SignedBuilderBidToEntry converts a getHeader response (for any fork) to a database entry
+func SignedBuilderBidToEntry(relay string, slot uint64, parentHash, proposerPubkey string, timeRequestStart, timeRequestEnd time.Time, bid *builderspec.VersionedSignedBuilderBid) (entry SignedBuilderBidEntry, err error) {
+	header, err := executionPayloadHeaderFromBid(bid)
+	if err != nil {
+		return entry, err
+	}
+	pubkey, err := bid.Builder()
+	if err != nil {
+		return entry, err
+	}
+	signature, err := bid.Signature()
+	if err != nil {
+		return entry, err
+	}
+	value, err := bid.Value()
+	if err != nil {
+		return entry, err
+	}
+
 	return SignedBuilderBidEntry{
 		Relay:       relay,
 		RequestedAt: timeRequestStart,
@@ -94,18 +123,55 @@ func SignedBuilderBidToEntry(relay string, slot uint64, parentHash, proposerPubk
 		ParentHash:     parentHash,
 		ProposerPubkey: proposerPubkey,
 
-		Pubkey:    bid.Message.Pubkey.String(),
-		Signature: bid.Signature.String(),
+		Pubkey:    pubkey.String(),
+		Signature: signature.String(),
 
-		Value:        bid.Message.Value.String(),
-		FeeRecipient: bid.Message.Header.FeeRecipient.String(),
-		BlockHash:    bid.Message.Header.BlockHash.String(),
-		BlockNumber:  bid.Message.Header.BlockNumber,
-		GasLimit:     bid.Message.Header.GasLimit,
-		GasUsed:      bid.Message.Header.GasUsed,
-		ExtraData:    ExtraDataToUtf8Str(bid.Message.Header.ExtraData),
+		Value:        value.Dec(),
+		FeeRecipient: header.FeeRecipient.String(),
+		BlockHash:    header.BlockHash.String(),
+		BlockNumber:  header.BlockNumber,
+		GasLimit:     header.GasLimit,
+		GasUsed:      header.GasUsed,
+		ExtraData:    ExtraDataToUtf8Str(header.ExtraData),
 		Epoch:        slot / 32,
-		Timestamp:    bid.Message.Header.Timestamp,
-		PrevRandao:   bid.Message.Header.Random.String(),
+		Timestamp:    header.Timestamp,
+		PrevRandao:   header.PrevRandao.String(),
+
+		Version: bid.Version.String(),
+	}, nil
+}
+
+// executionPayloadHeaderFields are the header fields that are stored for all forks
+type executionPayloadHeaderFields struct {
+	FeeRecipient consensusbellatrix.ExecutionAddress
+	BlockHash    phase0.Hash32
+	BlockNumber  uint64
+	GasLimit     uint64
+	GasUsed      uint64
+	Timestamp    uint64
+	ExtraData    []byte
+	PrevRandao   phase0.Root
+}
+
+func executionPayloadHeaderFromBid(bid *builderspec.VersionedSignedBuilderBid) (*executionPayloadHeaderFields, error) {
+	if bid.IsEmpty() {
+		return nil, ErrEmptyBid
+	}
+
+	switch bid.Version { //nolint:exhaustive
+	case consensusspec.DataVersionBellatrix:
+		h := bid.Bellatrix.Message.Header
+		return &executionPayloadHeaderFields{h.FeeRecipient, h.BlockHash, h.BlockNumber, h.GasLimit, h.GasUsed, h.Timestamp, h.ExtraData, h.PrevRandao}, nil
+	case consensusspec.DataVersionCapella:
+		h := bid.Capella.Message.Header
+		return &executionPayloadHeaderFields{h.FeeRecipient, h.BlockHash, h.BlockNumber, h.GasLimit, h.GasUsed, h.Timestamp, h.ExtraData, h.PrevRandao}, nil
+	case consensusspec.DataVersionDeneb:
+		h := bid.Deneb.Message.Header
+		return &executionPayloadHeaderFields{h.FeeRecipient, h.BlockHash, h.BlockNumber, h.GasLimit, h.GasUsed, h.Timestamp, h.ExtraData, h.PrevRandao}, nil
+	case consensusspec.DataVersionElectra:
+		h := bid.Electra.Message.Header
+		return &executionPayloadHeaderFields{h.FeeRecipient, h.BlockHash, h.BlockNumber, h.GasLimit, h.GasUsed, h.Timestamp, h.ExtraData, h.PrevRandao}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedBidVersion, bid.Version.String())
 	}
 }
@@ -15,4 +15,8 @@ var (
 	TableError                      = tableBase + "_error"
 	TableBlockBuilder               = tableBase + "_blockbuilder"
 	TableBlockBuilderInclusionStats = tableBase + "_blockbuilder_stats_inclusion"
+	TableDataAPIBackfillStatus      = tableBase + "_data_api_backfill_status"
+	TableBidCollectSourceStatus     = tableBase + "_bidcollect_source_status"
+	TableRelayAPICheck              = tableBase + "_relay_api_check"
+	TableRelayAPIConformance        = tableBase + "_relay_api_conformance"
 )
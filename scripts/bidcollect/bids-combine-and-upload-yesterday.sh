@@ -23,8 +23,8 @@ cd ../../
 # load environment variables
 source .env.prod
 
-# archive and upload!
-./scripts/bidcollect/bids-combine-and-upload.sh "/mnt/data/relayscan-bids/$d/"
+# archive and upload! (object stores are configured with BIDARCHIVE_STORES in .env.prod)
+./relayscan service bidcollect archive --date "$d" --out /mnt/data/relayscan-bids --delete
 
 # update website
 echo ""
@@ -0,0 +1,305 @@
+// Package alerting checks the collected data for problems (incorrect payload values, data gaps) and sends notifications.
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	"github.com/flashbots/relayscan/services/bidcollect/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	alertKeyValueCheck    = "value-check"
+	alertKeyDataAPI       = "data-api"
+	alertKeyCheckValueLag = "check-value-lag"
+	alertKeyBidCollect    = "bidcollect"
+
+	// bidcollect instances that haven't reported for this long are ignored (i.e. decommissioned)
+	bidCollectStatusMaxAge = 24 * time.Hour
+)
+
+// Alert is a problem found by a check. It's sent once when it's found, and again when it's resolved.
+type Alert struct {
+	Key      string    `json:"key"` // identifies the problem, i.e. "value-check/relay.example.com"
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	Resolved bool      `json:"resolved"`
+	Time     time.Time `json:"time"`
+}
+
+// Text returns the alert as a single line, for chat messages
+func (a *Alert) Text() string {
+	prefix := "[alert]"
+	if a.Resolved {
+		prefix = "[resolved]"
+	}
+	return fmt.Sprintf("%s %s: %s", prefix, a.Title, a.Message)
+}
+
+// DB is the subset of the database service used by the checks
+type DB interface {
+	GetRelayValueCheckStats(relay string, since, until time.Time) (*database.RelayValueCheckStatsEntry, error)
+	GetDataAPIBackfillStatus(relay string, limit int) ([]*database.DataAPIBackfillStatusEntry, error)
+	GetLatestCheckedSlot() (uint64, error)
+	GetBidCollectSourceStatus(since time.Time) ([]*database.BidCollectSourceStatusEntry, error)
+}
+
+type AlerterOpts struct {
+	Log       *logrus.Entry
+	DB        DB
+	Notifiers []Notifier
+	Relays    []common.RelayEntry
+	Clock     common.SlotClock // optional, the system clock by default
+
+	// Alert if more than MaxFailedValueChecks payloads of a relay within ValueCheckWindow have value_check_ok=false (disabled if the window is 0)
+	ValueCheckWindow     time.Duration
+	MaxFailedValueChecks uint64
+
+	// Alert if the last DataAPIMaxEmptyRuns backfill runs of a relay saved no new payloads (disabled if 0)
+	DataAPIMaxEmptyRuns int
+
+	// Alert if the latest checked payload is more than CheckValueMaxLagSlots behind head (disabled if 0)
+	CheckValueMaxLagSlots uint64
+
+	// Alert if a bidcollect source hasn't received a bid for longer than BidCollectMaxSilence (disabled if 0)
+	BidCollectMaxSilence time.Duration
+}
+
+// Alerter runs the checks and notifies about new and resolved alerts
+type Alerter struct {
+	opts  *AlerterOpts
+	log   *logrus.Entry
+	clock common.SlotClock
+
+	active map[string]*Alert // currently active alerts, by key
+}
+
+func NewAlerter(opts *AlerterOpts) *Alerter {
+	a := &Alerter{
+		opts:   opts,
+		log:    opts.Log,
+		clock:  opts.Clock,
+		active: make(map[string]*Alert),
+	}
+	if a.clock == nil {
+		a.clock = common.NewSystemSlotClock()
+	}
+	return a
+}
+
+type alertCheck struct {
+	key string
+	run func() ([]*Alert, error)
+}
+
+// Check runs all enabled checks. New alerts and resolved alerts are sent to all notifiers, alerts which
+// are still active are not sent again. If a check fails, its alerts stay as they are.
+func (a *Alerter) Check() {
+	checks := []alertCheck{}
+	if a.opts.ValueCheckWindow > 0 {
+		checks = append(checks, alertCheck{alertKeyValueCheck, a.checkValueChecks})
+	}
+	if a.opts.DataAPIMaxEmptyRuns > 0 {
+		checks = append(checks, alertCheck{alertKeyDataAPI, a.checkDataAPI})
+	}
+	if a.opts.CheckValueMaxLagSlots > 0 {
+		checks = append(checks, alertCheck{alertKeyCheckValueLag, a.checkCheckValueLag})
+	}
+	if a.opts.BidCollectMaxSilence > 0 {
+		checks = append(checks, alertCheck{alertKeyBidCollect, a.checkBidCollect})
+	}
+
+	now := a.clock.Now().UTC()
+	alerts := make(map[string]*Alert)
+	for _, check := range checks {
+		checkAlerts, err := check.run()
+		if err != nil {
+			a.log.WithError(err).WithField("check", check.key).Error("[alerting] check failed")
+			for key, alert := range a.active {
+				if key == check.key || strings.HasPrefix(key, check.key+"/") {
+					alerts[key] = alert
+				}
+			}
+			continue
+		}
+		for _, alert := range checkAlerts {
+			alert.Time = now
+			alerts[alert.Key] = alert
+		}
+	}
+
+	// Notify about new alerts
+	for _, key := range sortedKeys(alerts) {
+		if _, isActive := a.active[key]; !isActive {
+			a.notify(alerts[key])
+		}
+	}
+
+	// Notify about resolved alerts
+	for _, key := range sortedKeys(a.active) {
+		if _, isActive := alerts[key]; !isActive {
+			resolved := *a.active[key]
+			resolved.Resolved = true
+			resolved.Time = now
+			a.notify(&resolved)
+		}
+	}
+
+	a.active = alerts
+}
+
+func (a *Alerter) notify(alert *Alert) {
+	log := a.log.WithFields(logrus.Fields{
+		"key":      alert.Key,
+		"resolved": alert.Resolved,
+	})
+	log.Warn(alert.Text())
+
+	for _, notifier := range a.opts.Notifiers {
+		ctx, cancel := context.WithTimeout(context.Background(), notifierTimeout)
+		err := notifier.Notify(ctx, alert)
+		cancel()
+		if err != nil {
+			log.WithError(err).WithField("notifier", notifier.Name()).Error("[alerting] failed to send notification")
+		}
+	}
+}
+
+// checkValueChecks alerts about relays with too many payloads that delivered less than claimed
+func (a *Alerter) checkValueChecks() (alerts []*Alert, err error) {
+	until := a.clock.Now().UTC()
+	since := until.Add(-a.opts.ValueCheckWindow)
+	for _, relay := range a.opts.Relays {
+		stats, err := a.opts.DB.GetRelayValueCheckStats(relay.Hostname(), since, until)
+		if err != nil {
+			return nil, err
+		}
+		if stats.NumFailed <= a.opts.MaxFailedValueChecks {
+			continue
+		}
+		alerts = append(alerts, &Alert{
+			Key:     alertKeyValueCheck + "/" + relay.Hostname(),
+			Title:   "Incorrect payload values: " + relay.Hostname(),
+			Message: fmt.Sprintf("%d of %d checked payloads failed the value check in the last %s (claimed %s ETH, delivered %s ETH)", stats.NumFailed, stats.NumChecked, a.opts.ValueCheckWindow, stats.FailedClaimedEth, stats.FailedDeliveredEth),
+		})
+	}
+	return alerts, nil
+}
+
+// checkDataAPI alerts about relays whose data API didn't return new payloads in the last backfill runs
+func (a *Alerter) checkDataAPI() (alerts []*Alert, err error) {
+	for _, relay := range a.opts.Relays {
+		runs, err := a.opts.DB.GetDataAPIBackfillStatus(relay.Hostname(), a.opts.DataAPIMaxEmptyRuns)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) < a.opts.DataAPIMaxEmptyRuns {
+			continue // not enough history yet
+		}
+
+		numUnavailable := 0
+		hasPayloads := false
+		for _, run := range runs {
+			if run.NumPayloads > 0 {
+				hasPayloads = true
+				break
+			}
+			if !run.Available {
+				numUnavailable++
+			}
+		}
+		if hasPayloads {
+			continue
+		}
+
+		latest, oldest := runs[0], runs[len(runs)-1]
+		msg := fmt.Sprintf("no new payloads in the last %d backfill runs (since %s)", len(runs), oldest.InsertedAt.UTC().Format(time.DateTime))
+		if numUnavailable > 0 {
+			msg += fmt.Sprintf(", data API unavailable in %d of them", numUnavailable)
+		}
+		if latest.Error != "" {
+			msg += ", latest error: " + latest.Error
+		}
+		alerts = append(alerts, &Alert{
+			Key:     alertKeyDataAPI + "/" + relay.Hostname(),
+			Title:   "Data API stalled: " + relay.Hostname(),
+			Message: msg,
+		})
+	}
+	return alerts, nil
+}
+
+// checkCheckValueLag alerts if check-payload-value falls behind the head slot
+func (a *Alerter) checkCheckValueLag() (alerts []*Alert, err error) {
+	latestSlot, err := a.opts.DB.GetLatestCheckedSlot()
+	if err != nil {
+		return nil, err
+	}
+	headSlot := a.clock.CurrentSlot()
+	if latestSlot == 0 || headSlot <= latestSlot+a.opts.CheckValueMaxLagSlots {
+		return nil, nil
+	}
+	return []*Alert{{
+		Key:     alertKeyCheckValueLag,
+		Title:   "check-payload-value is lagging",
+		Message: fmt.Sprintf("latest checked slot %d is %d slots behind head slot %d", latestSlot, headSlot-latestSlot, headSlot),
+	}}, nil
+}
+
+// checkBidCollect alerts about bidcollect sources that stopped receiving bids
+func (a *Alerter) checkBidCollect() (alerts []*Alert, err error) {
+	now := a.clock.Now().UTC()
+	entries, err := a.opts.DB.GetBidCollectSourceStatus(now.Add(-bidCollectStatusMaxAge))
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		silence := now.Sub(entry.LastBidAt)
+		if silence <= a.opts.BidCollectMaxSilence {
+			continue
+		}
+
+		source := sourceTypeName(entry.SourceType)
+		if entry.Relay != "" {
+			source += " " + entry.Relay
+		}
+		msg := fmt.Sprintf("instance %s received no bids from %s for %s (last bid at %s)", entry.UID, source, silence.Truncate(time.Second), entry.LastBidAt.UTC().Format(time.DateTime))
+		if now.Sub(entry.UpdatedAt) > a.opts.BidCollectMaxSilence {
+			msg += fmt.Sprintf(", instance stopped reporting at %s", entry.UpdatedAt.UTC().Format(time.DateTime))
+		}
+		alerts = append(alerts, &Alert{
+			Key:     fmt.Sprintf("%s/%s/%s", alertKeyBidCollect, entry.UID, strings.ReplaceAll(source, " ", "/")),
+			Title:   "bidcollect source stalled: " + source,
+			Message: msg,
+		})
+	}
+	return alerts, nil
+}
+
+func sourceTypeName(sourceType int) string {
+	switch sourceType {
+	case types.SourceTypeGetHeader:
+		return "getHeader"
+	case types.SourceTypeDataAPI:
+		return "dataAPI"
+	case types.SourceTypeUltrasoundStream:
+		return "ultrasoundStream"
+	default:
+		return fmt.Sprintf("source-%d", sourceType)
+	}
+}
+
+func sortedKeys(alerts map[string]*Alert) []string {
+	keys := make([]string, 0, len(alerts))
+	for key := range alerts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
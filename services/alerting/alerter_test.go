@@ -0,0 +1,163 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	"github.com/flashbots/relayscan/services/bidcollect/types"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestDB = errors.New("db error")
+
+type fakeDB struct {
+	valueCheckStats   map[string]*database.RelayValueCheckStatsEntry
+	backfillStatus    map[string][]*database.DataAPIBackfillStatusEntry
+	latestCheckedSlot uint64
+	sourceStatus      []*database.BidCollectSourceStatusEntry
+	err               error
+}
+
+func (db *fakeDB) GetRelayValueCheckStats(relay string, since, until time.Time) (*database.RelayValueCheckStatsEntry, error) {
+	if stats, ok := db.valueCheckStats[relay]; ok {
+		return stats, db.err
+	}
+	return &database.RelayValueCheckStatsEntry{}, db.err
+}
+
+func (db *fakeDB) GetDataAPIBackfillStatus(relay string, limit int) ([]*database.DataAPIBackfillStatusEntry, error) {
+	runs := db.backfillStatus[relay]
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, db.err
+}
+
+func (db *fakeDB) GetLatestCheckedSlot() (uint64, error) {
+	return db.latestCheckedSlot, db.err
+}
+
+func (db *fakeDB) GetBidCollectSourceStatus(since time.Time) ([]*database.BidCollectSourceStatusEntry, error) {
+	return db.sourceStatus, db.err
+}
+
+type recordingNotifier struct {
+	alerts []*Alert
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Notify(ctx context.Context, alert *Alert) error {
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func (n *recordingNotifier) keys() (keys []string) {
+	for _, alert := range n.alerts {
+		if alert.Resolved {
+			keys = append(keys, "resolved:"+alert.Key)
+		} else {
+			keys = append(keys, alert.Key)
+		}
+	}
+	n.alerts = nil
+	return keys
+}
+
+func newTestAlerter(t *testing.T, db *fakeDB, clock common.SlotClock) (*Alerter, *recordingNotifier) {
+	t.Helper()
+	notifier := &recordingNotifier{}
+	relay, err := common.NewRelayEntry("https://0xac6e77dfe25ecd6110b8e780608cce0dab71fdd5ebea22a16c0205200f2f8e2e3ad3b71d3499c54ad14d6c21b41a37ae@relay1.example.com", false)
+	require.NoError(t, err)
+	alerter := NewAlerter(&AlerterOpts{
+		Log:                   common.Logger,
+		DB:                    db,
+		Notifiers:             []Notifier{notifier},
+		Relays:                []common.RelayEntry{relay},
+		Clock:                 clock,
+		ValueCheckWindow:      time.Hour,
+		MaxFailedValueChecks:  1,
+		DataAPIMaxEmptyRuns:   2,
+		CheckValueMaxLagSlots: 10,
+		BidCollectMaxSilence:  5 * time.Minute,
+	})
+	return alerter, notifier
+}
+
+func TestAlerterChecks(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	clock := common.NewFakeSlotClock(t0)
+	headSlot := clock.CurrentSlot()
+
+	db := &fakeDB{
+		valueCheckStats: map[string]*database.RelayValueCheckStatsEntry{
+			"relay1.example.com": {NumChecked: 10, NumFailed: 1, FailedClaimedEth: "0.1", FailedDeliveredEth: "0.05"}, // at the threshold
+		},
+		backfillStatus: map[string][]*database.DataAPIBackfillStatusEntry{
+			"relay1.example.com": {{Available: true, NumPayloads: 0}}, // not enough runs yet
+		},
+		latestCheckedSlot: headSlot - 10,
+		sourceStatus: []*database.BidCollectSourceStatusEntry{
+			{UID: "abc", SourceType: types.SourceTypeDataAPI, Relay: "relay1.example.com", UpdatedAt: t0, LastBidAt: t0.Add(-time.Minute)},
+		},
+	}
+	alerter, notifier := newTestAlerter(t, db, clock)
+
+	// all good
+	alerter.Check()
+	require.Empty(t, notifier.keys())
+
+	// every check fails
+	db.valueCheckStats["relay1.example.com"].NumFailed = 2
+	db.backfillStatus["relay1.example.com"] = []*database.DataAPIBackfillStatusEntry{
+		{Available: false, Error: "timeout"},
+		{Available: true},
+		{Available: true, NumPayloads: 5}, // outside of the checked runs
+	}
+	db.latestCheckedSlot = headSlot - 11
+	db.sourceStatus[0].LastBidAt = t0.Add(-6 * time.Minute)
+	alerter.Check()
+	alerts := notifier.alerts
+	require.Equal(t, []string{
+		"bidcollect/abc/dataAPI/relay1.example.com",
+		"check-value-lag",
+		"data-api/relay1.example.com",
+		"value-check/relay1.example.com",
+	}, notifier.keys())
+	require.Equal(t, t0, alerts[0].Time)
+	require.Contains(t, alerts[2].Message, "no new payloads in the last 2 backfill runs")
+	require.Contains(t, alerts[2].Message, "data API unavailable in 1 of them, latest error: timeout")
+	require.Equal(t, fmt.Sprintf("latest checked slot %d is 11 slots behind head slot %d", headSlot-11, headSlot), alerts[1].Message)
+
+	// active alerts are not sent again
+	alerter.Check()
+	require.Empty(t, notifier.keys())
+
+	// a failing check keeps its alerts, the others are resolved
+	db.err = errTestDB
+	alerter.Check()
+	require.Empty(t, notifier.keys())
+
+	db.err = nil
+	db.valueCheckStats["relay1.example.com"].NumFailed = 0
+	db.backfillStatus["relay1.example.com"][1].NumPayloads = 1
+	db.latestCheckedSlot = headSlot
+	alerter.Check()
+	require.Equal(t, []string{
+		"resolved:check-value-lag",
+		"resolved:data-api/relay1.example.com",
+		"resolved:value-check/relay1.example.com",
+	}, notifier.keys())
+
+	// the bidcollect instance stopped reporting
+	clock.Set(t0.Add(10 * time.Minute))
+	db.latestCheckedSlot = clock.CurrentSlot()
+	alerter.Check()
+	require.Empty(t, notifier.keys())
+	require.Contains(t, alerter.active["bidcollect/abc/dataAPI/relay1.example.com"].Message, "instance stopped reporting at 2024-06-01 10:00:00")
+}
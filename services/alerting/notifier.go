@@ -0,0 +1,110 @@
+package alerting
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/flashbots/relayscan/common"
+)
+
+const (
+	notifierTimeout = 10 * time.Second
+	pushoverAPIURL  = "https://api.pushover.net/1/messages.json"
+)
+
+// Notifier sends an alert to an external service
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, alert *Alert) error
+}
+
+// WebhookNotifier posts the alert as JSON to a generic webhook
+type WebhookNotifier struct {
+	url    string
+	client http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: http.Client{Timeout: notifierTimeout},
+	}
+}
+
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, alert *Alert) error {
+	_, err := common.SendHTTPRequest(ctx, n.client, http.MethodPost, n.url, alert, nil)
+	return err
+}
+
+// SlackNotifier posts the alert text to a Slack-compatible incoming webhook
+type SlackNotifier struct {
+	url    string
+	client http.Client
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func NewSlackNotifier(url string) *SlackNotifier {
+	return &SlackNotifier{
+		url:    url,
+		client: http.Client{Timeout: notifierTimeout},
+	}
+}
+
+func (n *SlackNotifier) Name() string {
+	return "slack"
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, alert *Alert) error {
+	_, err := common.SendHTTPRequest(ctx, n.client, http.MethodPost, n.url, slackMessage{Text: alert.Text()}, nil)
+	return err
+}
+
+// PushoverNotifier sends the alert through the Pushover messages API
+type PushoverNotifier struct {
+	apiURL string
+	token  string // application token
+	user   string // user or group key
+	client http.Client
+}
+
+type pushoverMessage struct {
+	Token   string `json:"token"`
+	User    string `json:"user"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+func NewPushoverNotifier(token, user string) *PushoverNotifier {
+	return &PushoverNotifier{
+		apiURL: pushoverAPIURL,
+		token:  token,
+		user:   user,
+		client: http.Client{Timeout: notifierTimeout},
+	}
+}
+
+func (n *PushoverNotifier) Name() string {
+	return "pushover"
+}
+
+func (n *PushoverNotifier) Notify(ctx context.Context, alert *Alert) error {
+	msg := pushoverMessage{
+		Token:   n.token,
+		User:    n.user,
+		Title:   alert.Title,
+		Message: alert.Message,
+	}
+	if alert.Resolved {
+		msg.Title = "[resolved] " + msg.Title
+	}
+	_, err := common.SendHTTPRequest(ctx, n.client, http.MethodPost, n.apiURL, msg, nil)
+	return err
+}
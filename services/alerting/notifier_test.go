@@ -0,0 +1,72 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newRecordingServer returns a server that decodes the JSON body of every request into a new map
+func newRecordingServer(t *testing.T, statusCode int) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	requests := []map[string]any{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body := make(map[string]any)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestNotifiers(t *testing.T) {
+	alert := &Alert{Key: "value-check/relay1", Title: "Incorrect payload values: relay1", Message: "1 of 10 checked payloads failed"}
+	resolved := *alert
+	resolved.Resolved = true
+
+	t.Run("webhook", func(t *testing.T) {
+		srv, requests := newRecordingServer(t, http.StatusOK)
+		n := NewWebhookNotifier(srv.URL)
+		require.NoError(t, n.Notify(context.Background(), alert))
+		require.Len(t, *requests, 1)
+		require.Equal(t, "value-check/relay1", (*requests)[0]["key"])
+		require.Equal(t, alert.Message, (*requests)[0]["message"])
+		require.Equal(t, false, (*requests)[0]["resolved"])
+	})
+
+	t.Run("slack", func(t *testing.T) {
+		srv, requests := newRecordingServer(t, http.StatusOK)
+		n := NewSlackNotifier(srv.URL)
+		require.NoError(t, n.Notify(context.Background(), alert))
+		require.NoError(t, n.Notify(context.Background(), &resolved))
+		require.Equal(t, []map[string]any{
+			{"text": "[alert] Incorrect payload values: relay1: 1 of 10 checked payloads failed"},
+			{"text": "[resolved] Incorrect payload values: relay1: 1 of 10 checked payloads failed"},
+		}, *requests)
+	})
+
+	t.Run("pushover", func(t *testing.T) {
+		srv, requests := newRecordingServer(t, http.StatusOK)
+		n := NewPushoverNotifier("token", "user")
+		n.apiURL = srv.URL
+		require.NoError(t, n.Notify(context.Background(), &resolved))
+		require.Equal(t, []map[string]any{{
+			"token":   "token",
+			"user":    "user",
+			"title":   "[resolved] Incorrect payload values: relay1",
+			"message": "1 of 10 checked payloads failed",
+		}}, *requests)
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		srv, _ := newRecordingServer(t, http.StatusBadRequest)
+		require.Error(t, NewWebhookNotifier(srv.URL).Notify(context.Background(), alert))
+	})
+}
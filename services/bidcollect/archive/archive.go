@@ -0,0 +1,649 @@
+// Package archive combines the bidcollect output files of a day into the daily bid archive.
+//
+// For a given date, it:
+//  1. merges the hourly CSV/TSV files of all collector instances (UIDs)
+//  2. deduplicates the bids by UniqueKey across instances (keeping the earliest received_at_ms),
+//     optionally recording which instances saw each bid
+//  3. recomputes the top bids from the merged bids
+//  4. merges the observation files (if the collectors recorded them), and derives per-bid observation stats
+//  5. writes zipped CSVs, a checksums file and a manifest
+//  6. uploads everything to the configured object stores
+package archive
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flashbots/relayscan/services/bidcollect/objectstore"
+	"github.com/flashbots/relayscan/services/bidcollect/types"
+	"github.com/flashbots/relayscan/vars"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrNoInputFiles = errors.New("no input files found")
+
+// slotsInCache is how many slots of bids are kept for deduplication and top-bid tracking (one hour bucket + one hour margin)
+const slotsInCache = 2 * types.BucketMinutes * 5
+
+type ArchiverOpts struct {
+	Log *logrus.Entry
+
+	Date   string // yyyy-mm-dd
+	OutDir string // bidcollect output directory (containing the <date> subdirectory)
+
+	Stores      []objectstore.ObjectStore // upload targets (none to skip upload)
+	DeleteInput bool                      // remove the input files after successful archiving
+
+	// RecordInstances writes an additional <date>_instances.csv, listing which collector instances saw each bid
+	RecordInstances bool
+}
+
+// Manifest describes the contents of a daily archive
+type Manifest struct {
+	Date      string    `json:"date"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   string    `json:"version"`
+
+	UIDs       []string `json:"uids"`
+	InputFiles []string `json:"input_files"`
+
+	NumBidsInput      uint64 `json:"num_bids_input"`
+	NumBidsDuplicates uint64 `json:"num_bids_duplicates"`
+	NumBidsAll        uint64 `json:"num_bids_all"`
+	NumBidsTop        uint64 `json:"num_bids_top"`
+	NumObservations   uint64 `json:"num_observations,omitempty"`
+
+	SourceTypesAll map[int]uint64 `json:"source_types_all"`
+	SourceTypesTop map[int]uint64 `json:"source_types_top"`
+
+	Files []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Filename string `json:"filename"`
+	Size     uint64 `json:"size"`
+	SHA256   string `json:"sha256"`
+}
+
+// InstancesCSVFields are the columns of the instances file (one line per unique bid)
+var InstancesCSVFields = []string{
+	"slot",
+	"block_hash",
+	"parent_hash",
+	"builder_pubkey",
+	"value",
+	"received_at_ms",
+	"num_instances",
+	"instances",
+}
+
+// inputFile is a single hourly output file of one collector instance
+type inputFile struct {
+	path      string
+	bucket    string // i.e. 2024-06-01_13-00
+	uid       string
+	separator string
+}
+
+type Archiver struct {
+	opts *ArchiverOpts
+	log  *logrus.Entry
+
+	dir string // input directory for the date
+
+	// dedup and top-bid state (map[slot]...)
+	seenBids map[uint64]map[string]*seenBid
+	topBids  map[uint64]*types.CommonBid
+
+	fInstances *os.File
+	wInstances *bufio.Writer
+}
+
+// seenBid is the earliest received copy of a bid, and the instances that received it
+type seenBid struct {
+	bid  *types.CommonBid
+	uids map[string]bool
+}
+
+// instanceBid is a bid as read from the output file of a collector instance
+type instanceBid struct {
+	bid *types.CommonBid
+	uid string
+}
+
+func NewArchiver(opts *ArchiverOpts) (*Archiver, error) {
+	if _, err := time.Parse(time.DateOnly, opts.Date); err != nil {
+		return nil, fmt.Errorf("invalid date %s: %w", opts.Date, err)
+	}
+
+	return &Archiver{
+		opts:     opts,
+		log:      opts.Log.WithField("date", opts.Date),
+		dir:      filepath.Join(opts.OutDir, opts.Date),
+		seenBids: make(map[uint64]map[string]*seenBid),
+		topBids:  make(map[uint64]*types.CommonBid),
+	}, nil
+}
+
+// ArchiveKeyPrefix returns the object store prefix for the month of the given date (i.e. ethereum/mainnet/2024-06)
+func ArchiveKeyPrefix(date string) string {
+	return types.ArchiveKeyPrefix + "/" + date[:7]
+}
+
+// Run creates the archive files and uploads them. Returns the manifest.
+func (a *Archiver) Run(ctx context.Context) (*Manifest, error) {
+	inputFiles, err := a.findInputFiles("all_")
+	if err != nil {
+		return nil, err
+	}
+	if len(inputFiles) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoInputFiles, a.dir)
+	}
+
+	obsFiles, err := a.findInputFiles("obs_")
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Date:           a.opts.Date,
+		CreatedAt:      time.Now().UTC(),
+		Version:        vars.Version,
+		UIDs:           []string{},
+		InputFiles:     []string{},
+		SourceTypesAll: make(map[int]uint64),
+		SourceTypesTop: make(map[int]uint64),
+	}
+
+	uids := make(map[string]bool)
+	for _, f := range inputFiles {
+		uids[f.uid] = true
+		manifest.InputFiles = append(manifest.InputFiles, filepath.Base(f.path))
+	}
+	for _, f := range obsFiles {
+		uids[f.uid] = true // with Redis dedup, an instance might only have observations
+	}
+	for uid := range uids {
+		manifest.UIDs = append(manifest.UIDs, uid)
+	}
+	sort.Strings(manifest.UIDs)
+	a.log.Infof("[archive] found %d input files from %d collector instances: %s", len(inputFiles), len(manifest.UIDs), strings.Join(manifest.UIDs, ", "))
+
+	// Combine into single CSVs
+	fnAll := filepath.Join(a.dir, a.opts.Date+"_all.csv")
+	fnTop := filepath.Join(a.dir, a.opts.Date+"_top.csv")
+	fnInstances := filepath.Join(a.dir, a.opts.Date+"_instances.csv")
+	err = a.combine(inputFiles, obsFiles, fnAll, fnTop, fnInstances, manifest)
+	if err != nil {
+		return nil, err
+	}
+	a.log.WithFields(logrus.Fields{
+		"input":      manifest.NumBidsInput,
+		"duplicates": manifest.NumBidsDuplicates,
+		"all":        manifest.NumBidsAll,
+		"top":        manifest.NumBidsTop,
+	}).Info("[archive] bids combined")
+
+	filesToZip := []string{fnAll, fnTop}
+	if a.opts.RecordInstances {
+		filesToZip = append(filesToZip, fnInstances)
+	}
+
+	// Combine observations (only present if the collectors ran with --observations)
+	if len(obsFiles) > 0 {
+		fnObs := filepath.Join(a.dir, a.opts.Date+"_observations.csv")
+		fnObsStats := filepath.Join(a.dir, a.opts.Date+"_observation_stats.csv")
+		err = combineObservations(obsFiles, fnObs, fnObsStats, manifest)
+		if err != nil {
+			return nil, err
+		}
+		a.log.Infof("[archive] %d observations combined from %d files", manifest.NumObservations, len(obsFiles))
+		filesToZip = append(filesToZip, fnObs, fnObsStats)
+	}
+
+	// Zip, and remove the uncompressed CSVs
+	filesToUpload := []string{}
+	for _, fn := range filesToZip {
+		fnZip := fn + ".zip"
+		a.log.Infof("[archive] writing %s ...", fnZip)
+		if err := zipFile(fn, fnZip); err != nil {
+			return nil, err
+		}
+		_ = os.Remove(fn)
+
+		mf, err := newManifestFile(fnZip)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, mf)
+		filesToUpload = append(filesToUpload, fnZip)
+	}
+
+	// Checksums (in the format of sha256sum)
+	fnChecksums := filepath.Join(a.dir, a.opts.Date+"_checksums.txt")
+	checksums := ""
+	for _, mf := range manifest.Files {
+		checksums += fmt.Sprintf("%s  %s\n", mf.SHA256, mf.Filename)
+	}
+	if err := os.WriteFile(fnChecksums, []byte(checksums), 0o600); err != nil {
+		return nil, err
+	}
+	filesToUpload = append(filesToUpload, fnChecksums)
+
+	// Manifest
+	fnManifest := filepath.Join(a.dir, a.opts.Date+"_manifest.json")
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(fnManifest, manifestJSON, 0o600); err != nil {
+		return nil, err
+	}
+	filesToUpload = append(filesToUpload, fnManifest)
+
+	// Upload
+	keyPrefix := ArchiveKeyPrefix(a.opts.Date)
+	for _, store := range a.opts.Stores {
+		for _, fn := range filesToUpload {
+			key := keyPrefix + "/" + filepath.Base(fn)
+			a.log.Infof("[archive] uploading %s to %s/%s ...", filepath.Base(fn), store.String(), key)
+			if err := store.Upload(ctx, fn, key); err != nil {
+				return nil, fmt.Errorf("upload to %s failed: %w", store.String(), err)
+			}
+		}
+	}
+
+	// Remove input files
+	if a.opts.DeleteInput {
+		for _, f := range append(inputFiles, obsFiles...) {
+			if err := os.Remove(f.path); err != nil {
+				return nil, err
+			}
+		}
+		a.log.Infof("[archive] removed %d input files", len(inputFiles)+len(obsFiles))
+	}
+
+	return manifest, nil
+}
+
+// findInputFiles returns the files with the given prefix of all collector instances for the date, ordered by bucket.
+// Filenames look like this: all_2024-06-01_13-00_<uid>.csv
+func (a *Archiver) findInputFiles(prefix string) ([]inputFile, error) {
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketLen := len("2006-01-02_15-04")
+	files := []inputFile{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		ext := filepath.Ext(name)
+		separator := ","
+		if ext == ".tsv" {
+			separator = "\t"
+		} else if ext != ".csv" {
+			continue
+		}
+
+		base := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if len(base) < bucketLen+2 || base[bucketLen] != '_' {
+			a.log.Warnf("[archive] skipping file with unexpected name: %s", name)
+			continue
+		}
+
+		files = append(files, inputFile{
+			path:      filepath.Join(a.dir, name),
+			bucket:    base[:bucketLen],
+			uid:       base[bucketLen+1:],
+			separator: separator,
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].bucket == files[j].bucket {
+			return files[i].uid < files[j].uid
+		}
+		return files[i].bucket < files[j].bucket
+	})
+	return files, nil
+}
+
+// combine processes the input files one hour bucket at a time, to keep memory usage bounded. If instances are
+// recorded, the observation files add the instances that saw a bid but didn't write it (i.e. with Redis dedup, only
+// the first instance writes a bid).
+func (a *Archiver) combine(inputFiles, obsFiles []inputFile, fnAll, fnTop, fnInstances string, manifest *Manifest) error {
+	fAll, err := newCSVWriter(fnAll)
+	if err != nil {
+		return err
+	}
+	defer fAll.Close() //nolint:errcheck
+
+	fTop, err := newCSVWriter(fnTop)
+	if err != nil {
+		return err
+	}
+	defer fTop.Close() //nolint:errcheck
+
+	if a.opts.RecordInstances {
+		a.fInstances, err = os.Create(fnInstances)
+		if err != nil {
+			return err
+		}
+		defer a.fInstances.Close() //nolint:errcheck
+		a.wInstances = bufio.NewWriter(a.fInstances)
+		if _, err = a.wInstances.WriteString(strings.Join(InstancesCSVFields, ",") + "\n"); err != nil {
+			return err
+		}
+	}
+
+	k := 0 // next observation file
+	for i := 0; i < len(inputFiles); {
+		// collect all files of the same bucket
+		j := i
+		for j < len(inputFiles) && inputFiles[j].bucket == inputFiles[i].bucket {
+			j++
+		}
+
+		bids := []instanceBid{}
+		for _, f := range inputFiles[i:j] {
+			fileBids, err := readBids(f)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", f.path, err)
+			}
+			for _, bid := range fileBids {
+				bids = append(bids, instanceBid{bid: bid, uid: f.uid})
+			}
+		}
+		manifest.NumBidsInput += uint64(len(bids))
+
+		// Process in the order the bids were received, across all instances
+		sort.SliceStable(bids, func(i, j int) bool {
+			return bids[i].bid.ReceivedAtMs < bids[j].bid.ReceivedAtMs
+		})
+
+		maxSlot := uint64(0)
+		for _, ib := range bids {
+			bid := ib.bid
+			isNewBid, isTopBid := a.processBid(bid, ib.uid)
+			if !isNewBid {
+				manifest.NumBidsDuplicates++
+				continue
+			}
+			if err := fAll.Write(bid); err != nil {
+				return err
+			}
+			manifest.NumBidsAll++
+			manifest.SourceTypesAll[bid.SourceType]++
+
+			if isTopBid {
+				if err := fTop.Write(bid); err != nil {
+					return err
+				}
+				manifest.NumBidsTop++
+				manifest.SourceTypesTop[bid.SourceType]++
+			}
+
+			if bid.Slot > maxSlot {
+				maxSlot = bid.Slot
+			}
+		}
+
+		for ; a.wInstances != nil && k < len(obsFiles) && obsFiles[k].bucket <= inputFiles[i].bucket; k++ {
+			if err := a.recordObservedInstances(obsFiles[k]); err != nil {
+				return err
+			}
+		}
+
+		if maxSlot > slotsInCache {
+			if err := a.evictSlotsBefore(maxSlot - slotsInCache); err != nil {
+				return err
+			}
+		}
+		i = j
+	}
+
+	// observations after the last bucket with bids, then flush the remaining slots
+	for ; a.wInstances != nil && k < len(obsFiles); k++ {
+		if err := a.recordObservedInstances(obsFiles[k]); err != nil {
+			return err
+		}
+	}
+	if err := a.evictSlotsBefore(math.MaxUint64); err != nil {
+		return err
+	}
+	if a.wInstances != nil {
+		if err := a.wInstances.Flush(); err != nil {
+			return err
+		}
+		if err := a.fInstances.Close(); err != nil {
+			return err
+		}
+	}
+
+	if err := fAll.Close(); err != nil {
+		return err
+	}
+	return fTop.Close()
+}
+
+// processBid returns whether the bid wasn't seen before, and whether it is a new top bid for the slot
+func (a *Archiver) processBid(bid *types.CommonBid, uid string) (isNewBid, isTopBid bool) {
+	if _, ok := a.seenBids[bid.Slot]; !ok {
+		a.seenBids[bid.Slot] = make(map[string]*seenBid)
+	}
+
+	key := bid.UniqueKey()
+	if seen, ok := a.seenBids[bid.Slot][key]; ok {
+		seen.uids[uid] = true
+		return false, false
+	}
+	a.seenBids[bid.Slot][key] = &seenBid{bid: bid, uids: map[string]bool{uid: true}}
+
+	topBid, ok := a.topBids[bid.Slot]
+	if !ok || bid.ValueAsBigInt().Cmp(topBid.ValueAsBigInt()) == 1 {
+		a.topBids[bid.Slot] = bid
+		isTopBid = true
+	}
+	return true, isTopBid
+}
+
+// recordObservedInstances adds the instance of an observation file to the bids it observed
+func (a *Archiver) recordObservedInstances(f inputFile) error {
+	observations, err := readObservations(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.path, err)
+	}
+	for _, obs := range observations {
+		if seen, ok := a.seenBids[obs.Slot][obs.UniqueKey()]; ok {
+			seen.uids[f.uid] = true
+		}
+	}
+	return nil
+}
+
+// evictSlotsBefore removes the state of old slots, and writes their instance records
+func (a *Archiver) evictSlotsBefore(minSlot uint64) error {
+	slots := []uint64{}
+	for slot := range a.seenBids {
+		if slot < minSlot {
+			slots = append(slots, slot)
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+
+	for _, slot := range slots {
+		if a.wInstances != nil {
+			if err := a.writeInstances(a.seenBids[slot]); err != nil {
+				return err
+			}
+		}
+		delete(a.seenBids, slot)
+		delete(a.topBids, slot)
+	}
+	return nil
+}
+
+func (a *Archiver) writeInstances(seenBids map[string]*seenBid) error {
+	entries := make([]*seenBid, 0, len(seenBids))
+	for _, seen := range seenBids {
+		entries = append(entries, seen)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].bid.ReceivedAtMs == entries[j].bid.ReceivedAtMs {
+			return entries[i].bid.UniqueKey() < entries[j].bid.UniqueKey()
+		}
+		return entries[i].bid.ReceivedAtMs < entries[j].bid.ReceivedAtMs
+	})
+
+	for _, seen := range entries {
+		uids := make([]string, 0, len(seen.uids))
+		for uid := range seen.uids {
+			uids = append(uids, uid)
+		}
+		sort.Strings(uids)
+
+		line := strings.Join([]string{
+			fmt.Sprint(seen.bid.Slot),
+			seen.bid.BlockHash,
+			seen.bid.ParentHash,
+			seen.bid.BuilderPubkey,
+			seen.bid.Value,
+			fmt.Sprint(seen.bid.ReceivedAtMs),
+			fmt.Sprint(len(uids)),
+			strings.Join(uids, " "),
+		}, ",")
+		if _, err := a.wInstances.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readBids(f inputFile) ([]*types.CommonBid, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() //nolint:errcheck
+
+	bids := []*types.CommonBid{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	isHeader := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if isHeader {
+			isHeader = false
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		bid, err := types.NewCommonBidFromCSVLine(line, f.separator)
+		if err != nil {
+			return nil, err
+		}
+		bids = append(bids, bid)
+	}
+	return bids, scanner.Err()
+}
+
+// csvWriter writes bids as CSV, including the header line
+type csvWriter struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+func newCSVWriter(fn string) (*csvWriter, error) {
+	f, err := os.Create(fn)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	_, err = w.WriteString(strings.Join(types.CommonBidCSVFields, ",") + "\n")
+	return &csvWriter{f: f, w: w}, err
+}
+
+func (c *csvWriter) Write(bid *types.CommonBid) error {
+	_, err := c.w.WriteString(bid.ToCSVLine(",") + "\n")
+	return err
+}
+
+func (c *csvWriter) Close() error {
+	if c.w == nil {
+		return nil
+	}
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+	c.w = nil
+	return c.f.Close()
+}
+
+func zipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close() //nolint:errcheck
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close() //nolint:errcheck
+
+	zw := zip.NewWriter(out)
+	w, err := zw.CreateHeader(&zip.FileHeader{ //nolint:exhaustruct
+		Name:     filepath.Base(src),
+		Method:   zip.Deflate,
+		Modified: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, in); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func newManifestFile(fn string) (mf ManifestFile, err error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return mf, err
+	}
+	defer f.Close() //nolint:errcheck
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return mf, err
+	}
+	return ManifestFile{
+		Filename: filepath.Base(fn),
+		Size:     uint64(size), //nolint:gosec
+		SHA256:   hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
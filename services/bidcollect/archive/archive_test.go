@@ -0,0 +1,232 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/services/bidcollect/objectstore"
+	"github.com/flashbots/relayscan/services/bidcollect/types"
+	"github.com/stretchr/testify/require"
+)
+
+func writeInputFile(t *testing.T, dir, name, separator string, bids []*types.CommonBid) {
+	t.Helper()
+	lines := []string{strings.Join(types.CommonBidCSVFields, separator)}
+	for _, bid := range bids {
+		lines = append(lines, bid.ToCSVLine(separator))
+	}
+	err := os.WriteFile(filepath.Join(dir, name), []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+	require.NoError(t, err)
+}
+
+func TestArchiver(t *testing.T) {
+	outDir := t.TempDir()
+	dayDir := filepath.Join(outDir, "2024-06-01")
+	require.NoError(t, os.MkdirAll(dayDir, os.ModePerm))
+
+	bid := func(receivedAtMs int64, slot uint64, blockHash, value string) *types.CommonBid {
+		return &types.CommonBid{
+			SourceType:   types.SourceTypeDataAPI,
+			ReceivedAtMs: receivedAtMs,
+			Slot:         slot,
+			BlockHash:    blockHash,
+			Value:        value,
+			Relay:        "relay.ultrasound.money",
+		}
+	}
+
+	// Two collector instances with overlapping bids
+	writeInputFile(t, dayDir, "all_2024-06-01_00-00_aaa.csv", ",", []*types.CommonBid{
+		bid(1000, 1, "0x1", "10"),
+		bid(1200, 1, "0x2", "20"),
+		bid(1500, 2, "0x3", "5"),
+	})
+	writeInputFile(t, dayDir, "all_2024-06-01_00-00_bbb.tsv", "\t", []*types.CommonBid{
+		bid(900, 1, "0x1", "10"),  // duplicate, seen earlier by bbb
+		bid(1300, 1, "0x4", "15"), // not a top bid
+		bid(1600, 2, "0x5", "30"),
+	})
+	writeInputFile(t, dayDir, "all_2024-06-01_01-00_aaa.csv", ",", []*types.CommonBid{
+		bid(3_601_000, 2, "0x5", "30"), // duplicate across buckets
+		bid(3_602_000, 3, "0x6", "1"),
+	})
+	// top files are ignored, top bids are recomputed
+	writeInputFile(t, dayDir, "top_2024-06-01_00-00_aaa.csv", ",", []*types.CommonBid{bid(1, 1, "0x9", "99")})
+
+	storeDir := t.TempDir()
+	store, err := objectstore.NewFilesystemStore(storeDir)
+	require.NoError(t, err)
+
+	archiver, err := NewArchiver(&ArchiverOpts{
+		Log:             common.Logger,
+		Date:            "2024-06-01",
+		OutDir:          outDir,
+		Stores:          []objectstore.ObjectStore{store},
+		DeleteInput:     true,
+		RecordInstances: true,
+	})
+	require.NoError(t, err)
+
+	manifest, err := archiver.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"aaa", "bbb"}, manifest.UIDs)
+	require.Equal(t, uint64(8), manifest.NumBidsInput)
+	require.Equal(t, uint64(2), manifest.NumBidsDuplicates)
+	require.Equal(t, uint64(6), manifest.NumBidsAll)
+	require.Equal(t, uint64(5), manifest.NumBidsTop)
+	require.Len(t, manifest.Files, 3)
+
+	// Input files were removed, top files are kept
+	_, err = os.Stat(filepath.Join(dayDir, "all_2024-06-01_00-00_aaa.csv"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dayDir, "top_2024-06-01_00-00_aaa.csv"))
+	require.NoError(t, err)
+
+	// Uploaded files
+	files, err := store.ListFiles(context.Background(), "ethereum/mainnet/2024-06")
+	require.NoError(t, err)
+	filenames := []string{}
+	for _, f := range files {
+		filenames = append(filenames, filepath.Base(f.Key))
+	}
+	require.Equal(t, []string{"2024-06-01_all.csv.zip", "2024-06-01_checksums.txt", "2024-06-01_instances.csv.zip", "2024-06-01_manifest.json", "2024-06-01_top.csv.zip"}, filenames)
+
+	folders, err := store.ListFolders(context.Background(), "ethereum/mainnet/")
+	require.NoError(t, err)
+	require.Equal(t, []string{"2024-06"}, folders)
+
+	// Checksums and manifest match
+	checksums, err := os.ReadFile(filepath.Join(storeDir, "ethereum/mainnet/2024-06/2024-06-01_checksums.txt"))
+	require.NoError(t, err)
+	require.Contains(t, string(checksums), manifest.Files[0].SHA256+"  2024-06-01_all.csv.zip")
+
+	// Instances file lists all collectors that saw a bid, with the earliest receive time
+	instances := readZippedFile(t, filepath.Join(storeDir, "ethereum/mainnet/2024-06/2024-06-01_instances.csv.zip"))
+	require.Equal(t, strings.Join(InstancesCSVFields, ","), instances[0])
+	require.Equal(t, "1,0x1,,,10,900,2,aaa bbb", instances[1])
+	require.Len(t, instances, 7)
+
+	manifestJSON, err := os.ReadFile(filepath.Join(storeDir, "ethereum/mainnet/2024-06/2024-06-01_manifest.json"))
+	require.NoError(t, err)
+	uploadedManifest := new(Manifest)
+	require.NoError(t, json.Unmarshal(manifestJSON, uploadedManifest))
+	require.Equal(t, manifest.NumBidsAll, uploadedManifest.NumBidsAll)
+}
+
+func TestArchiverKeepsEarliestBid(t *testing.T) {
+	archiver, err := NewArchiver(&ArchiverOpts{Log: common.Logger, Date: "2024-06-01", OutDir: t.TempDir()})
+	require.NoError(t, err)
+
+	first := &types.CommonBid{ReceivedAtMs: 1, Slot: 1, BlockHash: "0x1", Value: "10"}
+	isNew, isTop := archiver.processBid(first, "aaa")
+	require.True(t, isNew)
+	require.True(t, isTop)
+
+	isNew, isTop = archiver.processBid(&types.CommonBid{ReceivedAtMs: 2, Slot: 1, BlockHash: "0x1", Value: "10"}, "bbb")
+	require.False(t, isNew)
+	require.False(t, isTop)
+	require.Equal(t, first, archiver.topBids[1])
+	require.Equal(t, map[string]bool{"aaa": true, "bbb": true}, archiver.seenBids[1][first.UniqueKey()].uids)
+
+	_, err = NewArchiver(&ArchiverOpts{Log: common.Logger, Date: "2024-6-1"})
+	require.Error(t, err)
+}
+
+func TestArchiverObservations(t *testing.T) {
+	outDir := t.TempDir()
+	dayDir := filepath.Join(outDir, "2024-06-01")
+	require.NoError(t, os.MkdirAll(dayDir, os.ModePerm))
+
+	bid := &types.CommonBid{SourceType: types.SourceTypeDataAPI, ReceivedAtMs: 1000, Slot: 1, BlockHash: "0x1", Value: "10", Relay: "relay-a"}
+	writeInputFile(t, dayDir, "all_2024-06-01_00-00_aaa.csv", ",", []*types.CommonBid{bid})
+
+	writeObservations := func(name string, observations []*types.BidObservation) {
+		lines := []string{strings.Join(types.BidObservationCSVFields, ",")}
+		for _, obs := range observations {
+			lines = append(lines, obs.ToCSVLine(","))
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dayDir, name), []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+	}
+	obs := func(relay string, receivedAtMs int64) *types.BidObservation {
+		o := types.NewBidObservation(bid)
+		o.Relay = relay
+		o.ReceivedAtMs = receivedAtMs
+		return o
+	}
+	writeObservations("obs_2024-06-01_00-00_aaa.csv", []*types.BidObservation{obs("relay-a", 1000), obs("relay-b", 1400)})
+	writeObservations("obs_2024-06-01_00-00_bbb.csv", []*types.BidObservation{obs("relay-b", 1100), obs("relay-a", 1050)})
+
+	archiver, err := NewArchiver(&ArchiverOpts{Log: common.Logger, Date: "2024-06-01", OutDir: outDir, DeleteInput: true})
+	require.NoError(t, err)
+	manifest, err := archiver.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), manifest.NumObservations)
+	require.Len(t, manifest.Files, 4)
+
+	observations := readZippedFile(t, filepath.Join(dayDir, "2024-06-01_observations.csv.zip"))
+	require.Equal(t, strings.Join(ObservationsCSVFields, ","), observations[0])
+	require.Len(t, observations, 5)
+	require.Equal(t, "1,0x1,,,10,1,relay-a,1000,,,,aaa", observations[1])
+	require.Equal(t, "1,0x1,,,10,1,relay-a,1050,,,,bbb", observations[2])
+
+	stats := readZippedFile(t, filepath.Join(dayDir, "2024-06-01_observation_stats.csv.zip"))
+	require.Equal(t, []string{
+		strings.Join(types.BidObservationStatsCSVFields, ","),
+		"1,0x1,,,10,4,2,1,1000,relay-a,1,100,relay-a:0 relay-b:100",
+	}, stats)
+
+	_, err = os.Stat(filepath.Join(dayDir, "obs_2024-06-01_00-00_aaa.csv"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestArchiverInstancesFromObservations(t *testing.T) {
+	outDir := t.TempDir()
+	dayDir := filepath.Join(outDir, "2024-06-01")
+	require.NoError(t, os.MkdirAll(dayDir, os.ModePerm))
+
+	// With Redis dedup, only aaa wrote the bid, bbb and ccc only observed it (ccc in the next bucket)
+	bid := &types.CommonBid{SourceType: types.SourceTypeDataAPI, ReceivedAtMs: 1000, Slot: 1, BlockHash: "0x1", Value: "10", Relay: "relay-a"}
+	writeInputFile(t, dayDir, "all_2024-06-01_00-00_aaa.csv", ",", []*types.CommonBid{bid})
+	for name, receivedAtMs := range map[string]int64{
+		"obs_2024-06-01_00-00_aaa.csv": 1000,
+		"obs_2024-06-01_00-00_bbb.csv": 1100,
+		"obs_2024-06-01_01-00_ccc.csv": 3_600_100,
+	} {
+		obs := types.NewBidObservation(bid)
+		obs.ReceivedAtMs = receivedAtMs
+		content := strings.Join(types.BidObservationCSVFields, ",") + "\n" + obs.ToCSVLine(",") + "\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dayDir, name), []byte(content), 0o600))
+	}
+
+	archiver, err := NewArchiver(&ArchiverOpts{Log: common.Logger, Date: "2024-06-01", OutDir: outDir, RecordInstances: true})
+	require.NoError(t, err)
+	manifest, err := archiver.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"aaa", "bbb", "ccc"}, manifest.UIDs)
+	require.Equal(t, uint64(1), manifest.NumBidsAll)
+
+	instances := readZippedFile(t, filepath.Join(dayDir, "2024-06-01_instances.csv.zip"))
+	require.Equal(t, []string{strings.Join(InstancesCSVFields, ","), "1,0x1,,,10,1000,3,aaa bbb ccc"}, instances)
+}
+
+func readZippedFile(t *testing.T, fn string) []string {
+	t.Helper()
+	zr, err := zip.OpenReader(fn)
+	require.NoError(t, err)
+	defer zr.Close() //nolint:errcheck
+	require.Len(t, zr.File, 1)
+
+	f, err := zr.File[0].Open()
+	require.NoError(t, err)
+	defer f.Close() //nolint:errcheck
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/flashbots/relayscan/services/bidcollect/types"
+)
+
+// ObservationsCSVFields are the columns of the combined observations file (the observation fields plus the collector instance)
+var ObservationsCSVFields = append(append([]string{}, types.BidObservationCSVFields...), "instance")
+
+// combineObservations merges the observation files of all collector instances (one hour bucket at a time),
+// and writes the per-bid observation stats (first-seen relay, spread between relays, ...)
+func combineObservations(obsFiles []inputFile, fnObs, fnStats string, manifest *Manifest) error {
+	fObs, err := os.Create(fnObs)
+	if err != nil {
+		return err
+	}
+	defer fObs.Close() //nolint:errcheck
+	wObs := bufio.NewWriter(fObs)
+	if _, err = wObs.WriteString(strings.Join(ObservationsCSVFields, ",") + "\n"); err != nil {
+		return err
+	}
+
+	fStats, err := os.Create(fnStats)
+	if err != nil {
+		return err
+	}
+	defer fStats.Close() //nolint:errcheck
+	wStats := bufio.NewWriter(fStats)
+	if _, err = wStats.WriteString(strings.Join(types.BidObservationStatsCSVFields, ",") + "\n"); err != nil {
+		return err
+	}
+
+	// observations are kept per slot until the slot can't receive further observations
+	observationsBySlot := make(map[uint64][]*types.BidObservation)
+	evictSlotsBefore := func(minSlot uint64) error {
+		slots := []uint64{}
+		for slot := range observationsBySlot {
+			if slot < minSlot {
+				slots = append(slots, slot)
+			}
+		}
+		sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+
+		for _, slot := range slots {
+			for _, stats := range types.ComputeBidObservationStats(observationsBySlot[slot]) {
+				if _, err := wStats.WriteString(stats.ToCSVLine(",") + "\n"); err != nil {
+					return err
+				}
+			}
+			delete(observationsBySlot, slot)
+		}
+		return nil
+	}
+
+	for i := 0; i < len(obsFiles); {
+		// collect all files of the same bucket
+		j := i
+		for j < len(obsFiles) && obsFiles[j].bucket == obsFiles[i].bucket {
+			j++
+		}
+
+		type instanceObservation struct {
+			obs *types.BidObservation
+			uid string
+		}
+		observations := []instanceObservation{}
+		for _, f := range obsFiles[i:j] {
+			fileObservations, err := readObservations(f)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", f.path, err)
+			}
+			for _, obs := range fileObservations {
+				observations = append(observations, instanceObservation{obs: obs, uid: f.uid})
+			}
+		}
+		manifest.NumObservations += uint64(len(observations))
+
+		sort.SliceStable(observations, func(i, j int) bool {
+			return observations[i].obs.ReceivedAtMs < observations[j].obs.ReceivedAtMs
+		})
+
+		maxSlot := uint64(0)
+		for _, o := range observations {
+			if _, err := wObs.WriteString(o.obs.ToCSVLine(",") + "," + o.uid + "\n"); err != nil {
+				return err
+			}
+			observationsBySlot[o.obs.Slot] = append(observationsBySlot[o.obs.Slot], o.obs)
+			if o.obs.Slot > maxSlot {
+				maxSlot = o.obs.Slot
+			}
+		}
+
+		if maxSlot > slotsInCache {
+			if err := evictSlotsBefore(maxSlot - slotsInCache); err != nil {
+				return err
+			}
+		}
+		i = j
+	}
+
+	// flush the remaining slots
+	if err := evictSlotsBefore(math.MaxUint64); err != nil {
+		return err
+	}
+
+	if err := wObs.Flush(); err != nil {
+		return err
+	}
+	if err := fObs.Close(); err != nil {
+		return err
+	}
+	if err := wStats.Flush(); err != nil {
+		return err
+	}
+	return fStats.Close()
+}
+
+func readObservations(f inputFile) ([]*types.BidObservation, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() //nolint:errcheck
+
+	observations := []*types.BidObservation{}
+	scanner := bufio.NewScanner(file)
+	isHeader := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if isHeader {
+			isHeader = false
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		obs, err := types.NewBidObservationFromCSVLine(line, f.separator)
+		if err != nil {
+			return nil, err
+		}
+		observations = append(observations, obs)
+	}
+	return observations, scanner.Err()
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
 	"github.com/flashbots/relayscan/services/bidcollect/types"
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
@@ -20,6 +21,7 @@ import (
 // 2. Save bids to CSV
 //   - One CSV for all bids
 //   - One CSV for top bids only
+//   - Optionally one CSV with every observation of a bid (incl. duplicates)
 
 type BidProcessorOpts struct {
 	Log       *logrus.Entry
@@ -28,11 +30,31 @@ type BidProcessorOpts struct {
 	OutputTSV bool
 	RedisAddr string
 	UseRedis  bool
+
+	// UseRedisDedup shares the bid dedup and top-bid state with other collector instances through Redis
+	UseRedisDedup bool
+
+	// OutputObservations records every observation of a bid (source, relay, receive time), not only the first one.
+	// Always enabled with UseRedisDedup, so the archive knows all instances that saw a bid.
+	OutputObservations bool
+
+	// SourceStatusDB stores the last received bid per source and relay during housekeeping (optional)
+	SourceStatusDB *database.DatabaseService
+
+	// Clock is used for housekeeping (optional, the system clock by default)
+	Clock common.SlotClock
+}
+
+// sourceKey identifies a bid source: the source type and the relay
+type sourceKey struct {
+	sourceType int
+	relay      string
 }
 
 type OutFiles struct {
 	FAll *os.File
 	FTop *os.File
+	FObs *os.File // only set if observations are enabled
 }
 
 type BidProcessor struct {
@@ -42,23 +64,34 @@ type BidProcessor struct {
 	outFiles     map[int64]*OutFiles // map[slot][bidUniqueKey]Bid
 	outFilesLock sync.RWMutex
 
-	bidCache     map[uint64]map[string]*types.CommonBid // map[slot][bidUniqueKey]Bid
-	topBidCache  map[uint64]*types.CommonBid            // map[slot]Bid
-	bidCacheLock sync.RWMutex
+	dedup BidDeduplicator
+	clock common.SlotClock
 
 	csvSeparator  string
 	csvFileEnding string
 
+	writeObservations bool
+
 	redisClient *redis.Client
+
+	sourceStatus     map[sourceKey]*database.BidCollectSourceStatusEntry
+	sourceStatusLock sync.Mutex
 }
 
 func NewBidProcessor(opts *BidProcessorOpts) (*BidProcessor, error) {
 	c := &BidProcessor{
-		log:         opts.Log,
-		opts:        opts,
-		outFiles:    make(map[int64]*OutFiles),
-		bidCache:    make(map[uint64]map[string]*types.CommonBid),
-		topBidCache: make(map[uint64]*types.CommonBid),
+		log:      opts.Log,
+		opts:     opts,
+		outFiles: make(map[int64]*OutFiles),
+		dedup:    NewLocalBidDeduplicator(),
+		clock:    opts.Clock,
+
+		writeObservations: opts.OutputObservations || opts.UseRedisDedup,
+
+		sourceStatus: make(map[sourceKey]*database.BidCollectSourceStatusEntry),
+	}
+	if c.clock == nil {
+		c.clock = common.NewSystemSlotClock()
 	}
 
 	if opts.OutputTSV {
@@ -69,7 +102,7 @@ func NewBidProcessor(opts *BidProcessorOpts) (*BidProcessor, error) {
 		c.csvFileEnding = "csv"
 	}
 
-	if opts.UseRedis && opts.RedisAddr != "" {
+	if (opts.UseRedis || opts.UseRedisDedup) && opts.RedisAddr != "" {
 		c.redisClient = redis.NewClient(&redis.Options{
 			Addr:     opts.RedisAddr,
 			Password: "", // no password set
@@ -80,49 +113,34 @@ func NewBidProcessor(opts *BidProcessorOpts) (*BidProcessor, error) {
 		if _, err := c.redisClient.Ping(context.Background()).Result(); err != nil {
 			return nil, err
 		}
+
+		if opts.UseRedisDedup {
+			c.log.Info("[bid-processor] using redis for bid deduplication across collector instances")
+			c.dedup = NewRedisBidDeduplicator(opts.Log, c.redisClient)
+		}
 	}
 	return c, nil
 }
 
 func (c *BidProcessor) Start() {
 	for {
-		time.Sleep(30 * time.Second)
+		c.clock.Sleep(30 * time.Second)
 		c.housekeeping()
 	}
 }
 
 func (c *BidProcessor) processBids(bids []*types.CommonBid) {
-	c.bidCacheLock.Lock()
-	defer c.bidCacheLock.Unlock()
-
-	var isTopBid, isNewBid bool
 	for _, bid := range bids {
-		isNewBid, isTopBid = false, false
-		if _, ok := c.bidCache[bid.Slot]; !ok {
-			c.bidCache[bid.Slot] = make(map[string]*types.CommonBid)
-		}
+		c.updateSourceStatus(bid)
 
-		// Check if bid is new top bid
-		if topBid, ok := c.topBidCache[bid.Slot]; !ok {
-			c.topBidCache[bid.Slot] = bid // first one for the slot
-			isTopBid = true
-		} else {
-			// if current bid has higher value, use it as new top bid
-			if bid.ValueAsBigInt().Cmp(topBid.ValueAsBigInt()) == 1 {
-				c.topBidCache[bid.Slot] = bid
-				isTopBid = true
-			}
+		if c.writeObservations {
+			c.writeObservationToFile(bid)
 		}
 
-		// process regular bids only once per unique key (slot+blockhash+parenthash+builderpubkey+value)
-		if _, ok := c.bidCache[bid.Slot][bid.UniqueKey()]; !ok {
-			// yet unknown bid, save it
-			c.bidCache[bid.Slot][bid.UniqueKey()] = bid
-			isNewBid = true
-		}
+		isNewBid, isTopBid := c.dedup.CheckBid(bid)
 
 		// Send to Redis
-		if c.redisClient != nil {
+		if c.redisClient != nil && c.opts.UseRedis {
 			err := c.redisClient.Publish(context.Background(), types.RedisChannel, bid.ToCSVLine(",")).Err()
 			if err != nil {
 				c.log.WithError(err).Error("failed to publish bid to redis")
@@ -134,21 +152,60 @@ func (c *BidProcessor) processBids(bids []*types.CommonBid) {
 	}
 }
 
+// updateSourceStatus records the latest bid received from the source of the given bid
+func (c *BidProcessor) updateSourceStatus(bid *types.CommonBid) {
+	key := sourceKey{sourceType: bid.SourceType, relay: bid.Relay}
+	receivedAt := time.UnixMilli(bid.ReceivedAtMs).UTC()
+
+	c.sourceStatusLock.Lock()
+	defer c.sourceStatusLock.Unlock()
+
+	status, ok := c.sourceStatus[key]
+	if !ok {
+		status = &database.BidCollectSourceStatusEntry{
+			UID:        c.opts.UID,
+			SourceType: bid.SourceType,
+			Relay:      bid.Relay,
+		}
+		c.sourceStatus[key] = status
+	}
+	if receivedAt.After(status.LastBidAt) {
+		status.LastBidAt = receivedAt
+	}
+	status.NumBids++
+}
+
+// getSourceStatus returns a copy of the current source status entries, with UpdatedAt set to now
+func (c *BidProcessor) getSourceStatus() []*database.BidCollectSourceStatusEntry {
+	now := c.clock.Now().UTC()
+
+	c.sourceStatusLock.Lock()
+	defer c.sourceStatusLock.Unlock()
+
+	entries := make([]*database.BidCollectSourceStatusEntry, 0, len(c.sourceStatus))
+	for _, status := range c.sourceStatus {
+		entry := *status
+		entry.UpdatedAt = now
+		entries = append(entries, &entry)
+	}
+	return entries
+}
+
 func (c *BidProcessor) writeBidToFile(bid *types.CommonBid, isNewBid, isTopBid bool) {
-	fAll, fTop, err := c.getFiles(bid)
+	outFiles, err := c.getFiles(bid)
 	if err != nil {
 		c.log.WithError(err).Error("get get output file")
 		return
 	}
 	if isNewBid {
-		_, err = fmt.Fprint(fAll, bid.ToCSVLine(c.csvSeparator)+"\n")
+		_, err = fmt.Fprint(outFiles.FAll, bid.ToCSVLine(c.csvSeparator)+"\n")
 		if err != nil {
 			c.log.WithError(err).Error("couldn't write bid to file")
 			return
 		}
 	}
 	if isTopBid {
-		_, err = fmt.Fprint(fTop, bid.ToCSVLine(c.csvSeparator)+"\n")
+		_, err = fmt.Fprint(outFiles.FTop, bid.ToCSVLine(c.csvSeparator)+"\n")
 		if err != nil {
 			c.log.WithError(err).Error("couldn't write bid to file")
 			return
@@ -156,7 +213,19 @@ func (c *BidProcessor) writeBidToFile(bid *types.CommonBid, isNewBid, isTopBid b
 	}
 }
 
-func (c *BidProcessor) getFiles(bid *types.CommonBid) (fAll, fTop *os.File, err error) {
+func (c *BidProcessor) writeObservationToFile(bid *types.CommonBid) {
+	outFiles, err := c.getFiles(bid)
+	if err != nil {
+		c.log.WithError(err).Error("get get output file")
+		return
+	}
+	_, err = fmt.Fprint(outFiles.FObs, types.NewBidObservation(bid).ToCSVLine(c.csvSeparator)+"\n")
+	if err != nil {
+		c.log.WithError(err).Error("couldn't write observation to file")
+	}
+}
+
+func (c *BidProcessor) getFiles(bid *types.CommonBid) (outFiles *OutFiles, err error) {
 	// hourlybucket
 	sec := int64(types.BucketMinutes * 60)
 	bucketTS := bid.ReceivedAtMs / 1000 / sec * sec // timestamp down-round to start of bucket
@@ -168,61 +237,68 @@ func (c *BidProcessor) getFiles(bid *types.CommonBid) (fAll, fTop *os.File, err
 	c.outFilesLock.RUnlock()
 
 	if outFilesOk {
-		return outFiles.FAll, outFiles.FTop, nil
+		return outFiles, nil
+	}
+
+	c.outFilesLock.Lock()
+	defer c.outFilesLock.Unlock()
+
+	// another goroutine may have opened the files in the meantime
+	if outFiles, outFilesOk = c.outFiles[bucketTS]; outFilesOk {
+		return outFiles, nil
 	}
 
 	// Create output directory
 	dir := filepath.Join(c.opts.OutDir, t.Format(time.DateOnly))
 	err = os.MkdirAll(dir, os.ModePerm)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
+	outFiles = &OutFiles{}
+
 	// Open ALL BIDS CSV
-	fnAll := filepath.Join(dir, c.getFilename("all", bucketTS))
-	fAll, err = os.OpenFile(fnAll, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	outFiles.FAll, err = c.openCSVFile(filepath.Join(dir, c.getFilename("all", bucketTS)), types.CommonBidCSVFields)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	fi, err := fAll.Stat()
+
+	// Open TOP BIDS CSV
+	outFiles.FTop, err = c.openCSVFile(filepath.Join(dir, c.getFilename("top", bucketTS)), types.CommonBidCSVFields)
 	if err != nil {
-		c.log.WithError(err).Fatal("failed stat on output file")
+		return nil, err
 	}
-	if fi.Size() == 0 {
-		_, err = fmt.Fprint(fAll, strings.Join(types.CommonBidCSVFields, c.csvSeparator)+"\n")
+
+	// Open OBSERVATIONS CSV
+	if c.writeObservations {
+		outFiles.FObs, err = c.openCSVFile(filepath.Join(dir, c.getFilename("obs", bucketTS)), types.BidObservationCSVFields)
 		if err != nil {
-			c.log.WithError(err).Fatal("failed to write header to output file")
+			return nil, err
 		}
 	}
 
-	// Open TOP BIDS CSV
-	fnTop := filepath.Join(dir, c.getFilename("top", bucketTS))
-	fTop, err = os.OpenFile(fnTop, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	c.outFiles[bucketTS] = outFiles
+	return outFiles, nil
+}
+
+// openCSVFile opens a file for appending, and writes the header if it's a new file
+func (c *BidProcessor) openCSVFile(fn string, fields []string) (*os.File, error) {
+	f, err := os.OpenFile(fn, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	fi, err = fTop.Stat()
+	fi, err := f.Stat()
 	if err != nil {
 		c.log.WithError(err).Fatal("failed stat on output file")
 	}
 	if fi.Size() == 0 {
-		_, err = fmt.Fprint(fTop, strings.Join(types.CommonBidCSVFields, c.csvSeparator)+"\n")
+		_, err = fmt.Fprint(f, strings.Join(fields, c.csvSeparator)+"\n")
 		if err != nil {
 			c.log.WithError(err).Fatal("failed to write header to output file")
 		}
 	}
-
-	outFiles = &OutFiles{
-		FAll: fAll,
-		FTop: fTop,
-	}
-	c.outFilesLock.Lock()
-	c.outFiles[bucketTS] = outFiles
-	c.outFilesLock.Unlock()
-
-	c.log.Infof("[bid-processor] created output file: %s", fnAll)
-	c.log.Infof("[bid-processor] created output file: %s", fnTop)
-	return fAll, fTop, nil
+	c.log.Infof("[bid-processor] created output file: %s", fn)
+	return f, nil
 }
 
 func (c *BidProcessor) getFilename(prefix string, timestamp int64) string {
@@ -234,39 +310,38 @@ func (c *BidProcessor) getFilename(prefix string, timestamp int64) string {
 }
 
 func (c *BidProcessor) housekeeping() {
-	currentSlot := common.TimeToSlot(time.Now().UTC())
+	currentSlot := c.clock.CurrentSlot()
 	maxSlotInCache := currentSlot - 3
 
-	nDeleted := 0
-	nBids := 0
-
-	c.bidCacheLock.Lock()
-	defer c.bidCacheLock.Unlock()
-	for slot := range c.bidCache {
-		if slot < maxSlotInCache {
-			delete(c.bidCache, slot)
-			nDeleted += 1
-		} else {
-			nBids += len(c.bidCache[slot])
-		}
-	}
+	nSlots, nBids := c.dedup.Housekeeping(maxSlotInCache)
 
 	// Close and remove old files
-	now := time.Now().UTC().Unix()
-	filesBefore := len(c.outFiles)
+	now := c.clock.Now().Unix()
 	c.outFilesLock.Lock()
+	filesBefore := len(c.outFiles)
 	for timestamp, outFiles := range c.outFiles {
 		usageSec := types.BucketMinutes * 60 * 2
 		if now-timestamp > int64(usageSec) { // remove all handles from 2x usage seconds ago
-			c.log.Info("closing output files", timestamp)
+			c.log.Infof("[bid-processor] closing output files for bucket %d", timestamp)
 			delete(c.outFiles, timestamp)
 			_ = outFiles.FAll.Close()
 			_ = outFiles.FTop.Close()
+			if outFiles.FObs != nil {
+				_ = outFiles.FObs.Close()
+			}
 		}
 	}
 	nFiles := len(c.outFiles)
-	filesClosed := len(c.outFiles) - filesBefore
+	filesClosed := filesBefore - nFiles
 	c.outFilesLock.Unlock()
 
-	c.log.Infof("[bid-processor] cleanupBids - deleted slots: %d / total slots: %d / total bids: %d / files closed: %d, current: %d / memUsedMB: %d", nDeleted, len(c.bidCache), nBids, filesClosed, nFiles, common.GetMemMB())
+	// Save the source status, for alerting in backfill-runner
+	if c.opts.SourceStatusDB != nil {
+		err := c.opts.SourceStatusDB.SaveBidCollectSourceStatus(c.getSourceStatus())
+		if err != nil {
+			c.log.WithError(err).Error("[bid-processor] failed to save source status")
+		}
+	}
+
+	c.log.Infof("[bid-processor] cleanupBids - total slots: %d / total bids: %d / files closed: %d, current: %d / memUsedMB: %d", nSlots, nBids, filesClosed, nFiles, common.GetMemMB())
 }
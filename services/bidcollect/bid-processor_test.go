@@ -0,0 +1,107 @@
+package bidcollect
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/services/bidcollect/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBidProcessorFilesAndHousekeeping(t *testing.T) {
+	outDir := t.TempDir()
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	clock := common.NewFakeSlotClock(t0)
+
+	processor, err := NewBidProcessor(&BidProcessorOpts{
+		Log:                common.Logger,
+		UID:                "test",
+		OutDir:             outDir,
+		OutputObservations: true,
+		Clock:              clock,
+	})
+	require.NoError(t, err)
+
+	slot := common.TimeToSlot(t0)
+	bid1 := &types.CommonBid{Slot: slot, BlockHash: "0x1", Value: "10", ReceivedAtMs: t0.Add(59 * time.Minute).UnixMilli()}
+	bid2 := &types.CommonBid{Slot: slot, BlockHash: "0x1", Value: "10", ReceivedAtMs: t0.Add(61 * time.Minute).UnixMilli(), Relay: "other"}
+	processor.processBids([]*types.CommonBid{bid1, bid2})
+
+	// bids are written to hourly files by receive time (the duplicate only to the observations)
+	dir := filepath.Join(outDir, "2024-06-01")
+	readLines := func(fn string) []string {
+		data, err := os.ReadFile(filepath.Join(dir, fn))
+		require.NoError(t, err)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+	require.Len(t, readLines("all_2024-06-01_10-00_test.csv"), 2)
+	require.Len(t, readLines("top_2024-06-01_10-00_test.csv"), 2)
+	require.Len(t, readLines("obs_2024-06-01_10-00_test.csv"), 2)
+	require.Len(t, readLines("all_2024-06-01_11-00_test.csv"), 1)
+	require.Len(t, readLines("obs_2024-06-01_11-00_test.csv"), 2)
+	require.Len(t, processor.outFiles, 2)
+
+	// housekeeping removes the dedup state of old slots
+	clock.Set(common.SlotToTime(slot + 3))
+	processor.housekeeping()
+	nSlots, _ := processor.dedup.Housekeeping(0)
+	require.Equal(t, 1, nSlots)
+
+	clock.Set(common.SlotToTime(slot + 4))
+	processor.housekeeping()
+	nSlots, _ = processor.dedup.Housekeeping(0)
+	require.Equal(t, 0, nSlots)
+	require.Len(t, processor.outFiles, 2)
+
+	// and closes the files of buckets older than two hours
+	firstFiles := processor.outFiles[t0.Unix()]
+	clock.Set(t0.Add(2*time.Hour + time.Second))
+	processor.housekeeping()
+	require.Len(t, processor.outFiles, 1)
+	require.NotNil(t, processor.outFiles[t0.Add(time.Hour).Unix()])
+	_, err = firstFiles.FAll.WriteString("x")
+	require.ErrorIs(t, err, os.ErrClosed)
+	_, err = firstFiles.FObs.WriteString("x")
+	require.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestBidProcessorSourceStatus(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	clock := common.NewFakeSlotClock(t0)
+
+	processor, err := NewBidProcessor(&BidProcessorOpts{
+		Log:    common.Logger,
+		UID:    "test",
+		OutDir: t.TempDir(),
+		Clock:  clock,
+	})
+	require.NoError(t, err)
+
+	slot := common.TimeToSlot(t0)
+	processor.processBids([]*types.CommonBid{
+		{SourceType: types.SourceTypeDataAPI, Relay: "relay1", Slot: slot, BlockHash: "0x1", ReceivedAtMs: t0.Add(2 * time.Second).UnixMilli()},
+		{SourceType: types.SourceTypeDataAPI, Relay: "relay1", Slot: slot, BlockHash: "0x2", ReceivedAtMs: t0.Add(1 * time.Second).UnixMilli()},
+		{SourceType: types.SourceTypeGetHeader, Relay: "relay1", Slot: slot, BlockHash: "0x1", ReceivedAtMs: t0.Add(3 * time.Second).UnixMilli()},
+	})
+
+	clock.Set(t0.Add(time.Minute))
+	entries := processor.getSourceStatus()
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		require.Equal(t, "test", entry.UID)
+		require.Equal(t, "relay1", entry.Relay)
+		require.Equal(t, t0.Add(time.Minute), entry.UpdatedAt)
+		switch entry.SourceType {
+		case types.SourceTypeDataAPI:
+			require.Equal(t, uint64(2), entry.NumBids)
+			require.Equal(t, t0.Add(2*time.Second), entry.LastBidAt) // the latest receive time, not the last processed bid
+		case types.SourceTypeGetHeader:
+			require.Equal(t, uint64(1), entry.NumBids)
+			require.Equal(t, t0.Add(3*time.Second), entry.LastBidAt)
+		}
+	}
+}
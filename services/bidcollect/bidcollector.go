@@ -2,7 +2,11 @@
 package bidcollect
 
 import (
+	"time"
+
+	boosttypes "github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
 	"github.com/flashbots/relayscan/services/bidcollect/types"
 	"github.com/sirupsen/logrus"
 )
@@ -15,14 +19,26 @@ type BidCollectorOpts struct {
 	CollectGetHeader        bool
 	CollectDataAPI          bool
 
-	Relays        []common.RelayEntry
-	BeaconNodeURI string // for getHeader
+	Relays         []common.RelayEntry
+	BeaconNodeURIs []string                  // for getHeader (several for failover)
+	DB             *database.DatabaseService // for getHeader, to store the full signed bids (optional)
+
+	GetHeaderPollOffsets []time.Duration   // for getHeader (optional)
+	BuilderDomain        boosttypes.Domain // for getHeader, to verify the bid signatures (optional, mainnet by default)
+
+	DataAPIPollSchedules *DataAPIPollSchedules // for data API (optional)
+
+	SourceStatusDB *database.DatabaseService // to store the last received bid per source, for alerting (optional)
 
-	OutDir    string
-	OutputTSV bool
+	OutDir             string
+	OutputTSV          bool
+	OutputObservations bool
 
-	RedisAddr string
-	UseRedis  bool
+	RedisAddr     string
+	UseRedis      bool
+	UseRedisDedup bool
+
+	Clock common.SlotClock // optional, the system clock by default (sanity-checked with the beacon nodes for getHeader)
 }
 
 type BidCollector struct {
@@ -34,6 +50,7 @@ type BidCollector struct {
 	getHeaderBidC  chan GetHeaderPollerBidsMsg
 
 	processor *BidProcessor
+	bn        *common.MultiBeaconClient // for getHeader
 }
 
 func NewBidCollector(opts *BidCollectorOpts) (c *BidCollector, err error) {
@@ -51,14 +68,29 @@ func NewBidCollector(opts *BidCollectorOpts) (c *BidCollector, err error) {
 	c.ultrasoundBidC = make(chan UltrasoundStreamBidsMsg, types.BidCollectorInputChannelSize)
 	c.getHeaderBidC = make(chan GetHeaderPollerBidsMsg, types.BidCollectorInputChannelSize)
 
+	if c.opts.CollectGetHeader {
+		c.bn, err = common.NewMultiBeaconClientFromURIs(opts.Log, opts.BeaconNodeURIs)
+		if err != nil {
+			return nil, err
+		}
+		if c.opts.Clock == nil {
+			c.opts.Clock = common.NewBeaconCheckedSlotClock(opts.Log, c.bn)
+		}
+	}
+
 	// output
 	c.processor, err = NewBidProcessor(&BidProcessorOpts{
-		Log:       opts.Log,
-		UID:       opts.UID,
-		OutDir:    opts.OutDir,
-		OutputTSV: opts.OutputTSV,
-		RedisAddr: opts.RedisAddr,
-		UseRedis:  opts.UseRedis,
+		Log:           opts.Log,
+		UID:           opts.UID,
+		OutDir:        opts.OutDir,
+		OutputTSV:     opts.OutputTSV,
+		RedisAddr:     opts.RedisAddr,
+		UseRedis:      opts.UseRedis,
+		UseRedisDedup: opts.UseRedisDedup,
+
+		OutputObservations: opts.OutputObservations,
+		SourceStatusDB:     opts.SourceStatusDB,
+		Clock:              opts.Clock,
 	})
 	return c, err
 }
@@ -68,19 +100,26 @@ func (c *BidCollector) MustStart() {
 
 	if c.opts.CollectGetHeader {
 		poller := NewGetHeaderPoller(&GetHeaderPollerOpts{
-			Log:       c.log,
-			BidC:      c.getHeaderBidC,
-			BeaconURI: c.opts.BeaconNodeURI,
-			Relays:    c.opts.Relays,
+			Log:     c.log,
+			BidC:    c.getHeaderBidC,
+			BN:      c.bn,
+			Relays:  c.opts.Relays,
+			DB:      c.opts.DB,
+			Offsets: c.opts.GetHeaderPollOffsets,
+			Clock:   c.opts.Clock,
+
+			BuilderDomain: c.opts.BuilderDomain,
 		})
 		go poller.Start()
 	}
 
 	if c.opts.CollectDataAPI {
 		poller := NewDataAPIPoller(&DataAPIPollerOpts{
-			Log:    c.log,
-			BidC:   c.dataAPIBidC,
-			Relays: c.opts.Relays,
+			Log:       c.log,
+			BidC:      c.dataAPIBidC,
+			Relays:    c.opts.Relays,
+			Schedules: c.opts.DataAPIPollSchedules,
+			Clock:     c.opts.Clock,
 		})
 		go poller.Start()
 	}
@@ -102,7 +141,11 @@ func (c *BidCollector) MustStart() {
 			commonBids := DataAPIToCommonBids(bids)
 			c.processor.processBids(commonBids)
 		case bid := <-c.getHeaderBidC:
-			commonBid := GetHeaderToCommonBid(bid)
+			commonBid, err := GetHeaderToCommonBid(bid)
+			if err != nil {
+				c.log.WithError(err).Error("failed to convert getHeader bid")
+				continue
+			}
 			c.processor.processBids([]*types.CommonBid{commonBid})
 		}
 	}
@@ -23,39 +23,70 @@ type DataAPIPollerOpts struct {
 	Log    *logrus.Entry
 	BidC   chan DataAPIPollerBidsMsg
 	Relays []common.RelayEntry
+
+	// Schedules are the poll offsets per relay (optional, DefaultDataAPIPollOffsets for all relays if not set)
+	Schedules *DataAPIPollSchedules
+
+	// Clock is used for scheduling the polls (optional, the system clock by default)
+	Clock common.SlotClock
 }
 
 type DataAPIPoller struct {
-	Log    *logrus.Entry
-	BidC   chan DataAPIPollerBidsMsg
-	Relays []common.RelayEntry
+	Log       *logrus.Entry
+	BidC      chan DataAPIPollerBidsMsg
+	Relays    []common.RelayEntry
+	Schedules *DataAPIPollSchedules
+
+	clock       common.SlotClock
+	relayStates map[string]*dataAPIRelayState // map[hostname]state
 }
 
 func NewDataAPIPoller(opts *DataAPIPollerOpts) *DataAPIPoller {
+	schedules := opts.Schedules
+	if schedules == nil {
+		schedules = &DataAPIPollSchedules{Default: DefaultDataAPIPollOffsets}
+	}
+
+	clock := opts.Clock
+	if clock == nil {
+		clock = common.NewSystemSlotClock()
+	}
+
+	relayStates := make(map[string]*dataAPIRelayState)
+	for _, relay := range opts.Relays {
+		relayStates[relay.Hostname()] = newDataAPIRelayState()
+	}
+
 	return &DataAPIPoller{
-		Log:    opts.Log,
-		BidC:   opts.BidC,
-		Relays: opts.Relays,
+		Log:         opts.Log,
+		BidC:        opts.BidC,
+		Relays:      opts.Relays,
+		Schedules:   schedules,
+		clock:       clock,
+		relayStates: relayStates,
 	}
 }
 
 func (poller *DataAPIPoller) Start() {
 	poller.Log.WithField("relays", common.RelayEntriesToHostnameStrings(poller.Relays)).Info("Starting DataAPIPoller ...")
+	for _, relay := range poller.Relays {
+		poller.Log.Infof("[data-api poller] schedule for %s: %s (full list at t+%s)", relay.Hostname(), offsetsToString(poller.Schedules.ForRelay(relay)), DataAPICoveragePollOffset)
+	}
 
 	// initially, wait until start of next slot
-	t := time.Now().UTC()
+	t := poller.clock.Now()
 	slot := common.TimeToSlot(t)
 	nextSlot := slot + 1
 	tNextSlot := common.SlotToTime(nextSlot)
 	untilNextSlot := tNextSlot.Sub(t)
 
 	poller.Log.Infof("[data-api poller] waiting until start of next slot (%d - %s from now)", nextSlot, untilNextSlot.String())
-	time.Sleep(untilNextSlot)
+	poller.clock.Sleep(untilNextSlot)
 
 	// then run polling loop
 	for {
 		// calculate next slot details
-		t := time.Now().UTC()
+		t := poller.clock.Now()
 		slot := common.TimeToSlot(t)
 		nextSlot := slot + 1
 		tNextSlot := common.SlotToTime(nextSlot)
@@ -63,24 +94,38 @@ func (poller *DataAPIPoller) Start() {
 
 		poller.Log.Infof("[data-api poller] scheduling polling for upcoming slot: %d (%s - in %s)", nextSlot, tNextSlot.String(), untilNextSlot.String())
 
-		// Schedule polling at t-4, t-2, t=0, t=2, t=20
-		go poller.pollRelaysForBids(nextSlot, -4*time.Second)
-		go poller.pollRelaysForBids(nextSlot, -2*time.Second)
-		go poller.pollRelaysForBids(nextSlot, -500*time.Millisecond)
-		go poller.pollRelaysForBids(nextSlot, 500*time.Millisecond)
-		go poller.pollRelaysForBids(nextSlot, 2*time.Second)
-		go poller.pollRelaysForBids(nextSlot, 20*time.Second)
+		poller.schedulePollsForSlot(nextSlot)
+
+		// log the coverage stats once per epoch
+		if nextSlot%32 == 0 {
+			poller.logCoverageStats()
+		}
 
 		// wait until next slot
-		time.Sleep(untilNextSlot)
+		poller.clock.Sleep(untilNextSlot)
 	}
 }
 
+// schedulePollsForSlot starts the polling for a slot at the configured offsets (grouped by offset), and the full poll
+// at t+20 (for the bids and coverage stats)
+func (poller *DataAPIPoller) schedulePollsForSlot(slot uint64) {
+	relaysByOffset := make(map[time.Duration][]common.RelayEntry)
+	for _, relay := range poller.Relays {
+		for _, offset := range poller.Schedules.ForRelay(relay) {
+			relaysByOffset[offset] = append(relaysByOffset[offset], relay)
+		}
+	}
+	for offset, relays := range relaysByOffset {
+		go poller.pollRelaysForBids(slot, offset, relays, false)
+	}
+	go poller.pollRelaysForBids(slot, DataAPICoveragePollOffset, poller.Relays, true)
+}
+
 // pollRelaysForBids will poll data api for given slot with t seconds offset
-func (poller *DataAPIPoller) pollRelaysForBids(slot uint64, tOffset time.Duration) {
+func (poller *DataAPIPoller) pollRelaysForBids(slot uint64, tOffset time.Duration, relays []common.RelayEntry, isFullPoll bool) {
 	tSlotStart := common.SlotToTime(slot)
 	tStart := tSlotStart.Add(tOffset)
-	waitTime := tStart.Sub(time.Now().UTC())
+	waitTime := tStart.Sub(poller.clock.Now())
 
 	// poller.Log.Debugf("[data-api poller] - prepare polling for slot %d t %d (tSlotStart: %s, tStart: %s, waitTime: %s)", slot, t, tSlotStart.String(), tStart.String(), waitTime.String())
 	if waitTime < 0 {
@@ -89,18 +134,18 @@ func (poller *DataAPIPoller) pollRelaysForBids(slot uint64, tOffset time.Duratio
 	}
 
 	// Wait until expected time
-	time.Sleep(waitTime)
+	poller.clock.Sleep(waitTime)
 
 	// Poll for bids now
-	untilSlot := tSlotStart.Sub(time.Now().UTC())
+	untilSlot := tSlotStart.Sub(poller.clock.Now())
 	poller.Log.Debugf("[data-api poller] polling for slot %d at t=%s (tNow=%s)", slot, tOffset.String(), (untilSlot * -1).String())
 
-	for _, relay := range poller.Relays {
-		go poller._pollRelayForBids(slot, relay, tOffset)
+	for _, relay := range relays {
+		go poller._pollRelayForBids(slot, relay, tOffset, isFullPoll)
 	}
 }
 
-func (poller *DataAPIPoller) _pollRelayForBids(slot uint64, relay common.RelayEntry, t time.Duration) {
+func (poller *DataAPIPoller) _pollRelayForBids(slot uint64, relay common.RelayEntry, t time.Duration, isFullPoll bool) {
 	// log := poller.Log.WithField("relay", relay.Hostname()).WithField("slot", slot)
 	log := poller.Log.WithFields(logrus.Fields{
 		"relay": relay.Hostname(),
@@ -109,6 +154,13 @@ func (poller *DataAPIPoller) _pollRelayForBids(slot uint64, relay common.RelayEn
 	})
 	// log.Debugf("[data-api poller] polling relay %s for slot %d", relay.Hostname(), slot)
 
+	// scheduled polls are skipped while backing off after rate-limiting (but not the full poll, which is needed for coverage)
+	state := poller.relayStates[relay.Hostname()]
+	if !state.shouldPoll(poller.clock.Now(), isFullPoll) {
+		log.Debug("[data-api poller] skipping poll, relay is rate-limiting")
+		return
+	}
+
 	// build query URL
 	path := "/relay/v1/data/bidtraces/builder_blocks_received"
 	url := common.GetURIWithQuery(relay.URL, path, map[string]string{"slot": fmt.Sprintf("%d", slot)})
@@ -116,18 +168,65 @@ func (poller *DataAPIPoller) _pollRelayForBids(slot uint64, relay common.RelayEn
 
 	// start query
 	var data []relaycommon.BidTraceV2WithTimestampJSON
-	timeRequestStart := time.Now().UTC()
+	timeRequestStart := poller.clock.Now()
 	code, err := common.SendHTTPRequest(context.Background(), *http.DefaultClient, http.MethodGet, url, nil, &data)
-	timeRequestEnd := time.Now().UTC()
-	if err != nil {
+	timeRequestEnd := poller.clock.Now()
+	if code == http.StatusTooManyRequests {
+		backoffUntil := state.onRateLimited(timeRequestEnd)
+		log.WithField("backoffUntil", backoffUntil.String()).Warn("[data-api poller] rate-limited by relay, backing off")
+		return
+	} else if err != nil {
 		log.WithError(err).Error("[data-api poller] failed to get data")
 		return
 	}
+	state.onSuccess()
 	log = log.WithFields(logrus.Fields{"code": code, "entries": len(data), "durationMs": timeRequestEnd.Sub(timeRequestStart).Milliseconds()})
 	log.Debug("[data-api poller] request complete")
 
+	// update coverage
+	if isFullPoll {
+		slotStats := state.addFullBids(slot, data)
+		log.WithFields(logrus.Fields{"bidsFull": slotStats.BidsFull, "bidsCaptured": slotStats.BidsCaptured}).Debugf("[data-api poller] coverage for slot: %.1f%%", slotStats.Coverage()*100)
+	} else {
+		state.addScheduledBids(slot, data)
+	}
+
 	// send data to channel
-	poller.BidC <- DataAPIPollerBidsMsg{Bids: data, Relay: relay, ReceivedAt: time.Now().UTC()}
+	poller.BidC <- DataAPIPollerBidsMsg{Bids: data, Relay: relay, ReceivedAt: poller.clock.Now()}
+}
+
+// CoverageStats returns the coverage stats per relay hostname
+func (poller *DataAPIPoller) CoverageStats() map[string]DataAPICoverageStats {
+	stats := make(map[string]DataAPICoverageStats)
+	for hostname, state := range poller.relayStates {
+		stats[hostname] = state.getStats()
+	}
+	return stats
+}
+
+func (poller *DataAPIPoller) logCoverageStats() {
+	for hostname, stats := range poller.CoverageStats() {
+		poller.Log.WithFields(logrus.Fields{
+			"relay":            hostname,
+			"slots":            stats.Slots,
+			"bidsFull":         stats.BidsFull,
+			"bidsCaptured":     stats.BidsCaptured,
+			"polls":            stats.Polls,
+			"pollsRateLimited": stats.PollsRateLimited,
+			"pollsSkipped":     stats.PollsSkipped,
+		}).Infof("[data-api poller] coverage: %.1f%%", stats.Coverage()*100)
+	}
+}
+
+func offsetsToString(offsets []time.Duration) string {
+	s := make([]string, len(offsets))
+	for i, offset := range offsets {
+		s[i] = "t" + offset.String()
+		if offset >= 0 {
+			s[i] = "t+" + offset.String()
+		}
+	}
+	return strings.Join(s, ", ")
 }
 
 func DataAPIToCommonBids(bids DataAPIPollerBidsMsg) []*types.CommonBid {
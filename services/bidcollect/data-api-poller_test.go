@@ -0,0 +1,78 @@
+package bidcollect
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	relaycommon "github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/testutil/mockrelay"
+	"github.com/stretchr/testify/require"
+)
+
+// testDataAPIBid is a builder submission for the mock relay
+func testDataAPIBid(slot uint64, i int) relaycommon.BidTraceV2WithTimestampJSON {
+	bid := relaycommon.BidTraceV2WithTimestampJSON{}
+	bid.Slot = slot
+	bid.BlockHash = fmt.Sprintf("0x%064x", i)
+	bid.Value = "1"
+	return bid
+}
+
+func newTestDataAPIPoller(t *testing.T, relay *mockrelay.MockRelay, clock common.SlotClock, schedule string) *DataAPIPoller {
+	t.Helper()
+	schedules, err := ParseDataAPIPollSchedules([]string{"default=" + schedule})
+	require.NoError(t, err)
+	return NewDataAPIPoller(&DataAPIPollerOpts{
+		Log:       common.Logger,
+		BidC:      make(chan DataAPIPollerBidsMsg, 10),
+		Relays:    []common.RelayEntry{relay.RelayEntry()},
+		Schedules: schedules,
+		Clock:     clock,
+	})
+}
+
+func TestDataAPIPollerSchedule(t *testing.T) {
+	relay, err := mockrelay.New()
+	require.NoError(t, err)
+	t.Cleanup(relay.Close)
+
+	slot := uint64(100)
+	relay.AddBlocksReceived(testDataAPIBid(slot, 0))
+	clock := common.NewFakeSlotClock(common.SlotToTime(slot).Add(-5 * time.Second))
+	poller := newTestDataAPIPoller(t, relay, clock, "-2s,1s")
+
+	// polls at t-2, t+1 and the full poll at t+20
+	poller.schedulePollsForSlot(slot)
+	clock.WaitForWaiters(3)
+	require.Equal(t, 0, relay.NumRequests(mockrelay.PathBlocksReceived))
+
+	clock.Advance(3 * time.Second)
+	msg := <-poller.BidC
+	require.Equal(t, common.SlotToTime(slot).Add(-2*time.Second), msg.ReceivedAt)
+	require.Len(t, msg.Bids, 1)
+
+	relay.AddBlocksReceived(testDataAPIBid(slot, 1), testDataAPIBid(slot, 2))
+	clock.Advance(3 * time.Second)
+	msg = <-poller.BidC
+	require.Equal(t, common.SlotToTime(slot).Add(1*time.Second), msg.ReceivedAt)
+	require.Len(t, msg.Bids, 3)
+
+	// one more bid after the scheduled polls
+	relay.AddBlocksReceived(testDataAPIBid(slot, 3))
+	clock.Advance(19 * time.Second)
+	msg = <-poller.BidC
+	require.Len(t, msg.Bids, 4)
+	require.Equal(t, 3, relay.NumRequests(mockrelay.PathBlocksReceived))
+
+	stats := poller.CoverageStats()[poller.Relays[0].Hostname()]
+	require.Equal(t, uint64(1), stats.Slots)
+	require.Equal(t, uint64(4), stats.BidsFull)
+	require.Equal(t, uint64(3), stats.BidsCaptured)
+	require.Equal(t, uint64(3), stats.Polls)
+
+	// polls in the past are skipped
+	poller.pollRelaysForBids(slot, 0, poller.Relays, false)
+	require.Equal(t, 3, relay.NumRequests(mockrelay.PathBlocksReceived))
+}
@@ -0,0 +1,201 @@
+package bidcollect
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	relaycommon "github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/relayscan/common"
+)
+
+var (
+	// DefaultDataAPIPollOffsets are the offsets (relative to slot start) at which relays are polled, unless configured otherwise
+	DefaultDataAPIPollOffsets = []time.Duration{-4 * time.Second, -2 * time.Second, -500 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second}
+
+	// DataAPICoveragePollOffset is when the full list of bids for a slot is fetched, to measure coverage of the scheduled polls
+	DataAPICoveragePollOffset = 20 * time.Second
+
+	// dataAPIMinPollOffset is the earliest allowed poll offset (exclusive). Polls of a slot are scheduled at the start
+	// of the previous slot, earlier offsets would already be in the past.
+	dataAPIMinPollOffset = -12 * time.Second
+
+	// dataAPIMaxBackoffSlots caps the backoff after repeated rate-limiting
+	dataAPIMaxBackoffSlots uint64 = 32
+
+	ErrInvalidPollSchedule = errors.New("invalid data API poll schedule")
+)
+
+// DataAPIPollSchedules holds the poll offsets per relay hostname, and a default for all other relays
+type DataAPIPollSchedules struct {
+	Default []time.Duration
+	Relays  map[string][]time.Duration
+}
+
+// ParseDataAPIPollSchedules parses schedules like "relay.ultrasound.money=-6s,-4s,-2s,-1s,0s,1s,2s".
+// Use "default" instead of a hostname to change the schedule for all other relays.
+func ParseDataAPIPollSchedules(specs []string) (*DataAPIPollSchedules, error) {
+	schedules := &DataAPIPollSchedules{
+		Default: DefaultDataAPIPollOffsets,
+		Relays:  make(map[string][]time.Duration),
+	}
+
+	for _, spec := range specs {
+		hostname, offsetsStr, found := strings.Cut(spec, "=")
+		hostname = strings.TrimSpace(hostname)
+		if !found || hostname == "" {
+			return nil, fmt.Errorf("%w: %s (expected <relay-hostname|default>=<offset>,...)", ErrInvalidPollSchedule, spec)
+		}
+
+		offsets := []time.Duration{}
+		seen := make(map[time.Duration]bool)
+		for _, offsetStr := range strings.Split(offsetsStr, ",") {
+			offsetStr = strings.TrimSpace(offsetStr)
+			if offsetStr == "" {
+				continue
+			}
+			offset, err := time.ParseDuration(offsetStr)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalidPollSchedule, spec, err)
+			}
+			if offset <= dataAPIMinPollOffset || offset >= DataAPICoveragePollOffset {
+				return nil, fmt.Errorf("%w: %s: offsets must be after t%s and before t+%s", ErrInvalidPollSchedule, spec, dataAPIMinPollOffset, DataAPICoveragePollOffset)
+			}
+			if !seen[offset] {
+				seen[offset] = true
+				offsets = append(offsets, offset)
+			}
+		}
+		sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+		if hostname == "default" {
+			schedules.Default = offsets
+		} else {
+			schedules.Relays[hostname] = offsets
+		}
+	}
+	return schedules, nil
+}
+
+// ForRelay returns the poll offsets of the given relay
+func (s *DataAPIPollSchedules) ForRelay(relay common.RelayEntry) []time.Duration {
+	if offsets, ok := s.Relays[relay.Hostname()]; ok {
+		return offsets
+	}
+	return s.Default
+}
+
+// DataAPICoverageStats compare the bids of the scheduled polls with the full list of bids fetched at t+20s
+type DataAPICoverageStats struct {
+	Slots        uint64 `json:"slots"`         // slots with a full list of bids
+	BidsFull     uint64 `json:"bids_full"`     // bids in the full lists
+	BidsCaptured uint64 `json:"bids_captured"` // of those, bids already received by the scheduled polls
+
+	Polls            uint64 `json:"polls"`
+	PollsRateLimited uint64 `json:"polls_rate_limited"`
+	PollsSkipped     uint64 `json:"polls_skipped"` // skipped due to backoff
+}
+
+// Coverage is the share of bids captured by the scheduled polls (0..1)
+func (s DataAPICoverageStats) Coverage() float64 {
+	if s.BidsFull == 0 {
+		return 0
+	}
+	return float64(s.BidsCaptured) / float64(s.BidsFull)
+}
+
+// dataAPIRelayState tracks the backoff and coverage of a single relay
+type dataAPIRelayState struct {
+	lock sync.Mutex
+
+	backoffSlots uint64    // current backoff length, doubled on each 429 and halved on success
+	backoffUntil time.Time // scheduled polls are skipped until then
+
+	seenBids map[uint64]map[string]bool // map[slot][key] bids received by the scheduled polls
+	stats    DataAPICoverageStats
+}
+
+func newDataAPIRelayState() *dataAPIRelayState {
+	return &dataAPIRelayState{
+		seenBids: make(map[uint64]map[string]bool),
+	}
+}
+
+// shouldPoll returns false while in backoff (unless forced)
+func (s *dataAPIRelayState) shouldPoll(now time.Time, force bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !force && now.Before(s.backoffUntil) {
+		s.stats.PollsSkipped++
+		return false
+	}
+	s.stats.Polls++
+	return true
+}
+
+// onRateLimited doubles the backoff, and returns until when polls are skipped
+func (s *dataAPIRelayState) onRateLimited(now time.Time) time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stats.PollsRateLimited++
+	s.backoffSlots = min(max(1, s.backoffSlots*2), dataAPIMaxBackoffSlots)
+	s.backoffUntil = now.Add(time.Duration(s.backoffSlots) * 12 * time.Second) //nolint:gosec
+	return s.backoffUntil
+}
+
+// onSuccess slowly reduces the backoff again
+func (s *dataAPIRelayState) onSuccess() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.backoffSlots /= 2
+}
+
+// addScheduledBids records the bids received by a scheduled poll
+func (s *dataAPIRelayState) addScheduledBids(slot uint64, bids []relaycommon.BidTraceV2WithTimestampJSON) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.seenBids[slot]; !ok {
+		s.seenBids[slot] = make(map[string]bool)
+	}
+	for _, bid := range bids {
+		s.seenBids[slot][dataAPIBidKey(bid)] = true
+	}
+}
+
+// addFullBids compares the full list of bids of a slot with the bids of the scheduled polls, and returns the result
+// for this slot. The slot (and all earlier slots) are removed from the state.
+func (s *dataAPIRelayState) addFullBids(slot uint64, bids []relaycommon.BidTraceV2WithTimestampJSON) (slotStats DataAPICoverageStats) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	slotStats.Slots = 1
+	for _, bid := range bids {
+		slotStats.BidsFull++
+		if s.seenBids[slot][dataAPIBidKey(bid)] {
+			slotStats.BidsCaptured++
+		}
+	}
+	s.stats.Slots += slotStats.Slots
+	s.stats.BidsFull += slotStats.BidsFull
+	s.stats.BidsCaptured += slotStats.BidsCaptured
+
+	for seenSlot := range s.seenBids {
+		if seenSlot <= slot {
+			delete(s.seenBids, seenSlot)
+		}
+	}
+	return slotStats
+}
+
+func (s *dataAPIRelayState) getStats() DataAPICoverageStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.stats
+}
+
+func dataAPIBidKey(bid relaycommon.BidTraceV2WithTimestampJSON) string {
+	return strings.ToLower(fmt.Sprintf("%s-%s-%s-%s", bid.BlockHash, bid.ParentHash, bid.BuilderPubkey, bid.Value))
+}
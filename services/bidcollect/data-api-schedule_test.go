@@ -0,0 +1,90 @@
+package bidcollect
+
+import (
+	"testing"
+	"time"
+
+	relaycommon "github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/relayscan/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDataAPIPollSchedules(t *testing.T) {
+	schedules, err := ParseDataAPIPollSchedules(nil)
+	require.NoError(t, err)
+	require.Equal(t, DefaultDataAPIPollOffsets, schedules.Default)
+
+	schedules, err = ParseDataAPIPollSchedules([]string{
+		"relay.ultrasound.money=2s, -1s,-4s,-1s",
+		"default=-2s,500ms",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []time.Duration{-2 * time.Second, 500 * time.Millisecond}, schedules.Default)
+
+	ultrasound := common.MustNewRelayEntry("https://0xa1559ace749633b997cb3fdacffb890aeebdb0f5a3b6aaa7eeeaf1a38af0a8fe88b9e4b1f61f236d2e64d95733327a62@relay.ultrasound.money", false)
+	require.Equal(t, []time.Duration{-4 * time.Second, -1 * time.Second, 2 * time.Second}, schedules.ForRelay(ultrasound))
+
+	for _, spec := range []string{"-2s,0s", "=-2s", "relay.ultrasound.money=foo", "default=20s", "default=-12s", "default=-13s,1s"} {
+		_, err = ParseDataAPIPollSchedules([]string{spec})
+		require.ErrorIs(t, err, ErrInvalidPollSchedule, spec)
+	}
+}
+
+func TestDataAPIRelayStateBackoff(t *testing.T) {
+	state := newDataAPIRelayState()
+	now := time.Unix(1717156924, 0)
+	require.True(t, state.shouldPoll(now, false))
+
+	// backoff doubles on each 429
+	require.Equal(t, now.Add(12*time.Second), state.onRateLimited(now))
+	require.False(t, state.shouldPoll(now.Add(11*time.Second), false))
+	require.True(t, state.shouldPoll(now.Add(11*time.Second), true)) // forced, i.e. the full poll
+	require.Equal(t, now.Add(24*time.Second), state.onRateLimited(now))
+	require.Equal(t, now.Add(48*time.Second), state.onRateLimited(now))
+
+	// capped
+	for range 10 {
+		state.onRateLimited(now)
+	}
+	require.Equal(t, dataAPIMaxBackoffSlots, state.backoffSlots)
+
+	// halved on success
+	state.onSuccess()
+	require.Equal(t, dataAPIMaxBackoffSlots/2, state.backoffSlots)
+
+	stats := state.getStats()
+	require.Equal(t, uint64(2), stats.Polls)
+	require.Equal(t, uint64(1), stats.PollsSkipped)
+	require.Equal(t, uint64(13), stats.PollsRateLimited)
+}
+
+func TestDataAPIRelayStateCoverage(t *testing.T) {
+	bid := func(blockHash string) relaycommon.BidTraceV2WithTimestampJSON {
+		b := relaycommon.BidTraceV2WithTimestampJSON{}
+		b.BlockHash = blockHash
+		b.Value = "1"
+		return b
+	}
+
+	state := newDataAPIRelayState()
+	state.addScheduledBids(1, []relaycommon.BidTraceV2WithTimestampJSON{bid("0x1"), bid("0x2")})
+	state.addScheduledBids(1, []relaycommon.BidTraceV2WithTimestampJSON{bid("0x2"), bid("0x3")})
+	state.addScheduledBids(2, []relaycommon.BidTraceV2WithTimestampJSON{bid("0x5")})
+
+	slotStats := state.addFullBids(1, []relaycommon.BidTraceV2WithTimestampJSON{bid("0x1"), bid("0x2"), bid("0x3"), bid("0x4")})
+	require.Equal(t, uint64(4), slotStats.BidsFull)
+	require.Equal(t, uint64(3), slotStats.BidsCaptured)
+	require.InDelta(t, 0.75, slotStats.Coverage(), 0.0001)
+
+	// slot 1 is removed, slot 2 is kept
+	require.Len(t, state.seenBids, 1)
+
+	slotStats = state.addFullBids(2, []relaycommon.BidTraceV2WithTimestampJSON{bid("0x5"), bid("0x6")})
+	require.Equal(t, uint64(1), slotStats.BidsCaptured)
+
+	stats := state.getStats()
+	require.Equal(t, uint64(2), stats.Slots)
+	require.Equal(t, uint64(6), stats.BidsFull)
+	require.Equal(t, uint64(4), stats.BidsCaptured)
+	require.Empty(t, state.seenBids)
+}
@@ -0,0 +1,144 @@
+package bidcollect
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/flashbots/relayscan/services/bidcollect/types"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// BidDeduplicator decides whether a bid is seen for the first time, and whether it is a new top bid for its slot.
+type BidDeduplicator interface {
+	CheckBid(bid *types.CommonBid) (isNewBid, isTopBid bool)
+
+	// Housekeeping removes all state for slots before minSlot, and returns the number of slots and bids still cached
+	Housekeeping(minSlot uint64) (nSlots, nBids int)
+}
+
+// LocalBidDeduplicator keeps the dedup state in memory (per collector instance)
+type LocalBidDeduplicator struct {
+	bidCache     map[uint64]map[string]*types.CommonBid // map[slot][bidUniqueKey]Bid
+	topBidCache  map[uint64]*types.CommonBid            // map[slot]Bid
+	bidCacheLock sync.Mutex
+}
+
+func NewLocalBidDeduplicator() *LocalBidDeduplicator {
+	return &LocalBidDeduplicator{
+		bidCache:    make(map[uint64]map[string]*types.CommonBid),
+		topBidCache: make(map[uint64]*types.CommonBid),
+	}
+}
+
+func (d *LocalBidDeduplicator) CheckBid(bid *types.CommonBid) (isNewBid, isTopBid bool) {
+	d.bidCacheLock.Lock()
+	defer d.bidCacheLock.Unlock()
+
+	if _, ok := d.bidCache[bid.Slot]; !ok {
+		d.bidCache[bid.Slot] = make(map[string]*types.CommonBid)
+	}
+
+	// Check if bid is new top bid
+	if topBid, ok := d.topBidCache[bid.Slot]; !ok {
+		d.topBidCache[bid.Slot] = bid // first one for the slot
+		isTopBid = true
+	} else {
+		// if current bid has higher value, use it as new top bid
+		if bid.ValueAsBigInt().Cmp(topBid.ValueAsBigInt()) == 1 {
+			d.topBidCache[bid.Slot] = bid
+			isTopBid = true
+		}
+	}
+
+	// process regular bids only once per unique key (slot+blockhash+parenthash+builderpubkey+value)
+	if _, ok := d.bidCache[bid.Slot][bid.UniqueKey()]; !ok {
+		// yet unknown bid, save it
+		d.bidCache[bid.Slot][bid.UniqueKey()] = bid
+		isNewBid = true
+	}
+	return isNewBid, isTopBid
+}
+
+func (d *LocalBidDeduplicator) Housekeeping(minSlot uint64) (nSlots, nBids int) {
+	d.bidCacheLock.Lock()
+	defer d.bidCacheLock.Unlock()
+
+	for slot := range d.bidCache {
+		if slot < minSlot {
+			delete(d.bidCache, slot)
+			delete(d.topBidCache, slot)
+		} else {
+			nBids += len(d.bidCache[slot])
+		}
+	}
+	return len(d.bidCache), nBids
+}
+
+// redisCheckBidScript atomically marks a bid as seen and updates the top bid of the slot.
+//
+// KEYS[1]: bid key, KEYS[2]: top-bid key of the slot
+// ARGV[1]: bid value (wei, decimal string), ARGV[2]: TTL in seconds
+//
+// Values are compared as decimal strings (by length first), because Lua numbers can't hold wei amounts precisely.
+var redisCheckBidScript = redis.NewScript(`
+local isNew = 0
+if redis.call('SET', KEYS[1], 1, 'NX', 'EX', ARGV[2]) then
+	isNew = 1
+end
+
+local isTop = 1
+local top = redis.call('GET', KEYS[2])
+if top then
+	if string.len(ARGV[1]) < string.len(top) or (string.len(ARGV[1]) == string.len(top) and ARGV[1] <= top) then
+		isTop = 0
+	end
+end
+if isTop == 1 then
+	redis.call('SET', KEYS[2], ARGV[1], 'EX', ARGV[2])
+end
+
+return {isNew, isTop}
+`)
+
+// RedisBidDeduplicator shares the dedup state across collector instances through Redis, so all
+// instances agree on the first-seen bids and top bids. If Redis is unavailable, it falls back to
+// local deduplication.
+//
+// Only the first instance writes a bid, the other instances that saw it are known from their
+// observations (the bid processor always writes them with Redis dedup).
+type RedisBidDeduplicator struct {
+	log      *logrus.Entry
+	client   *redis.Client
+	ttl      time.Duration
+	fallback *LocalBidDeduplicator
+}
+
+func NewRedisBidDeduplicator(log *logrus.Entry, client *redis.Client) *RedisBidDeduplicator {
+	return &RedisBidDeduplicator{
+		log:      log,
+		client:   client,
+		ttl:      types.RedisDedupTTL,
+		fallback: NewLocalBidDeduplicator(),
+	}
+}
+
+func (d *RedisBidDeduplicator) CheckBid(bid *types.CommonBid) (isNewBid, isTopBid bool) {
+	keys := []string{
+		fmt.Sprintf("%s/bid/%s", types.RedisDedupPrefix, bid.UniqueKey()),
+		fmt.Sprintf("%s/top/%d", types.RedisDedupPrefix, bid.Slot),
+	}
+	res, err := redisCheckBidScript.Run(context.Background(), d.client, keys, bid.ValueAsBigInt().String(), int(d.ttl.Seconds())).Int64Slice()
+	if err != nil || len(res) != 2 {
+		d.log.WithError(err).Error("[bid-processor] redis dedup failed, using local dedup")
+		return d.fallback.CheckBid(bid)
+	}
+	return res[0] == 1, res[1] == 1
+}
+
+func (d *RedisBidDeduplicator) Housekeeping(minSlot uint64) (nSlots, nBids int) {
+	// Redis keys expire by themselves, only the fallback cache needs cleanup
+	return d.fallback.Housekeeping(minSlot)
+}
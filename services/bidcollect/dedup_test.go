@@ -0,0 +1,45 @@
+package bidcollect
+
+import (
+	"testing"
+
+	"github.com/flashbots/relayscan/services/bidcollect/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalBidDeduplicator(t *testing.T) {
+	d := NewLocalBidDeduplicator()
+
+	bid1 := &types.CommonBid{Slot: 1, BlockHash: "0x1", Value: "10"}
+	isNew, isTop := d.CheckBid(bid1)
+	require.True(t, isNew)
+	require.True(t, isTop)
+
+	// Same bid again (i.e. from another relay or source)
+	isNew, isTop = d.CheckBid(&types.CommonBid{Slot: 1, BlockHash: "0x1", Value: "10", Relay: "other"})
+	require.False(t, isNew)
+	require.False(t, isTop)
+
+	// Lower value is new, but not top
+	isNew, isTop = d.CheckBid(&types.CommonBid{Slot: 1, BlockHash: "0x2", Value: "9"})
+	require.True(t, isNew)
+	require.False(t, isTop)
+
+	// Higher value is new top bid
+	isNew, isTop = d.CheckBid(&types.CommonBid{Slot: 1, BlockHash: "0x3", Value: "100"})
+	require.True(t, isNew)
+	require.True(t, isTop)
+
+	// Other slot
+	isNew, isTop = d.CheckBid(&types.CommonBid{Slot: 5, BlockHash: "0x1", Value: "1"})
+	require.True(t, isNew)
+	require.True(t, isTop)
+
+	// Housekeeping removes old slots, including the top bids
+	nSlots, nBids := d.Housekeeping(2)
+	require.Equal(t, 1, nSlots)
+	require.Equal(t, 1, nBids)
+	isNew, isTop = d.CheckBid(&types.CommonBid{Slot: 1, BlockHash: "0x2", Value: "9"})
+	require.True(t, isNew)
+	require.True(t, isTop)
+}
@@ -0,0 +1,210 @@
+package bidcollect
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	"github.com/flashbots/relayscan/services/bidcollect/types"
+	"github.com/flashbots/relayscan/testutil/mockrelay"
+	"github.com/flashbots/relayscan/testutil/testdb"
+	"github.com/stretchr/testify/require"
+)
+
+const testProposerPubkey = "0xb0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0"
+
+// newE2EGetHeaderRelay starts a mock relay serving a single getHeader bid for slot 100
+func newE2EGetHeaderRelay(t *testing.T) (*mockrelay.MockRelay, getHeaderPollTarget) {
+	t.Helper()
+	relay, err := mockrelay.New()
+	require.NoError(t, err)
+	t.Cleanup(relay.Close)
+
+	parentHash := phase0.Hash32{0x02}
+	_, err = relay.AddGetHeaderBid(mockrelay.GetHeaderBid{
+		Slot:           100,
+		ParentHash:     parentHash,
+		ProposerPubkey: testProposerPubkey,
+		BlockHash:      phase0.Hash32{0x01},
+		BlockNumber:    20_000_000,
+		Value:          123456789,
+		NumBlobs:       2,
+	})
+	require.NoError(t, err)
+
+	target := getHeaderPollTarget{Slot: 100, ParentHash: parentHash.String(), ProposerPubkey: testProposerPubkey, SlotStart: common.SlotToTime(100)}
+	return relay, target
+}
+
+func newE2EGetHeaderPoller(relay *mockrelay.MockRelay, db *database.DatabaseService) *GetHeaderPoller {
+	return NewGetHeaderPoller(&GetHeaderPollerOpts{
+		Log:    common.Logger,
+		BidC:   make(chan GetHeaderPollerBidsMsg, 10),
+		Relays: []common.RelayEntry{relay.RelayEntry()},
+		DB:     db,
+		Clock:  common.NewFakeSlotClock(common.SlotToTime(100)),
+	})
+}
+
+func TestE2EGetHeaderPoller(t *testing.T) {
+	relay, target := newE2EGetHeaderRelay(t)
+	poller := newE2EGetHeaderPoller(relay, nil)
+
+	for _, encoding := range []string{GetHeaderEncodingJSON, GetHeaderEncodingSSZ} {
+		relay.SetGetHeaderSSZ(encoding == GetHeaderEncodingSSZ)
+		poller.pollRelaysNow(target, 0)
+		msg := <-poller.bidC
+		require.Equal(t, encoding, msg.Encoding)
+		require.Equal(t, BidSignatureValid, msg.SignatureStatus)
+
+		bid, err := GetHeaderToCommonBid(msg)
+		require.NoError(t, err)
+		require.Equal(t, "123456789", bid.Value)
+		require.Equal(t, 2, bid.NumBlobs)
+	}
+
+	// 429s are ignored
+	relay.InjectRateLimit(mockrelay.PathGetHeader, 1)
+	poller.pollRelaysNow(target, 0)
+	require.Eventually(t, func() bool { return relay.NumRequests(mockrelay.PathGetHeader) == 3 }, time.Second, 10*time.Millisecond)
+
+	// no bid for another parent hash
+	otherTarget := target
+	otherTarget.ParentHash = phase0.Hash32{0x03}.String()
+	poller.pollRelaysNow(otherTarget, 0)
+	require.Eventually(t, func() bool { return relay.NumRequests(mockrelay.PathGetHeader) == 4 }, time.Second, 10*time.Millisecond)
+	require.Never(t, func() bool { return len(poller.bidC) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestE2EGetHeaderPollerSavesSignedBids(t *testing.T) {
+	db := testdb.New(t)
+	relay, target := newE2EGetHeaderRelay(t)
+	poller := newE2EGetHeaderPoller(relay, db)
+
+	relay.SetGetHeaderSSZ(true)
+	poller.pollRelaysNow(target, 0)
+	<-poller.bidC
+
+	entries, err := db.GetSignedBuilderBidsForSlot(100)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	relayEntry := relay.RelayEntry()
+	require.Equal(t, relayEntry.Hostname(), entries[0].Relay)
+	require.Equal(t, target.ParentHash, entries[0].ParentHash)
+	require.Equal(t, testProposerPubkey, entries[0].ProposerPubkey)
+	require.Equal(t, "123456789", entries[0].Value)
+	require.Equal(t, GetHeaderEncodingSSZ, entries[0].Encoding)
+	require.Equal(t, string(BidSignatureValid), entries[0].SignatureStatus)
+	require.Equal(t, uint64(2), entries[0].NumBlobs)
+}
+
+// newE2EDataAPIRelay starts a mock relay serving the builder_blocks_received fixture, and returns its slot
+func newE2EDataAPIRelay(t *testing.T) (*mockrelay.MockRelay, uint64) {
+	t.Helper()
+	relay, err := mockrelay.New()
+	require.NoError(t, err)
+	t.Cleanup(relay.Close)
+
+	bids, err := mockrelay.FixtureBlocksReceived()
+	require.NoError(t, err)
+	relay.SetBlocksReceived(bids)
+	return relay, bids[0].Slot
+}
+
+func TestE2EDataAPIPoller(t *testing.T) {
+	relay, slot := newE2EDataAPIRelay(t)
+	clock := common.NewFakeSlotClock(common.SlotToTime(slot).Add(-5 * time.Second))
+	poller := newTestDataAPIPoller(t, relay, clock, "-2s,1s,4s,8s")
+	stats := func() DataAPICoverageStats { return poller.CoverageStats()[poller.Relays[0].Hostname()] }
+
+	// polls at t-2, t+1, t+4, t+8 and the full poll at t+20
+	poller.schedulePollsForSlot(slot)
+	clock.WaitForWaiters(5)
+
+	// errors are logged, without backoff
+	relay.InjectError(mockrelay.PathBlocksReceived, http.StatusInternalServerError, `{"code":500,"message":"internal error"}`, 1)
+	clock.Advance(3 * time.Second)
+	require.Eventually(t, func() bool { return relay.NumRequests(mockrelay.PathBlocksReceived) == 1 }, time.Second, time.Millisecond)
+
+	clock.Advance(3 * time.Second)
+	msg := <-poller.BidC
+	require.Equal(t, common.SlotToTime(slot).Add(1*time.Second), msg.ReceivedAt)
+	require.Len(t, msg.Bids, 4)
+	require.Empty(t, poller.BidC) // nothing from the failed poll
+
+	bids := DataAPIToCommonBids(msg)
+	require.Equal(t, types.SourceTypeDataAPI, bids[0].SourceType)
+	require.Equal(t, poller.Relays[0].Hostname(), bids[0].Relay)
+	require.Equal(t, slot, bids[0].Slot)
+	require.Equal(t, "51234567890000000", bids[0].Value)
+	require.Equal(t, int64(1718956859000), bids[0].TimestampMs)
+	require.True(t, bids[0].OptimisticSubmission)
+
+	// rate-limited at t+4, so the poll at t+8 is skipped (backoff of one slot)
+	relay.InjectRateLimit(mockrelay.PathBlocksReceived, 1)
+	clock.Advance(3 * time.Second)
+	require.Eventually(t, func() bool { return stats().PollsRateLimited == 1 }, time.Second, time.Millisecond)
+
+	clock.Advance(4 * time.Second)
+	require.Eventually(t, func() bool { return stats().PollsSkipped == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, 3, relay.NumRequests(mockrelay.PathBlocksReceived))
+
+	// the full poll at t+20 is never skipped
+	clock.Advance(12 * time.Second)
+	msg = <-poller.BidC
+	require.Len(t, msg.Bids, 4)
+	require.Equal(t, 4, relay.NumRequests(mockrelay.PathBlocksReceived))
+	require.Equal(t, uint64(4), stats().Polls)
+	require.Equal(t, uint64(4), stats().BidsFull)
+	require.Equal(t, uint64(4), stats().BidsCaptured)
+}
+
+func TestE2EDataAPIPollerSavesSourceStatus(t *testing.T) {
+	db := testdb.New(t)
+	relay, slot := newE2EDataAPIRelay(t)
+	clock := common.NewFakeSlotClock(common.SlotToTime(slot).Add(20 * time.Second))
+	poller := newTestDataAPIPoller(t, relay, clock, "1s")
+
+	processor, err := NewBidProcessor(&BidProcessorOpts{
+		Log:            common.Logger,
+		UID:            "e2e",
+		OutDir:         t.TempDir(),
+		SourceStatusDB: db,
+		Clock:          clock,
+	})
+	require.NoError(t, err)
+
+	poller._pollRelayForBids(slot, relay.RelayEntry(), DataAPICoveragePollOffset, true)
+	processor.processBids(DataAPIToCommonBids(<-poller.BidC))
+	processor.housekeeping()
+
+	entries, err := db.GetBidCollectSourceStatus(clock.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "e2e", entries[0].UID)
+	require.Equal(t, types.SourceTypeDataAPI, entries[0].SourceType)
+	require.Equal(t, poller.Relays[0].Hostname(), entries[0].Relay)
+	require.Equal(t, uint64(4), entries[0].NumBids)
+	require.Equal(t, clock.Now(), entries[0].UpdatedAt.UTC())
+}
+
+func TestE2EUltrasoundStream(t *testing.T) {
+	relay, err := mockrelay.New()
+	require.NoError(t, err)
+	defer relay.Close()
+	relay.SetTopBids([]common.UltrasoundStreamBid{{Slot: 100, BlockNumber: 20_000_000, BlockHash: common.Hash{0x01}}})
+
+	bidC := make(chan UltrasoundStreamBidsMsg, 10)
+	stream := NewUltrasoundStreamConnection(UltrasoundStreamOpts{Log: common.Logger, BidC: bidC, URL: relay.TopBidStreamURL()})
+	go stream.Start()
+
+	msg := <-bidC
+	require.Equal(t, uint64(100), msg.Bid.Slot)
+	bid := UltrasoundStreamToCommonBid(&msg)
+	require.Equal(t, uint64(20_000_000), bid.BlockNumber)
+	require.Equal(t, "0x01"+strings.Repeat("00", 31), bid.BlockHash)
+}
@@ -0,0 +1,125 @@
+package bidcollect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+
+	builderapibellatrix "github.com/attestantio/go-builder-client/api/bellatrix"
+	builderapicapella "github.com/attestantio/go-builder-client/api/capella"
+	builderapideneb "github.com/attestantio/go-builder-client/api/deneb"
+	builderapielectra "github.com/attestantio/go-builder-client/api/electra"
+	builderspec "github.com/attestantio/go-builder-client/spec"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+)
+
+const (
+	GetHeaderEncodingJSON = "json"
+	GetHeaderEncodingSSZ  = "ssz"
+
+	// getHeaderAcceptHeader prefers SSZ, but accepts JSON from relays that don't support SSZ
+	getHeaderAcceptHeader = "application/octet-stream;q=1.0,application/json;q=0.9"
+
+	// HeaderEthConsensusVersion is the response header with the fork of a SSZ-encoded bid
+	HeaderEthConsensusVersion = "Eth-Consensus-Version"
+)
+
+var (
+	ErrGetHeaderErrorResponse = errors.New("getHeader error response")
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+	ErrUnknownBidVersion      = errors.New("unknown bid version")
+
+	// sszDecodeOrder is the order in which forks are tried for SSZ responses without a consensus version header
+	sszDecodeOrder = []consensusspec.DataVersion{consensusspec.DataVersionElectra, consensusspec.DataVersionDeneb, consensusspec.DataVersionCapella, consensusspec.DataVersionBellatrix}
+)
+
+// DecodeGetHeaderResponse decodes a getHeader response body of any fork, either JSON or SSZ (depending on the
+// content type). For SSZ, the fork is taken from the Eth-Consensus-Version header (if present). Returns the bid and
+// the encoding.
+func DecodeGetHeaderResponse(contentType, consensusVersion string, body []byte) (bid *builderspec.VersionedSignedBuilderBid, encoding string, err error) {
+	mediaType := "application/json" // default, if not set by the relay
+	if contentType != "" {
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+		}
+	}
+
+	switch mediaType {
+	case "application/json":
+		bid = new(builderspec.VersionedSignedBuilderBid)
+		if err := json.Unmarshal(body, bid); err != nil {
+			return nil, GetHeaderEncodingJSON, err
+		}
+		return bid, GetHeaderEncodingJSON, nil
+
+	case "application/octet-stream":
+		if consensusVersion != "" {
+			version, err := parseDataVersion(consensusVersion)
+			if err != nil {
+				return nil, GetHeaderEncodingSSZ, err
+			}
+			bid, err = decodeGetHeaderSSZ(version, body)
+			return bid, GetHeaderEncodingSSZ, err
+		}
+
+		// without version header, try the forks from newest to oldest
+		for _, version := range sszDecodeOrder {
+			bid, err = decodeGetHeaderSSZ(version, body)
+			if err == nil {
+				return bid, GetHeaderEncodingSSZ, nil
+			}
+		}
+		return nil, GetHeaderEncodingSSZ, fmt.Errorf("failed to decode SSZ bid for any fork: %w", err)
+
+	default:
+		return nil, "", fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+}
+
+func decodeGetHeaderSSZ(version consensusspec.DataVersion, body []byte) (*builderspec.VersionedSignedBuilderBid, error) {
+	var err error
+	bid := &builderspec.VersionedSignedBuilderBid{Version: version}
+	switch version { //nolint:exhaustive
+	case consensusspec.DataVersionBellatrix:
+		bid.Bellatrix = new(builderapibellatrix.SignedBuilderBid)
+		err = bid.Bellatrix.UnmarshalSSZ(body)
+	case consensusspec.DataVersionCapella:
+		bid.Capella = new(builderapicapella.SignedBuilderBid)
+		err = bid.Capella.UnmarshalSSZ(body)
+	case consensusspec.DataVersionDeneb:
+		bid.Deneb = new(builderapideneb.SignedBuilderBid)
+		err = bid.Deneb.UnmarshalSSZ(body)
+	case consensusspec.DataVersionElectra:
+		bid.Electra = new(builderapielectra.SignedBuilderBid)
+		err = bid.Electra.UnmarshalSSZ(body)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownBidVersion, version.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bid, nil
+}
+
+func parseDataVersion(s string) (version consensusspec.DataVersion, err error) {
+	err = version.UnmarshalJSON([]byte(fmt.Sprintf("%q", strings.TrimSpace(s))))
+	if err != nil {
+		return version, fmt.Errorf("%w: %s", ErrUnknownBidVersion, s)
+	}
+	return version, nil
+}
+
+// GetHeaderBidNumBlobs returns the number of blob KZG commitments of a bid (0 before deneb)
+func GetHeaderBidNumBlobs(bid *builderspec.VersionedSignedBuilderBid) int {
+	switch {
+	case bid.Version == consensusspec.DataVersionDeneb && bid.Deneb != nil && bid.Deneb.Message != nil:
+		return len(bid.Deneb.Message.BlobKZGCommitments)
+	case bid.Version == consensusspec.DataVersionElectra && bid.Electra != nil && bid.Electra.Message != nil:
+		return len(bid.Electra.Message.BlobKZGCommitments)
+	default:
+		return 0
+	}
+}
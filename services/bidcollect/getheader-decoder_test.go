@@ -0,0 +1,126 @@
+package bidcollect
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	builderapielectra "github.com/attestantio/go-builder-client/api/electra"
+	builderspec "github.com/attestantio/go-builder-client/spec"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/electra"
+	"github.com/flashbots/go-boost-utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeGetHeaderResponse(t *testing.T) {
+	bid, relayPubkey := signedTestBid(t)
+	bidJSON, err := json.Marshal(bid)
+	require.NoError(t, err)
+	bidSSZ, err := bid.Deneb.MarshalSSZ()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name             string
+		contentType      string
+		consensusVersion string
+		body             []byte
+		expectedEncoding string
+	}{
+		{"json", "application/json", "", bidJSON, GetHeaderEncodingJSON},
+		{"json with charset", "application/json; charset=utf-8", "", bidJSON, GetHeaderEncodingJSON},
+		{"json without content type", "", "", bidJSON, GetHeaderEncodingJSON},
+		{"ssz", "application/octet-stream", "deneb", bidSSZ, GetHeaderEncodingSSZ},
+		{"ssz without version", "application/octet-stream", "", bidSSZ, GetHeaderEncodingSSZ},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, encoding, err := DecodeGetHeaderResponse(tc.contentType, tc.consensusVersion, tc.body)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedEncoding, encoding)
+			require.Equal(t, consensusspec.DataVersionDeneb, decoded.Version)
+			require.Equal(t, 1, GetHeaderBidNumBlobs(decoded))
+
+			// the decoded bid still has a valid signature
+			status, err := VerifyGetHeaderBid(decoded, relayPubkey, types.DomainBuilder)
+			require.NoError(t, err)
+			require.Equal(t, BidSignatureValid, status)
+		})
+	}
+
+	_, _, err = DecodeGetHeaderResponse("text/html", "", []byte("<html>"))
+	require.ErrorIs(t, err, ErrUnsupportedContentType)
+
+	_, _, err = DecodeGetHeaderResponse("application/octet-stream", "fulu", bidSSZ)
+	require.ErrorIs(t, err, ErrUnknownBidVersion)
+
+	_, _, err = DecodeGetHeaderResponse("application/octet-stream", "", []byte{0x01, 0x02})
+	require.Error(t, err)
+}
+
+func TestDecodeGetHeaderResponseElectra(t *testing.T) {
+	denebBid, _ := signedTestBid(t)
+	bid := &builderspec.VersionedSignedBuilderBid{
+		Version: consensusspec.DataVersionElectra,
+		Electra: &builderapielectra.SignedBuilderBid{
+			Message: &builderapielectra.BuilderBid{
+				Header:             denebBid.Deneb.Message.Header,
+				BlobKZGCommitments: []deneb.KZGCommitment{{0x01}, {0x02}},
+				ExecutionRequests:  &electra.ExecutionRequests{},
+				Value:              denebBid.Deneb.Message.Value,
+				Pubkey:             denebBid.Deneb.Message.Pubkey,
+			},
+			Signature: denebBid.Deneb.Signature,
+		},
+	}
+	bidSSZ, err := bid.Electra.MarshalSSZ()
+	require.NoError(t, err)
+
+	for _, consensusVersion := range []string{"electra", ""} {
+		decoded, _, err := DecodeGetHeaderResponse("application/octet-stream", consensusVersion, bidSSZ)
+		require.NoError(t, err)
+		require.Equal(t, consensusspec.DataVersionElectra, decoded.Version)
+		require.Equal(t, 2, GetHeaderBidNumBlobs(decoded))
+	}
+}
+
+func TestRequestGetHeader(t *testing.T) {
+	bid, _ := signedTestBid(t)
+	bidSSZ, err := bid.Deneb.MarshalSSZ()
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ssz":
+			require.Equal(t, getHeaderAcceptHeader, r.Header.Get("Accept"))
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set(HeaderEthConsensusVersion, "deneb")
+			_, _ = w.Write(bidSSZ)
+		case "/nobid":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, `{"code":400,"message":"no builder bid"}`, http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	code, decoded, encoding, err := requestGetHeader(context.Background(), srv.URL+"/ssz")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, GetHeaderEncodingSSZ, encoding)
+	require.Equal(t, consensusspec.DataVersionDeneb, decoded.Version)
+
+	code, decoded, _, err = requestGetHeader(context.Background(), srv.URL+"/nobid")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, code)
+	require.Nil(t, decoded)
+
+	code, _, _, err = requestGetHeader(context.Background(), srv.URL+"/error")
+	require.ErrorIs(t, err, ErrGetHeaderErrorResponse)
+	require.Contains(t, err.Error(), "no builder bid")
+	require.Equal(t, http.StatusBadRequest, code)
+}
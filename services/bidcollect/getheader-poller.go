@@ -2,153 +2,231 @@ package bidcollect
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/flashbots/go-boost-utils/types"
+	builderspec "github.com/attestantio/go-builder-client/spec"
+	boosttypes "github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/mev-boost-relay/beaconclient"
 	relaycommon "github.com/flashbots/mev-boost-relay/common"
 	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
 	bidcollecttypes "github.com/flashbots/relayscan/services/bidcollect/types"
 	"github.com/sirupsen/logrus"
 )
 
 type GetHeaderPollerBidsMsg struct {
-	Slot       uint64
-	Bid        types.GetHeaderResponse
-	Relay      common.RelayEntry
-	ReceivedAt time.Time
+	Slot            uint64
+	ParentHash      string
+	ProposerPubkey  string
+	Bid             *builderspec.VersionedSignedBuilderBid
+	Encoding        string // json or ssz
+	SignatureStatus BidSignatureStatus
+	Relay           common.RelayEntry
+	RequestedAt     time.Time
+	ReceivedAt      time.Time
 }
 
 type GetHeaderPollerOpts struct {
-	Log       *logrus.Entry
-	BidC      chan GetHeaderPollerBidsMsg
-	BeaconURI string
-	Relays    []common.RelayEntry
+	Log    *logrus.Entry
+	BidC   chan GetHeaderPollerBidsMsg
+	BN     *common.MultiBeaconClient
+	Relays []common.RelayEntry
+
+	// DB is used to store the full signed bids (optional)
+	DB *database.DatabaseService
+
+	// Offsets are the poll offsets relative to slot start (optional, DefaultGetHeaderPollOffsets if not set)
+	Offsets []time.Duration
+
+	// Clock is used for scheduling the polls (optional, the system clock by default)
+	Clock common.SlotClock
+
+	// BuilderDomain is the signing domain of the bids (optional, mainnet by default, see ComputeBuilderDomain)
+	BuilderDomain boosttypes.Domain
 }
 
 type GetHeaderPoller struct {
 	log    *logrus.Entry
 	bidC   chan GetHeaderPollerBidsMsg
 	relays []common.RelayEntry
-	bn     *beaconclient.ProdBeaconInstance
+	bn     *common.MultiBeaconClient
+	db     *database.DatabaseService
+
+	offsets []time.Duration
+	duties  *proposerDutiesCache
+	clock   common.SlotClock
+
+	builderDomain boosttypes.Domain
 }
 
 func NewGetHeaderPoller(opts *GetHeaderPollerOpts) *GetHeaderPoller {
+	offsets := opts.Offsets
+	if len(offsets) == 0 {
+		offsets = DefaultGetHeaderPollOffsets
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = common.NewSystemSlotClock()
+	}
+	builderDomain := opts.BuilderDomain
+	if builderDomain == (boosttypes.Domain{}) {
+		builderDomain = boosttypes.DomainBuilder
+	}
+
 	return &GetHeaderPoller{
-		log:    opts.Log,
-		bidC:   opts.BidC,
-		relays: opts.Relays,
-		bn:     beaconclient.NewProdBeaconInstance(opts.Log, opts.BeaconURI),
+		log:     opts.Log,
+		bidC:    opts.BidC,
+		relays:  opts.Relays,
+		bn:      opts.BN,
+		db:      opts.DB,
+		offsets: offsets,
+		duties:  newProposerDutiesCache(),
+		clock:   clock,
+
+		builderDomain: builderDomain,
 	}
 }
 
 func (poller *GetHeaderPoller) Start() {
 	poller.log.WithField("relays", common.RelayEntriesToHostnameStrings(poller.relays)).Info("Starting GetHeaderPoller ...")
+	poller.log.Infof("[getHeader poller] poll offsets: %s", offsetsToString(poller.offsets))
 
-	//  Check beacon-node sync status, process current slot and start slot updates
-	syncStatus, err := poller.bn.SyncStatus()
-	if err != nil {
-		poller.log.WithError(err).Fatal("couldn't get BN sync status")
-	} else if syncStatus.IsSyncing {
-		poller.log.Fatal("beacon node is syncing")
-	}
-
-	// var headSlot uint64
-	var headSlot, nextSlot, currentEpoch, lastDutyUpdateEpoch uint64
-	var duties map[uint64]string
-
-	// subscribe to head events (because then, the BN will know the block + proposer details for the next slot)
-	c := make(chan beaconclient.HeadEventData)
-	go poller.bn.SubscribeToHeadEvents(c)
-
-	// then run polling loop
+	// Wait for a synced beacon node, then keep checking the health of all nodes (for failover)
 	for {
-		headEvent := <-c
-		if headEvent.Slot <= headSlot {
-			continue
+		syncStatus, err := poller.bn.BestSyncStatus(false)
+		if err == nil {
+			poller.log.Infof("[getHeader poller] beacon node head slot: %d", syncStatus.HeadSlot)
+			break
 		}
+		poller.log.WithError(err).Errorf("[getHeader poller] no synced beacon node, retrying in %s", beaconHealthCheckInterval)
+		poller.clock.Sleep(beaconHealthCheckInterval)
+	}
+	go poller.bn.StartHealthChecks(poller.clock, beaconHealthCheckInterval)
 
-		headSlot = headEvent.Slot
-		nextSlot = headSlot + 1
-		tNextSlot := common.SlotToTime(nextSlot)
-		untilNextSlot := tNextSlot.Sub(time.Now().UTC())
-
-		currentEpoch = headSlot / relaycommon.SlotsPerEpoch
-		poller.log.Infof("[getHeader poller] headSlot slot: %d / next slot: %d (%s), waitTime: %s", headSlot, nextSlot, tNextSlot.String(), untilNextSlot.String())
-
-		// On every new epoch, get proposer duties for current and next epoch (to avoid boundary problems)
-		if len(duties) == 0 || currentEpoch > lastDutyUpdateEpoch {
-			dutiesResp, err := poller.bn.GetProposerDuties(currentEpoch)
-			if err != nil {
-				poller.log.WithError(err).Error("couldn't get proposer duties")
-				continue
-			}
+	// subscribe to payload_attributes events, which have the parent hash and proposer of the next slot. The BN sends a
+	// new event whenever the head changes, so a second event for the same slot with a different parent is a reorg.
+	c := make(chan beaconclient.PayloadAttributesEvent)
+	go poller.bn.SubscribeToPayloadAttributesEvents(c)
 
-			duties = make(map[uint64]string)
-			for _, d := range dutiesResp.Data {
-				duties[d.Slot] = d.Pubkey
-			}
+	// head events are only used to notice missing payload_attributes events
+	headC := make(chan beaconclient.HeadEventData)
+	go poller.bn.SubscribeToHeadEvents(headC)
 
-			dutiesResp, err = poller.bn.GetProposerDuties(currentEpoch + 1)
-			if err != nil {
-				poller.log.WithError(err).Error("failed get proposer duties")
-			} else {
-				for _, d := range dutiesResp.Data {
-					duties[d.Slot] = d.Pubkey
-				}
+	tracker := getHeaderSlotTracker{}
+	watch := payloadAttributesWatch{}
+	cancelPolls := func() {}
+	for {
+		var event beaconclient.PayloadAttributesEvent
+		select {
+		case head := <-headC:
+			if missingSlots, warn := watch.onHead(head.Slot); warn {
+				poller.log.Warnf("[getHeader poller] no payload_attributes events for %d slots (head slot %d), the beacon node must prepare payloads for all slots (lighthouse --always-prepare-payload, prysm --prepare-all-payloads)", missingSlots, head.Slot)
 			}
-			poller.log.Debugf("[getHeader poller] duties updated: %d entries", len(duties))
-			lastDutyUpdateEpoch = currentEpoch
+			continue
+		case event = <-c:
+			watch.onPayloadAttributes(event.Data.ProposalSlot)
 		}
 
-		// Now get the latest block, for the execution payload
-		block, err := poller.bn.GetBlock("head")
-		if err != nil {
-			poller.log.WithError(err).Error("failed get latest block from BN")
+		target := getHeaderPollTargetFromEvent(event)
+		update := tracker.classify(target)
+		if update == getHeaderTargetIgnored {
 			continue
 		}
 
-		if block.Data.Message.Slot != headSlot {
-			poller.log.WithField("slot", headSlot).WithField("bnSlot", block.Data.Message.Slot).Error("latest block slot is not current slot")
+		log := poller.log.WithFields(logrus.Fields{
+			"slot":          target.Slot,
+			"parentHash":    target.ParentHash,
+			"proposerIndex": target.ProposerIndex,
+		})
+
+		var err error
+		target.ProposerPubkey, err = poller.getProposerPubkey(target.Slot, target.ProposerIndex)
+		if err != nil {
+			log.WithError(err).Error("[getHeader poller] failed to get proposer pubkey")
 			continue
 		}
+		tracker.set(target)
 
-		nextProposerPubkey := duties[nextSlot]
-		poller.log.Debugf("[getHeader poller] next slot: %d / block: %s / parent: %s / proposerPubkey: %s", nextSlot, block.Data.Message.Body.ExecutionPayload.BlockHash.String(), block.Data.Message.Body.ExecutionPayload.ParentHash, nextProposerPubkey)
+		// stop polling for the previous target (either the previous slot, or the reorged parent)
+		cancelPolls()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelPolls = cancel
 
-		if nextProposerPubkey == "" {
-			poller.log.WithField("duties", duties).Error("no proposerPubkey for next slot")
+		if update == getHeaderTargetReorg {
+			log.Warn("[getHeader poller] reorg: new parent for slot")
 		} else {
-			// go poller.pollRelaysForBids(0*time.Second, nextSlot, block.Data.Message.Body.ExecutionPayload.BlockHash.String(), duties[nextSlot])
-			go poller.pollRelaysForBids(1000*time.Millisecond, nextSlot, block.Data.Message.Body.ExecutionPayload.BlockHash.String(), duties[nextSlot])
+			log.Infof("[getHeader poller] next slot: %d (%s), waitTime: %s", target.Slot, target.SlotStart.String(), target.SlotStart.Sub(poller.clock.Now()).String())
 		}
+		go poller.pollRelaysForBids(ctx, target, update == getHeaderTargetReorg)
 	}
 }
 
-// pollRelaysForBids will poll data api for given slot with t seconds offset
-func (poller *GetHeaderPoller) pollRelaysForBids(tOffset time.Duration, slot uint64, parentHash, proposerPubkey string) {
-	tSlotStart := common.SlotToTime(slot)
-	tStart := tSlotStart.Add(tOffset)
-	waitTime := tStart.Sub(time.Now().UTC())
+// getProposerPubkey returns the pubkey of the proposer of a slot, and (re-)loads the duties if they are missing or
+// don't match the proposer index of the payload_attributes event
+func (poller *GetHeaderPoller) getProposerPubkey(slot, proposerIndex uint64) (string, error) {
+	if pubkey, found := poller.duties.get(slot, proposerIndex); found {
+		return pubkey, nil
+	}
 
-	// poller.Log.Debugf("[getHeader poller] - prepare polling for slot %d t %d (tSlotStart: %s, tStart: %s, waitTime: %s)", slot, t, tSlotStart.String(), tStart.String(), waitTime.String())
-	if waitTime < 0 {
-		poller.log.Debugf("[getHeader poller] waitTime is negative: %s", waitTime.String())
-		return
+	// load the duties of the epoch of the slot, and of the next epoch (to avoid boundary problems)
+	epoch := slot / relaycommon.SlotsPerEpoch
+	dutiesResp, err := poller.bn.GetProposerDuties(epoch)
+	if err != nil {
+		return "", err
 	}
+	poller.duties.add(dutiesResp)
 
-	// Wait until expected time
-	time.Sleep(waitTime)
+	dutiesResp, err = poller.bn.GetProposerDuties(epoch + 1)
+	if err != nil {
+		poller.log.WithError(err).Error("failed get proposer duties")
+	} else {
+		poller.duties.add(dutiesResp)
+	}
+	poller.duties.prune(epoch * relaycommon.SlotsPerEpoch)
+	poller.log.Debugf("[getHeader poller] duties updated for epoch %d", epoch)
+
+	pubkey, found := poller.duties.get(slot, proposerIndex)
+	if !found {
+		return "", fmt.Errorf("%w: slot %d, proposer index %d", errNoProposerDuty, slot, proposerIndex)
+	}
+	return pubkey, nil
+}
 
-	// Poll for bids now
-	untilSlot := tSlotStart.Sub(time.Now().UTC())
-	poller.log.Debugf("[getHeader poller] polling for slot %d at t=%s (tNow=%s)", slot, tOffset.String(), (untilSlot * -1).String())
+// pollRelaysForBids calls getHeader on all relays at each of the poll offsets, until the context is cancelled. Offsets
+// in the past are skipped, but after a reorg the relays are polled immediately once.
+func (poller *GetHeaderPoller) pollRelaysForBids(ctx context.Context, target getHeaderPollTarget, pollNow bool) {
+	if pollNow {
+		poller.pollRelaysNow(target, poller.clock.Now().Sub(target.SlotStart))
+	}
 
+	for _, tOffset := range poller.offsets {
+		waitTime := target.SlotStart.Add(tOffset).Sub(poller.clock.Now())
+		if waitTime < 0 {
+			poller.log.Debugf("[getHeader poller] waitTime is negative: %s", waitTime.String())
+			continue
+		}
+
+		// Wait until expected time
+		select {
+		case <-ctx.Done():
+			return
+		case <-poller.clock.After(waitTime):
+		}
+
+		poller.pollRelaysNow(target, tOffset)
+	}
+}
+
+func (poller *GetHeaderPoller) pollRelaysNow(target getHeaderPollTarget, tOffset time.Duration) {
+	poller.log.Debugf("[getHeader poller] polling for slot %d at t=%s (tNow=%s)", target.Slot, tOffset.String(), poller.clock.Now().Sub(target.SlotStart).String())
 	for _, relay := range poller.relays {
-		go poller._pollRelayForBids(relay, tOffset, slot, parentHash, proposerPubkey)
+		go poller._pollRelayForBids(relay, tOffset, target.Slot, target.ParentHash, target.ProposerPubkey)
 	}
 }
 
@@ -165,10 +243,9 @@ func (poller *GetHeaderPoller) _pollRelayForBids(relay common.RelayEntry, t time
 	url := relay.GetURI(path)
 	// log.Debugf("Querying %s", url)
 
-	var bid types.GetHeaderResponse
-	timeRequestStart := time.Now().UTC()
-	code, err := common.SendHTTPRequest(context.Background(), *http.DefaultClient, http.MethodGet, url, nil, &bid)
-	timeRequestEnd := time.Now().UTC()
+	timeRequestStart := poller.clock.Now()
+	code, bid, encoding, err := requestGetHeader(context.Background(), url)
+	timeRequestEnd := poller.clock.Now()
 	if err != nil {
 		msg := err.Error()
 		if strings.Contains(msg, "no builder bid") {
@@ -185,26 +262,138 @@ func (poller *GetHeaderPoller) _pollRelayForBids(relay common.RelayEntry, t time
 		}).WithError(err).Error("[getHeader poller] error on getHeader request")
 		return
 	}
-	if code != 200 {
+	if code != 200 || bid == nil || bid.IsEmpty() {
 		log.WithField("code", code).Debug("[getHeader poller] no bid received")
 		return
 	}
-	log.WithField("durationMs", timeRequestEnd.Sub(timeRequestStart).Milliseconds()).Infof("[getHeader poller] bid received! slot: %d - value: %s - block_hash: %s -", slot, bid.Data.Message.Value.String(), bid.Data.Message.Header.BlockHash.String())
+
+	msg := GetHeaderPollerBidsMsg{
+		Slot:           slot,
+		ParentHash:     parentHash,
+		ProposerPubkey: proposerPubkey,
+		Bid:            bid,
+		Encoding:       encoding,
+		Relay:          relay,
+		RequestedAt:    timeRequestStart,
+		ReceivedAt:     timeRequestEnd,
+	}
+	commonBid, err := GetHeaderToCommonBid(msg)
+	if err != nil {
+		log.WithError(err).Error("[getHeader poller] failed to decode bid")
+		return
+	}
+
+	// Verify the signature against the relay pubkey
+	msg.SignatureStatus, err = VerifyGetHeaderBid(bid, relay.PublicKey, poller.builderDomain)
+	if err != nil {
+		log.WithError(err).Error("[getHeader poller] failed to verify bid signature")
+		return
+	}
+	log = log.WithFields(logrus.Fields{
+		"durationMs": timeRequestEnd.Sub(timeRequestStart).Milliseconds(),
+		"version":    bid.Version.String(),
+		"encoding":   encoding,
+		"numBlobs":   commonBid.NumBlobs,
+		"signature":  msg.SignatureStatus,
+	})
+	if msg.SignatureStatus != BidSignatureValid {
+		log.Warnf("[getHeader poller] bid with %s signature! slot: %d - value: %s - block_hash: %s", msg.SignatureStatus, slot, commonBid.Value, commonBid.BlockHash)
+	} else {
+		log.Infof("[getHeader poller] bid received! slot: %d - value: %s - block_hash: %s -", slot, commonBid.Value, commonBid.BlockHash)
+	}
+
+	// Store the full signed bid
+	if poller.db != nil {
+		poller.saveSignedBid(log, msg)
+	}
 
 	// send data to channel
-	poller.bidC <- GetHeaderPollerBidsMsg{Slot: slot, Bid: bid, Relay: relay, ReceivedAt: time.Now().UTC()}
+	poller.bidC <- msg
+}
+
+// requestGetHeader sends a getHeader request (preferring SSZ) and decodes the bid. On error responses, the error
+// contains the response body.
+func requestGetHeader(ctx context.Context, url string) (code int, bid *builderspec.VersionedSignedBuilderBid, encoding string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, nil, "", fmt.Errorf("could not prepare request: %w", err)
+	}
+	req.Header.Set("Accept", getHeaderAcceptHeader)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, "", err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, "", fmt.Errorf("could not read response body: %w", err)
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return resp.StatusCode, nil, "", nil
+	}
+	if resp.StatusCode > 299 {
+		return resp.StatusCode, nil, "", fmt.Errorf("%w: %d / %s", ErrGetHeaderErrorResponse, resp.StatusCode, string(body))
+	}
+
+	bid, encoding, err = DecodeGetHeaderResponse(resp.Header.Get("Content-Type"), resp.Header.Get(HeaderEthConsensusVersion), body)
+	return resp.StatusCode, bid, encoding, err
 }
 
-func GetHeaderToCommonBid(bid GetHeaderPollerBidsMsg) *bidcollecttypes.CommonBid {
+func (poller *GetHeaderPoller) saveSignedBid(log *logrus.Entry, msg GetHeaderPollerBidsMsg) {
+	entry, err := database.SignedBuilderBidToEntry(msg.Relay.Hostname(), msg.Slot, msg.ParentHash, msg.ProposerPubkey, msg.RequestedAt, msg.ReceivedAt, msg.Bid)
+	if err != nil {
+		log.WithError(err).Error("[getHeader poller] failed to convert signed bid")
+		return
+	}
+	entry.SignatureStatus = string(msg.SignatureStatus)
+	entry.Encoding = msg.Encoding
+	entry.NumBlobs = uint64(GetHeaderBidNumBlobs(msg.Bid)) //nolint:gosec
+
+	signedBidJSON, err := json.Marshal(msg.Bid)
+	if err != nil {
+		log.WithError(err).Error("[getHeader poller] failed to encode signed bid")
+		return
+	}
+	entry.SignedBid = sql.NullString{String: string(signedBidJSON), Valid: true}
+
+	err = poller.db.SaveSignedBuilderBid(entry)
+	if err != nil {
+		log.WithError(err).Error("[getHeader poller] failed to save signed bid")
+	}
+}
+
+func GetHeaderToCommonBid(bid GetHeaderPollerBidsMsg) (*bidcollecttypes.CommonBid, error) {
+	blockNumber, err := bid.Bid.BlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	blockHash, err := bid.Bid.BlockHash()
+	if err != nil {
+		return nil, err
+	}
+	parentHash, err := bid.Bid.ParentHash()
+	if err != nil {
+		return nil, err
+	}
+	value, err := bid.Bid.Value()
+	if err != nil {
+		return nil, err
+	}
+
 	return &bidcollecttypes.CommonBid{
 		SourceType:   bidcollecttypes.SourceTypeGetHeader,
 		ReceivedAtMs: bid.ReceivedAt.UnixMilli(),
 		Relay:        bid.Relay.Hostname(),
 		Slot:         bid.Slot,
 
-		BlockNumber: bid.Bid.Data.Message.Header.BlockNumber,
-		BlockHash:   strings.ToLower(bid.Bid.Data.Message.Header.BlockHash.String()),
-		ParentHash:  strings.ToLower(bid.Bid.Data.Message.Header.ParentHash.String()),
-		Value:       bid.Bid.Data.Message.Value.String(),
-	}
+		BlockNumber: blockNumber,
+		BlockHash:   strings.ToLower(blockHash.String()),
+		ParentHash:  strings.ToLower(parentHash.String()),
+		Value:       value.Dec(),
+
+		ForkVersion: bid.Bid.Version.String(),
+		NumBlobs:    GetHeaderBidNumBlobs(bid.Bid),
+	}, nil
 }
@@ -0,0 +1,90 @@
+package bidcollect
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flashbots/relayscan/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHeaderPollerSchedule(t *testing.T) {
+	bid, relayPubkey := signedTestBid(t)
+	bidSSZ, err := bid.Deneb.MarshalSSZ()
+	require.NoError(t, err)
+
+	// request paths are checked in the test, not in the handler goroutine
+	requests := new(atomic.Int64)
+	paths := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		paths <- r.URL.Path
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set(HeaderEthConsensusVersion, "deneb")
+		_, _ = w.Write(bidSSZ)
+	}))
+	defer srv.Close()
+
+	relayURL := strings.Replace(srv.URL, "http://", "http://"+relayPubkey.String()+"@", 1)
+	slotStart := common.SlotToTime(100)
+	clock := common.NewFakeSlotClock(slotStart.Add(-1 * time.Second))
+	poller := NewGetHeaderPoller(&GetHeaderPollerOpts{
+		Log:     common.Logger,
+		BidC:    make(chan GetHeaderPollerBidsMsg, 10),
+		Relays:  []common.RelayEntry{common.MustNewRelayEntry(relayURL, false)},
+		Offsets: []time.Duration{0, 1 * time.Second},
+		Clock:   clock,
+	})
+
+	target := getHeaderPollTarget{Slot: 100, ParentHash: "0x02", ProposerPubkey: "0xproposer", SlotStart: slotStart}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		poller.pollRelaysForBids(ctx, target, false)
+		close(done)
+	}()
+
+	// first poll at t+0
+	clock.WaitForWaiters(1)
+	clock.Advance(1 * time.Second)
+	msg := <-poller.bidC
+	require.Equal(t, "/eth/v1/builder/header/100/0x02/0xproposer", <-paths)
+	require.Equal(t, uint64(100), msg.Slot)
+	require.Equal(t, slotStart, msg.ReceivedAt)
+	require.Equal(t, BidSignatureValid, msg.SignatureStatus)
+	require.Equal(t, GetHeaderEncodingSSZ, msg.Encoding)
+
+	// cancelled (i.e. by a reorg) before the poll at t+1
+	clock.WaitForWaiters(1)
+	cancel()
+	<-done
+	clock.Advance(1500 * time.Millisecond)
+	require.Equal(t, int64(1), requests.Load())
+
+	// after a reorg at t+0.5, the relays are polled immediately, and then at the remaining offset t+1
+	clock.Set(slotStart.Add(500 * time.Millisecond))
+	done = make(chan bool)
+	go func() {
+		poller.pollRelaysForBids(context.Background(), target, true)
+		close(done)
+	}()
+	msg = <-poller.bidC
+	require.Equal(t, slotStart.Add(500*time.Millisecond), msg.ReceivedAt)
+	require.Equal(t, int64(2), requests.Load())
+
+	clock.WaitForWaiters(1)
+	clock.Advance(500 * time.Millisecond)
+	msg = <-poller.bidC
+	require.Equal(t, slotStart.Add(1*time.Second), msg.ReceivedAt)
+	<-done
+	require.Equal(t, int64(3), requests.Load())
+	require.Equal(t, 0, clock.NumWaiters())
+	for range 2 {
+		require.Equal(t, "/eth/v1/builder/header/100/0x02/0xproposer", <-paths)
+	}
+}
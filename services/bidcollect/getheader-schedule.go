@@ -0,0 +1,183 @@
+package bidcollect
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/beaconclient"
+)
+
+var (
+	// DefaultGetHeaderPollOffsets are the offsets (relative to slot start) at which getHeader is called, unless configured otherwise
+	DefaultGetHeaderPollOffsets = []time.Duration{0, 500 * time.Millisecond, 1 * time.Second, 2 * time.Second}
+
+	// getHeaderMaxPollOffset is the latest allowed poll offset (relays don't serve bids much later in the slot)
+	getHeaderMaxPollOffset = 4 * time.Second
+
+	// beaconHealthCheckInterval is how often the sync status of the beacon nodes is checked
+	beaconHealthCheckInterval = 12 * time.Second
+
+	// payloadAttributesMissingSlots is after how many slots with head events but no payload_attributes events a
+	// warning is logged (and again every epoch while they are missing)
+	payloadAttributesMissingSlots uint64 = 3
+
+	ErrInvalidGetHeaderPollOffsets = errors.New("invalid getHeader poll offsets")
+	errNoProposerDuty              = errors.New("no proposer duty")
+)
+
+// ParseGetHeaderPollOffsets parses a comma-separated list of offsets relative to slot start, i.e. "-500ms,0s,1s,2s"
+func ParseGetHeaderPollOffsets(s string) ([]time.Duration, error) {
+	offsets := []time.Duration{}
+	seen := make(map[time.Duration]bool)
+	for _, offsetStr := range strings.Split(s, ",") {
+		offsetStr = strings.TrimSpace(offsetStr)
+		if offsetStr == "" {
+			continue
+		}
+		offset, err := time.ParseDuration(offsetStr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidGetHeaderPollOffsets, s, err)
+		}
+		if offset > getHeaderMaxPollOffset {
+			return nil, fmt.Errorf("%w: %s: offsets must be at most t+%s", ErrInvalidGetHeaderPollOffsets, s, getHeaderMaxPollOffset)
+		}
+		if !seen[offset] {
+			seen[offset] = true
+			offsets = append(offsets, offset)
+		}
+	}
+	if len(offsets) == 0 {
+		return nil, fmt.Errorf("%w: no offsets", ErrInvalidGetHeaderPollOffsets)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets, nil
+}
+
+// getHeaderPollTarget is what getHeader is requested for: the proposal slot on top of a specific parent
+type getHeaderPollTarget struct {
+	Slot           uint64
+	ParentHash     string
+	ProposerIndex  uint64
+	ProposerPubkey string
+	SlotStart      time.Time
+}
+
+// getHeaderPollTargetFromEvent converts a payload_attributes event (the proposer pubkey is filled in later, from the duties)
+func getHeaderPollTargetFromEvent(event beaconclient.PayloadAttributesEvent) getHeaderPollTarget {
+	return getHeaderPollTarget{
+		Slot:          event.Data.ProposalSlot,
+		ParentHash:    strings.ToLower(event.Data.ParentBlockHash),
+		ProposerIndex: event.Data.ProposerIndex,
+		SlotStart:     time.Unix(int64(event.Data.PayloadAttributes.Timestamp), 0).UTC(), //nolint:gosec
+	}
+}
+
+// getHeaderSlotTracker keeps the current poll target, and detects new slots and reorgs (a new parent for the same slot)
+type getHeaderSlotTracker struct {
+	current *getHeaderPollTarget
+}
+
+type getHeaderTargetUpdate int
+
+const (
+	getHeaderTargetIgnored getHeaderTargetUpdate = iota // old slot, or duplicate event
+	getHeaderTargetNewSlot
+	getHeaderTargetReorg
+)
+
+// classify returns whether the target is a new slot, a reorg of the current slot, or should be ignored
+func (t *getHeaderSlotTracker) classify(target getHeaderPollTarget) getHeaderTargetUpdate {
+	switch {
+	case t.current == nil || target.Slot > t.current.Slot:
+		return getHeaderTargetNewSlot
+	case target.Slot == t.current.Slot && target.ParentHash != t.current.ParentHash:
+		return getHeaderTargetReorg
+	default:
+		return getHeaderTargetIgnored
+	}
+}
+
+// set makes the target the current one (once polling for it starts)
+func (t *getHeaderSlotTracker) set(target getHeaderPollTarget) {
+	t.current = &target
+}
+
+// payloadAttributesWatch detects a beacon node that sends head events, but no payload_attributes events (it only
+// prepares payloads for its own validators, unless configured to prepare them for all slots)
+type payloadAttributesWatch struct {
+	lastProposalSlot uint64 // latest slot with a payload_attributes event (or the first head slot)
+	lastWarnedSlot   uint64
+}
+
+// onPayloadAttributes records a payload_attributes event for the proposal slot
+func (w *payloadAttributesWatch) onPayloadAttributes(proposalSlot uint64) {
+	w.lastProposalSlot = max(w.lastProposalSlot, proposalSlot)
+}
+
+// onHead records a head event, and returns for how many slots payload_attributes events are missing if a warning
+// should be logged. The payload_attributes event for a slot is sent before the head event of that slot.
+func (w *payloadAttributesWatch) onHead(slot uint64) (missingSlots uint64, warn bool) {
+	if w.lastProposalSlot == 0 {
+		w.lastProposalSlot = slot
+		return 0, false
+	}
+	if slot <= w.lastProposalSlot {
+		return 0, false
+	}
+	missingSlots = slot - w.lastProposalSlot
+	if missingSlots < payloadAttributesMissingSlots || (w.lastWarnedSlot > w.lastProposalSlot && slot < w.lastWarnedSlot+32) {
+		return missingSlots, false
+	}
+	w.lastWarnedSlot = slot
+	return missingSlots, true
+}
+
+// proposerDuty is a single entry of the proposer duties
+type proposerDuty struct {
+	Pubkey         string
+	ValidatorIndex uint64
+}
+
+// proposerDutiesCache keeps the proposer duties by slot
+type proposerDutiesCache struct {
+	lock   sync.Mutex
+	duties map[uint64]proposerDuty // map[slot]duty
+}
+
+func newProposerDutiesCache() *proposerDutiesCache {
+	return &proposerDutiesCache{duties: make(map[uint64]proposerDuty)}
+}
+
+func (c *proposerDutiesCache) add(resp *beaconclient.ProposerDutiesResponse) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, d := range resp.Data {
+		c.duties[d.Slot] = proposerDuty{Pubkey: strings.ToLower(d.Pubkey), ValidatorIndex: d.ValidatorIndex}
+	}
+}
+
+// get returns the proposer pubkey of a slot, only if the duty matches the expected proposer index
+func (c *proposerDutiesCache) get(slot, proposerIndex uint64) (pubkey string, found bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	duty, ok := c.duties[slot]
+	if !ok || duty.ValidatorIndex != proposerIndex {
+		return "", false
+	}
+	return duty.Pubkey, true
+}
+
+// prune removes all duties before the given slot
+func (c *proposerDutiesCache) prune(beforeSlot uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for slot := range c.duties {
+		if slot < beforeSlot {
+			delete(c.duties, slot)
+		}
+	}
+}
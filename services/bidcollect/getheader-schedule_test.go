@@ -0,0 +1,113 @@
+package bidcollect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/beaconclient"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGetHeaderPollOffsets(t *testing.T) {
+	offsets, err := ParseGetHeaderPollOffsets("2s, -500ms,0s,2s,")
+	require.NoError(t, err)
+	require.Equal(t, []time.Duration{-500 * time.Millisecond, 0, 2 * time.Second}, offsets)
+
+	for _, s := range []string{"", " , ", "foo", "1s,5s"} {
+		_, err = ParseGetHeaderPollOffsets(s)
+		require.ErrorIs(t, err, ErrInvalidGetHeaderPollOffsets, s)
+	}
+}
+
+func TestGetHeaderPollTargetFromEvent(t *testing.T) {
+	event := beaconclient.PayloadAttributesEvent{}
+	event.Data.ProposalSlot = 10
+	event.Data.ProposerIndex = 123
+	event.Data.ParentBlockHash = "0x9A2FEFD2FDB57F74993C7780EA5B9030D2897B615B89F808011CA5AEBED54EAF"
+	event.Data.PayloadAttributes.Timestamp = 1717156924
+
+	target := getHeaderPollTargetFromEvent(event)
+	require.Equal(t, uint64(10), target.Slot)
+	require.Equal(t, uint64(123), target.ProposerIndex)
+	require.Equal(t, "0x9a2fefd2fdb57f74993c7780ea5b9030d2897b615b89f808011ca5aebed54eaf", target.ParentHash)
+	require.Equal(t, time.Unix(1717156924, 0).UTC(), target.SlotStart)
+}
+
+func TestGetHeaderSlotTracker(t *testing.T) {
+	tracker := getHeaderSlotTracker{}
+	target := getHeaderPollTarget{Slot: 10, ParentHash: "0x1"}
+	require.Equal(t, getHeaderTargetNewSlot, tracker.classify(target))
+
+	// not set yet (i.e. no proposer pubkey), so still new
+	require.Equal(t, getHeaderTargetNewSlot, tracker.classify(target))
+	tracker.set(target)
+
+	// duplicate event and older slots are ignored
+	require.Equal(t, getHeaderTargetIgnored, tracker.classify(target))
+	require.Equal(t, getHeaderTargetIgnored, tracker.classify(getHeaderPollTarget{Slot: 9, ParentHash: "0x2"}))
+
+	// same slot with a new parent is a reorg
+	reorged := getHeaderPollTarget{Slot: 10, ParentHash: "0x2"}
+	require.Equal(t, getHeaderTargetReorg, tracker.classify(reorged))
+	tracker.set(reorged)
+	require.Equal(t, getHeaderTargetIgnored, tracker.classify(reorged))
+
+	require.Equal(t, getHeaderTargetNewSlot, tracker.classify(getHeaderPollTarget{Slot: 11, ParentHash: "0x3"}))
+}
+
+func TestPayloadAttributesWatch(t *testing.T) {
+	watch := payloadAttributesWatch{}
+
+	// the first head slot is the baseline, then a payload_attributes event for the next slot arrives before its head
+	_, warn := watch.onHead(100)
+	require.False(t, warn)
+	for slot := uint64(101); slot <= 110; slot++ {
+		watch.onPayloadAttributes(slot)
+		_, warn = watch.onHead(slot)
+		require.False(t, warn)
+	}
+
+	// missing payload_attributes events: warning after payloadAttributesMissingSlots slots, then once per epoch
+	missingSlots, warn := watch.onHead(112)
+	require.False(t, warn)
+	require.Equal(t, uint64(2), missingSlots)
+	missingSlots, warn = watch.onHead(113)
+	require.True(t, warn)
+	require.Equal(t, uint64(3), missingSlots)
+	_, warn = watch.onHead(114)
+	require.False(t, warn)
+	missingSlots, warn = watch.onHead(145)
+	require.True(t, warn)
+	require.Equal(t, uint64(35), missingSlots)
+
+	// events are back
+	watch.onPayloadAttributes(146)
+	_, warn = watch.onHead(146)
+	require.False(t, warn)
+	_, warn = watch.onHead(149)
+	require.True(t, warn)
+}
+
+func TestProposerDutiesCache(t *testing.T) {
+	cache := newProposerDutiesCache()
+	cache.add(&beaconclient.ProposerDutiesResponse{Data: []beaconclient.ProposerDutiesResponseData{
+		{Slot: 31, Pubkey: "0xAA", ValidatorIndex: 1},
+		{Slot: 32, Pubkey: "0xbb", ValidatorIndex: 2},
+	}})
+
+	pubkey, found := cache.get(31, 1)
+	require.True(t, found)
+	require.Equal(t, "0xaa", pubkey)
+
+	// proposer index must match
+	_, found = cache.get(32, 1)
+	require.False(t, found)
+	_, found = cache.get(33, 1)
+	require.False(t, found)
+
+	cache.prune(32)
+	_, found = cache.get(31, 1)
+	require.False(t, found)
+	_, found = cache.get(32, 2)
+	require.True(t, found)
+}
@@ -0,0 +1,69 @@
+package bidcollect
+
+import (
+	"errors"
+	"fmt"
+
+	builderspec "github.com/attestantio/go-builder-client/spec"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/flashbots/go-boost-utils/bls"
+	"github.com/flashbots/go-boost-utils/types"
+)
+
+// BidSignatureStatus is the result of verifying a getHeader bid
+type BidSignatureStatus string
+
+var (
+	BidSignatureValid          BidSignatureStatus = "valid"
+	BidSignatureInvalid        BidSignatureStatus = "invalid"         // signature doesn't match the bid pubkey
+	BidSignaturePubkeyMismatch BidSignatureStatus = "pubkey_mismatch" // valid signature, but the bid pubkey isn't the relay pubkey
+
+	ErrEmptyBid           = errors.New("empty bid")
+	ErrInvalidForkVersion = errors.New("invalid fork version")
+)
+
+// ComputeBuilderDomain returns the builder signing domain for the genesis fork version of a network (i.e. 0x00000000
+// for mainnet)
+func ComputeBuilderDomain(genesisForkVersion string) (types.Domain, error) {
+	b, err := hexutil.Decode(genesisForkVersion)
+	if err != nil || len(b) != len(types.ForkVersion{}) {
+		return types.Domain{}, fmt.Errorf("%w: %s", ErrInvalidForkVersion, genesisForkVersion)
+	}
+	return types.ComputeDomain(types.DomainTypeAppBuilder, types.ForkVersion(b), types.Root{}), nil
+}
+
+// VerifyGetHeaderBid verifies the BLS signature of a getHeader bid (with the builder domain of the network, see
+// ComputeBuilderDomain), and that it was signed with the pubkey of the relay. If relayPubkey is empty, only the
+// signature is checked.
+func VerifyGetHeaderBid(bid *builderspec.VersionedSignedBuilderBid, relayPubkey types.PublicKey, builderDomain types.Domain) (BidSignatureStatus, error) {
+	if bid == nil || bid.IsEmpty() {
+		return "", ErrEmptyBid
+	}
+
+	messageRoot, err := bid.MessageHashTreeRoot()
+	if err != nil {
+		return "", err
+	}
+	bidPubkey, err := bid.Builder()
+	if err != nil {
+		return "", err
+	}
+	signature, err := bid.Signature()
+	if err != nil {
+		return "", err
+	}
+
+	signingRoot, err := (&types.SigningData{Root: types.Root(messageRoot), Domain: builderDomain}).HashTreeRoot()
+	if err != nil {
+		return "", err
+	}
+	ok, err := bls.VerifySignatureBytes(signingRoot[:], signature[:], bidPubkey[:])
+	if err != nil || !ok {
+		return BidSignatureInvalid, nil //nolint:nilerr
+	}
+
+	if relayPubkey != (types.PublicKey{}) && types.PublicKey(bidPubkey) != relayPubkey {
+		return BidSignaturePubkeyMismatch, nil
+	}
+	return BidSignatureValid, nil
+}
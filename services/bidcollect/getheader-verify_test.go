@@ -0,0 +1,120 @@
+package bidcollect
+
+import (
+	"testing"
+	"time"
+
+	builderapideneb "github.com/attestantio/go-builder-client/api/deneb"
+	builderspec "github.com/attestantio/go-builder-client/spec"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/go-boost-utils/bls"
+	"github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/relayscan/common"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+// signedTestBid returns a deneb getHeader bid, signed with a new relay key (mainnet builder domain)
+func signedTestBid(t *testing.T) (*builderspec.VersionedSignedBuilderBid, types.PublicKey) {
+	t.Helper()
+	return signedTestBidForDomain(t, types.DomainBuilder)
+}
+
+func signedTestBidForDomain(t *testing.T, domain types.Domain) (*builderspec.VersionedSignedBuilderBid, types.PublicKey) {
+	t.Helper()
+	sk, pk, err := bls.GenerateNewKeypair()
+	require.NoError(t, err)
+	var pubkey types.PublicKey
+	require.NoError(t, pubkey.FromSlice(bls.PublicKeyToBytes(pk)))
+
+	message := &builderapideneb.BuilderBid{
+		Header: &deneb.ExecutionPayloadHeader{
+			BlockNumber:   20_000_000,
+			BlockHash:     phase0.Hash32{0x01},
+			ParentHash:    phase0.Hash32{0x02},
+			GasLimit:      30_000_000,
+			BaseFeePerGas: uint256.NewInt(7),
+			ExtraData:     []byte("builder"),
+		},
+		BlobKZGCommitments: []deneb.KZGCommitment{{0x03}},
+		Value:              uint256.NewInt(123456789),
+		Pubkey:             phase0.BLSPubKey(pubkey),
+	}
+	signature, err := types.SignMessage(message, domain, sk)
+	require.NoError(t, err)
+
+	return &builderspec.VersionedSignedBuilderBid{
+		Version: consensusspec.DataVersionDeneb,
+		Deneb:   &builderapideneb.SignedBuilderBid{Message: message, Signature: phase0.BLSSignature(signature)},
+	}, pubkey
+}
+
+func TestVerifyGetHeaderBid(t *testing.T) {
+	bid, relayPubkey := signedTestBid(t)
+
+	status, err := VerifyGetHeaderBid(bid, relayPubkey, types.DomainBuilder)
+	require.NoError(t, err)
+	require.Equal(t, BidSignatureValid, status)
+
+	// without relay pubkey, only the signature is checked
+	status, err = VerifyGetHeaderBid(bid, types.PublicKey{}, types.DomainBuilder)
+	require.NoError(t, err)
+	require.Equal(t, BidSignatureValid, status)
+
+	// signed by a different key than the relay's
+	status, err = VerifyGetHeaderBid(bid, types.PublicKey{0x01}, types.DomainBuilder)
+	require.NoError(t, err)
+	require.Equal(t, BidSignaturePubkeyMismatch, status)
+
+	// tampered value
+	bid.Deneb.Message.Value = uint256.NewInt(987654321)
+	status, err = VerifyGetHeaderBid(bid, relayPubkey, types.DomainBuilder)
+	require.NoError(t, err)
+	require.Equal(t, BidSignatureInvalid, status)
+
+	_, err = VerifyGetHeaderBid(&builderspec.VersionedSignedBuilderBid{Version: consensusspec.DataVersionDeneb}, relayPubkey, types.DomainBuilder)
+	require.ErrorIs(t, err, ErrEmptyBid)
+}
+
+func TestVerifyGetHeaderBidOtherNetwork(t *testing.T) {
+	domain, err := ComputeBuilderDomain(types.GenesisForkVersionMainnet)
+	require.NoError(t, err)
+	require.Equal(t, types.DomainBuilder, domain)
+
+	sepoliaDomain, err := ComputeBuilderDomain(types.GenesisForkVersionSepolia)
+	require.NoError(t, err)
+	bid, relayPubkey := signedTestBidForDomain(t, sepoliaDomain)
+
+	status, err := VerifyGetHeaderBid(bid, relayPubkey, sepoliaDomain)
+	require.NoError(t, err)
+	require.Equal(t, BidSignatureValid, status)
+
+	// not valid with the mainnet domain
+	status, err = VerifyGetHeaderBid(bid, relayPubkey, types.DomainBuilder)
+	require.NoError(t, err)
+	require.Equal(t, BidSignatureInvalid, status)
+
+	for _, invalid := range []string{"", "0x00", "0x0000000000", "mainnet"} {
+		_, err = ComputeBuilderDomain(invalid)
+		require.ErrorIs(t, err, ErrInvalidForkVersion)
+	}
+}
+
+func TestGetHeaderToCommonBid(t *testing.T) {
+	bid, _ := signedTestBid(t)
+	receivedAt := time.UnixMilli(1717156924123)
+	commonBid, err := GetHeaderToCommonBid(GetHeaderPollerBidsMsg{
+		Slot:       9328044,
+		Bid:        bid,
+		Relay:      common.MustNewRelayEntry("https://0xa1559ace749633b997cb3fdacffb890aeebdb0f5a3b6aaa7eeeaf1a38af0a8fe88b9e4b1f61f236d2e64d95733327a62@relay.ultrasound.money", false),
+		ReceivedAt: receivedAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(20_000_000), commonBid.BlockNumber)
+	require.Equal(t, "0x0100000000000000000000000000000000000000000000000000000000000000", commonBid.BlockHash)
+	require.Equal(t, "123456789", commonBid.Value)
+	require.Equal(t, "relay.ultrasound.money", commonBid.Relay)
+	require.Equal(t, receivedAt.UnixMilli(), commonBid.ReceivedAtMs)
+}
@@ -0,0 +1,99 @@
+package objectstore
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FilesystemStore keeps objects as files below a base directory. It's used for
+// local archives and for tests.
+type FilesystemStore struct {
+	baseDir string
+}
+
+func NewFilesystemStore(baseDir string) (*FilesystemStore, error) {
+	if err := os.MkdirAll(baseDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &FilesystemStore{baseDir: baseDir}, nil
+}
+
+func (s *FilesystemStore) String() string {
+	return "file://" + s.baseDir
+}
+
+func (s *FilesystemStore) path(key string) string {
+	return filepath.Join(s.baseDir, filepath.FromSlash(strings.TrimPrefix(key, "/")))
+}
+
+func (s *FilesystemStore) Upload(ctx context.Context, localPath, key string) error {
+	dst := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close() //nolint:errcheck
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, src); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *FilesystemStore) ListFolders(ctx context.Context, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(s.path(prefix))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	folders := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			folders = append(folders, entry.Name())
+		}
+	}
+	sort.Strings(folders)
+	return folders, nil
+}
+
+func (s *FilesystemStore) ListFiles(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	entries, err := os.ReadDir(s.path(prefix))
+	if os.IsNotExist(err) {
+		return []ObjectInfo{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	files := []ObjectInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, ObjectInfo{
+			Key:          strings.TrimSuffix(prefix, "/") + "/" + entry.Name(),
+			Size:         uint64(fi.Size()), //nolint:gosec
+			LastModified: fi.ModTime().UTC(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Key < files[j].Key })
+	return files, nil
+}
@@ -0,0 +1,75 @@
+// Package objectstore provides a minimal abstraction over object storage (S3, R2, local filesystem) for the bid archive
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+var ErrUnsupportedScheme = errors.New("unsupported object store scheme")
+
+// ObjectInfo describes a single object (file) in the store
+type ObjectInfo struct {
+	Key          string
+	Size         uint64
+	LastModified time.Time
+}
+
+// ObjectStore is implemented by all storage backends. Keys use forward slashes
+// (i.e. "ethereum/mainnet/2024-06/2024-06-01_all.csv.zip").
+type ObjectStore interface {
+	// String returns a human readable description of the store (used in logs)
+	String() string
+
+	// Upload stores the local file under the given key
+	Upload(ctx context.Context, localPath, key string) error
+
+	// ListFolders returns the names of the direct subfolders of prefix (without trailing slash)
+	ListFolders(ctx context.Context, prefix string) ([]string, error)
+
+	// ListFiles returns the objects directly inside prefix (not recursive)
+	ListFiles(ctx context.Context, prefix string) ([]ObjectInfo, error)
+}
+
+// New creates an object store from an URI:
+//
+//   - file:///mnt/data/bidarchive
+//   - s3://bucket-name (credentials from env: AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY)
+//   - s3://bucket-name?endpoint=<accountid>.r2.cloudflarestorage.com&profile=r2 (credentials from ~/.aws/credentials)
+func New(uri string) (ObjectStore, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "file":
+		return NewFilesystemStore(u.Path)
+	case "s3":
+		q := u.Query()
+		return NewS3Store(&S3StoreOpts{
+			Bucket:   u.Host,
+			Endpoint: q.Get("endpoint"),
+			Region:   q.Get("region"),
+			Profile:  q.Get("profile"),
+		})
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedScheme, u.Scheme)
+	}
+}
+
+// MustNewMany creates an object store for each URI, and panics on error
+func MustNewMany(uris []string) []ObjectStore {
+	stores := make([]ObjectStore, len(uris))
+	for i, uri := range uris {
+		store, err := New(uri)
+		if err != nil {
+			panic(fmt.Errorf("invalid object store %s: %w", uri, err))
+		}
+		stores[i] = store
+	}
+	return stores
+}
@@ -0,0 +1,96 @@
+package objectstore
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type S3StoreOpts struct {
+	Bucket   string
+	Endpoint string // empty for AWS S3, i.e. "<accountid>.r2.cloudflarestorage.com" for Cloudflare R2
+	Region   string
+	Profile  string // if set, credentials are loaded from the AWS shared credentials file
+}
+
+// S3Store uploads to and lists any S3-compatible storage (AWS S3, Cloudflare R2)
+type S3Store struct {
+	opts   *S3StoreOpts
+	client *minio.Client
+}
+
+func NewS3Store(opts *S3StoreOpts) (*S3Store, error) {
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+
+	creds := credentials.NewEnvAWS()
+	if opts.Profile != "" {
+		creds = credentials.NewFileAWSCredentials("", opts.Profile)
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{ //nolint:exhaustruct
+		Creds:  creds,
+		Secure: true,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Store{opts: opts, client: client}, nil
+}
+
+func (s *S3Store) String() string {
+	if s.opts.Endpoint != "" {
+		return "s3://" + s.opts.Bucket + " @ " + s.opts.Endpoint
+	}
+	return "s3://" + s.opts.Bucket
+}
+
+func (s *S3Store) Upload(ctx context.Context, localPath, key string) error {
+	_, err := s.client.FPutObject(ctx, s.opts.Bucket, strings.TrimPrefix(key, "/"), localPath, minio.PutObjectOptions{}) //nolint:exhaustruct
+	return err
+}
+
+func (s *S3Store) list(ctx context.Context, prefix string) <-chan minio.ObjectInfo {
+	prefix = strings.TrimPrefix(prefix, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return s.client.ListObjects(ctx, s.opts.Bucket, minio.ListObjectsOptions{Prefix: prefix}) //nolint:exhaustruct
+}
+
+func (s *S3Store) ListFolders(ctx context.Context, prefix string) ([]string, error) {
+	folders := []string{}
+	for obj := range s.list(ctx, prefix) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		if strings.HasSuffix(obj.Key, "/") {
+			folders = append(folders, path.Base(obj.Key))
+		}
+	}
+	return folders, nil
+}
+
+func (s *S3Store) ListFiles(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	files := []ObjectInfo{}
+	for obj := range s.list(ctx, prefix) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		if strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+		files = append(files, ObjectInfo{
+			Key:          obj.Key,
+			Size:         uint64(obj.Size), //nolint:gosec
+			LastModified: obj.LastModified.UTC(),
+		})
+	}
+	return files, nil
+}
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 const (
 	SourceTypeGetHeader        = 0
 	SourceTypeDataAPI          = 1
@@ -16,6 +18,13 @@ const (
 	BidCollectorInputChannelSize = 1000
 
 	RedisChannel = "bidcollect/bids"
+
+	// RedisDedupPrefix is the key prefix for bid dedup state shared between collector instances
+	RedisDedupPrefix = "bidcollect/dedup"
+	RedisDedupTTL    = 5 * time.Minute
+
+	// ArchiveKeyPrefix is the object store prefix for the daily bid archive files
+	ArchiveKeyPrefix = "ethereum/mainnet"
 )
 
 var (
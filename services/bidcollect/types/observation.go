@@ -0,0 +1,259 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BidObservationCSVFields are the columns of the observations output. An observation is
+// recorded for every sighting of a bid, not only the first one.
+var BidObservationCSVFields = []string{
+	"slot",
+	"block_hash",
+	"parent_hash",
+	"builder_pubkey",
+	"value",
+
+	"source_type",
+	"relay",
+	"received_at_ms",
+	"timestamp_ms",
+
+	"fork_version",
+	"num_blobs",
+}
+
+// BidObservation is a single sighting of a bid (by source type and relay)
+type BidObservation struct {
+	Slot          uint64 `json:"slot"`
+	BlockHash     string `json:"block_hash"`
+	ParentHash    string `json:"parent_hash"`
+	BuilderPubkey string `json:"builder_pubkey"`
+	Value         string `json:"value"`
+
+	SourceType   int    `json:"source_type"`
+	Relay        string `json:"relay"`
+	ReceivedAtMs int64  `json:"received_at_ms"`
+	TimestampMs  int64  `json:"timestamp_ms"`
+
+	// only for getHeader bids
+	ForkVersion string `json:"fork_version"`
+	NumBlobs    int    `json:"num_blobs"`
+}
+
+func NewBidObservation(bid *CommonBid) *BidObservation {
+	return &BidObservation{
+		Slot:          bid.Slot,
+		BlockHash:     bid.BlockHash,
+		ParentHash:    bid.ParentHash,
+		BuilderPubkey: bid.BuilderPubkey,
+		Value:         bid.Value,
+		SourceType:    bid.SourceType,
+		Relay:         bid.Relay,
+		ReceivedAtMs:  bid.ReceivedAtMs,
+		TimestampMs:   bid.TimestampMs,
+		ForkVersion:   bid.ForkVersion,
+		NumBlobs:      bid.NumBlobs,
+	}
+}
+
+// UniqueKey is the same key as CommonBid.UniqueKey
+func (obs *BidObservation) UniqueKey() string {
+	return fmt.Sprintf("%d-%s-%s-%s-%s", obs.Slot, obs.BlockHash, obs.ParentHash, obs.BuilderPubkey, obs.Value)
+}
+
+func (obs *BidObservation) ToCSVLine(separator string) string {
+	timestampMs := ""
+	if obs.TimestampMs > 0 {
+		timestampMs = fmt.Sprint(obs.TimestampMs)
+	}
+	numBlobs := ""
+	if obs.ForkVersion != "" {
+		numBlobs = fmt.Sprint(obs.NumBlobs)
+	}
+
+	return strings.Join([]string{
+		fmt.Sprint(obs.Slot),
+		obs.BlockHash,
+		obs.ParentHash,
+		obs.BuilderPubkey,
+		obs.Value,
+		fmt.Sprint(obs.SourceType),
+		obs.Relay,
+		fmt.Sprint(obs.ReceivedAtMs),
+		timestampMs,
+		obs.ForkVersion,
+		numBlobs,
+	}, separator)
+}
+
+func NewBidObservationFromCSVLine(line, separator string) (obs *BidObservation, err error) {
+	fields := strings.Split(line, separator)
+	if len(fields) != len(BidObservationCSVFields) {
+		return nil, fmt.Errorf("%w: expected %d fields, got %d", ErrInvalidCSVLine, len(BidObservationCSVFields), len(fields))
+	}
+
+	obs = &BidObservation{
+		BlockHash:     fields[1],
+		ParentHash:    fields[2],
+		BuilderPubkey: fields[3],
+		Value:         fields[4],
+		Relay:         fields[6],
+		ForkVersion:   fields[9],
+	}
+	obs.Slot, err = strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slot: %w", err)
+	}
+	obs.SourceType, err = strconv.Atoi(fields[5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid source_type: %w", err)
+	}
+	obs.ReceivedAtMs, err = strconv.ParseInt(fields[7], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid received_at_ms: %w", err)
+	}
+	if fields[8] != "" {
+		obs.TimestampMs, err = strconv.ParseInt(fields[8], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp_ms: %w", err)
+		}
+	}
+	if fields[10] != "" {
+		obs.NumBlobs, err = strconv.Atoi(fields[10])
+		if err != nil {
+			return nil, fmt.Errorf("invalid num_blobs: %w", err)
+		}
+	}
+	return obs, nil
+}
+
+// BidObservationStatsCSVFields are the columns of the derived per-bid observation stats
+var BidObservationStatsCSVFields = []string{
+	"slot",
+	"block_hash",
+	"parent_hash",
+	"builder_pubkey",
+	"value",
+
+	"num_observations",
+	"num_relays",
+	"num_source_types",
+	"first_seen_at_ms",
+	"first_seen_relay",
+	"first_seen_source_type",
+	"relay_spread_ms",
+	"relays",
+}
+
+// BidObservationStats are derived from all observations of a single bid
+type BidObservationStats struct {
+	Slot          uint64
+	BlockHash     string
+	ParentHash    string
+	BuilderPubkey string
+	Value         string
+
+	NumObservations     int
+	NumSourceTypes      int
+	FirstSeenAtMs       int64
+	FirstSeenRelay      string
+	FirstSeenSourceType int
+
+	// RelayFirstSeenAtMs is the first sighting per relay
+	RelayFirstSeenAtMs map[string]int64
+
+	// RelaySpreadMs is the time between the first sighting on the first and the last relay
+	RelaySpreadMs int64
+}
+
+// ComputeBidObservationStats groups observations by bid, and returns the stats ordered by first sighting
+func ComputeBidObservationStats(observations []*BidObservation) []*BidObservationStats {
+	statsByKey := make(map[string]*BidObservationStats)
+	sourceTypes := make(map[string]map[int]bool)
+	for _, obs := range observations {
+		key := obs.UniqueKey()
+		stats, ok := statsByKey[key]
+		if !ok {
+			stats = &BidObservationStats{
+				Slot:                obs.Slot,
+				BlockHash:           obs.BlockHash,
+				ParentHash:          obs.ParentHash,
+				BuilderPubkey:       obs.BuilderPubkey,
+				Value:               obs.Value,
+				FirstSeenAtMs:       obs.ReceivedAtMs,
+				FirstSeenRelay:      obs.Relay,
+				FirstSeenSourceType: obs.SourceType,
+				RelayFirstSeenAtMs:  make(map[string]int64),
+			}
+			statsByKey[key] = stats
+			sourceTypes[key] = make(map[int]bool)
+		}
+
+		stats.NumObservations++
+		sourceTypes[key][obs.SourceType] = true
+		if obs.ReceivedAtMs < stats.FirstSeenAtMs {
+			stats.FirstSeenAtMs = obs.ReceivedAtMs
+			stats.FirstSeenRelay = obs.Relay
+			stats.FirstSeenSourceType = obs.SourceType
+		}
+		if t, ok := stats.RelayFirstSeenAtMs[obs.Relay]; !ok || obs.ReceivedAtMs < t {
+			stats.RelayFirstSeenAtMs[obs.Relay] = obs.ReceivedAtMs
+		}
+	}
+
+	ret := make([]*BidObservationStats, 0, len(statsByKey))
+	for key, stats := range statsByKey {
+		stats.NumSourceTypes = len(sourceTypes[key])
+		for _, t := range stats.RelayFirstSeenAtMs {
+			if spread := t - stats.FirstSeenAtMs; spread > stats.RelaySpreadMs {
+				stats.RelaySpreadMs = spread
+			}
+		}
+		ret = append(ret, stats)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].FirstSeenAtMs == ret[j].FirstSeenAtMs {
+			return ret[i].BlockHash < ret[j].BlockHash
+		}
+		return ret[i].FirstSeenAtMs < ret[j].FirstSeenAtMs
+	})
+	return ret
+}
+
+// ToCSVLine encodes the stats, with the relays as space-separated "relay:offsetMs" entries (ordered by first sighting)
+func (stats *BidObservationStats) ToCSVLine(separator string) string {
+	relays := make([]string, 0, len(stats.RelayFirstSeenAtMs))
+	for relay := range stats.RelayFirstSeenAtMs {
+		relays = append(relays, relay)
+	}
+	sort.Slice(relays, func(i, j int) bool {
+		ti, tj := stats.RelayFirstSeenAtMs[relays[i]], stats.RelayFirstSeenAtMs[relays[j]]
+		if ti == tj {
+			return relays[i] < relays[j]
+		}
+		return ti < tj
+	})
+	for i, relay := range relays {
+		relays[i] = fmt.Sprintf("%s:%d", relay, stats.RelayFirstSeenAtMs[relay]-stats.FirstSeenAtMs)
+	}
+
+	return strings.Join([]string{
+		fmt.Sprint(stats.Slot),
+		stats.BlockHash,
+		stats.ParentHash,
+		stats.BuilderPubkey,
+		stats.Value,
+		fmt.Sprint(stats.NumObservations),
+		fmt.Sprint(len(stats.RelayFirstSeenAtMs)),
+		fmt.Sprint(stats.NumSourceTypes),
+		fmt.Sprint(stats.FirstSeenAtMs),
+		stats.FirstSeenRelay,
+		fmt.Sprint(stats.FirstSeenSourceType),
+		fmt.Sprint(stats.RelaySpreadMs),
+		strings.Join(relays, " "),
+	}, separator)
+}
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBidObservationCSVRoundtrip(t *testing.T) {
+	obs := &BidObservation{
+		Slot:          9328044,
+		BlockHash:     "0x1",
+		ParentHash:    "0x2",
+		BuilderPubkey: "0x3",
+		Value:         "123",
+		SourceType:    SourceTypeGetHeader,
+		Relay:         "relay.ultrasound.money",
+		ReceivedAtMs:  1717156924000,
+		ForkVersion:   "electra",
+		NumBlobs:      3,
+	}
+	for _, separator := range []string{",", "\t"} {
+		obs2, err := NewBidObservationFromCSVLine(obs.ToCSVLine(separator), separator)
+		require.NoError(t, err)
+		require.Equal(t, obs, obs2)
+	}
+
+	_, err := NewBidObservationFromCSVLine("1,2,3", ",")
+	require.ErrorIs(t, err, ErrInvalidCSVLine)
+}
+
+func TestComputeBidObservationStats(t *testing.T) {
+	obs := func(blockHash, relay string, sourceType int, receivedAtMs int64) *BidObservation {
+		return &BidObservation{Slot: 1, BlockHash: blockHash, Value: "10", Relay: relay, SourceType: sourceType, ReceivedAtMs: receivedAtMs}
+	}
+
+	stats := ComputeBidObservationStats([]*BidObservation{
+		obs("0x1", "relay-b", SourceTypeDataAPI, 1300),
+		obs("0x1", "relay-a", SourceTypeGetHeader, 1000),
+		obs("0x1", "relay-a", SourceTypeDataAPI, 1100),
+		obs("0x1", "relay-c", SourceTypeDataAPI, 1250),
+		obs("0x1", "relay-b", SourceTypeDataAPI, 1200),
+		obs("0x2", "relay-a", SourceTypeDataAPI, 900),
+	})
+	require.Len(t, stats, 2)
+
+	// ordered by first sighting
+	require.Equal(t, "0x2", stats[0].BlockHash)
+	require.Equal(t, int64(0), stats[0].RelaySpreadMs)
+
+	s := stats[1]
+	require.Equal(t, 5, s.NumObservations)
+	require.Equal(t, 2, s.NumSourceTypes)
+	require.Equal(t, int64(1000), s.FirstSeenAtMs)
+	require.Equal(t, "relay-a", s.FirstSeenRelay)
+	require.Equal(t, SourceTypeGetHeader, s.FirstSeenSourceType)
+	require.Equal(t, int64(250), s.RelaySpreadMs)
+	require.Equal(t, map[string]int64{"relay-a": 1000, "relay-b": 1200, "relay-c": 1250}, s.RelayFirstSeenAtMs)
+	require.Equal(t, "1,0x1,,,10,5,3,2,1000,relay-a,0,250,relay-a:0 relay-b:200 relay-c:250", s.ToCSVLine(","))
+}
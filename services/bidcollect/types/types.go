@@ -2,13 +2,17 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
+	"strconv"
 	"strings"
 
 	"github.com/flashbots/relayscan/common"
 )
 
+var ErrInvalidCSVLine = errors.New("invalid CSV line")
+
 var CommonBidCSVFields = []string{
 	"source_type",
 	"received_at_ms",
@@ -28,8 +32,15 @@ var CommonBidCSVFields = []string{
 	"proposer_pubkey",
 	"proposer_fee_recipient",
 	"optimistic_submission",
+
+	"fork_version",
+	"num_blobs",
 }
 
+// numLegacyCommonBidCSVFields is the number of fields before fork_version and num_blobs were added, files in the old
+// format can still be parsed
+const numLegacyCommonBidCSVFields = 15
+
 type CommonBid struct {
 	// Collector-internal fields
 	SourceType   int   `json:"source_type"`
@@ -54,6 +65,10 @@ type CommonBid struct {
 	ProposerPubkey       string `json:"proposer_pubkey"`
 	ProposerFeeRecipient string `json:"proposer_fee_recipient"`
 	OptimisticSubmission bool   `json:"optimistic_submission"`
+
+	// GetHeader
+	ForkVersion string `json:"fork_version,omitempty"`
+	NumBlobs    int    `json:"num_blobs,omitempty"`
 }
 
 func (bid *CommonBid) UniqueKey() string {
@@ -85,6 +100,12 @@ func (bid *CommonBid) ToCSVFields() []string {
 		bidIsOptimisticString = boolToString(bid.OptimisticSubmission)
 	}
 
+	// Number of blobs is only known for getHeader bids, which have the fork version
+	numBlobsString := ""
+	if bid.ForkVersion != "" {
+		numBlobsString = fmt.Sprint(bid.NumBlobs)
+	}
+
 	return []string{
 		// Collector-internal fields
 		fmt.Sprint(bid.SourceType),
@@ -111,6 +132,10 @@ func (bid *CommonBid) ToCSVFields() []string {
 		bid.ProposerPubkey,
 		bid.ProposerFeeRecipient,
 		bidIsOptimisticString,
+
+		// GetHeader
+		bid.ForkVersion,
+		numBlobsString,
 	}
 }
 
@@ -118,6 +143,64 @@ func (bid *CommonBid) ToCSVLine(separator string) string {
 	return strings.Join(bid.ToCSVFields(), separator)
 }
 
+// NewCommonBidFromCSVFields parses a bid from CSV fields (in the order of CommonBidCSVFields, fork_version and
+// num_blobs can be missing in older files)
+func NewCommonBidFromCSVFields(fields []string) (bid *CommonBid, err error) {
+	if len(fields) != len(CommonBidCSVFields) && len(fields) != numLegacyCommonBidCSVFields {
+		return nil, fmt.Errorf("%w: expected %d fields, got %d", ErrInvalidCSVLine, len(CommonBidCSVFields), len(fields))
+	}
+
+	bid = &CommonBid{
+		Value:                fields[5],
+		BlockHash:            fields[6],
+		ParentHash:           fields[7],
+		BuilderPubkey:        fields[8],
+		BlockFeeRecipient:    fields[10],
+		Relay:                fields[11],
+		ProposerPubkey:       fields[12],
+		ProposerFeeRecipient: fields[13],
+		OptimisticSubmission: fields[14] == "true",
+	}
+
+	bid.SourceType, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid source_type: %w", err)
+	}
+	bid.ReceivedAtMs, err = strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid received_at_ms: %w", err)
+	}
+	if fields[2] != "" {
+		bid.TimestampMs, err = strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp_ms: %w", err)
+		}
+	}
+	bid.Slot, err = strconv.ParseUint(fields[3], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slot: %w", err)
+	}
+	bid.BlockNumber, err = strconv.ParseUint(fields[9], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block_number: %w", err)
+	}
+	if len(fields) > numLegacyCommonBidCSVFields {
+		bid.ForkVersion = fields[15]
+		if fields[16] != "" {
+			bid.NumBlobs, err = strconv.Atoi(fields[16])
+			if err != nil {
+				return nil, fmt.Errorf("invalid num_blobs: %w", err)
+			}
+		}
+	}
+	return bid, nil
+}
+
+// NewCommonBidFromCSVLine parses a single CSV/TSV line into a bid
+func NewCommonBidFromCSVLine(line, separator string) (*CommonBid, error) {
+	return NewCommonBidFromCSVFields(strings.Split(line, separator))
+}
+
 func boolToString(b bool) string {
 	if b {
 		return "true"
@@ -14,8 +14,9 @@ func TestSourceTypes(t *testing.T) {
 }
 
 func TestCSVHasNotChanged(t *testing.T) {
-	// The specific field ordering is used in many places throughout the ecosystem and must not be changed.
-	expectedResult := "source_type,received_at_ms,timestamp_ms,slot,slot_t_ms,value,block_hash,parent_hash,builder_pubkey,block_number,block_fee_recipient,relay,proposer_pubkey,proposer_fee_recipient,optimistic_submission"
+	// The specific field ordering is used in many places throughout the ecosystem and must not be changed (new fields
+	// are only appended).
+	expectedResult := "source_type,received_at_ms,timestamp_ms,slot,slot_t_ms,value,block_hash,parent_hash,builder_pubkey,block_number,block_fee_recipient,relay,proposer_pubkey,proposer_fee_recipient,optimistic_submission,fork_version,num_blobs"
 	currentResult := strings.Join(CommonBidCSVFields, ",")
 	require.Equal(t, expectedResult, currentResult)
 
@@ -36,12 +37,60 @@ func TestCSVHasNotChanged(t *testing.T) {
 		OptimisticSubmission: true,
 	}
 	asCSV := bid.ToCSVLine(",")
-	expected := "0,1,2,3,-1606824058998,8,5,6,7,4,9,10,11,12,"
+	expected := "0,1,2,3,-1606824058998,8,5,6,7,4,9,10,11,12,,,"
 	require.Equal(t, expected, asCSV)
 
 	// When source type is data-api, then optimistic field is included
 	bid.SourceType = SourceTypeDataAPI
 	asCSV = bid.ToCSVLine(",")
-	expected = "1,1,2,3,-1606824058998,8,5,6,7,4,9,10,11,12,true"
+	expected = "1,1,2,3,-1606824058998,8,5,6,7,4,9,10,11,12,true,,"
 	require.Equal(t, expected, asCSV)
+
+	// GetHeader bids include the fork version and number of blobs
+	bid.SourceType = SourceTypeGetHeader
+	bid.ForkVersion = "electra"
+	bid.NumBlobs = 0
+	asCSV = bid.ToCSVLine(",")
+	expected = "0,1,2,3,-1606824058998,8,5,6,7,4,9,10,11,12,,electra,0"
+	require.Equal(t, expected, asCSV)
+}
+
+func TestCSVRoundtrip(t *testing.T) {
+	bid := &CommonBid{
+		SourceType:           SourceTypeDataAPI,
+		ReceivedAtMs:         1717156924300,
+		TimestampMs:          1717156924272,
+		Slot:                 9194409,
+		BlockNumber:          19989686,
+		BlockHash:            "0x2c02",
+		ParentHash:           "0x4d06",
+		BuilderPubkey:        "0xa03b",
+		Value:                "55539751698389157",
+		Relay:                "relay.ultrasound.money",
+		ProposerPubkey:       "0x8e01",
+		ProposerFeeRecipient: "0x9b4e",
+		OptimisticSubmission: true,
+	}
+
+	for _, sep := range []string{",", "\t"} {
+		parsed, err := NewCommonBidFromCSVLine(bid.ToCSVLine(sep), sep)
+		require.NoError(t, err)
+		require.Equal(t, bid, parsed)
+	}
+
+	// Missing timestamp is allowed (i.e. getHeader bids)
+	bid = &CommonBid{SourceType: SourceTypeGetHeader, ReceivedAtMs: 1, Slot: 2, BlockNumber: 3, Value: "4", ForkVersion: "deneb", NumBlobs: 6}
+	parsed, err := NewCommonBidFromCSVLine(bid.ToCSVLine(","), ",")
+	require.NoError(t, err)
+	require.Equal(t, bid, parsed)
+
+	// Lines without fork_version and num_blobs (older files)
+	parsed, err = NewCommonBidFromCSVLine("1,1,2,3,-1606824058998,8,5,6,7,4,9,10,11,12,true", ",")
+	require.NoError(t, err)
+	require.Equal(t, "12", parsed.ProposerFeeRecipient)
+	require.Empty(t, parsed.ForkVersion)
+
+	// Wrong number of fields
+	_, err = NewCommonBidFromCSVLine("1,2,3", ",")
+	require.ErrorIs(t, err, ErrInvalidCSVLine)
 }
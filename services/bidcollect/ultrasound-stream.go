@@ -20,6 +20,7 @@ type UltrasoundStreamBidsMsg struct {
 type UltrasoundStreamOpts struct {
 	Log  *logrus.Entry
 	BidC chan UltrasoundStreamBidsMsg
+	URL  string // optional, defaults to types.UltrasoundStreamDefaultURL
 }
 
 type UltrasoundStreamConnection struct {
@@ -30,9 +31,13 @@ type UltrasoundStreamConnection struct {
 }
 
 func NewUltrasoundStreamConnection(opts UltrasoundStreamOpts) *UltrasoundStreamConnection {
+	if opts.URL == "" {
+		opts.URL = types.UltrasoundStreamDefaultURL
+	}
+
 	return &UltrasoundStreamConnection{
 		log:        opts.Log,
-		url:        types.UltrasoundStreamDefaultURL,
+		url:        opts.URL,
 		bidC:       opts.BidC,
 		backoffSec: types.InitialBackoffSec,
 	}
@@ -6,37 +6,38 @@ package website
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
-	"regexp"
-	"strconv"
 	"strings"
 
+	"github.com/flashbots/relayscan/services/bidcollect/objectstore"
+	"github.com/flashbots/relayscan/services/bidcollect/types"
 	"github.com/sirupsen/logrus"
 	"github.com/tdewolff/minify"
 	"github.com/tdewolff/minify/css"
 	"github.com/tdewolff/minify/html"
 )
 
-func BuildProdWebsite(log *logrus.Entry, outDir string, upload bool) {
+func BuildProdWebsite(log *logrus.Entry, store objectstore.ObjectStore, outDir string, upload bool) {
 	log.Infof("Creating build server in %s", outDir)
 	err := os.MkdirAll(outDir, os.ModePerm)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	dir := "ethereum/mainnet/"
+	dir := types.ArchiveKeyPrefix + "/"
 
 	// Setup minifier
 	minifier := minify.New()
 	minifier.AddFunc("text/html", html.Minify)
 	minifier.AddFunc("text/css", css.Minify)
 
-	// Load month folders from S3
-	log.Infof("Getting folders from S3 for %s ...", dir)
-	months, err := getFoldersFromS3(dir)
+	// Load month folders from the object store
+	log.Infof("Getting folders from %s for %s ...", store.String(), dir)
+	months, err := getFolders(store, dir)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -81,9 +82,9 @@ func BuildProdWebsite(log *logrus.Entry, outDir string, upload bool) {
 
 	// build files pages
 	for _, month := range months {
-		dir := "ethereum/mainnet/" + month + "/"
-		log.Infof("Getting files from S3 for %s ...", dir)
-		files, err := getFilesFromS3(dir)
+		dir := types.ArchiveKeyPrefix + "/" + month + "/"
+		log.Infof("Getting files from %s for %s ...", store.String(), dir)
+		files, err := getFiles(store, dir)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -132,80 +133,52 @@ func BuildProdWebsite(log *logrus.Entry, outDir string, upload bool) {
 	}
 
 	if upload {
-		log.Info("Uploading to S3 ...")
-		// for _, file := range toUpload {
-		// 	fmt.Printf("- %s -> %s\n", file.from, file.to)
-		// }
-
+		log.Infof("Uploading to %s ...", store.String())
 		for _, file := range toUpload {
-			app := "./scripts/bidcollect/s3/upload-file-to-r2.sh"
-			cmd := exec.Command(app, file.from, file.to) //nolint:gosec
-			stdout, err := cmd.Output()
+			key := file.to + "index.html"
+			log.Infof("- %s -> %s", file.from, key)
+			err = store.Upload(context.Background(), file.from, key)
 			if err != nil {
 				log.Fatal(err)
 			}
-			fmt.Println(string(stdout))
 		}
 	}
 }
 
-func getFoldersFromS3(dir string) ([]string, error) {
+func getFolders(store objectstore.ObjectStore, dir string) ([]string, error) {
 	folders := []string{}
-
-	app := "./scripts/bidcollect/s3/get-folders.sh"
-	cmd := exec.Command(app, dir)
-	stdout, err := cmd.Output()
+	entries, err := store.ListFolders(context.Background(), dir)
 	if err != nil {
 		return folders, err
 	}
-
-	// Print the output
-	lines := strings.Split(string(stdout), "\n")
-	for _, line := range lines {
-		if line != "" && strings.HasPrefix(line, "20") {
-			folders = append(folders, strings.TrimSuffix(line, "/"))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry, "20") {
+			folders = append(folders, entry)
 		}
 	}
 	return folders, nil
 }
 
-func getFilesFromS3(month string) ([]FileEntry, error) {
+func getFiles(store objectstore.ObjectStore, dir string) ([]FileEntry, error) {
 	files := []FileEntry{}
-
-	app := "./scripts/bidcollect/s3/get-files.sh"
-	cmd := exec.Command(app, month)
-	stdout, err := cmd.Output()
+	entries, err := store.ListFiles(context.Background(), dir)
 	if err != nil {
 		return files, err
 	}
 
-	space := regexp.MustCompile(`\s+`)
-	lines := strings.Split(string(stdout), "\n")
-	for _, line := range lines {
-		if line != "" {
-			line = space.ReplaceAllString(line, " ")
-			parts := strings.Split(line, " ")
-
-			// parts[2] is the size
-			size, err := strconv.ParseUint(parts[2], 10, 64)
-			if err != nil {
-				return files, err
-			}
-
-			filename := parts[3]
-
-			if filename == "index.html" {
-				continue
-			} else if strings.HasSuffix(filename, ".csv.gz") {
-				continue
-			}
-
-			files = append(files, FileEntry{
-				Filename: filename,
-				Size:     size,
-				Modified: parts[1] + " " + parts[0],
-			})
+	for _, entry := range entries {
+		filename := path.Base(entry.Key)
+		if filename == "index.html" {
+			continue
+		} else if strings.HasSuffix(filename, ".csv.gz") {
+			continue
 		}
+
+		files = append(files, FileEntry{
+			Filename: filename,
+			Size:     entry.Size,
+			Modified: entry.LastModified.Format("15:04:05 2006-01-02"),
+		})
 	}
 	return files, nil
 }
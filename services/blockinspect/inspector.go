@@ -0,0 +1,288 @@
+// Package blockinspect combines the relay data, the collected bids and the on-chain data of a slot or block into a single report
+package blockinspect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	"github.com/sirupsen/logrus"
+)
+
+var errMissingSlotOrHash = errors.New("slot or block hash required")
+
+// DB is the database access needed by the inspector (i.e. database.DatabaseService)
+type DB interface {
+	GetDeliveredPayloadsForSlot(slot uint64) ([]*database.DataAPIPayloadDeliveredEntry, error)
+	GetDeliveredPayloadsForBlockHash(blockHash string) ([]*database.DataAPIPayloadDeliveredEntry, error)
+	GetSignedBuilderBidsForSlot(slot uint64) ([]*database.SignedBuilderBidEntry, error)
+	GetDataAPIBidsForSlot(slot uint64) ([]*database.DataAPIBuilderBidEntry, error)
+}
+
+// EthClient is the execution client access needed by the inspector (i.e. common.EthNode)
+type EthClient interface {
+	BlockByHash(ctx context.Context, hash ethcommon.Hash) (*types.Block, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BalancesAt(ctx context.Context, queries []common.BalanceQuery) ([]*big.Int, error)
+}
+
+type InspectorOpts struct {
+	Log *logrus.Entry
+	DB  DB
+	Eth EthClient
+}
+
+type Inspector struct {
+	log *logrus.Entry
+	db  DB
+	eth EthClient
+}
+
+func NewInspector(opts *InspectorOpts) *Inspector {
+	return &Inspector{
+		log: opts.Log,
+		db:  opts.DB,
+		eth: opts.Eth,
+	}
+}
+
+// Inspect creates the report for a slot, or for a block hash if slot is 0. If a slot is given, the inspected block is the
+// one the relays claim to have delivered, because the execution client doesn't know about slots.
+func (i *Inspector) Inspect(ctx context.Context, slot uint64, blockHash string) (*Report, error) {
+	if slot == 0 && blockHash == "" {
+		return nil, errMissingSlotOrHash
+	}
+
+	report := &Report{Slot: slot, BlockHash: strings.ToLower(blockHash)}
+
+	// Delivered payloads, by slot or block hash
+	var err error
+	var entries []*database.DataAPIPayloadDeliveredEntry
+	if slot > 0 {
+		entries, err = i.db.GetDeliveredPayloadsForSlot(slot)
+	} else {
+		entries, err = i.db.GetDeliveredPayloadsForBlockHash(report.BlockHash)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivered payloads: %w", err)
+	}
+	if report.Slot == 0 && len(entries) > 0 {
+		report.Slot = entries[0].Slot
+	}
+	if report.BlockHash == "" {
+		report.BlockHash = mostClaimedBlockHash(entries)
+	}
+	report.addDeliveredPayloads(entries)
+
+	// Bids collected from the relays (getHeader and data API)
+	if report.Slot > 0 {
+		bids, err := i.db.GetSignedBuilderBidsForSlot(report.Slot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bids: %w", err)
+		}
+		dataAPIBids, err := i.db.GetDataAPIBidsForSlot(report.Slot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get data API bids: %w", err)
+		}
+		report.addBids(bids, dataAPIBids)
+		if len(report.Bids) == 0 {
+			report.warn("no collected bids for slot %d (getHeader bids are only stored with bidcollect --save-signed-bids)", report.Slot)
+		}
+	}
+
+	if report.BlockHash == "" {
+		report.warn("no relay delivered a payload for slot %d, the block is unknown (use the block hash instead)", report.Slot)
+		return report, nil
+	}
+
+	err = i.addOnChainData(ctx, report)
+	return report, err
+}
+
+// addOnChainData adds the block, the proposer payment, the coinbase balance diff and the top-level coinbase transfers
+func (i *Inspector) addOnChainData(ctx context.Context, report *Report) error {
+	block, err := i.eth.BlockByHash(ctx, ethcommon.HexToHash(report.BlockHash))
+	if errors.Is(err, ethereum.NotFound) {
+		report.warn("block %s not found on-chain", report.BlockHash)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get block %s: %w", report.BlockHash, err)
+	}
+	report.Block = newBlockInfo(block)
+
+	header, err := i.eth.HeaderByNumber(ctx, block.Number())
+	if err != nil {
+		return fmt.Errorf("failed to get block %d by number: %w", block.NumberU64(), err)
+	}
+	report.Block.Canonical = header.Hash() == block.Hash()
+	if !report.Block.Canonical {
+		report.warn("block %d is not canonical (canonical hash: %s), the slot was probably missed", block.NumberU64(), header.Hash().Hex())
+	}
+
+	// Balance diffs of the coinbase and the proposer fee recipient in a single batch
+	feeRecipient := report.feeRecipient()
+	addresses := []ethcommon.Address{block.Coinbase()}
+	if feeRecipient != nil && *feeRecipient != block.Coinbase() {
+		addresses = append(addresses, *feeRecipient)
+	}
+	queries := []common.BalanceQuery{}
+	blockNumberMinusOne := new(big.Int).Sub(block.Number(), big.NewInt(1))
+	for _, address := range addresses {
+		queries = append(queries, common.BalanceQuery{Address: address, BlockNumber: blockNumberMinusOne}, common.BalanceQuery{Address: address, BlockNumber: block.Number()})
+	}
+	balances, err := i.eth.BalancesAt(ctx, queries)
+	if err != nil {
+		return fmt.Errorf("failed to get balance diffs: %w", err)
+	}
+	balanceDiffs := make(map[ethcommon.Address]*big.Int)
+	for j := 0; j < len(queries); j += 2 {
+		balanceDiffs[queries[j].Address] = new(big.Int).Sub(balances[j+1], balances[j])
+	}
+
+	report.CoinbaseTxTransfers = coinbaseTxTransfers(block)
+	report.Builder = newBuilderBalance(block, balanceDiffs[block.Coinbase()])
+	if feeRecipient != nil {
+		report.ProposerPayment = newProposerPayment(block, *feeRecipient, balanceDiffs[*feeRecipient], report.claimedValue())
+		if !report.ProposerPayment.Ok {
+			report.warn("proposer payment differs from the claimed value by %s ETH", report.ProposerPayment.DiffEth)
+		}
+	}
+	return nil
+}
+
+// mostClaimedBlockHash returns the block hash delivered by most relays (there's usually only one)
+func mostClaimedBlockHash(entries []*database.DataAPIPayloadDeliveredEntry) string {
+	counts := make(map[string]int)
+	bestHash := ""
+	for _, entry := range entries {
+		hash := strings.ToLower(entry.BlockHash)
+		counts[hash]++
+		if counts[hash] > counts[bestHash] || (counts[hash] == counts[bestHash] && hash < bestHash) {
+			bestHash = hash
+		}
+	}
+	return bestHash
+}
+
+func newBlockInfo(block *types.Block) *BlockInfo {
+	info := &BlockInfo{
+		Number:     block.NumberU64(),
+		Hash:       block.Hash().Hex(),
+		ParentHash: block.ParentHash().Hex(),
+		Timestamp:  time.Unix(int64(block.Time()), 0).UTC(), //nolint:gosec
+		ExtraData:  database.ExtraDataToUtf8Str(block.Extra()),
+		Coinbase:   block.Coinbase().Hex(),
+		GasUsed:    block.GasUsed(),
+		GasLimit:   block.GasLimit(),
+		NumTx:      len(block.Transactions()),
+	}
+	for _, tx := range block.Transactions() {
+		if tx.Type() == types.BlobTxType {
+			info.NumBlobTxs++
+			info.NumBlobs += len(tx.BlobHashes())
+		}
+	}
+	return info
+}
+
+// newProposerPayment checks the payment to the proposer fee recipient, the same way as check-payload-value
+func newProposerPayment(block *types.Block, feeRecipient ethcommon.Address, balanceDiff, claimedValue *big.Int) *ProposerPayment {
+	// without a claim there's nothing to compare with, but the payment tx is still reported
+	claimed := claimedValue
+	if claimed == nil {
+		claimed = balanceDiff
+	}
+	check := common.CheckProposerPayment(block, feeRecipient, balanceDiff, claimed)
+
+	payment := &ProposerPayment{
+		FeeRecipient:       feeRecipient.Hex(),
+		CoinbaseIsProposer: feeRecipient == block.Coinbase(),
+		BalanceDiffWei:     balanceDiff.String(),
+		BalanceDiffEth:     common.WeiToEthStr(balanceDiff),
+		Method:             check.Method,
+		Ok:                 check.Ok(),
+	}
+	if tx := check.PaymentTx; tx != nil {
+		payment.PaymentTxHash = tx.Hash().Hex()
+		payment.PaymentTxFrom = txSender(tx)
+		payment.PaymentTxValueWei = tx.Value().String()
+		payment.PaymentTxValueEth = common.WeiToEthStr(tx.Value())
+	}
+	if claimedValue != nil {
+		payment.ClaimedWei = claimedValue.String()
+		payment.DiffWei = check.DiffWei.String()
+		payment.DiffEth = common.WeiToEthStr(check.DiffWei)
+	}
+	return payment
+}
+
+// newBuilderBalance returns the coinbase balance diff, adjusted for transfers from the coinbase to builder-owned addresses
+func newBuilderBalance(block *types.Block, balanceDiff *big.Int) *BuilderBalance {
+	profit := common.BuilderProfit(block, balanceDiff)
+	return &BuilderBalance{
+		Coinbase:       block.Coinbase().Hex(),
+		BalanceDiffWei: balanceDiff.String(),
+		BalanceDiffEth: common.WeiToEthStr(balanceDiff),
+		ProfitWei:      profit.String(),
+		ProfitEth:      common.WeiToEthStr(profit),
+	}
+}
+
+// coinbaseTxTransfers returns the transactions with value sent to or from the coinbase. Transfers by internal calls
+// aren't visible without tracing the block.
+func coinbaseTxTransfers(block *types.Block) (transfers []*Transfer) {
+	coinbase := block.Coinbase().Hex()
+	for index, tx := range block.Transactions() {
+		if tx.Value().Sign() == 0 {
+			continue
+		}
+		from := txSender(tx)
+		to := ""
+		if tx.To() != nil {
+			to = tx.To().Hex()
+		}
+
+		direction := ""
+		if to == coinbase {
+			direction = TransferIn
+		} else if from == coinbase {
+			direction = TransferOut
+		} else {
+			continue
+		}
+		transfers = append(transfers, &Transfer{
+			Index:     index,
+			TxHash:    tx.Hash().Hex(),
+			Direction: direction,
+			From:      from,
+			To:        to,
+			ValueWei:  tx.Value().String(),
+			ValueEth:  common.WeiToEthStr(tx.Value()),
+		})
+	}
+	return transfers
+}
+
+// txSender returns the hex address of the sender, or an empty string if it can't be recovered
+func txSender(tx *types.Transaction) string {
+	from, err := common.TxSender(tx)
+	if err != nil {
+		return ""
+	}
+	return from.Hex()
+}
+
+func sortBidsByValue(bids []*Bid) {
+	sort.SliceStable(bids, func(i, j int) bool {
+		return common.StrToBigInt(bids[i].ValueWei).Cmp(common.StrToBigInt(bids[j].ValueWei)) > 0
+	})
+}
@@ -0,0 +1,201 @@
+package blockinspect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	"github.com/flashbots/relayscan/testutil/ethchain"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDB struct {
+	payloads    []*database.DataAPIPayloadDeliveredEntry
+	bids        []*database.SignedBuilderBidEntry
+	dataAPIBids []*database.DataAPIBuilderBidEntry
+}
+
+func (db *fakeDB) GetDeliveredPayloadsForSlot(slot uint64) (res []*database.DataAPIPayloadDeliveredEntry, err error) {
+	for _, entry := range db.payloads {
+		if entry.Slot == slot {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+func (db *fakeDB) GetDeliveredPayloadsForBlockHash(blockHash string) (res []*database.DataAPIPayloadDeliveredEntry, err error) {
+	for _, entry := range db.payloads {
+		if entry.BlockHash == blockHash {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+func (db *fakeDB) GetSignedBuilderBidsForSlot(slot uint64) (res []*database.SignedBuilderBidEntry, err error) {
+	for _, entry := range db.bids {
+		if entry.Slot == slot {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+func (db *fakeDB) GetDataAPIBidsForSlot(slot uint64) (res []*database.DataAPIBuilderBidEntry, err error) {
+	for _, entry := range db.dataAPIBids {
+		if entry.Slot == slot {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+func TestInspect(t *testing.T) {
+	builderKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	userKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	builder := crypto.PubkeyToAddress(builderKey.PublicKey)
+	user := crypto.PubkeyToAddress(userKey.PublicKey)
+	proposer := ethcommon.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	const slot = 9_000_000
+	const blockNumber = 100
+
+	// user pays the coinbase, blob tx, builder pays the proposer in the last tx
+	block := ethchain.NewBlock(blockNumber, ethcommon.Hash{}, builder, []byte("builder0x69"),
+		ethchain.NewTransfer(userKey, 0, builder, big.NewInt(500)),
+		ethchain.NewBlobTx(userKey, 1, user, 3),
+		ethchain.NewTransfer(builderKey, 0, proposer, big.NewInt(1000)),
+	)
+	otherBlock := ethchain.NewBlock(blockNumber, ethcommon.HexToHash("0x01"), builder, nil)
+	blockHash := strings.ToLower(block.Hash().Hex())
+
+	chain := ethchain.New()
+	chain.AddBlock(block)
+	chain.SetBalance(proposer, blockNumber, big.NewInt(1000))
+	chain.SetBalance(builder, blockNumber-1, big.NewInt(10_000))
+	chain.SetBalance(builder, blockNumber, big.NewInt(10_300))
+
+	db := &fakeDB{
+		payloads: []*database.DataAPIPayloadDeliveredEntry{
+			{Relay: "relay-a", Slot: slot, BlockHash: blockHash, ProposerFeeRecipient: proposer.Hex(), ValueClaimedWei: "1000"},
+			{Relay: "relay-b", Slot: slot, BlockHash: blockHash, ProposerFeeRecipient: proposer.Hex(), ValueClaimedWei: "1000"},
+		},
+		bids: []*database.SignedBuilderBidEntry{
+			{Relay: "relay-a", Slot: slot, BlockHash: otherBlock.Hash().Hex(), Value: "900", FeeRecipient: proposer.Hex()},
+			{Relay: "relay-a", Slot: slot, BlockHash: blockHash, Value: "1000", FeeRecipient: proposer.Hex(), NumBlobs: 3},
+		},
+		dataAPIBids: []*database.DataAPIBuilderBidEntry{
+			{Relay: "relay-b", Slot: slot, BlockHash: otherBlock.Hash().Hex(), Value: "950", BuilderPubkey: "0xabcd", ProposerFeeRecipient: proposer.Hex()},
+		},
+	}
+	inspector := NewInspector(&InspectorOpts{Log: common.Logger, DB: db, Eth: chain})
+
+	t.Run("by slot", func(t *testing.T) {
+		report, err := inspector.Inspect(context.Background(), slot, "")
+		require.NoError(t, err)
+		require.Equal(t, blockHash, report.BlockHash)
+		require.True(t, report.ClaimsConsistent)
+		require.Len(t, report.DeliveredPayloads, 2)
+		require.Empty(t, report.Warnings)
+
+		require.Len(t, report.Bids, 3)
+		require.Equal(t, "1000", report.Bids[0].ValueWei)
+		require.Equal(t, BidSourceGetHeader, report.Bids[0].Source)
+		require.True(t, report.Bids[0].Delivered)
+		require.Equal(t, BidSourceDataAPI, report.Bids[1].Source)
+		require.Equal(t, "0xabcd", report.Bids[1].BuilderPubkey)
+		require.False(t, report.Bids[1].Delivered)
+		require.False(t, report.Bids[2].Delivered)
+
+		require.NotNil(t, report.Block)
+		require.True(t, report.Block.Canonical)
+		require.Equal(t, "builder0x69", report.Block.ExtraData)
+		require.Equal(t, 3, report.Block.NumTx)
+		require.Equal(t, 1, report.Block.NumBlobTxs)
+		require.Equal(t, 3, report.Block.NumBlobs)
+
+		require.NotNil(t, report.ProposerPayment)
+		require.True(t, report.ProposerPayment.Ok)
+		require.Equal(t, "balanceDiff", report.ProposerPayment.Method)
+		require.Equal(t, "1000", report.ProposerPayment.BalanceDiffWei)
+		require.Equal(t, builder.Hex(), report.ProposerPayment.PaymentTxFrom)
+		require.False(t, report.ProposerPayment.CoinbaseIsProposer)
+
+		require.Equal(t, "300", report.Builder.BalanceDiffWei)
+		require.Len(t, report.CoinbaseTxTransfers, 2)
+		require.Equal(t, TransferIn, report.CoinbaseTxTransfers[0].Direction)
+		require.Equal(t, "500", report.CoinbaseTxTransfers[0].ValueWei)
+		require.Equal(t, TransferOut, report.CoinbaseTxTransfers[1].Direction)
+		require.Equal(t, 2, report.CoinbaseTxTransfers[1].Index)
+
+		// both output formats work
+		var buf bytes.Buffer
+		report.Print(&buf)
+		require.Contains(t, buf.String(), "builder0x69")
+		_, err = json.Marshal(report)
+		require.NoError(t, err)
+	})
+
+	t.Run("by hash", func(t *testing.T) {
+		report, err := inspector.Inspect(context.Background(), 0, block.Hash().Hex())
+		require.NoError(t, err)
+		require.Equal(t, uint64(slot), report.Slot)
+		require.Len(t, report.DeliveredPayloads, 2)
+		require.Len(t, report.Bids, 3)
+		require.NotNil(t, report.Block)
+	})
+
+	t.Run("inconsistent claims and wrong value", func(t *testing.T) {
+		db.payloads[1].ValueClaimedWei = "1200"
+		defer func() { db.payloads[1].ValueClaimedWei = "1000" }()
+		db.payloads[0].ValueClaimedWei = "1200"
+		defer func() { db.payloads[0].ValueClaimedWei = "1000" }()
+		db.payloads = append(db.payloads, &database.DataAPIPayloadDeliveredEntry{Relay: "relay-c", Slot: slot, BlockHash: otherBlock.Hash().Hex(), ValueClaimedWei: "900"})
+		defer func() { db.payloads = db.payloads[:2] }()
+
+		report, err := inspector.Inspect(context.Background(), slot, "")
+		require.NoError(t, err)
+		require.Equal(t, blockHash, report.BlockHash)
+		require.False(t, report.ClaimsConsistent)
+		require.False(t, report.ProposerPayment.Ok)
+		require.Equal(t, "200", report.ProposerPayment.DiffWei)
+		require.Len(t, report.Warnings, 2)
+	})
+
+	t.Run("no delivered payload", func(t *testing.T) {
+		report, err := inspector.Inspect(context.Background(), slot+1, "")
+		require.NoError(t, err)
+		require.Nil(t, report.Block)
+		require.Empty(t, report.Bids)
+		require.Len(t, report.Warnings, 2) // no bids, and no delivered payload
+
+		var buf bytes.Buffer
+		report.Print(&buf)
+		require.NotContains(t, buf.String(), "Bids:")
+		require.Contains(t, buf.String(), "no collected bids")
+	})
+
+	t.Run("missed slot", func(t *testing.T) {
+		chain.AddOrphanedBlock(otherBlock)
+		report, err := inspector.Inspect(context.Background(), 0, otherBlock.Hash().Hex())
+		require.NoError(t, err)
+		require.NotNil(t, report.Block)
+		require.False(t, report.Block.Canonical)
+		require.Nil(t, report.ProposerPayment) // not delivered by any relay, so the slot and fee recipient are unknown
+	})
+
+	t.Run("missing slot and hash", func(t *testing.T) {
+		_, err := inspector.Inspect(context.Background(), 0, "")
+		require.ErrorIs(t, err, errMissingSlotOrHash)
+	})
+}
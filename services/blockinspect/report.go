@@ -0,0 +1,325 @@
+package blockinspect
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	"github.com/olekukonko/tablewriter"
+)
+
+const (
+	TransferIn  = "in"  // to the coinbase
+	TransferOut = "out" // from the coinbase
+)
+
+// Report is the combined data of a slot / block
+type Report struct {
+	Slot      uint64 `json:"slot"`
+	BlockHash string `json:"block_hash"`
+
+	DeliveredPayloads []*DeliveredPayload `json:"delivered_payloads"`
+	ClaimsConsistent  bool                `json:"claims_consistent"` // all relays claim the same block and value
+	Bids              []*Bid              `json:"bids"`              // sorted by value, highest first
+
+	Block           *BlockInfo       `json:"block,omitempty"` // nil if not found on-chain
+	ProposerPayment *ProposerPayment `json:"proposer_payment,omitempty"`
+	Builder         *BuilderBalance  `json:"builder,omitempty"`
+
+	// Transactions with value to or from the coinbase. Only top-level transaction values, payments by internal calls
+	// (i.e. block.coinbase.transfer) are only part of the coinbase balance diff.
+	CoinbaseTxTransfers []*Transfer `json:"coinbase_tx_transfers"`
+
+	Warnings []string `json:"warnings"`
+}
+
+// DeliveredPayload is a payload a relay claims to have delivered
+type DeliveredPayload struct {
+	Relay                string `json:"relay"`
+	BlockHash            string `json:"block_hash"`
+	BuilderPubkey        string `json:"builder_pubkey"`
+	ProposerPubkey       string `json:"proposer_pubkey"`
+	ProposerFeeRecipient string `json:"proposer_fee_recipient"`
+	ValueClaimedWei      string `json:"value_claimed_wei"`
+	ValueClaimedEth      string `json:"value_claimed_eth"`
+	MatchesBlock         bool   `json:"matches_block"` // claimed block hash is the inspected block
+}
+
+const (
+	BidSourceGetHeader = "getHeader"
+	BidSourceDataAPI   = "data-api"
+)
+
+// Bid is a bid collected from a relay's getHeader endpoint or data API. Data API bids have no extra_data, blobs and
+// signature, getHeader bids have no builder pubkey.
+type Bid struct {
+	Source          string    `json:"source"`
+	Relay           string    `json:"relay"`
+	BlockHash       string    `json:"block_hash"`
+	BuilderPubkey   string    `json:"builder_pubkey"`
+	FeeRecipient    string    `json:"fee_recipient"`
+	ValueWei        string    `json:"value_wei"`
+	ValueEth        string    `json:"value_eth"`
+	ExtraData       string    `json:"extra_data"`
+	NumBlobs        uint64    `json:"num_blobs"`
+	SignatureStatus string    `json:"signature_status"`
+	ReceivedAt      time.Time `json:"received_at"`
+	Delivered       bool      `json:"delivered"` // the bid is for the inspected block
+}
+
+// BlockInfo is the on-chain block
+type BlockInfo struct {
+	Number     uint64    `json:"number"`
+	Hash       string    `json:"hash"`
+	ParentHash string    `json:"parent_hash"`
+	Timestamp  time.Time `json:"timestamp"`
+	ExtraData  string    `json:"extra_data"`
+	Coinbase   string    `json:"coinbase"`
+	GasUsed    uint64    `json:"gas_used"`
+	GasLimit   uint64    `json:"gas_limit"`
+	NumTx      int       `json:"num_tx"`
+	NumBlobTxs int       `json:"num_blob_txs"`
+	NumBlobs   int       `json:"num_blobs"`
+	Canonical  bool      `json:"canonical"` // false if another block with the same number is canonical (missed slot)
+}
+
+// ProposerPayment is the payment to the proposer fee recipient, compared to the value claimed by the relays
+type ProposerPayment struct {
+	FeeRecipient       string `json:"fee_recipient"`
+	CoinbaseIsProposer bool   `json:"coinbase_is_proposer"`
+	BalanceDiffWei     string `json:"balance_diff_wei"`
+	BalanceDiffEth     string `json:"balance_diff_eth"`
+
+	PaymentTxHash     string `json:"payment_tx_hash,omitempty"` // last tx of the block, if it's sent to the fee recipient
+	PaymentTxFrom     string `json:"payment_tx_from,omitempty"`
+	PaymentTxValueWei string `json:"payment_tx_value_wei,omitempty"`
+	PaymentTxValueEth string `json:"payment_tx_value_eth,omitempty"`
+
+	ClaimedWei string `json:"claimed_wei,omitempty"`
+	DiffWei    string `json:"diff_wei,omitempty"` // claimed - delivered
+	DiffEth    string `json:"diff_eth,omitempty"`
+	Method     string `json:"method"`
+	Ok         bool   `json:"ok"`
+}
+
+// BuilderBalance is the balance diff of the block coinbase
+type BuilderBalance struct {
+	Coinbase       string `json:"coinbase"`
+	BalanceDiffWei string `json:"balance_diff_wei"`
+	BalanceDiffEth string `json:"balance_diff_eth"`
+	ProfitWei      string `json:"profit_wei"` // balance diff plus transfers to builder-owned addresses
+	ProfitEth      string `json:"profit_eth"`
+}
+
+// Transfer is a transaction with value to or from the coinbase (top-level value only)
+type Transfer struct {
+	Index     int    `json:"index"`
+	TxHash    string `json:"tx_hash"`
+	Direction string `json:"direction"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	ValueWei  string `json:"value_wei"`
+	ValueEth  string `json:"value_eth"`
+}
+
+func (r *Report) warn(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (r *Report) addDeliveredPayloads(entries []*database.DataAPIPayloadDeliveredEntry) {
+	r.ClaimsConsistent = true
+	for _, entry := range entries {
+		payload := &DeliveredPayload{
+			Relay:                entry.Relay,
+			BlockHash:            entry.BlockHash,
+			BuilderPubkey:        entry.BuilderPubkey,
+			ProposerPubkey:       entry.ProposerPubkey,
+			ProposerFeeRecipient: entry.ProposerFeeRecipient,
+			ValueClaimedWei:      entry.ValueClaimedWei,
+			ValueClaimedEth:      common.WeiStrToEthStr(entry.ValueClaimedWei, 6),
+			MatchesBlock:         strings.EqualFold(entry.BlockHash, r.BlockHash),
+		}
+		if !payload.MatchesBlock || payload.ValueClaimedWei != entries[0].ValueClaimedWei {
+			r.ClaimsConsistent = false
+		}
+		r.DeliveredPayloads = append(r.DeliveredPayloads, payload)
+	}
+	if !r.ClaimsConsistent {
+		r.warn("relays claim different blocks or values for slot %d", r.Slot)
+	}
+}
+
+func (r *Report) addBids(entries []*database.SignedBuilderBidEntry, dataAPIEntries []*database.DataAPIBuilderBidEntry) {
+	for _, entry := range entries {
+		r.Bids = append(r.Bids, &Bid{
+			Source:          BidSourceGetHeader,
+			Relay:           entry.Relay,
+			BlockHash:       entry.BlockHash,
+			BuilderPubkey:   entry.Pubkey,
+			FeeRecipient:    entry.FeeRecipient,
+			ValueWei:        entry.Value,
+			ValueEth:        common.WeiStrToEthStr(entry.Value, 6),
+			ExtraData:       entry.ExtraData,
+			NumBlobs:        entry.NumBlobs,
+			SignatureStatus: entry.SignatureStatus,
+			ReceivedAt:      entry.ReceivedAt,
+			Delivered:       strings.EqualFold(entry.BlockHash, r.BlockHash),
+		})
+	}
+	for _, entry := range dataAPIEntries {
+		r.Bids = append(r.Bids, &Bid{
+			Source:        BidSourceDataAPI,
+			Relay:         entry.Relay,
+			BlockHash:     entry.BlockHash,
+			BuilderPubkey: entry.BuilderPubkey,
+			FeeRecipient:  entry.ProposerFeeRecipient,
+			ValueWei:      entry.Value,
+			ValueEth:      common.WeiStrToEthStr(entry.Value, 6),
+			ReceivedAt:    entry.Timestamp,
+			Delivered:     strings.EqualFold(entry.BlockHash, r.BlockHash),
+		})
+	}
+	sortBidsByValue(r.Bids)
+}
+
+// feeRecipient returns the proposer fee recipient of the inspected block, from the delivered payloads or else from the bids
+func (r *Report) feeRecipient() *ethcommon.Address {
+	for _, payload := range r.DeliveredPayloads {
+		if payload.MatchesBlock {
+			address := ethcommon.HexToAddress(payload.ProposerFeeRecipient)
+			return &address
+		}
+	}
+	for _, bid := range r.Bids {
+		if bid.Delivered {
+			address := ethcommon.HexToAddress(bid.FeeRecipient)
+			return &address
+		}
+	}
+	return nil
+}
+
+// claimedValue returns the value the relays claim for the inspected block (nil if no relay delivered it)
+func (r *Report) claimedValue() *big.Int {
+	for _, payload := range r.DeliveredPayloads {
+		if payload.MatchesBlock {
+			return common.StrToBigInt(payload.ValueClaimedWei)
+		}
+	}
+	return nil
+}
+
+// Print writes the report as tables
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "Slot %d / block %s\n\n", r.Slot, r.BlockHash)
+
+	fmt.Fprintln(w, "Delivered payloads:")
+	table := newTable(w, "Relay", "Block hash", "Value (ETH)", "Builder", "Fee recipient", "Matches block")
+	for _, payload := range r.DeliveredPayloads {
+		table.Append([]string{payload.Relay, payload.BlockHash, payload.ValueClaimedEth, shortHex(payload.BuilderPubkey), payload.ProposerFeeRecipient, strconv.FormatBool(payload.MatchesBlock)})
+	}
+	table.Render()
+
+	if len(r.Bids) > 0 {
+		fmt.Fprintln(w, "\nBids:")
+		table = newTable(w, "Source", "Relay", "Block hash", "Value (ETH)", "Builder", "Extra data", "Blobs", "Signature", "Delivered")
+		for _, bid := range r.Bids {
+			numBlobs := strconv.FormatUint(bid.NumBlobs, 10)
+			if bid.Source == BidSourceDataAPI {
+				numBlobs = "" // unknown
+			}
+			table.Append([]string{bid.Source, bid.Relay, bid.BlockHash, bid.ValueEth, shortHex(bid.BuilderPubkey), bid.ExtraData, numBlobs, bid.SignatureStatus, strconv.FormatBool(bid.Delivered)})
+		}
+		table.Render()
+	}
+
+	if r.Block != nil {
+		fmt.Fprintln(w, "\nBlock:")
+		table = newTable(w, "Field", "Value")
+		table.AppendBulk([][]string{
+			{"Number", strconv.FormatUint(r.Block.Number, 10)},
+			{"Hash", r.Block.Hash},
+			{"Parent hash", r.Block.ParentHash},
+			{"Timestamp", r.Block.Timestamp.Format(time.RFC3339)},
+			{"Canonical", strconv.FormatBool(r.Block.Canonical)},
+			{"Extra data", r.Block.ExtraData},
+			{"Coinbase", r.Block.Coinbase},
+			{"Gas used", fmt.Sprintf("%d / %d", r.Block.GasUsed, r.Block.GasLimit)},
+			{"Transactions", strconv.Itoa(r.Block.NumTx)},
+			{"Blobs", fmt.Sprintf("%d (in %d tx)", r.Block.NumBlobs, r.Block.NumBlobTxs)},
+		})
+		table.Render()
+	}
+
+	if r.ProposerPayment != nil {
+		p := r.ProposerPayment
+		fmt.Fprintln(w, "\nProposer payment:")
+		table = newTable(w, "Field", "Value")
+		table.AppendBulk([][]string{
+			{"Fee recipient", p.FeeRecipient},
+			{"Coinbase is proposer", strconv.FormatBool(p.CoinbaseIsProposer)},
+			{"Balance diff (ETH)", p.BalanceDiffEth},
+		})
+		if p.PaymentTxHash != "" {
+			table.Append([]string{"Payment tx", fmt.Sprintf("%s (%s ETH from %s)", p.PaymentTxHash, p.PaymentTxValueEth, p.PaymentTxFrom)})
+		}
+		if p.ClaimedWei != "" {
+			table.Append([]string{"Claimed (ETH)", common.WeiStrToEthStr(p.ClaimedWei, 6)})
+			table.Append([]string{"Claimed - delivered (ETH)", p.DiffEth})
+		}
+		table.Append([]string{"Check", fmt.Sprintf("%s (%s)", okStr(p.Ok), p.Method)})
+		table.Render()
+	}
+
+	if r.Builder != nil {
+		fmt.Fprintln(w, "\nBuilder (coinbase):")
+		table = newTable(w, "Field", "Value")
+		table.AppendBulk([][]string{
+			{"Coinbase", r.Builder.Coinbase},
+			{"Balance diff (ETH)", r.Builder.BalanceDiffEth},
+			{"Profit (ETH)", r.Builder.ProfitEth},
+		})
+		table.Render()
+
+		fmt.Fprintln(w, "\nCoinbase transfers (top-level tx value only, internal calls aren't included):")
+		table = newTable(w, "Tx", "Hash", "Direction", "From", "To", "Value (ETH)")
+		for _, transfer := range r.CoinbaseTxTransfers {
+			table.Append([]string{strconv.Itoa(transfer.Index), transfer.TxHash, transfer.Direction, transfer.From, transfer.To, transfer.ValueEth})
+		}
+		table.Render()
+	}
+
+	if len(r.Warnings) > 0 {
+		fmt.Fprintln(w, "\nWarnings:")
+		for _, warning := range r.Warnings {
+			fmt.Fprintf(w, "- %s\n", warning)
+		}
+	}
+}
+
+func newTable(w io.Writer, header ...string) *tablewriter.Table {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.SetAutoWrapText(false)
+	return table
+}
+
+func shortHex(s string) string {
+	if len(s) <= 14 {
+		return s
+	}
+	return s[:8] + "..." + s[len(s)-4:]
+}
+
+func okStr(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "mismatch"
+}
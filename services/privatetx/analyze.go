@@ -0,0 +1,43 @@
+package privatetx
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BlockResult is the private transaction share of a block
+type BlockResult struct {
+	NumTx        int
+	NumPrivateTx int
+	Share        float64 // NumPrivateTx / NumTx, 0 for empty blocks
+}
+
+// Covers returns whether the dataset can be used for a block at the given time: the collector was running at that
+// time, and without a gap for at least minHistory before (transactions can wait in the mempool for a while before
+// inclusion)
+func (d *MempoolDataset) Covers(blockTime time.Time, minHistory time.Duration) bool {
+	fromMs, toMs := blockTime.Add(-minHistory).UnixMilli(), blockTime.UnixMilli()
+	for _, r := range d.coverage {
+		if r[0] <= fromMs && r[1] >= toMs {
+			return true
+		}
+	}
+	return false
+}
+
+// AnalyzeBlock counts the transactions that weren't seen in the mempool before the block timestamp (the start of the
+// slot). Transactions only seen later were private when the builder included them.
+func AnalyzeBlock(block *types.Block, dataset *MempoolDataset) *BlockResult {
+	blockTime := time.Unix(int64(block.Time()), 0) //nolint:gosec
+	result := &BlockResult{NumTx: len(block.Transactions())}
+	for _, tx := range block.Transactions() {
+		if !dataset.SeenBefore(tx.Hash(), blockTime) {
+			result.NumPrivateTx++
+		}
+	}
+	if result.NumTx > 0 {
+		result.Share = float64(result.NumPrivateTx) / float64(result.NumTx)
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package privatetx
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/flashbots/relayscan/testutil/ethchain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeBlock(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	to := ethcommon.Address{0x01}
+	txPublic := ethchain.NewTransfer(key, 0, to, big.NewInt(1))
+	txLate := ethchain.NewTransfer(key, 1, to, big.NewInt(1))
+	txPrivate := ethchain.NewTransfer(key, 2, to, big.NewInt(1))
+	txPublic2 := ethchain.NewTransfer(key, 3, to, big.NewInt(1))
+	block := ethchain.NewBlock(100, ethcommon.Hash{}, to, nil, txPublic, txLate, txPrivate, txPublic2)
+	blockTime := time.Unix(int64(block.Time()), 0) //nolint:gosec
+
+	dataset := NewMempoolDataset()
+	dataset.Add(txPublic.Hash(), blockTime.Add(-time.Minute).UnixMilli())
+	dataset.Add(txPublic2.Hash(), blockTime.UnixMilli())
+	dataset.Add(txLate.Hash(), blockTime.Add(time.Second).UnixMilli()) // only seen after the block was built
+
+	result := AnalyzeBlock(block, dataset)
+	require.Equal(t, &BlockResult{NumTx: 4, NumPrivateTx: 2, Share: 0.5}, result)
+
+	empty := ethchain.NewBlock(101, block.Hash(), to, nil)
+	require.Equal(t, &BlockResult{}, AnalyzeBlock(empty, dataset))
+}
+
+func TestMempoolDatasetCovers(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	dataset := NewMempoolDataset()
+	require.False(t, dataset.Covers(start, 0))
+
+	// two files without a gap
+	dataset.AddCoverage(start, start.Add(time.Hour))
+	dataset.AddCoverage(start.Add(time.Hour+time.Second), start.Add(2*time.Hour))
+	require.Empty(t, dataset.Gaps())
+
+	require.True(t, dataset.Covers(start.Add(time.Hour), time.Hour))
+	require.True(t, dataset.Covers(start.Add(2*time.Hour), time.Hour))
+	require.False(t, dataset.Covers(start.Add(59*time.Minute), time.Hour))
+	require.False(t, dataset.Covers(start.Add(2*time.Hour+time.Second), time.Hour))
+	require.True(t, dataset.Covers(start, 0))
+
+	// a later file after a gap: blocks in the gap, or without enough history after it, aren't covered
+	dataset.AddCoverage(start.Add(3*time.Hour), start.Add(6*time.Hour))
+	require.Equal(t, []TimeRange{{Start: start.Add(2 * time.Hour).UTC(), End: start.Add(3 * time.Hour).UTC()}}, dataset.Gaps())
+	require.False(t, dataset.Covers(start.Add(150*time.Minute), 0))
+	require.False(t, dataset.Covers(start.Add(3*time.Hour+30*time.Minute), time.Hour))
+	require.True(t, dataset.Covers(start.Add(4*time.Hour), time.Hour))
+
+	// filling the gap merges the ranges
+	dataset.AddCoverage(start.Add(2*time.Hour), start.Add(3*time.Hour))
+	require.Empty(t, dataset.Gaps())
+	require.True(t, dataset.Covers(start.Add(3*time.Hour+30*time.Minute), time.Hour))
+}
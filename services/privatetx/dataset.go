@@ -0,0 +1,204 @@
+// Package privatetx finds the transactions of a block that weren't seen in the public mempool (exclusive order flow)
+package privatetx
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrMissingColumn = errors.New("missing column")
+	ErrEmptyDataset  = errors.New("no transactions in mempool dataset")
+)
+
+// Columns of the mempool dataset, other columns are ignored (compatible with the mempool-dumpster transaction files)
+const (
+	ColumnHash        = "hash"
+	ColumnTimestampMs = "timestamp_ms"
+)
+
+// coverageMaxGap is how far apart the observations of two files can be to still count as continuous coverage (the
+// public mempool has new transactions every few seconds, so larger gaps mean the collector wasn't running)
+const coverageMaxGap = time.Minute
+
+// TimeRange is a time range of the dataset, i.e. a gap in the coverage
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// MempoolDataset is the time each transaction was first seen in the public mempool
+type MempoolDataset struct {
+	firstSeen map[ethcommon.Hash]int64 // unix ms
+	minMs     int64
+	maxMs     int64
+
+	coverage [][2]int64 // unix ms ranges the collector was running, sorted and merged
+}
+
+func NewMempoolDataset() *MempoolDataset {
+	return &MempoolDataset{firstSeen: make(map[ethcommon.Hash]int64)}
+}
+
+// LoadMempoolDataset reads CSV files with a header line and (at least) the hash and timestamp_ms columns. Files ending
+// in .gz are decompressed. A transaction in several files keeps the earliest time.
+func LoadMempoolDataset(paths ...string) (*MempoolDataset, error) {
+	dataset := NewMempoolDataset()
+	for _, path := range paths {
+		err := dataset.loadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	if dataset.Len() == 0 {
+		return nil, ErrEmptyDataset
+	}
+	return dataset, nil
+}
+
+func (d *MempoolDataset) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close() //nolint:errcheck
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close() //nolint:errcheck
+		r = gz
+	}
+	return d.Read(r)
+}
+
+// Read adds the transactions of a CSV file. The columns are looked up by name in the header line.
+func (d *MempoolDataset) Read(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // checked below, only the needed columns have to be there
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil // empty file
+	} else if err != nil {
+		return err
+	}
+
+	hashIndex, timestampIndex := -1, -1
+	for i, column := range header {
+		switch strings.TrimSpace(column) {
+		case ColumnHash:
+			hashIndex = i
+		case ColumnTimestampMs:
+			timestampIndex = i
+		}
+	}
+	if hashIndex == -1 {
+		return fmt.Errorf("%w: %s", ErrMissingColumn, ColumnHash)
+	} else if timestampIndex == -1 {
+		return fmt.Errorf("%w: %s", ErrMissingColumn, ColumnTimestampMs)
+	}
+
+	// the file covers the time from its first to its last observation
+	fileMinMs, fileMaxMs := int64(0), int64(0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			if fileMaxMs > 0 {
+				d.AddCoverage(time.UnixMilli(fileMinMs), time.UnixMilli(fileMaxMs))
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(record) <= hashIndex || len(record) <= timestampIndex {
+			return fmt.Errorf("line %d: expected at least %d columns, got %d", line, max(hashIndex, timestampIndex)+1, len(record))
+		}
+		timestampMs, err := strconv.ParseInt(record[timestampIndex], 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid %s: %w", line, ColumnTimestampMs, err)
+		}
+		d.Add(ethcommon.HexToHash(record[hashIndex]), timestampMs)
+		if fileMaxMs == 0 || timestampMs < fileMinMs {
+			fileMinMs = timestampMs
+		}
+		fileMaxMs = max(fileMaxMs, timestampMs)
+	}
+}
+
+// AddCoverage records a time range the collector was running (Read adds the range of each file). Ranges less than
+// coverageMaxGap apart are merged.
+func (d *MempoolDataset) AddCoverage(start, end time.Time) {
+	d.coverage = append(d.coverage, [2]int64{start.UnixMilli(), end.UnixMilli()})
+	sort.Slice(d.coverage, func(i, j int) bool { return d.coverage[i][0] < d.coverage[j][0] })
+
+	merged := d.coverage[:1]
+	for _, r := range d.coverage[1:] {
+		last := &merged[len(merged)-1]
+		if r[0]-last[1] <= coverageMaxGap.Milliseconds() {
+			last[1] = max(last[1], r[1])
+		} else {
+			merged = append(merged, r)
+		}
+	}
+	d.coverage = merged
+}
+
+// Gaps returns the time ranges between Start and End that aren't covered
+func (d *MempoolDataset) Gaps() (gaps []TimeRange) {
+	for i := 1; i < len(d.coverage); i++ {
+		gaps = append(gaps, TimeRange{Start: time.UnixMilli(d.coverage[i-1][1]).UTC(), End: time.UnixMilli(d.coverage[i][0]).UTC()})
+	}
+	return gaps
+}
+
+// Add records a transaction, keeping the earliest time it was seen
+func (d *MempoolDataset) Add(hash ethcommon.Hash, timestampMs int64) {
+	if seen, found := d.firstSeen[hash]; found && seen <= timestampMs {
+		return
+	}
+	d.firstSeen[hash] = timestampMs
+	if len(d.firstSeen) == 1 || timestampMs < d.minMs {
+		d.minMs = timestampMs
+	}
+	if timestampMs > d.maxMs {
+		d.maxMs = timestampMs
+	}
+}
+
+// Len returns the number of transactions
+func (d *MempoolDataset) Len() int {
+	return len(d.firstSeen)
+}
+
+// Start returns the time of the earliest observation
+func (d *MempoolDataset) Start() time.Time {
+	return time.UnixMilli(d.minMs).UTC()
+}
+
+// End returns the time of the latest observation
+func (d *MempoolDataset) End() time.Time {
+	return time.UnixMilli(d.maxMs).UTC()
+}
+
+// SeenBefore returns whether the transaction was seen in the mempool at or before t
+func (d *MempoolDataset) SeenBefore(hash ethcommon.Hash, t time.Time) bool {
+	seen, found := d.firstSeen[hash]
+	return found && seen <= t.UnixMilli()
+}
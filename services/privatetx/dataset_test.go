@@ -0,0 +1,77 @@
+package privatetx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMempoolDatasetRead(t *testing.T) {
+	dataset := NewMempoolDataset()
+	err := dataset.Read(strings.NewReader("timestamp_ms,hash,chain_id\n1700000000500,0x01,1\n\n1700000001000,0x02,1\n"))
+	require.NoError(t, err)
+	require.Equal(t, 2, dataset.Len())
+	require.Equal(t, time.UnixMilli(1700000000500).UTC(), dataset.Start())
+	require.Equal(t, time.UnixMilli(1700000001000).UTC(), dataset.End())
+	require.True(t, dataset.Covers(time.UnixMilli(1700000001000), 500*time.Millisecond))
+	require.False(t, dataset.Covers(time.UnixMilli(1700000001000), time.Second))
+
+	// seen at or before
+	hash := ethcommon.HexToHash("0x01")
+	require.True(t, dataset.SeenBefore(hash, time.UnixMilli(1700000000500)))
+	require.False(t, dataset.SeenBefore(hash, time.UnixMilli(1700000000499)))
+	require.False(t, dataset.SeenBefore(ethcommon.HexToHash("0x03"), time.UnixMilli(1800000000000)))
+
+	// the earliest observation is kept
+	dataset.Add(hash, 1700000000100)
+	dataset.Add(hash, 1700000000900)
+	require.True(t, dataset.SeenBefore(hash, time.UnixMilli(1700000000100)))
+	require.Equal(t, time.UnixMilli(1700000000100).UTC(), dataset.Start())
+
+	// quoted fields with commas don't shift the columns
+	quoted := NewMempoolDataset()
+	err = quoted.Read(strings.NewReader("sources,hash,timestamp_ms\n\"local,blx\",0x04,1700000000000\n"))
+	require.NoError(t, err)
+	require.True(t, quoted.SeenBefore(ethcommon.HexToHash("0x04"), time.UnixMilli(1700000000000)))
+
+	err = NewMempoolDataset().Read(strings.NewReader("hash,chain_id\n0x01,1\n"))
+	require.ErrorIs(t, err, ErrMissingColumn)
+	err = NewMempoolDataset().Read(strings.NewReader("timestamp_ms,hash\nfoo,0x01\n"))
+	require.Error(t, err)
+	err = NewMempoolDataset().Read(strings.NewReader("timestamp_ms,hash\n1700000000000\n"))
+	require.Error(t, err)
+}
+
+func TestLoadMempoolDataset(t *testing.T) {
+	dir := t.TempDir()
+	plainFile := filepath.Join(dir, "a.csv")
+	require.NoError(t, os.WriteFile(plainFile, []byte("hash,timestamp_ms\n0x01,1700000000000\n"), 0o600))
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte("hash,timestamp_ms\n0x01,1600000000000\n0x02,1700000000000\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	gzFile := filepath.Join(dir, "b.csv.gz")
+	require.NoError(t, os.WriteFile(gzFile, buf.Bytes(), 0o600))
+
+	dataset, err := LoadMempoolDataset(plainFile, gzFile)
+	require.NoError(t, err)
+	require.Equal(t, 2, dataset.Len())
+	require.Equal(t, time.UnixMilli(1600000000000).UTC(), dataset.Start())
+
+	emptyFile := filepath.Join(dir, "empty.csv")
+	require.NoError(t, os.WriteFile(emptyFile, []byte("hash,timestamp_ms\n"), 0o600))
+	_, err = LoadMempoolDataset(emptyFile)
+	require.ErrorIs(t, err, ErrEmptyDataset)
+
+	_, err = LoadMempoolDataset(filepath.Join(dir, "missing.csv"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,374 @@
+// Package relaycheck checks the data API and builder API of a relay for conformance with the relay-specs
+// (https://flashbots.github.io/relay-specs/), and for the deviations relayscan has to handle.
+package relaycheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	relaycommon "github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/relayscan/common"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	CheckBuilderStatus      = "builder API: status"
+	CheckPayloadsLimit      = "payloads: limit"
+	CheckPayloadsFields     = "payloads: fields"
+	CheckPayloadsOrder      = "payloads: order"
+	CheckPayloadsCursor     = "payloads: cursor"
+	CheckPayloadsSlotFilter = "payloads: slot filter"
+	CheckBidsSlotQuery      = "bids: slot query"
+	CheckBidsFields         = "bids: fields"
+	CheckBidsTimestampMs    = "bids: timestamp_ms"
+	CheckConsistency        = "bids/payloads consistency"
+
+	pathStatus           = "/eth/v1/builder/status"
+	pathPayloadDelivered = "/relay/v1/data/bidtraces/proposer_payload_delivered"
+	pathBlocksReceived   = "/relay/v1/data/bidtraces/builder_blocks_received"
+
+	specPageLimit     = 200 // maximum limit of proposer_payload_delivered in the relay-specs
+	fallbackPageLimit = 100 // i.e. bloxroute
+)
+
+var errHTTPStatus = errors.New("HTTP error response")
+
+// httpStatusError is a non-200 response (wraps errHTTPStatus)
+type httpStatusError struct {
+	statusCode int
+	body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("%s: %d / %s", errHTTPStatus, e.statusCode, e.body)
+}
+
+func (e *httpStatusError) Unwrap() error {
+	return errHTTPStatus
+}
+
+// isBadRequest returns whether the relay rejected the request parameters (400)
+func isBadRequest(err error) bool {
+	var statusErr *httpStatusError
+	return errors.As(err, &statusErr) && statusErr.statusCode == http.StatusBadRequest
+}
+
+type CheckerOpts struct {
+	Log     *logrus.Entry
+	Relay   common.RelayEntry
+	Timeout time.Duration // per request
+
+	// NumConsistencySlots is the number of recent delivered payloads which are compared with builder_blocks_received
+	NumConsistencySlots int
+}
+
+type Checker struct {
+	opts   *CheckerOpts
+	log    *logrus.Entry
+	client http.Client
+	report *Report
+
+	pageLimit uint64 // the limit the relay accepts
+}
+
+func NewChecker(opts *CheckerOpts) *Checker {
+	return &Checker{
+		opts:   opts,
+		log:    opts.Log,
+		client: http.Client{Timeout: opts.Timeout},
+	}
+}
+
+// Run runs all checks and returns the report
+func (c *Checker) Run() *Report {
+	c.report = &Report{
+		Relay: c.opts.Relay.Hostname(),
+		Time:  time.Now().UTC(),
+	}
+
+	c.checkBuilderStatus()
+
+	payloads, ok := c.checkPayloads()
+	if ok && len(payloads) > 0 {
+		c.checkCursor(payloads)
+		c.checkSlotFilter(payloads)
+		c.checkBids(payloads)
+	} else {
+		for _, name := range []string{CheckPayloadsCursor, CheckPayloadsSlotFilter, CheckBidsSlotQuery, CheckBidsFields, CheckBidsTimestampMs, CheckConsistency} {
+			c.report.add(name, ResultSkip, "no delivered payloads")
+		}
+	}
+	return c.report
+}
+
+// get requests a relay API endpoint and returns the body (an error for non-200 responses)
+func (c *Checker) get(path string, query map[string]string) (body []byte, err error) {
+	url := common.GetURIWithQuery(c.opts.Relay.URL, path, query)
+	c.log.WithField("url", url).Debug("[relaycheck] request")
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return body, &httpStatusError{statusCode: resp.StatusCode, body: strings.TrimSpace(string(body))}
+	}
+	return body, nil
+}
+
+// getEntries requests a data API endpoint, and decodes the response both as raw fields and as bid traces
+func getEntries[T any](c *Checker, path string, query map[string]string) (raw []map[string]json.RawMessage, entries []T, err error) {
+	body, err := c.get(path, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = json.Unmarshal(body, &raw); err != nil {
+		return nil, nil, fmt.Errorf("response is not a JSON list: %w", err)
+	}
+	if err = json.Unmarshal(body, &entries); err != nil {
+		return raw, nil, fmt.Errorf("could not decode entries: %w", err)
+	}
+	return raw, entries, nil
+}
+
+func (c *Checker) checkBuilderStatus() {
+	_, err := c.get(pathStatus, nil)
+	if err != nil {
+		c.report.add(CheckBuilderStatus, ResultFail, err.Error())
+		return
+	}
+	c.report.add(CheckBuilderStatus, ResultPass, "")
+}
+
+// checkPayloads requests the latest delivered payloads with the maximum limit, and checks the limit, fields and order
+func (c *Checker) checkPayloads() (payloads []relaycommon.BidTraceV2JSON, ok bool) {
+	c.pageLimit = specPageLimit
+	raw, payloads, err := getEntries[relaycommon.BidTraceV2JSON](c, pathPayloadDelivered, limitQuery(specPageLimit))
+	if isBadRequest(err) {
+		// some relays only allow a smaller limit (other errors, i.e. rate limits, aren't retried)
+		c.pageLimit = fallbackPageLimit
+		raw, payloads, err = getEntries[relaycommon.BidTraceV2JSON](c, pathPayloadDelivered, limitQuery(fallbackPageLimit))
+	}
+	if err != nil {
+		c.report.add(CheckPayloadsLimit, ResultFail, err.Error())
+		if raw != nil {
+			c.checkPayloadFields(raw) // the fields show why decoding failed
+		} else {
+			c.report.add(CheckPayloadsFields, ResultSkip, "request failed")
+		}
+		c.report.add(CheckPayloadsOrder, ResultSkip, "request failed")
+		return nil, false
+	}
+
+	switch {
+	case uint64(len(payloads)) > c.pageLimit:
+		c.report.add(CheckPayloadsLimit, ResultFail, fmt.Sprintf("limit=%d returned %d payloads", c.pageLimit, len(payloads)))
+	case c.pageLimit != specPageLimit:
+		c.report.add(CheckPayloadsLimit, ResultWarn, fmt.Sprintf("limit=%d is rejected, limit=%d works", specPageLimit, c.pageLimit))
+	default:
+		c.report.add(CheckPayloadsLimit, ResultPass, fmt.Sprintf("limit=%d returned %d payloads", c.pageLimit, len(payloads)))
+	}
+
+	c.checkPayloadFields(raw)
+
+	for i := 1; i < len(payloads); i++ {
+		if payloads[i].Slot > payloads[i-1].Slot {
+			c.report.add(CheckPayloadsOrder, ResultFail, fmt.Sprintf("slot %d after slot %d, expected descending slots", payloads[i].Slot, payloads[i-1].Slot))
+			return payloads, true
+		}
+	}
+	c.report.add(CheckPayloadsOrder, ResultPass, "")
+	return payloads, true
+}
+
+func (c *Checker) checkPayloadFields(raw []map[string]json.RawMessage) {
+	issues := checkFields(raw, payloadFields)
+	if !issues.ok() {
+		c.report.add(CheckPayloadsFields, ResultFail, issues.String())
+		return
+	}
+	c.report.add(CheckPayloadsFields, ResultPass, fmt.Sprintf("%d entries", len(raw)))
+}
+
+// checkCursor requests the page starting at a slot in the middle of the first page. It must only contain payloads up
+// to the cursor slot (inclusive), and continue the first page without gaps.
+func (c *Checker) checkCursor(payloads []relaycommon.BidTraceV2JSON) {
+	cursor := payloads[len(payloads)/2].Slot
+	if cursor == payloads[0].Slot {
+		c.report.add(CheckPayloadsCursor, ResultSkip, "not enough payloads")
+		return
+	}
+
+	query := limitQuery(c.pageLimit)
+	query["cursor"] = strconv.FormatUint(cursor, 10)
+	_, page, err := getEntries[relaycommon.BidTraceV2JSON](c, pathPayloadDelivered, query)
+	if err != nil {
+		c.report.add(CheckPayloadsCursor, ResultFail, err.Error())
+		return
+	}
+
+	for _, payload := range page {
+		if payload.Slot > cursor {
+			c.report.add(CheckPayloadsCursor, ResultFail, fmt.Sprintf("cursor=%d is ignored, returned slot %d", cursor, payload.Slot))
+			return
+		}
+	}
+
+	// the payloads of the first page up to the cursor must be in the second page (as far as it goes)
+	pageBlocks := make(map[string]bool)
+	minSlot := uint64(0)
+	for _, payload := range page {
+		pageBlocks[payload.BlockHash] = true
+		if minSlot == 0 || payload.Slot < minSlot {
+			minSlot = payload.Slot
+		}
+	}
+	for _, payload := range payloads {
+		if payload.Slot > cursor || payload.Slot < minSlot {
+			continue
+		}
+		if !pageBlocks[payload.BlockHash] {
+			c.report.add(CheckPayloadsCursor, ResultFail, fmt.Sprintf("cursor=%d page is missing the payload of slot %d", cursor, payload.Slot))
+			return
+		}
+	}
+
+	if len(page) == 0 || page[0].Slot != cursor {
+		c.report.add(CheckPayloadsCursor, ResultWarn, fmt.Sprintf("cursor=%d is exclusive, expected the page to start at the cursor slot", cursor))
+		return
+	}
+	c.report.add(CheckPayloadsCursor, ResultPass, fmt.Sprintf("cursor=%d returned %d payloads", cursor, len(page)))
+}
+
+func (c *Checker) checkSlotFilter(payloads []relaycommon.BidTraceV2JSON) {
+	slot := payloads[len(payloads)-1].Slot // not the latest, so a relay ignoring the filter is noticed
+	_, page, err := getEntries[relaycommon.BidTraceV2JSON](c, pathPayloadDelivered, map[string]string{"slot": strconv.FormatUint(slot, 10)})
+	if err != nil {
+		c.report.add(CheckPayloadsSlotFilter, ResultFail, err.Error())
+		return
+	}
+	if len(page) == 0 {
+		c.report.add(CheckPayloadsSlotFilter, ResultFail, fmt.Sprintf("slot=%d returned no payloads", slot))
+		return
+	}
+	for _, payload := range page {
+		if payload.Slot != slot {
+			c.report.add(CheckPayloadsSlotFilter, ResultFail, fmt.Sprintf("slot=%d returned slot %d", slot, payload.Slot))
+			return
+		}
+	}
+	c.report.add(CheckPayloadsSlotFilter, ResultPass, "")
+}
+
+// checkBids requests builder_blocks_received for the slots of the latest delivered payloads. The delivered block
+// has to be one of the received blocks, with the same builder and value.
+func (c *Checker) checkBids(payloads []relaycommon.BidTraceV2JSON) {
+	numSlots := 0
+	numBids := 0
+	numWithoutTimestampMs := 0
+	fieldIssues := ""
+	inconsistencies := []string{}
+
+	checkedSlots := make(map[uint64]bool)
+	for _, payload := range payloads {
+		if numSlots >= c.opts.NumConsistencySlots {
+			break
+		}
+		if checkedSlots[payload.Slot] {
+			continue
+		}
+		checkedSlots[payload.Slot] = true
+		numSlots++
+
+		raw, bids, err := getEntries[relaycommon.BidTraceV2WithTimestampJSON](c, pathBlocksReceived, map[string]string{"slot": strconv.FormatUint(payload.Slot, 10)})
+		if err != nil {
+			c.report.add(CheckBidsSlotQuery, ResultFail, fmt.Sprintf("slot=%d: %s", payload.Slot, err))
+			c.report.add(CheckBidsFields, ResultSkip, "request failed")
+			c.report.add(CheckBidsTimestampMs, ResultSkip, "request failed")
+			c.report.add(CheckConsistency, ResultSkip, "request failed")
+			return
+		}
+		numBids += len(bids)
+
+		for _, bid := range bids {
+			if bid.Slot != payload.Slot {
+				c.report.add(CheckBidsSlotQuery, ResultFail, fmt.Sprintf("slot=%d returned slot %d", payload.Slot, bid.Slot))
+				c.report.add(CheckBidsFields, ResultSkip, "wrong slot")
+				c.report.add(CheckBidsTimestampMs, ResultSkip, "wrong slot")
+				c.report.add(CheckConsistency, ResultSkip, "wrong slot")
+				return
+			}
+		}
+
+		if issues := checkFields(raw, bidFields); !issues.ok() && fieldIssues == "" {
+			fieldIssues = fmt.Sprintf("slot=%d: %s", payload.Slot, issues)
+		}
+		for _, entry := range raw {
+			if _, found := entry["timestamp_ms"]; !found {
+				numWithoutTimestampMs++
+			}
+		}
+
+		if msg := compareDeliveredWithBids(payload, bids); msg != "" {
+			inconsistencies = append(inconsistencies, msg)
+		}
+	}
+
+	c.report.add(CheckBidsSlotQuery, ResultPass, fmt.Sprintf("%d bids in %d slots", numBids, numSlots))
+	if fieldIssues != "" {
+		c.report.add(CheckBidsFields, ResultFail, fieldIssues)
+	} else {
+		c.report.add(CheckBidsFields, ResultPass, "")
+	}
+	if numWithoutTimestampMs > 0 {
+		c.report.add(CheckBidsTimestampMs, ResultWarn, fmt.Sprintf("missing in %d of %d bids (relayscan falls back to timestamp)", numWithoutTimestampMs, numBids))
+	} else {
+		c.report.add(CheckBidsTimestampMs, ResultPass, "")
+	}
+	if len(inconsistencies) > 0 {
+		c.report.add(CheckConsistency, ResultFail, strings.Join(inconsistencies, "; "))
+	} else {
+		c.report.add(CheckConsistency, ResultPass, fmt.Sprintf("%d delivered payloads found in the received blocks", numSlots))
+	}
+}
+
+// compareDeliveredWithBids returns a description of the inconsistency between a delivered payload and the received
+// bids of the slot (empty if they're consistent)
+func compareDeliveredWithBids(payload relaycommon.BidTraceV2JSON, bids []relaycommon.BidTraceV2WithTimestampJSON) string {
+	for _, bid := range bids {
+		if !strings.EqualFold(bid.BlockHash, payload.BlockHash) {
+			continue
+		}
+		if !strings.EqualFold(bid.BuilderPubkey, payload.BuilderPubkey) {
+			return fmt.Sprintf("slot %d: delivered builder %s, received from %s", payload.Slot, payload.BuilderPubkey, bid.BuilderPubkey)
+		}
+		if bid.Value != payload.Value {
+			return fmt.Sprintf("slot %d: delivered value %s, received value %s", payload.Slot, payload.Value, bid.Value)
+		}
+		return ""
+	}
+	return fmt.Sprintf("slot %d: delivered block %s not in builder_blocks_received", payload.Slot, payload.BlockHash)
+}
+
+func limitQuery(limit uint64) map[string]string {
+	return map[string]string{"limit": strconv.FormatUint(limit, 10)}
+}
@@ -0,0 +1,142 @@
+package relaycheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	relaycommon "github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/testutil/mockrelay"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRelay returns a mock relay with 10 delivered payloads, and two received bids for each of them
+// (one of them the delivered block)
+func newTestRelay(t *testing.T, withTimestampMs bool) (*mockrelay.MockRelay, []relaycommon.BidTraceV2JSON) {
+	t.Helper()
+	relay, err := mockrelay.New()
+	require.NoError(t, err)
+	t.Cleanup(relay.Close)
+
+	payloads := mockrelay.GeneratePayloadsDelivered(1000, 10)
+	relay.SetPayloadsDelivered(payloads)
+
+	bids := []relaycommon.BidTraceV2WithTimestampJSON{}
+	for _, payload := range payloads {
+		timestamp := common.SlotToTime(payload.Slot).Unix()
+		other := payload
+		other.BlockHash = fmt.Sprintf("0x%064x", payload.Slot+1_000_000)
+		other.Value = "1"
+		for _, bid := range []relaycommon.BidTraceV2JSON{other, payload} {
+			entry := relaycommon.BidTraceV2WithTimestampJSON{BidTraceV2JSON: bid, Timestamp: timestamp}
+			if withTimestampMs {
+				entry.TimestampMs = timestamp * 1000
+			}
+			bids = append(bids, entry)
+		}
+	}
+	relay.SetBlocksReceived(bids)
+	return relay, payloads
+}
+
+func runChecker(relay *mockrelay.MockRelay) *Report {
+	checker := NewChecker(&CheckerOpts{
+		Log:                 common.Logger,
+		Relay:               relay.RelayEntry(),
+		Timeout:             5 * time.Second,
+		NumConsistencySlots: 3,
+	})
+	return checker.Run()
+}
+
+func TestCheckerConformingRelay(t *testing.T) {
+	relay, _ := newTestRelay(t, true)
+	report := runChecker(relay)
+
+	require.Equal(t, "127.0.0.1", report.Relay)
+	require.Len(t, report.Results, 10)
+	for _, res := range report.Results {
+		require.Equal(t, ResultPass, res.Result, res.Name+": "+res.Details)
+	}
+	require.Equal(t, "limit=200 returned 10 payloads", report.Get(CheckPayloadsLimit).Details)
+	require.Equal(t, "cursor=1004 returned 5 payloads", report.Get(CheckPayloadsCursor).Details)
+	require.Equal(t, "6 bids in 3 slots", report.Get(CheckBidsSlotQuery).Details)
+	require.Equal(t, 3, relay.NumRequests(mockrelay.PathBlocksReceived))
+
+	buf := bytes.Buffer{}
+	report.Print(&buf)
+	require.Contains(t, buf.String(), "bids/payloads consistency")
+}
+
+func TestCheckerDeviatingRelay(t *testing.T) {
+	relay, payloads := newTestRelay(t, false)
+	relay.SetPageLimit(100)
+	relay.SetIgnoreCursor(true)
+
+	// the latest delivered block is missing in builder_blocks_received
+	bids := []relaycommon.BidTraceV2WithTimestampJSON{}
+	for _, payload := range payloads[:9] {
+		bids = append(bids, relaycommon.BidTraceV2WithTimestampJSON{BidTraceV2JSON: payload, Timestamp: 1})
+	}
+	relay.SetBlocksReceived(bids)
+
+	report := runChecker(relay)
+	require.Equal(t, ResultWarn, report.Get(CheckPayloadsLimit).Result)
+	require.Equal(t, "limit=200 is rejected, limit=100 works", report.Get(CheckPayloadsLimit).Details)
+	require.Equal(t, ResultFail, report.Get(CheckPayloadsCursor).Result)
+	require.Equal(t, "cursor=1004 is ignored, returned slot 1009", report.Get(CheckPayloadsCursor).Details)
+	require.Equal(t, ResultPass, report.Get(CheckPayloadsSlotFilter).Result)
+	require.Equal(t, ResultWarn, report.Get(CheckBidsTimestampMs).Result)
+	require.Equal(t, "missing in 2 of 2 bids (relayscan falls back to timestamp)", report.Get(CheckBidsTimestampMs).Details)
+	require.Equal(t, ResultFail, report.Get(CheckConsistency).Result)
+	require.Contains(t, report.Get(CheckConsistency).Details, "slot 1009: delivered block 0x")
+	require.Equal(t, 2, report.Count(ResultFail))
+}
+
+func TestCheckerFailingRelay(t *testing.T) {
+	relay, _ := newTestRelay(t, true)
+	relay.InjectError(mockrelay.PathPayloadDelivered, http.StatusInternalServerError, "internal error", -1)
+	relay.InjectError(mockrelay.PathStatus, http.StatusServiceUnavailable, "", -1)
+
+	report := runChecker(relay)
+	require.Len(t, report.Results, 10)
+	require.Equal(t, ResultFail, report.Get(CheckBuilderStatus).Result)
+	require.Equal(t, ResultFail, report.Get(CheckPayloadsLimit).Result)
+	require.Contains(t, report.Get(CheckPayloadsLimit).Details, "500 / internal error")
+	require.Equal(t, 1, relay.NumRequests(mockrelay.PathPayloadDelivered)) // no fallback to the smaller limit
+	require.Equal(t, 2, report.Count(ResultFail))
+	require.Equal(t, 8, report.Count(ResultSkip))
+}
+
+func TestCheckerRateLimitedPayloads(t *testing.T) {
+	relay, _ := newTestRelay(t, false)
+	relay.InjectRateLimit(mockrelay.PathPayloadDelivered, 1)
+
+	// a rate limit isn't reported as a rejected limit
+	report := runChecker(relay)
+	require.Equal(t, ResultFail, report.Get(CheckPayloadsLimit).Result)
+	require.Contains(t, report.Get(CheckPayloadsLimit).Details, "429 / ")
+	require.Equal(t, 1, relay.NumRequests(mockrelay.PathPayloadDelivered))
+}
+
+func TestCheckFields(t *testing.T) {
+	payload := mockrelay.GeneratePayloadsDelivered(1000, 1)[0]
+	entries := []map[string]json.RawMessage{}
+	err := json.Unmarshal([]byte(fmt.Sprintf(`[
+		{"slot":"1000","parent_hash":"%s","block_hash":"%s","builder_pubkey":"%s","proposer_pubkey":"%s","proposer_fee_recipient":"%s","gas_limit":"1","gas_used":"1","value":"1","num_tx":"1","block_number":"1"},
+		{"slot":1000,"parent_hash":"0x1234","block_hash":"%s","builder_pubkey":"%s","proposer_pubkey":"%s","proposer_fee_recipient":"%s","gas_limit":"1","gas_used":"1","value":"-1","block_number":"1"}
+	]`, payload.ParentHash, payload.BlockHash, payload.BuilderPubkey, payload.ProposerPubkey, payload.ProposerFeeRecipient,
+		payload.BlockHash, payload.BuilderPubkey, payload.ProposerPubkey, payload.ProposerFeeRecipient)), &entries)
+	require.NoError(t, err)
+
+	issues := checkFields(entries[:1], payloadFields)
+	require.True(t, issues.ok())
+
+	issues = checkFields(entries, payloadFields)
+	require.False(t, issues.ok())
+	require.Equal(t, "num_tx missing in 1 entries, parent_hash invalid in 1 entries, slot invalid in 1 entries, value invalid in 1 entries", issues.String())
+}
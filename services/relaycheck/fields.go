@@ -0,0 +1,116 @@
+package relaycheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type fieldFormat int
+
+const (
+	formatUint    fieldFormat = iota // decimal number as string
+	formatHash                       // 32 bytes hex
+	formatPubkey                     // 48 bytes hex
+	formatAddress                    // 20 bytes hex
+)
+
+type fieldSpec struct {
+	name   string
+	format fieldFormat
+}
+
+var (
+	reUint    = regexp.MustCompile(`^[0-9]+$`)
+	reHash    = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+	rePubkey  = regexp.MustCompile(`^0x[0-9a-fA-F]{96}$`)
+	reAddress = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+	// payloadFields are the required fields of proposer_payload_delivered (BidTraceV2)
+	payloadFields = []fieldSpec{
+		{"slot", formatUint},
+		{"parent_hash", formatHash},
+		{"block_hash", formatHash},
+		{"builder_pubkey", formatPubkey},
+		{"proposer_pubkey", formatPubkey},
+		{"proposer_fee_recipient", formatAddress},
+		{"gas_limit", formatUint},
+		{"gas_used", formatUint},
+		{"value", formatUint},
+		{"num_tx", formatUint},
+		{"block_number", formatUint},
+	}
+
+	// bidFields are the required fields of builder_blocks_received (BidTraceV2WithTimestamp). timestamp_ms is
+	// checked separately, because several relays omit it.
+	bidFields = append(append([]fieldSpec{}, payloadFields...), fieldSpec{"timestamp", formatUint})
+)
+
+func (f fieldFormat) valid(s string) bool {
+	switch f {
+	case formatUint:
+		return reUint.MatchString(s)
+	case formatHash:
+		return reHash.MatchString(s)
+	case formatPubkey:
+		return rePubkey.MatchString(s)
+	case formatAddress:
+		return reAddress.MatchString(s)
+	}
+	return false
+}
+
+// fieldIssues counts the entries with a missing or invalid field, by field name
+type fieldIssues struct {
+	missing map[string]int
+	invalid map[string]int
+}
+
+func checkFields(entries []map[string]json.RawMessage, fields []fieldSpec) fieldIssues {
+	issues := fieldIssues{
+		missing: make(map[string]int),
+		invalid: make(map[string]int),
+	}
+	for _, entry := range entries {
+		for _, field := range fields {
+			raw, found := entry[field.name]
+			if !found {
+				issues.missing[field.name]++
+				continue
+			}
+
+			// all fields are strings, also the numbers
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil || !field.format.valid(value) {
+				issues.invalid[field.name]++
+			}
+		}
+	}
+	return issues
+}
+
+func (i fieldIssues) ok() bool {
+	return len(i.missing) == 0 && len(i.invalid) == 0
+}
+
+func (i fieldIssues) String() string {
+	parts := []string{}
+	for _, name := range sortedFieldNames(i.missing) {
+		parts = append(parts, fmt.Sprintf("%s missing in %d entries", name, i.missing[name]))
+	}
+	for _, name := range sortedFieldNames(i.invalid) {
+		parts = append(parts, fmt.Sprintf("%s invalid in %d entries", name, i.invalid[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sortedFieldNames(m map[string]int) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
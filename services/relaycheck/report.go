@@ -0,0 +1,66 @@
+package relaycheck
+
+import (
+	"io"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+type Result string
+
+const (
+	ResultPass Result = "pass"
+	ResultWarn Result = "warn" // deviates from the relay-specs, but relayscan can handle it
+	ResultFail Result = "fail"
+	ResultSkip Result = "skip" // not checked, i.e. because a previous request failed
+)
+
+// CheckResult is the result of a single conformance check
+type CheckResult struct {
+	Name    string `json:"name"`
+	Result  Result `json:"result"`
+	Details string `json:"details,omitempty"`
+}
+
+// Report is the conformance report of a relay
+type Report struct {
+	Relay   string         `json:"relay"`
+	Time    time.Time      `json:"time"`
+	Results []*CheckResult `json:"results"`
+}
+
+func (r *Report) add(name string, result Result, details string) {
+	r.Results = append(r.Results, &CheckResult{Name: name, Result: result, Details: details})
+}
+
+// Count returns the number of checks with the given result
+func (r *Report) Count(result Result) (n int) {
+	for _, res := range r.Results {
+		if res.Result == result {
+			n++
+		}
+	}
+	return n
+}
+
+// Get returns the result of the check with the given name (nil if it's not part of the report)
+func (r *Report) Get(name string) *CheckResult {
+	for _, res := range r.Results {
+		if res.Name == name {
+			return res
+		}
+	}
+	return nil
+}
+
+// Print writes the report as a table
+func (r *Report) Print(w io.Writer) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Check", "Result", "Details"})
+	table.SetAutoWrapText(false)
+	for _, res := range r.Results {
+		table.Append([]string{res.Name, string(res.Result), res.Details})
+	}
+	table.Render()
+}
@@ -0,0 +1,186 @@
+// Package relaymonitor periodically checks the availability and latency of the relay APIs.
+package relaymonitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	EndpointStatus  = "status"
+	EndpointDataAPI = "data_api"
+
+	maxErrorBodyLength = 200
+)
+
+type endpointRequest struct {
+	path  string
+	query map[string]string
+}
+
+// endpointRequests are the relay API requests of every check
+var endpointRequests = map[string]endpointRequest{
+	EndpointStatus:  {path: "/eth/v1/builder/status"},
+	EndpointDataAPI: {path: "/relay/v1/data/bidtraces/proposer_payload_delivered", query: map[string]string{"limit": "1"}},
+}
+
+// DB is the subset of the database service used by the relay monitor
+type DB interface {
+	SaveRelayAPIChecks(entries []*database.RelayAPICheckEntry) error
+	DeleteRelayAPIChecksBefore(before time.Time) (int64, error)
+}
+
+type RelayMonitorOpts struct {
+	Log       *logrus.Entry
+	DB        DB
+	Relays    []common.RelayEntry
+	Interval  time.Duration
+	Timeout   time.Duration    // per request
+	Retention time.Duration    // older checks are deleted after every check, 0 keeps all checks
+	Clock     common.SlotClock // optional, the system clock by default
+}
+
+type RelayMonitor struct {
+	opts   *RelayMonitorOpts
+	log    *logrus.Entry
+	clock  common.SlotClock
+	client http.Client
+}
+
+func NewRelayMonitor(opts *RelayMonitorOpts) *RelayMonitor {
+	m := &RelayMonitor{
+		opts:   opts,
+		log:    opts.Log,
+		clock:  opts.Clock,
+		client: http.Client{Timeout: opts.Timeout},
+	}
+	if m.clock == nil {
+		m.clock = common.NewSystemSlotClock()
+	}
+	return m
+}
+
+// Start checks all relays every interval, forever. The interval is measured from the start of a check, if a check
+// takes longer than the interval the next one starts right after it.
+func (m *RelayMonitor) Start() {
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		m.CheckAndSave()
+		<-ticker.C
+	}
+}
+
+// CheckAndSave checks all endpoints of all relays and saves the results
+func (m *RelayMonitor) CheckAndSave() {
+	entries := m.CheckAll()
+
+	numOK := 0
+	for _, entry := range entries {
+		if entry.OK {
+			numOK++
+		} else {
+			m.log.WithFields(logrus.Fields{
+				"relay":      entry.Relay,
+				"endpoint":   entry.Endpoint,
+				"statusCode": entry.StatusCode,
+				"latencyMS":  entry.LatencyMS,
+			}).Warn("[relay-monitor] check failed: " + entry.Error)
+		}
+	}
+	m.log.Infof("[relay-monitor] %d of %d checks ok", numOK, len(entries))
+
+	if err := m.opts.DB.SaveRelayAPIChecks(entries); err != nil {
+		m.log.WithError(err).Error("[relay-monitor] failed to save checks")
+	}
+
+	if m.opts.Retention > 0 {
+		numDeleted, err := m.opts.DB.DeleteRelayAPIChecksBefore(m.clock.Now().Add(-m.opts.Retention))
+		if err != nil {
+			m.log.WithError(err).Error("[relay-monitor] failed to delete old checks")
+		} else if numDeleted > 0 {
+			m.log.Debugf("[relay-monitor] deleted %d old checks", numDeleted)
+		}
+	}
+}
+
+// CheckAll checks all endpoints of all relays concurrently. The results are sorted by relay and endpoint.
+func (m *RelayMonitor) CheckAll() []*database.RelayAPICheckEntry {
+	endpoints := []string{EndpointStatus, EndpointDataAPI}
+	entries := make([]*database.RelayAPICheckEntry, len(m.opts.Relays)*len(endpoints))
+
+	var wg sync.WaitGroup
+	for i, relay := range m.opts.Relays {
+		for j, endpoint := range endpoints {
+			wg.Add(1)
+			go func(idx int, relay common.RelayEntry, endpoint string) {
+				defer wg.Done()
+				entries[idx] = m.check(relay, endpoint)
+			}(i*len(endpoints)+j, relay, endpoint)
+		}
+	}
+	wg.Wait()
+	return entries
+}
+
+// check requests one endpoint of a relay. The latency includes reading the response body.
+func (m *RelayMonitor) check(relay common.RelayEntry, endpoint string) *database.RelayAPICheckEntry {
+	entry := &database.RelayAPICheckEntry{
+		CheckedAt: m.clock.Now().UTC(),
+		Relay:     relay.Hostname(),
+		Endpoint:  endpoint,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.opts.Timeout)
+	defer cancel()
+
+	endpointReq := endpointRequests[endpoint]
+	url := common.GetURIWithQuery(relay.URL, endpointReq.path, endpointReq.query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+
+	start := time.Now()
+	resp, err := m.client.Do(req)
+	if err != nil {
+		entry.LatencyMS = time.Since(start).Milliseconds()
+		entry.Error = err.Error()
+		return entry
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(resp.Body)
+	entry.LatencyMS = time.Since(start).Milliseconds()
+	entry.StatusCode = resp.StatusCode
+	switch {
+	case err != nil:
+		entry.Error = fmt.Sprintf("could not read response body: %s", err)
+	case resp.StatusCode != http.StatusOK:
+		entry.Error = fmt.Sprintf("HTTP %d: %s", resp.StatusCode, truncateUTF8(body, maxErrorBodyLength))
+	default:
+		entry.OK = true
+	}
+	return entry
+}
+
+// truncateUTF8 shortens s to at most n bytes, without splitting a multi-byte character
+func truncateUTF8(s []byte, n int) []byte {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,132 @@
+package relaymonitor
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flashbots/relayscan/common"
+	"github.com/flashbots/relayscan/database"
+	"github.com/flashbots/relayscan/testutil/mockrelay"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDB struct {
+	entries []*database.RelayAPICheckEntry
+}
+
+func (db *fakeDB) SaveRelayAPIChecks(entries []*database.RelayAPICheckEntry) error {
+	db.entries = append(db.entries, entries...)
+	return nil
+}
+
+func (db *fakeDB) DeleteRelayAPIChecksBefore(before time.Time) (int64, error) {
+	kept := []*database.RelayAPICheckEntry{}
+	for _, entry := range db.entries {
+		if !entry.CheckedAt.Before(before) {
+			kept = append(kept, entry)
+		}
+	}
+	numDeleted := len(db.entries) - len(kept)
+	db.entries = kept
+	return int64(numDeleted), nil
+}
+
+func TestRelayMonitor(t *testing.T) {
+	relay1, err := mockrelay.New()
+	require.NoError(t, err)
+	defer relay1.Close()
+
+	relay2, err := mockrelay.New()
+	require.NoError(t, err)
+	defer relay2.Close()
+	relay2.InjectError(mockrelay.PathPayloadDelivered, http.StatusServiceUnavailable, `{"code":503,"message":"unavailable"}`, -1)
+
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	db := &fakeDB{}
+	monitor := NewRelayMonitor(&RelayMonitorOpts{
+		Log:     common.Logger,
+		DB:      db,
+		Relays:  []common.RelayEntry{relay1.RelayEntry(), relay2.RelayEntry()},
+		Timeout: 5 * time.Second,
+		Clock:   common.NewFakeSlotClock(t0),
+	})
+	monitor.CheckAndSave()
+
+	require.Len(t, db.entries, 4)
+	require.Equal(t, 1, relay1.NumRequests(mockrelay.PathStatus))
+	require.Equal(t, 1, relay1.NumRequests(mockrelay.PathPayloadDelivered))
+
+	for i, endpoint := range []string{EndpointStatus, EndpointDataAPI, EndpointStatus, EndpointDataAPI} {
+		entry := db.entries[i]
+		require.Equal(t, endpoint, entry.Endpoint)
+		require.Equal(t, t0, entry.CheckedAt)
+		require.Equal(t, "127.0.0.1", entry.Relay)
+	}
+
+	for _, entry := range db.entries[:3] {
+		require.True(t, entry.OK)
+		require.Equal(t, http.StatusOK, entry.StatusCode)
+		require.Empty(t, entry.Error)
+	}
+
+	failed := db.entries[3]
+	require.False(t, failed.OK)
+	require.Equal(t, http.StatusServiceUnavailable, failed.StatusCode)
+	require.Equal(t, `HTTP 503: {"code":503,"message":"unavailable"}`, failed.Error)
+}
+
+func TestRelayMonitorRetention(t *testing.T) {
+	relay, err := mockrelay.New()
+	require.NoError(t, err)
+	defer relay.Close()
+
+	clock := common.NewFakeSlotClock(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+	db := &fakeDB{}
+	monitor := NewRelayMonitor(&RelayMonitorOpts{
+		Log:       common.Logger,
+		DB:        db,
+		Relays:    []common.RelayEntry{relay.RelayEntry()},
+		Timeout:   5 * time.Second,
+		Retention: time.Hour,
+		Clock:     clock,
+	})
+	monitor.CheckAndSave()
+	clock.Advance(30 * time.Minute)
+	monitor.CheckAndSave()
+	require.Len(t, db.entries, 4)
+
+	// the checks of the first run are more than an hour old
+	clock.Advance(31 * time.Minute)
+	monitor.CheckAndSave()
+	require.Len(t, db.entries, 4)
+	require.Equal(t, clock.Now().Add(-31*time.Minute), db.entries[0].CheckedAt)
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	require.Equal(t, "abc", string(truncateUTF8([]byte("abc"), 5)))
+	require.Equal(t, "ab", string(truncateUTF8([]byte("abc"), 2)))
+	require.Equal(t, "a", string(truncateUTF8([]byte("aé"), 2))) // é is 2 bytes
+	require.Equal(t, "aé", string(truncateUTF8([]byte("aéb"), 3)))
+}
+
+func TestRelayMonitorConnectionError(t *testing.T) {
+	relay, err := mockrelay.New()
+	require.NoError(t, err)
+	entry := relay.RelayEntry()
+	relay.Close()
+
+	monitor := NewRelayMonitor(&RelayMonitorOpts{
+		Log:     common.Logger,
+		DB:      &fakeDB{},
+		Relays:  []common.RelayEntry{entry},
+		Timeout: 5 * time.Second,
+	})
+	entries := monitor.CheckAll()
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		require.False(t, entry.OK)
+		require.Equal(t, 0, entry.StatusCode)
+		require.Contains(t, entry.Error, "connection refused")
+	}
+}
@@ -2,6 +2,7 @@ package website
 
 import (
 	_ "embed"
+	"net/url"
 	"text/template"
 	"time"
 
@@ -57,6 +58,102 @@ type HTMLDataDailyStats struct {
 	BuilderProfits       []*database.BuilderProfitEntry
 }
 
+// RelayStats contains the history and health of a single relay
+type RelayStats struct {
+	Relay   string
+	Since   time.Time
+	Until   time.Time
+	TimeStr string
+
+	PayloadsOverTime []*RelayPayloadsOverTimeEntry
+	TopBuilders      []*TopBuilderDisplayEntry
+
+	ValueCheck            *database.RelayValueCheckStatsEntry
+	ValueCheckFailureRate string
+	IncorrectClaims       []*database.RelayPayloadCheckEntry
+	MissedSlots           []*database.RelayPayloadCheckEntry
+
+	DataAPIStatus       []*database.DataAPIBackfillStatusEntry
+	DataAPIAvailability string // percent of backfill runs in which the data API was available
+
+	Uptime *RelayUptime // from the relay monitor (nil if there are no checks)
+}
+
+type HTMLDataRelay struct {
+	Title     string
+	TimeSpans []string
+	TimeSpan  string
+
+	Stats *RelayStats
+}
+
+// BuilderStats contains the history of a single (consolidated) builder
+type BuilderStats struct {
+	Name    string
+	Since   time.Time
+	Until   time.Time
+	TimeStr string
+
+	NumBlocks      uint64
+	MarketShare    string
+	ProfitTotal    string
+	SubsidiesTotal string
+
+	// Transactions not seen in the public mempool, only for blocks analyzed with analyze-private-tx
+	PrivateTx        *database.BuilderPrivateTxEntry
+	PrivateTxPercent string // empty if no block was analyzed
+
+	OverTime     []*BuilderStatsOverTimeEntry
+	Aliases      []*database.TopBuilderEntry // extra_data values
+	Pubkeys      []*database.BuilderPubkeyEntry
+	Relays       []*database.TopRelayEntry
+	RecentBlocks []*database.BuilderBlockEntry
+}
+
+type HTMLDataBuilder struct {
+	Title     string
+	TimeSpans []string
+	TimeSpan  string
+
+	Stats *BuilderStats
+}
+
+// ValueClaimReport compares claimed and delivered payload values per relay and per builder
+type ValueClaimReport struct {
+	Since   time.Time
+	Until   time.Time
+	TimeStr string // the timespan, or empty for a custom time range
+
+	Relays     []*ValueClaimAccuracy
+	Builders   []*ValueClaimAccuracy
+	Overclaims []*database.ValueOverclaimEntry // largest shortfalls
+}
+
+type HTMLDataValueClaims struct {
+	Title     string
+	TimeSpans []string
+	TimeSpan  string
+
+	Report *ValueClaimReport
+}
+
+// RelayMonitorReport is the uptime and API latency of all relays
+type RelayMonitorReport struct {
+	Since   time.Time
+	Until   time.Time
+	TimeStr string // the timespan, or empty for a custom time range
+
+	Relays []*RelayUptime
+}
+
+type HTMLDataRelayMonitor struct {
+	Title     string
+	TimeSpans []string
+	TimeSpan  string
+
+	Report *RelayMonitorReport
+}
+
 var funcMap = template.FuncMap{
 	"weiToEth":              weiToEth,
 	"prettyInt":             prettyInt,
@@ -67,6 +164,7 @@ var funcMap = template.FuncMap{
 	"builderProfitTable":    builderProfitTable,
 	"humanTime":             humanize.Time,
 	"lowercaseNoWhitespace": lowercaseNoWhitespace,
+	"pathEscape":            url.PathEscape,
 }
 
 func ParseIndexTemplate() (*template.Template, error) {
@@ -76,3 +174,19 @@ func ParseIndexTemplate() (*template.Template, error) {
 func ParseDailyStatsTemplate() (*template.Template, error) {
 	return template.New("daily-stats.html").Funcs(funcMap).ParseFiles("services/website/templates/daily-stats.html", "services/website/templates/base.html")
 }
+
+func ParseRelayTemplate() (*template.Template, error) {
+	return template.New("relay.html").Funcs(funcMap).ParseFiles("services/website/templates/relay.html", "services/website/templates/base.html")
+}
+
+func ParseBuilderTemplate() (*template.Template, error) {
+	return template.New("builder.html").Funcs(funcMap).ParseFiles("services/website/templates/builder.html", "services/website/templates/base.html")
+}
+
+func ParseValueClaimsTemplate() (*template.Template, error) {
+	return template.New("value-claims.html").Funcs(funcMap).ParseFiles("services/website/templates/value-claims.html", "services/website/templates/base.html")
+}
+
+func ParseRelayMonitorTemplate() (*template.Template, error) {
+	return template.New("relay-monitor.html").Funcs(funcMap).ParseFiles("services/website/templates/relay-monitor.html", "services/website/templates/base.html")
+}
@@ -1,6 +1,10 @@
 package website
 
-import "github.com/flashbots/relayscan/database"
+import (
+	"time"
+
+	"github.com/flashbots/relayscan/database"
+)
 
 type HTTPErrorResp struct {
 	Code    int    `json:"code"`
@@ -11,3 +15,56 @@ type TopBuilderDisplayEntry struct {
 	Info     *database.TopBuilderEntry   `json:"info"`
 	Children []*database.TopBuilderEntry `json:"children"`
 }
+
+type RelayPayloadsOverTimeEntry struct {
+	SlotStart   uint64    `json:"slot_start"`
+	Time        time.Time `json:"time"`
+	NumPayloads uint64    `json:"num_payloads"`
+	BarPercent  string    `json:"-"` // relative to the bucket with the most payloads
+}
+
+type BuilderStatsOverTimeEntry struct {
+	SlotStart      uint64    `json:"slot_start"`
+	Time           time.Time `json:"time"`
+	NumBlocks      uint64    `json:"num_blocks"`
+	NumBlocksTotal uint64    `json:"num_blocks_total"`
+	MarketShare    string    `json:"market_share"`
+	ProfitTotal    string    `json:"profit_total"`
+	SubsidiesTotal string    `json:"subsidies_total"`
+	BarPercent     string    `json:"-"` // relative to the bucket with the most blocks
+}
+
+// ValueClaimAccuracy is the value claim accuracy of a relay or builder
+type ValueClaimAccuracy struct {
+	Name                  string             `json:"name"`
+	NumPayloads           uint64             `json:"num_payloads"`
+	NumOverclaims         uint64             `json:"num_overclaims"`
+	NumUnderclaims        uint64             `json:"num_underclaims"`
+	OverclaimRate         string             `json:"overclaim_rate"`
+	ShortfallTotalEth     string             `json:"shortfall_total_eth"`
+	ShortfallMaxEth       string             `json:"shortfall_max_eth"`
+	ExcessTotalEth        string             `json:"excess_total_eth"`
+	ShortfallDistribution []*ShortfallBucket `json:"shortfall_distribution"`
+}
+
+type ShortfallBucket struct {
+	Label         string `json:"label"`
+	NumOverclaims uint64 `json:"num_overclaims"`
+}
+
+// RelayUptime is the availability and latency of the API endpoints of a relay, measured by the relay monitor
+type RelayUptime struct {
+	Relay   string               `json:"relay"`
+	Status  *RelayEndpointUptime `json:"status"`   // builder status endpoint
+	DataAPI *RelayEndpointUptime `json:"data_api"` // proposer_payload_delivered
+}
+
+type RelayEndpointUptime struct {
+	NumChecks  uint64 `json:"num_checks"`
+	NumOK      uint64 `json:"num_ok"`
+	Uptime     string `json:"uptime"` // percent of successful checks
+	LatencyP50 string `json:"latency_p50_ms"`
+	LatencyP90 string `json:"latency_p90_ms"`
+	LatencyP99 string `json:"latency_p99_ms"`
+	LastError  string `json:"last_error,omitempty"`
+}
@@ -4,12 +4,15 @@ import (
 	_ "embed"
 	"fmt"
 	"math/big"
+	"net/http"
 	"sort"
 	"strings"
 	"time"
 	"unicode"
 
+	"github.com/flashbots/relayscan/common"
 	"github.com/flashbots/relayscan/database"
+	"github.com/flashbots/relayscan/services/relaymonitor"
 	"github.com/flashbots/relayscan/vars"
 	"github.com/olekukonko/tablewriter"
 	"golang.org/x/text/cases"
@@ -276,6 +279,307 @@ func getLastWednesday() time.Time {
 	return targetDate
 }
 
+// timespanToDuration returns the duration of one of the supported timespans (i.e. 24h or 7d)
+func timespanToDuration(timespan string) (time.Duration, error) {
+	if !common.StringSliceContains(timespans, timespan) {
+		return 0, fmt.Errorf("%w: %s", errInvalidTimespan, timespan)
+	}
+	if timespan == "7d" {
+		return 7 * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(timespan)
+}
+
+// statsBucketSlots returns the number of slots per bucket of the over-time charts
+func statsBucketSlots(duration time.Duration) uint64 {
+	switch {
+	case duration > 24*time.Hour:
+		return 7200 // 1 day
+	case duration > time.Hour:
+		return 300 // 1 hour
+	default:
+		return 25 // 5 minutes
+	}
+}
+
+// fillRelayPayloadsBuckets returns one entry for every bucket between startSlot and endSlot, including the empty ones
+func fillRelayPayloadsBuckets(entries []*database.RelayPayloadsBucketEntry, startSlot, endSlot, bucketSlots uint64) []*RelayPayloadsOverTimeEntry {
+	payloads := make(map[uint64]uint64)
+	maxPayloads := uint64(0)
+	for _, entry := range entries {
+		payloads[entry.SlotStart] = entry.NumPayloads
+		maxPayloads = max(maxPayloads, entry.NumPayloads)
+	}
+
+	res := []*RelayPayloadsOverTimeEntry{}
+	for slot := (startSlot / bucketSlots) * bucketSlots; slot <= endSlot; slot += bucketSlots {
+		entry := &RelayPayloadsOverTimeEntry{
+			SlotStart:   slot,
+			Time:        common.SlotToTime(slot),
+			NumPayloads: payloads[slot],
+			BarPercent:  "0",
+		}
+		if maxPayloads > 0 {
+			entry.BarPercent = percent(entry.NumPayloads, maxPayloads)
+		}
+		res = append(res, entry)
+	}
+	return res
+}
+
+// fillBuilderStatsBuckets returns one entry for every bucket between startSlot and endSlot, including the empty ones
+func fillBuilderStatsBuckets(entries []*database.BuilderStatsBucketEntry, startSlot, endSlot, bucketSlots uint64) []*BuilderStatsOverTimeEntry {
+	buckets := make(map[uint64]*database.BuilderStatsBucketEntry)
+	maxBlocks := uint64(0)
+	for _, entry := range entries {
+		buckets[entry.SlotStart] = entry
+		maxBlocks = max(maxBlocks, entry.NumBlocks)
+	}
+
+	res := []*BuilderStatsOverTimeEntry{}
+	for slot := (startSlot / bucketSlots) * bucketSlots; slot <= endSlot; slot += bucketSlots {
+		entry := &BuilderStatsOverTimeEntry{
+			SlotStart:      slot,
+			Time:           common.SlotToTime(slot),
+			MarketShare:    "0",
+			ProfitTotal:    "0",
+			SubsidiesTotal: "0",
+			BarPercent:     "0",
+		}
+		if bucket, found := buckets[slot]; found {
+			entry.NumBlocks = bucket.NumBlocks
+			entry.NumBlocksTotal = bucket.NumBlocksTotal
+			entry.ProfitTotal = bucket.ProfitTotal
+			entry.SubsidiesTotal = bucket.SubsidiesTotal
+			if bucket.NumBlocksTotal > 0 {
+				entry.MarketShare = percent(bucket.NumBlocks, bucket.NumBlocksTotal)
+			}
+		}
+		if maxBlocks > 0 {
+			entry.BarPercent = percent(entry.NumBlocks, maxBlocks)
+		}
+		res = append(res, entry)
+	}
+	return res
+}
+
+// findBuilder returns the (consolidated) builder with the given name, i.e. a builder group or an extra_data value
+func findBuilder(builders []*TopBuilderDisplayEntry, name string) (builder *TopBuilderDisplayEntry, extraData []string) {
+	for _, entry := range builders {
+		if entry.Info.ExtraData != name {
+			continue
+		}
+		if len(entry.Children) == 0 {
+			return entry, []string{entry.Info.ExtraData}
+		}
+		for _, child := range entry.Children {
+			extraData = append(extraData, child.ExtraData)
+		}
+		return entry, extraData
+	}
+	return nil, nil
+}
+
+// builderFromExtraData returns the builder with the given name (a builder group or an extra_data value) from all known
+// extra_data values, without blocks, for builders that have no blocks in the selected time range
+func builderFromExtraData(extraDataValues []string, name string) (builder *TopBuilderDisplayEntry, extraData []string) {
+	builder = &TopBuilderDisplayEntry{
+		Info:     &database.TopBuilderEntry{ExtraData: name},
+		Children: []*database.TopBuilderEntry{},
+	}
+	_, isGroup := vars.BuilderGroups[name]
+	for _, value := range extraDataValues {
+		if vars.BuilderNameFromExtraData(value) != name {
+			continue
+		}
+		extraData = append(extraData, value)
+		if isGroup {
+			builder.Children = append(builder.Children, &database.TopBuilderEntry{ExtraData: value})
+		}
+	}
+	if len(extraData) == 0 {
+		return nil, nil
+	}
+	return builder, extraData
+}
+
+// maxTimeRange is the longest since/until range of a request, the reports aren't cached and scan the whole range
+const maxTimeRange = 31 * 24 * time.Hour
+
+// timeRangeFromRequest returns the time range of a request: either since/until query args (dates or times in UTC, a
+// date as until includes that day, at most maxTimeRange), or a timespan (query arg t, 24h by default) until now
+func timeRangeFromRequest(req *http.Request, now time.Time) (since, until time.Time, timespan string, err error) {
+	sinceArg := req.URL.Query().Get("since")
+	untilArg := req.URL.Query().Get("until")
+	if sinceArg == "" && untilArg == "" {
+		timespan = timespanFromRequest(req)
+		duration, err := timespanToDuration(timespan)
+		if err != nil {
+			return since, until, "", err
+		}
+		return now.Add(-duration), now, timespan, nil
+	}
+
+	if sinceArg == "" {
+		return since, until, "", fmt.Errorf("%w: since is required", errInvalidTimeRange)
+	}
+	since, _, err = parseTimeArg(sinceArg)
+	if err != nil {
+		return since, until, "", err
+	}
+
+	until = now
+	if untilArg != "" {
+		var isDate bool
+		until, isDate, err = parseTimeArg(untilArg)
+		if err != nil {
+			return since, until, "", err
+		}
+		if isDate {
+			until = until.Add(24*time.Hour - time.Second)
+		}
+	}
+
+	if !since.Before(until) {
+		return since, until, "", fmt.Errorf("%w: since must be before until", errInvalidTimeRange)
+	}
+	if until.Sub(since) > maxTimeRange {
+		return since, until, "", fmt.Errorf("%w: at most %d days", errInvalidTimeRange, maxTimeRange/(24*time.Hour))
+	}
+	return since, until, "", nil
+}
+
+// parseTimeArg parses a date (2006-01-02) or time (2006-01-02 15:04 or RFC3339) in UTC
+func parseTimeArg(arg string) (t time.Time, isDate bool, err error) {
+	t, err = time.Parse("2006-01-02", arg)
+	if err == nil {
+		return t, true, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", time.RFC3339} {
+		t, err = time.Parse(layout, arg)
+		if err == nil {
+			return t.UTC(), false, nil
+		}
+	}
+	return t, false, fmt.Errorf("%w: %s", errInvalidTimeRange, arg)
+}
+
+var shortfallBucketLabels = []string{"<= 0.001 ETH", "<= 0.01 ETH", "<= 0.1 ETH", "<= 1 ETH", "> 1 ETH"}
+
+// consolidateValueClaimAccuracy merges the entries with the same name (as returned by nameFn, i.e. the builder of an
+// extra_data value), and returns them with the largest total shortfall first
+func consolidateValueClaimAccuracy(entries []*database.ValueClaimAccuracyEntry, nameFn func(string) string) []*ValueClaimAccuracy {
+	type accuracy struct {
+		entry          database.ValueClaimAccuracyEntry
+		shortfallTotal *big.Int
+		shortfallMax   *big.Int
+		excessTotal    *big.Int
+	}
+
+	byName := make(map[string]*accuracy)
+	for _, entry := range entries {
+		name := nameFn(entry.Name)
+		acc, found := byName[name]
+		if !found {
+			acc = &accuracy{entry: database.ValueClaimAccuracyEntry{Name: name}, shortfallTotal: new(big.Int), shortfallMax: new(big.Int), excessTotal: new(big.Int)}
+			byName[name] = acc
+		}
+		acc.entry.NumPayloads += entry.NumPayloads
+		acc.entry.NumOverclaims += entry.NumOverclaims
+		acc.entry.NumUnderclaims += entry.NumUnderclaims
+		acc.entry.NumShortfallLte0001 += entry.NumShortfallLte0001
+		acc.entry.NumShortfallLte001 += entry.NumShortfallLte001
+		acc.entry.NumShortfallLte01 += entry.NumShortfallLte01
+		acc.entry.NumShortfallLte1 += entry.NumShortfallLte1
+		acc.entry.NumShortfallGt1 += entry.NumShortfallGt1
+		acc.shortfallTotal.Add(acc.shortfallTotal, common.StrToBigInt(entry.ShortfallTotalWei))
+		acc.excessTotal.Add(acc.excessTotal, common.StrToBigInt(entry.ExcessTotalWei))
+		if shortfallMax := common.StrToBigInt(entry.ShortfallMaxWei); shortfallMax.Cmp(acc.shortfallMax) > 0 {
+			acc.shortfallMax = shortfallMax
+		}
+	}
+
+	accs := []*accuracy{}
+	for _, acc := range byName {
+		accs = append(accs, acc)
+	}
+	sort.Slice(accs, func(i, j int) bool {
+		if cmp := accs[i].shortfallTotal.Cmp(accs[j].shortfallTotal); cmp != 0 {
+			return cmp > 0
+		}
+		if accs[i].entry.NumPayloads != accs[j].entry.NumPayloads {
+			return accs[i].entry.NumPayloads > accs[j].entry.NumPayloads
+		}
+		return accs[i].entry.Name < accs[j].entry.Name
+	})
+
+	resp := make([]*ValueClaimAccuracy, len(accs))
+	for i, acc := range accs {
+		entry := acc.entry
+		resp[i] = &ValueClaimAccuracy{
+			Name:              entry.Name,
+			NumPayloads:       entry.NumPayloads,
+			NumOverclaims:     entry.NumOverclaims,
+			NumUnderclaims:    entry.NumUnderclaims,
+			OverclaimRate:     percent(entry.NumOverclaims, entry.NumPayloads),
+			ShortfallTotalEth: common.WeiToEthStr(acc.shortfallTotal),
+			ShortfallMaxEth:   common.WeiToEthStr(acc.shortfallMax),
+			ExcessTotalEth:    common.WeiToEthStr(acc.excessTotal),
+		}
+		for j, num := range []uint64{entry.NumShortfallLte0001, entry.NumShortfallLte001, entry.NumShortfallLte01, entry.NumShortfallLte1, entry.NumShortfallGt1} {
+			resp[i].ShortfallDistribution = append(resp[i].ShortfallDistribution, &ShortfallBucket{Label: shortfallBucketLabels[j], NumOverclaims: num})
+		}
+	}
+	return resp
+}
+
+// dataAPIAvailability returns the percentage of backfill runs in which the data API was available
+func dataAPIAvailability(status []*database.DataAPIBackfillStatusEntry) string {
+	if len(status) == 0 {
+		return ""
+	}
+	numAvailable := uint64(0)
+	for _, entry := range status {
+		if entry.Available {
+			numAvailable++
+		}
+	}
+	return percent(numAvailable, uint64(len(status)))
+}
+
+// consolidateRelayUptime merges the per-endpoint uptime entries into one entry per relay (sorted by relay)
+func consolidateRelayUptime(entries []*database.RelayAPIUptimeEntry) []*RelayUptime {
+	resp := []*RelayUptime{}
+	relays := make(map[string]*RelayUptime)
+	for _, entry := range entries {
+		relay, found := relays[entry.Relay]
+		if !found {
+			relay = &RelayUptime{Relay: entry.Relay}
+			relays[entry.Relay] = relay
+			resp = append(resp, relay)
+		}
+
+		endpoint := &RelayEndpointUptime{
+			NumChecks:  entry.NumChecks,
+			NumOK:      entry.NumOK,
+			Uptime:     percent(entry.NumOK, entry.NumChecks),
+			LatencyP50: fmt.Sprintf("%.0f", entry.LatencyP50MS),
+			LatencyP90: fmt.Sprintf("%.0f", entry.LatencyP90MS),
+			LatencyP99: fmt.Sprintf("%.0f", entry.LatencyP99MS),
+			LastError:  entry.LastError,
+		}
+		switch entry.Endpoint {
+		case relaymonitor.EndpointStatus:
+			relay.Status = endpoint
+		case relaymonitor.EndpointDataAPI:
+			relay.DataAPI = endpoint
+		}
+	}
+
+	sort.Slice(resp, func(i, j int) bool { return resp[i].Relay < resp[j].Relay })
+	return resp
+}
+
 func lowercaseNoWhitespace(str string) string {
 	return strings.Map(func(r rune) rune {
 		if unicode.IsSpace(r) {
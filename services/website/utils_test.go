@@ -1,9 +1,15 @@
 package website
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/flashbots/relayscan/common"
 	"github.com/flashbots/relayscan/database"
+	"github.com/flashbots/relayscan/services/relaymonitor"
+	"github.com/flashbots/relayscan/vars"
 	"github.com/stretchr/testify/require"
 )
 
@@ -150,3 +156,181 @@ func TestLowercaseNoWhitespace(t *testing.T) {
 	c1 := lowercaseNoWhitespace("abCD 123!@#")
 	require.Equal(t, "abcd123!@#", c1)
 }
+
+func TestTimespanToDuration(t *testing.T) {
+	d, err := timespanToDuration("7d")
+	require.NoError(t, err)
+	require.Equal(t, 7*24*time.Hour, d)
+
+	d, err = timespanToDuration("12h")
+	require.NoError(t, err)
+	require.Equal(t, 12*time.Hour, d)
+
+	_, err = timespanToDuration("5h")
+	require.ErrorIs(t, err, errInvalidTimespan)
+}
+
+func TestFillRelayPayloadsBuckets(t *testing.T) {
+	entries := []*database.RelayPayloadsBucketEntry{
+		{SlotStart: 1000, NumPayloads: 10},
+		{SlotStart: 1050, NumPayloads: 5},
+	}
+	buckets := fillRelayPayloadsBuckets(entries, 1010, 1060, 25)
+	require.Len(t, buckets, 3)
+	require.Equal(t, uint64(1000), buckets[0].SlotStart)
+	require.Equal(t, uint64(10), buckets[0].NumPayloads)
+	require.Equal(t, "100.00", buckets[0].BarPercent)
+	require.Equal(t, uint64(0), buckets[1].NumPayloads)
+	require.Equal(t, "0.00", buckets[1].BarPercent)
+	require.Equal(t, uint64(5), buckets[2].NumPayloads)
+	require.Equal(t, "50.00", buckets[2].BarPercent)
+	require.Equal(t, common.SlotToTime(1050), buckets[2].Time)
+}
+
+func TestDataAPIAvailability(t *testing.T) {
+	require.Empty(t, dataAPIAvailability(nil))
+	status := []*database.DataAPIBackfillStatusEntry{{Available: true}, {Available: false}, {Available: true}, {Available: true}}
+	require.Equal(t, "75.00", dataAPIAvailability(status))
+}
+
+func TestFillBuilderStatsBuckets(t *testing.T) {
+	entries := []*database.BuilderStatsBucketEntry{
+		{SlotStart: 300, NumBlocks: 30, NumBlocksTotal: 300, ProfitTotal: "1.5", SubsidiesTotal: "0.1"},
+		{SlotStart: 900, NumBlocks: 15, NumBlocksTotal: 300, ProfitTotal: "-0.2", SubsidiesTotal: "0.3"},
+	}
+	buckets := fillBuilderStatsBuckets(entries, 310, 1000, 300)
+	require.Len(t, buckets, 3)
+	require.Equal(t, uint64(300), buckets[0].SlotStart)
+	require.Equal(t, "10.00", buckets[0].MarketShare)
+	require.Equal(t, "1.5", buckets[0].ProfitTotal)
+	require.Equal(t, "100.00", buckets[0].BarPercent)
+	require.Equal(t, uint64(0), buckets[1].NumBlocks)
+	require.Equal(t, "0", buckets[1].MarketShare)
+	require.Equal(t, "0", buckets[1].ProfitTotal)
+	require.Equal(t, "5.00", buckets[2].MarketShare)
+	require.Equal(t, "50.00", buckets[2].BarPercent)
+}
+
+func TestFindBuilder(t *testing.T) {
+	builders := consolidateBuilderEntries([]*database.TopBuilderEntry{
+		{ExtraData: "made by builder0x69", NumBlocks: 1},
+		{ExtraData: "builder0x69", NumBlocks: 2},
+		{ExtraData: "foo-builder", NumBlocks: 1},
+	})
+
+	builder, extraData := findBuilder(builders, "builder0x69")
+	require.NotNil(t, builder)
+	require.Equal(t, uint64(3), builder.Info.NumBlocks)
+	require.ElementsMatch(t, []string{"builder0x69", "made by builder0x69"}, extraData)
+
+	builder, extraData = findBuilder(builders, "foo-builder")
+	require.NotNil(t, builder)
+	require.Equal(t, []string{"foo-builder"}, extraData)
+
+	builder, _ = findBuilder(builders, "made by builder0x69")
+	require.Nil(t, builder)
+}
+
+func TestBuilderFromExtraData(t *testing.T) {
+	extraDataValues := []string{"made by builder0x69", "builder0x69", "foo-builder", ""}
+
+	builder, extraData := builderFromExtraData(extraDataValues, "builder0x69")
+	require.NotNil(t, builder)
+	require.Equal(t, uint64(0), builder.Info.NumBlocks)
+	require.Len(t, builder.Children, 2)
+	require.ElementsMatch(t, []string{"builder0x69", "made by builder0x69"}, extraData)
+
+	builder, extraData = builderFromExtraData(extraDataValues, "foo-builder")
+	require.NotNil(t, builder)
+	require.Empty(t, builder.Children)
+	require.Equal(t, []string{"foo-builder"}, extraData)
+
+	builder, _ = builderFromExtraData(extraDataValues, "made by builder0x69")
+	require.Nil(t, builder)
+
+	builder, _ = builderFromExtraData(extraDataValues, "unknown-builder")
+	require.Nil(t, builder)
+}
+
+func TestTimeRangeFromRequest(t *testing.T) {
+	now := time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC)
+
+	req := httptest.NewRequest(http.MethodGet, "/value-claims", nil)
+	since, until, timespan, err := timeRangeFromRequest(req, now)
+	require.NoError(t, err)
+	require.Equal(t, "24h", timespan)
+	require.Equal(t, now.Add(-24*time.Hour), since)
+	require.Equal(t, now, until)
+
+	req = httptest.NewRequest(http.MethodGet, "/value-claims?since=2024-06-01&until=2024-06-02", nil)
+	since, until, timespan, err = timeRangeFromRequest(req, now)
+	require.NoError(t, err)
+	require.Empty(t, timespan)
+	require.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), since)
+	require.Equal(t, time.Date(2024, 6, 2, 23, 59, 59, 0, time.UTC), until)
+
+	req = httptest.NewRequest(http.MethodGet, "/value-claims?since=2024-06-01+10:30", nil)
+	since, until, _, err = timeRangeFromRequest(req, now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 6, 1, 10, 30, 0, 0, time.UTC), since)
+	require.Equal(t, now, until)
+
+	req = httptest.NewRequest(http.MethodGet, "/value-claims?since=2024-05-01&until=2024-05-31", nil)
+	_, _, _, err = timeRangeFromRequest(req, now)
+	require.NoError(t, err)
+
+	for _, query := range []string{"t=5h", "until=2024-06-01", "since=yesterday", "since=2024-06-02&until=2024-06-01+12:00", "since=2024-05-01&until=2024-06-01", "since=2024-01-01"} {
+		req = httptest.NewRequest(http.MethodGet, "/value-claims?"+query, nil)
+		_, _, _, err = timeRangeFromRequest(req, now)
+		require.Error(t, err, query)
+	}
+}
+
+func TestConsolidateValueClaimAccuracy(t *testing.T) {
+	entries := []*database.ValueClaimAccuracyEntry{
+		{Name: "foo-builder", NumPayloads: 10, ShortfallTotalWei: "0", ShortfallMaxWei: "0", ExcessTotalWei: "0"},
+		{Name: "made by builder0x69", NumPayloads: 4, NumOverclaims: 1, ShortfallTotalWei: "2000000000000000", ShortfallMaxWei: "2000000000000000", ExcessTotalWei: "0", NumShortfallLte001: 1},
+		{Name: "builder0x69", NumPayloads: 6, NumOverclaims: 2, NumUnderclaims: 1, ShortfallTotalWei: "1500000000000000000", ShortfallMaxWei: "1000000000000000000", ExcessTotalWei: "5", NumShortfallLte0001: 1, NumShortfallLte1: 1},
+	}
+
+	res := consolidateValueClaimAccuracy(entries, vars.BuilderNameFromExtraData)
+	require.Len(t, res, 2)
+
+	require.Equal(t, "builder0x69", res[0].Name)
+	require.Equal(t, uint64(10), res[0].NumPayloads)
+	require.Equal(t, uint64(3), res[0].NumOverclaims)
+	require.Equal(t, uint64(1), res[0].NumUnderclaims)
+	require.Equal(t, "30.00", res[0].OverclaimRate)
+	require.Equal(t, "1.502000", res[0].ShortfallTotalEth)
+	require.Equal(t, "1.000000", res[0].ShortfallMaxEth)
+	require.Len(t, res[0].ShortfallDistribution, 5)
+	require.Equal(t, uint64(1), res[0].ShortfallDistribution[0].NumOverclaims)
+	require.Equal(t, uint64(1), res[0].ShortfallDistribution[1].NumOverclaims)
+	require.Equal(t, uint64(1), res[0].ShortfallDistribution[3].NumOverclaims)
+
+	require.Equal(t, "foo-builder", res[1].Name)
+	require.Equal(t, "0.00", res[1].OverclaimRate)
+}
+
+func TestConsolidateRelayUptime(t *testing.T) {
+	entries := []*database.RelayAPIUptimeEntry{
+		{Relay: "relay2", Endpoint: relaymonitor.EndpointStatus, NumChecks: 4, NumOK: 4, LatencyP50MS: 20, LatencyP90MS: 30.4, LatencyP99MS: 49.6},
+		{Relay: "relay1", Endpoint: relaymonitor.EndpointDataAPI, NumChecks: 4, NumOK: 3, LatencyP50MS: 100, LatencyP90MS: 200, LatencyP99MS: 300, LastError: "HTTP 503"},
+		{Relay: "relay1", Endpoint: relaymonitor.EndpointStatus, NumChecks: 4, NumOK: 0},
+	}
+
+	res := consolidateRelayUptime(entries)
+	require.Len(t, res, 2)
+
+	require.Equal(t, "relay1", res[0].Relay)
+	require.Equal(t, "0.00", res[0].Status.Uptime)
+	require.Equal(t, "75.00", res[0].DataAPI.Uptime)
+	require.Equal(t, "100", res[0].DataAPI.LatencyP50)
+	require.Equal(t, "HTTP 503", res[0].DataAPI.LastError)
+
+	require.Equal(t, "relay2", res[1].Relay)
+	require.Equal(t, "100.00", res[1].Status.Uptime)
+	require.Equal(t, "30", res[1].Status.LatencyP90)
+	require.Equal(t, "50", res[1].Status.LatencyP99)
+	require.Nil(t, res[1].DataAPI)
+}
@@ -2,6 +2,7 @@
 package website
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -27,6 +28,10 @@ import (
 
 var (
 	ErrServerAlreadyStarted = errors.New("server was already started")
+	errInvalidTimespan      = errors.New("invalid timespan")
+	errInvalidTimeRange     = errors.New("invalid time range")
+	errRelayNotFound        = errors.New("relay not found")
+	errBuilderNotFound      = errors.New("builder not found")
 	envSkip7dStats          = os.Getenv("SKIP_7D_STATS") != ""
 	timespans               = []string{"7d", "24h", "12h", "1h"}
 )
@@ -38,20 +43,27 @@ type WebserverOpts struct {
 	EnablePprof   bool
 	Dev           bool // reloads template on every request
 	Only24h       bool
+
+	SlotClock common.SlotClock // optional, the system clock by default
 }
 
 type Webserver struct {
 	opts *WebserverOpts
 	log  *logrus.Entry
 
-	db *database.DatabaseService
+	db        *database.DatabaseService
+	slotClock common.SlotClock
 
 	srv        *http.Server
 	srvStarted uberatomic.Bool
 	minifier   *minify.M
 
-	templateIndex      *template.Template
-	templateDailyStats *template.Template
+	templateIndex       *template.Template
+	templateDailyStats  *template.Template
+	templateRelay       *template.Template
+	templateBuilder     *template.Template
+	templateValueClaims *template.Template
+	templateRelayMon    *template.Template
 
 	// data
 	stats    map[string]*Stats
@@ -75,10 +87,16 @@ func NewWebserver(opts *WebserverOpts) (*Webserver, error) {
 
 	opts.Only24h = opts.Dev
 
+	slotClock := opts.SlotClock
+	if slotClock == nil {
+		slotClock = common.NewSystemSlotClock()
+	}
+
 	server := &Webserver{
 		opts:                  opts,
 		log:                   opts.Log,
 		db:                    opts.DB,
+		slotClock:             slotClock,
 		stats:                 make(map[string]*Stats),
 		html:                  make(map[string]*[]byte),
 		minifier:              minifier,
@@ -96,6 +114,26 @@ func NewWebserver(opts *WebserverOpts) (*Webserver, error) {
 		return nil, err
 	}
 
+	server.templateRelay, err = ParseRelayTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	server.templateBuilder, err = ParseBuilderTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	server.templateValueClaims, err = ParseValueClaimsTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	server.templateRelayMon, err = ParseRelayMonitorTemplate()
+	if err != nil {
+		return nil, err
+	}
+
 	return server, nil
 }
 
@@ -144,6 +182,18 @@ func (srv *Webserver) getRouter() http.Handler {
 	r.HandleFunc("/stats/cowstats", srv.handleCowstatsJSON).Methods(http.MethodGet)
 	r.HandleFunc("/stats/day/{day:[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}}", srv.handleDailyStats).Methods(http.MethodGet)
 	r.HandleFunc("/stats/day/{day:[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}}/json", srv.handleDailyStatsJSON).Methods(http.MethodGet)
+	r.HandleFunc("/relay/{relay:[a-zA-Z0-9.-]+}", srv.handleRelay).Methods(http.MethodGet)
+	r.HandleFunc("/relay/{relay:[a-zA-Z0-9.-]+}/json", srv.handleRelayJSON).Methods(http.MethodGet)
+
+	r.HandleFunc("/builder/{name}", srv.handleBuilder).Methods(http.MethodGet)
+	r.HandleFunc("/builder/{name}/json", srv.handleBuilderJSON).Methods(http.MethodGet)
+
+	r.HandleFunc("/value-claims", srv.handleValueClaims).Methods(http.MethodGet)
+	r.HandleFunc("/value-claims/json", srv.handleValueClaimsJSON).Methods(http.MethodGet)
+
+	r.HandleFunc("/relay-monitor", srv.handleRelayMonitor).Methods(http.MethodGet)
+	r.HandleFunc("/relay-monitor/json", srv.handleRelayMonitorJSON).Methods(http.MethodGet)
+
 	r.HandleFunc("/stats/_test/extradata-payloads", srv.handleExtraDataPayloads).Methods(http.MethodGet)
 
 	r.HandleFunc("/livez", srv.handleLivenessCheck)
@@ -415,6 +465,353 @@ func (srv *Webserver) handleDailyStatsJSON(w http.ResponseWriter, req *http.Requ
 	srv.RespondOK(w, resp)
 }
 
+// timespanFromRequest returns the timespan of a request (query arg t, 24h by default)
+func timespanFromRequest(req *http.Request) string {
+	timespan := req.URL.Query().Get("t")
+	if timespan == "" {
+		timespan = "24h"
+	}
+	return timespan
+}
+
+// respondStatsError responds with the error of loading the relay or builder stats
+func (srv *Webserver) respondStatsError(w http.ResponseWriter, _log *logrus.Entry, err error) {
+	if errors.Is(err, errInvalidTimespan) || errors.Is(err, errInvalidTimeRange) {
+		srv.RespondError(w, http.StatusBadRequest, err.Error())
+	} else if errors.Is(err, errRelayNotFound) || errors.Is(err, errBuilderNotFound) {
+		srv.RespondError(w, http.StatusNotFound, err.Error())
+	} else {
+		_log.WithError(err).Error("error getting stats")
+		srv.RespondError(w, http.StatusInternalServerError, "error getting stats")
+	}
+}
+
+// getRelayStatsForRequest loads the relay stats for the relay and timespan of a request
+func (srv *Webserver) getRelayStatsForRequest(w http.ResponseWriter, req *http.Request) (*RelayStats, bool) {
+	relay := mux.Vars(req)["relay"]
+	stats, err := srv.getRelayStats(relay, timespanFromRequest(req))
+	if err != nil {
+		srv.respondStatsError(w, srv.log.WithField("relay", relay), err)
+		return nil, false
+	}
+	return stats, true
+}
+
+func (srv *Webserver) handleRelay(w http.ResponseWriter, req *http.Request) {
+	stats, ok := srv.getRelayStatsForRequest(w, req)
+	if !ok {
+		return
+	}
+
+	htmlData := &HTMLDataRelay{
+		Title:     "MEV-Boost Relay " + stats.Relay,
+		TimeSpans: timespans,
+		TimeSpan:  stats.TimeStr,
+		Stats:     stats,
+	}
+
+	tpl := srv.templateRelay
+	if srv.opts.Dev {
+		var err error
+		tpl, err = ParseRelayTemplate()
+		if err != nil {
+			srv.log.WithError(err).Error("relay: error parsing template")
+			srv.RespondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	htmlBuf := bytes.Buffer{}
+	if err := tpl.ExecuteTemplate(&htmlBuf, "base", htmlData); err != nil {
+		srv.log.WithError(err).Error("relay: error executing template")
+		srv.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(htmlBuf.Bytes())
+}
+
+func (srv *Webserver) handleRelayJSON(w http.ResponseWriter, req *http.Request) {
+	stats, ok := srv.getRelayStatsForRequest(w, req)
+	if !ok {
+		return
+	}
+
+	type apiResp struct {
+		Relay                 string                                 `json:"relay"`
+		Timespan              string                                 `json:"timespan"`
+		Since                 string                                 `json:"since"`
+		Until                 string                                 `json:"until"`
+		PayloadsOverTime      []*RelayPayloadsOverTimeEntry          `json:"payloads_over_time"`
+		Builders              []*TopBuilderDisplayEntry              `json:"builders"`
+		ValueCheck            *database.RelayValueCheckStatsEntry    `json:"value_check"`
+		ValueCheckFailureRate string                                 `json:"value_check_failure_rate"`
+		IncorrectClaims       []*database.RelayPayloadCheckEntry     `json:"incorrect_claims"`
+		MissedSlots           []*database.RelayPayloadCheckEntry     `json:"missed_slots"`
+		DataAPIAvailability   string                                 `json:"data_api_availability"`
+		DataAPIStatus         []*database.DataAPIBackfillStatusEntry `json:"data_api_status"`
+		Uptime                *RelayUptime                           `json:"uptime"`
+	}
+
+	resp := apiResp{
+		Relay:                 stats.Relay,
+		Timespan:              stats.TimeStr,
+		Since:                 stats.Since.Format("2006-01-02 15:04:05"),
+		Until:                 stats.Until.Format("2006-01-02 15:04:05"),
+		PayloadsOverTime:      stats.PayloadsOverTime,
+		Builders:              stats.TopBuilders,
+		ValueCheck:            stats.ValueCheck,
+		ValueCheckFailureRate: stats.ValueCheckFailureRate,
+		IncorrectClaims:       stats.IncorrectClaims,
+		MissedSlots:           stats.MissedSlots,
+		DataAPIAvailability:   stats.DataAPIAvailability,
+		DataAPIStatus:         stats.DataAPIStatus,
+		Uptime:                stats.Uptime,
+	}
+
+	srv.RespondOK(w, resp)
+}
+
+// getBuilderStatsForRequest loads the builder stats for the builder and timespan of a request
+func (srv *Webserver) getBuilderStatsForRequest(w http.ResponseWriter, req *http.Request) (*BuilderStats, bool) {
+	name := mux.Vars(req)["name"]
+	stats, err := srv.getBuilderStats(name, timespanFromRequest(req))
+	if err != nil {
+		srv.respondStatsError(w, srv.log.WithField("builder", name), err)
+		return nil, false
+	}
+	return stats, true
+}
+
+func (srv *Webserver) handleBuilder(w http.ResponseWriter, req *http.Request) {
+	stats, ok := srv.getBuilderStatsForRequest(w, req)
+	if !ok {
+		return
+	}
+
+	htmlData := &HTMLDataBuilder{
+		Title:     "MEV-Boost Builder " + stats.Name,
+		TimeSpans: timespans,
+		TimeSpan:  stats.TimeStr,
+		Stats:     stats,
+	}
+
+	tpl := srv.templateBuilder
+	if srv.opts.Dev {
+		var err error
+		tpl, err = ParseBuilderTemplate()
+		if err != nil {
+			srv.log.WithError(err).Error("builder: error parsing template")
+			srv.RespondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	htmlBuf := bytes.Buffer{}
+	if err := tpl.ExecuteTemplate(&htmlBuf, "base", htmlData); err != nil {
+		srv.log.WithError(err).Error("builder: error executing template")
+		srv.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(htmlBuf.Bytes())
+}
+
+func (srv *Webserver) handleBuilderJSON(w http.ResponseWriter, req *http.Request) {
+	stats, ok := srv.getBuilderStatsForRequest(w, req)
+	if !ok {
+		return
+	}
+
+	type apiResp struct {
+		Builder        string                          `json:"builder"`
+		Timespan       string                          `json:"timespan"`
+		Since          string                          `json:"since"`
+		Until          string                          `json:"until"`
+		NumBlocks      uint64                          `json:"num_blocks"`
+		MarketShare    string                          `json:"market_share"`
+		ProfitTotal    string                          `json:"profit_total"`
+		SubsidiesTotal string                          `json:"subsidies_total"`
+		PrivateTx      *database.BuilderPrivateTxEntry `json:"private_tx"`
+		OverTime       []*BuilderStatsOverTimeEntry    `json:"over_time"`
+		Aliases        []*database.TopBuilderEntry     `json:"extra_data"`
+		Pubkeys        []*database.BuilderPubkeyEntry  `json:"pubkeys"`
+		Relays         []*database.TopRelayEntry       `json:"relays"`
+		RecentBlocks   []*database.BuilderBlockEntry   `json:"recent_blocks"`
+	}
+
+	resp := apiResp{
+		Builder:        stats.Name,
+		Timespan:       stats.TimeStr,
+		Since:          stats.Since.Format("2006-01-02 15:04:05"),
+		Until:          stats.Until.Format("2006-01-02 15:04:05"),
+		NumBlocks:      stats.NumBlocks,
+		MarketShare:    stats.MarketShare,
+		ProfitTotal:    stats.ProfitTotal,
+		SubsidiesTotal: stats.SubsidiesTotal,
+		PrivateTx:      stats.PrivateTx,
+		OverTime:       stats.OverTime,
+		Aliases:        stats.Aliases,
+		Pubkeys:        stats.Pubkeys,
+		Relays:         stats.Relays,
+		RecentBlocks:   stats.RecentBlocks,
+	}
+
+	srv.RespondOK(w, resp)
+}
+
+// getValueClaimReportForRequest loads the value claim report for the time range of a request
+func (srv *Webserver) getValueClaimReportForRequest(w http.ResponseWriter, req *http.Request) (*ValueClaimReport, bool) {
+	since, until, timespan, err := timeRangeFromRequest(req, srv.slotClock.Now().UTC())
+	if err != nil {
+		srv.respondStatsError(w, srv.log, err)
+		return nil, false
+	}
+
+	report, err := srv.getValueClaimReport(since, until)
+	if err != nil {
+		srv.respondStatsError(w, srv.log, err)
+		return nil, false
+	}
+	report.TimeStr = timespan
+	return report, true
+}
+
+func (srv *Webserver) handleValueClaims(w http.ResponseWriter, req *http.Request) {
+	report, ok := srv.getValueClaimReportForRequest(w, req)
+	if !ok {
+		return
+	}
+
+	htmlData := &HTMLDataValueClaims{
+		Title:     "MEV-Boost Value Claim Accuracy",
+		TimeSpans: timespans,
+		TimeSpan:  report.TimeStr,
+		Report:    report,
+	}
+
+	tpl := srv.templateValueClaims
+	if srv.opts.Dev {
+		var err error
+		tpl, err = ParseValueClaimsTemplate()
+		if err != nil {
+			srv.log.WithError(err).Error("value-claims: error parsing template")
+			srv.RespondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	htmlBuf := bytes.Buffer{}
+	if err := tpl.ExecuteTemplate(&htmlBuf, "base", htmlData); err != nil {
+		srv.log.WithError(err).Error("value-claims: error executing template")
+		srv.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(htmlBuf.Bytes())
+}
+
+func (srv *Webserver) handleValueClaimsJSON(w http.ResponseWriter, req *http.Request) {
+	report, ok := srv.getValueClaimReportForRequest(w, req)
+	if !ok {
+		return
+	}
+
+	type apiResp struct {
+		Timespan   string                          `json:"timespan,omitempty"`
+		Since      string                          `json:"since"`
+		Until      string                          `json:"until"`
+		Relays     []*ValueClaimAccuracy           `json:"relays"`
+		Builders   []*ValueClaimAccuracy           `json:"builders"`
+		Overclaims []*database.ValueOverclaimEntry `json:"overclaims"`
+	}
+
+	resp := apiResp{
+		Timespan:   report.TimeStr,
+		Since:      report.Since.Format("2006-01-02 15:04:05"),
+		Until:      report.Until.Format("2006-01-02 15:04:05"),
+		Relays:     report.Relays,
+		Builders:   report.Builders,
+		Overclaims: report.Overclaims,
+	}
+
+	srv.RespondOK(w, resp)
+}
+
+// getRelayMonitorReportForRequest loads the relay uptime report for the time range of a request
+func (srv *Webserver) getRelayMonitorReportForRequest(w http.ResponseWriter, req *http.Request) (*RelayMonitorReport, bool) {
+	since, until, timespan, err := timeRangeFromRequest(req, srv.slotClock.Now().UTC())
+	if err != nil {
+		srv.respondStatsError(w, srv.log, err)
+		return nil, false
+	}
+
+	report, err := srv.getRelayMonitorReport(since, until)
+	if err != nil {
+		srv.respondStatsError(w, srv.log, err)
+		return nil, false
+	}
+	report.TimeStr = timespan
+	return report, true
+}
+
+func (srv *Webserver) handleRelayMonitor(w http.ResponseWriter, req *http.Request) {
+	report, ok := srv.getRelayMonitorReportForRequest(w, req)
+	if !ok {
+		return
+	}
+
+	htmlData := &HTMLDataRelayMonitor{
+		Title:     "MEV-Boost Relay Uptime",
+		TimeSpans: timespans,
+		TimeSpan:  report.TimeStr,
+		Report:    report,
+	}
+
+	tpl := srv.templateRelayMon
+	if srv.opts.Dev {
+		var err error
+		tpl, err = ParseRelayMonitorTemplate()
+		if err != nil {
+			srv.log.WithError(err).Error("relay-monitor: error parsing template")
+			srv.RespondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	htmlBuf := bytes.Buffer{}
+	if err := tpl.ExecuteTemplate(&htmlBuf, "base", htmlData); err != nil {
+		srv.log.WithError(err).Error("relay-monitor: error executing template")
+		srv.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(htmlBuf.Bytes())
+}
+
+func (srv *Webserver) handleRelayMonitorJSON(w http.ResponseWriter, req *http.Request) {
+	report, ok := srv.getRelayMonitorReportForRequest(w, req)
+	if !ok {
+		return
+	}
+
+	type apiResp struct {
+		Timespan string         `json:"timespan,omitempty"`
+		Since    string         `json:"since"`
+		Until    string         `json:"until"`
+		Relays   []*RelayUptime `json:"relays"`
+	}
+
+	resp := apiResp{
+		Timespan: report.TimeStr,
+		Since:    report.Since.Format("2006-01-02 15:04:05"),
+		Until:    report.Until.Format("2006-01-02 15:04:05"),
+		Relays:   report.Relays,
+	}
+
+	srv.RespondOK(w, resp)
+}
+
 func (srv *Webserver) handleCowstatsJSON(w http.ResponseWriter, req *http.Request) {
 	// builder stats for wednesday utc 00:00 to next wednesday 00:00
 	type apiResp struct {
@@ -448,7 +845,7 @@ func (srv *Webserver) handleLivenessCheck(w http.ResponseWriter, r *http.Request
 }
 
 func (srv *Webserver) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
-	currentSlot := common.TimeToSlot(time.Now().UTC())
+	currentSlot := srv.slotClock.CurrentSlot()
 	slotsPerMinute := 5
 	maxMinutesSinceLastUpdate := 10
 	maxSlotsSinceLastUpdate := maxMinutesSinceLastUpdate * slotsPerMinute
@@ -9,6 +9,7 @@ import (
 
 	"github.com/flashbots/relayscan/common"
 	"github.com/flashbots/relayscan/database"
+	"github.com/flashbots/relayscan/vars"
 	"github.com/sirupsen/logrus"
 )
 
@@ -215,3 +216,212 @@ func (srv *Webserver) _getDailyStats(t time.Time) (since, until, minDate time.Ti
 	relays, builders, builderProfits, err = srv.db.GetStatsForTimerange(since, until, "")
 	return since, until, minDate, relays, builders, builderProfits, err
 }
+
+const (
+	relayPageMaxEntries      = 50  // max incorrect claims and missed slots shown on the relay page
+	relayPageMaxBackfillRuns = 100 // number of backfill runs used for the data API availability
+	builderPageMaxBlocks     = 50  // recent blocks shown on the builder page
+	valueClaimsMaxOverclaims = 100 // largest overclaims shown in the value claim report
+)
+
+func (srv *Webserver) getRelayStats(relay, timespan string) (*RelayStats, error) {
+	duration, err := timespanToDuration(timespan)
+	if err != nil {
+		return nil, err
+	}
+
+	until := srv.slotClock.Now().UTC()
+	since := until.Add(-duration)
+	stats := &RelayStats{
+		Relay:   relay,
+		Since:   since,
+		Until:   until,
+		TimeStr: timespan,
+	}
+
+	stats.ValueCheck, err = srv.db.GetRelayValueCheckStats(relay, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.DataAPIStatus, err = srv.db.GetDataAPIBackfillStatus(relay, relayPageMaxBackfillRuns)
+	if err != nil {
+		return nil, err
+	}
+	stats.DataAPIAvailability = dataAPIAvailability(stats.DataAPIStatus)
+
+	if stats.ValueCheck.NumPayloads == 0 && len(stats.DataAPIStatus) == 0 {
+		return nil, fmt.Errorf("%w: %s", errRelayNotFound, relay)
+	}
+
+	if stats.ValueCheck.NumChecked > 0 {
+		stats.ValueCheckFailureRate = percent(stats.ValueCheck.NumFailed, stats.ValueCheck.NumChecked)
+	}
+
+	bucketSlots := statsBucketSlots(duration)
+	payloadBuckets, err := srv.db.GetRelayPayloadsOverTime(relay, since, until, bucketSlots)
+	if err != nil {
+		return nil, err
+	}
+	stats.PayloadsOverTime = fillRelayPayloadsBuckets(payloadBuckets, common.TimeToSlot(since), common.TimeToSlot(until), bucketSlots)
+
+	// Top builders are already computed for the overview, otherwise loaded from the database
+	srv.dataLock.RLock()
+	overviewStats, found := srv.stats[timespan]
+	srv.dataLock.RUnlock()
+	if found {
+		stats.TopBuilders = overviewStats.TopBuildersByRelay[relay]
+	} else {
+		topBuilders, err := srv.db.GetTopBuilders(since, until, relay)
+		if err != nil {
+			return nil, err
+		}
+		stats.TopBuilders = consolidateBuilderEntries(topBuilders)
+	}
+
+	stats.IncorrectClaims, err = srv.db.GetRelayIncorrectClaims(relay, since, until, relayPageMaxEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.MissedSlots, err = srv.db.GetRelayMissedSlots(relay, since, until, relayPageMaxEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	uptime, err := srv.db.GetRelayAPIUptime(since, until, relay)
+	if err != nil {
+		return nil, err
+	}
+	if relays := consolidateRelayUptime(uptime); len(relays) > 0 {
+		stats.Uptime = relays[0]
+	}
+	return stats, nil
+}
+
+func (srv *Webserver) getBuilderStats(name, timespan string) (*BuilderStats, error) {
+	duration, err := timespanToDuration(timespan)
+	if err != nil {
+		return nil, err
+	}
+
+	until := srv.slotClock.Now().UTC()
+	since := until.Add(-duration)
+
+	// Top builders are already computed for the overview, otherwise loaded from the database
+	srv.dataLock.RLock()
+	overviewStats, found := srv.stats[timespan]
+	srv.dataLock.RUnlock()
+	var topBuilders []*TopBuilderDisplayEntry
+	if found {
+		topBuilders = overviewStats.TopBuilders
+	} else {
+		entries, err := srv.db.GetTopBuilders(since, until, "")
+		if err != nil {
+			return nil, err
+		}
+		topBuilders = consolidateBuilderEntries(entries)
+	}
+
+	builder, extraData := findBuilder(topBuilders, name)
+	if builder == nil {
+		// No blocks in this time range, resolve the name from all known extra_data values
+		extraDataValues, err := srv.db.GetExtraDataValues()
+		if err != nil {
+			return nil, err
+		}
+		builder, extraData = builderFromExtraData(extraDataValues, name)
+		if builder == nil {
+			return nil, fmt.Errorf("%w: %s", errBuilderNotFound, name)
+		}
+	}
+
+	stats := &BuilderStats{
+		Name:           name,
+		Since:          since,
+		Until:          until,
+		TimeStr:        timespan,
+		NumBlocks:      builder.Info.NumBlocks,
+		MarketShare:    builder.Info.Percent,
+		ProfitTotal:    "0",
+		SubsidiesTotal: "0",
+		Aliases:        builder.Children,
+	}
+	if len(stats.Aliases) == 0 {
+		stats.Aliases = []*database.TopBuilderEntry{builder.Info}
+	}
+
+	bucketSlots := statsBucketSlots(duration)
+	buckets, err := srv.db.GetBuilderStatsOverTime(extraData, since, until, bucketSlots)
+	if err != nil {
+		return nil, err
+	}
+	stats.OverTime = fillBuilderStatsBuckets(buckets, common.TimeToSlot(since), common.TimeToSlot(until), bucketSlots)
+	for _, bucket := range buckets {
+		stats.ProfitTotal = addFloatStrings(stats.ProfitTotal, bucket.ProfitTotal, 4)
+		stats.SubsidiesTotal = addFloatStrings(stats.SubsidiesTotal, bucket.SubsidiesTotal, 4)
+	}
+
+	stats.Pubkeys, err = srv.db.GetBuilderPubkeys(extraData, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	relays, err := srv.db.GetBuilderRelays(extraData, since, until)
+	if err != nil {
+		return nil, err
+	}
+	stats.Relays = prepareRelaysEntries(relays)
+
+	stats.PrivateTx, err = srv.db.GetBuilderPrivateTxShare(extraData, since, until)
+	if err != nil {
+		return nil, err
+	}
+	if stats.PrivateTx.NumTx > 0 {
+		stats.PrivateTxPercent = percent(stats.PrivateTx.NumPrivateTx, stats.PrivateTx.NumTx)
+	}
+
+	stats.RecentBlocks, err = srv.db.GetBuilderRecentBlocks(extraData, since, until, builderPageMaxBlocks)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (srv *Webserver) getValueClaimReport(since, until time.Time) (*ValueClaimReport, error) {
+	relays, err := srv.db.GetValueClaimAccuracy(since, until, database.GroupByRelay)
+	if err != nil {
+		return nil, err
+	}
+
+	builders, err := srv.db.GetValueClaimAccuracy(since, until, database.GroupByExtraData)
+	if err != nil {
+		return nil, err
+	}
+
+	overclaims, err := srv.db.GetValueOverclaims(since, until, valueClaimsMaxOverclaims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ValueClaimReport{
+		Since:      since,
+		Until:      until,
+		Relays:     consolidateValueClaimAccuracy(relays, func(relay string) string { return relay }),
+		Builders:   consolidateValueClaimAccuracy(builders, vars.BuilderNameFromExtraData),
+		Overclaims: overclaims,
+	}, nil
+}
+
+func (srv *Webserver) getRelayMonitorReport(since, until time.Time) (*RelayMonitorReport, error) {
+	uptime, err := srv.db.GetRelayAPIUptime(since, until, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &RelayMonitorReport{
+		Since:  since,
+		Until:  until,
+		Relays: consolidateRelayUptime(uptime),
+	}, nil
+}
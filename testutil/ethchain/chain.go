@@ -0,0 +1,292 @@
+// Package ethchain provides an in-memory execution chain for tests. It implements common.EthClient.
+package ethchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/flashbots/relayscan/common"
+	"github.com/holiman/uint256"
+)
+
+var _ common.EthClient = (*Chain)(nil)
+
+// ChainID is used for all transactions created by the helpers
+var ChainID = big.NewInt(1)
+
+// Chain is an in-memory chain. Blocks are added as canonical (AddBlock) or only known by hash (AddOrphanedBlock),
+// balances are set per address from a block number on (SetBalance). Nonces are the number of transactions sent in the
+// canonical blocks.
+type Chain struct {
+	lock         sync.Mutex
+	blocksByHash map[ethcommon.Hash]*types.Block
+	canonical    map[uint64]*types.Block
+	balances     map[ethcommon.Address]map[uint64]*big.Int // map[address]map[fromBlockNumber]balance
+	err          error                                     // if set, returned by all calls
+	numCalls     int
+}
+
+func New() *Chain {
+	return &Chain{
+		blocksByHash: make(map[ethcommon.Hash]*types.Block),
+		canonical:    make(map[uint64]*types.Block),
+		balances:     make(map[ethcommon.Address]map[uint64]*big.Int),
+	}
+}
+
+// AddBlock adds a canonical block (replacing a previous block with the same number)
+func (c *Chain) AddBlock(block *types.Block) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.blocksByHash[block.Hash()] = block
+	c.canonical[block.NumberU64()] = block
+}
+
+// AddOrphanedBlock adds a block that can be queried by hash, but isn't canonical (i.e. a missed slot)
+func (c *Chain) AddOrphanedBlock(block *types.Block) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.blocksByHash[block.Hash()] = block
+}
+
+// SetBalance sets the balance of an address from blockNumber on (balances are 0 before the first one)
+func (c *Chain) SetBalance(address ethcommon.Address, blockNumber uint64, balance *big.Int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.balances[address] == nil {
+		c.balances[address] = make(map[uint64]*big.Int)
+	}
+	c.balances[address][blockNumber] = new(big.Int).Set(balance)
+}
+
+// SetError makes all further calls fail with err (nil to reset)
+func (c *Chain) SetError(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.err = err
+}
+
+// NumCalls returns the number of calls to the client methods
+func (c *Chain) NumCalls() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.numCalls
+}
+
+func (c *Chain) BlockByHash(ctx context.Context, hash ethcommon.Hash) (*types.Block, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.numCalls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	block, found := c.blocksByHash[hash]
+	if !found {
+		return nil, ethereum.NotFound
+	}
+	return block, nil
+}
+
+func (c *Chain) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.numCalls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	block, found := c.canonical[number.Uint64()]
+	if !found {
+		return nil, ethereum.NotFound
+	}
+	return block, nil
+}
+
+func (c *Chain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.numCalls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	block, found := c.canonical[number.Uint64()]
+	if !found {
+		return nil, ethereum.NotFound
+	}
+	return block.Header(), nil
+}
+
+func (c *Chain) BalanceAt(ctx context.Context, account ethcommon.Address, blockNumber *big.Int) (*big.Int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.numCalls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.balanceAt(account, blockNumber), nil
+}
+
+// BalancesAt counts as a single call, like a JSON-RPC batch
+func (c *Chain) BalancesAt(ctx context.Context, queries []common.BalanceQuery) ([]*big.Int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.numCalls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	balances := make([]*big.Int, len(queries))
+	for i, query := range queries {
+		if query.Nonce {
+			balances[i] = c.nonceAt(query.Address, query.BlockNumber)
+		} else {
+			balances[i] = c.balanceAt(query.Address, query.BlockNumber)
+		}
+	}
+	return balances, nil
+}
+
+// HeadersByHash counts as a single call, like a JSON-RPC batch
+func (c *Chain) HeadersByHash(ctx context.Context, hashes []ethcommon.Hash) ([]*types.Header, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.numCalls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	headers := make([]*types.Header, len(hashes))
+	for i, hash := range hashes {
+		if block, found := c.blocksByHash[hash]; found {
+			headers[i] = block.Header()
+		}
+	}
+	return headers, nil
+}
+
+// ReceiptsByBlockHash returns receipts as if every transaction used all its gas, at the effective gas price of the block
+func (c *Chain) ReceiptsByBlockHash(ctx context.Context, hash ethcommon.Hash) ([]*types.Receipt, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.numCalls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	block, found := c.blocksByHash[hash]
+	if !found {
+		return nil, ethereum.NotFound
+	}
+	receipts := make([]*types.Receipt, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		tip, err := tx.EffectiveGasTip(block.BaseFee())
+		if err != nil {
+			return nil, err // fee cap below the base fee, the test block is invalid
+		}
+		receipts[i] = &types.Receipt{
+			Type:              tx.Type(),
+			Status:            types.ReceiptStatusSuccessful,
+			TxHash:            tx.Hash(),
+			GasUsed:           tx.Gas(),
+			EffectiveGasPrice: new(big.Int).Add(block.BaseFee(), tip),
+			BlockHash:         hash,
+			BlockNumber:       block.Number(),
+			TransactionIndex:  uint(i), //nolint:gosec
+		}
+		if tx.Type() == types.BlobTxType {
+			receipts[i].BlobGasUsed = tx.BlobGas()
+			receipts[i].BlobGasPrice = big.NewInt(1)
+		}
+	}
+	return receipts, nil
+}
+
+func (c *Chain) balanceAt(account ethcommon.Address, blockNumber *big.Int) *big.Int {
+	fromBlocks := make([]uint64, 0, len(c.balances[account]))
+	for fromBlock := range c.balances[account] {
+		fromBlocks = append(fromBlocks, fromBlock)
+	}
+	sort.Slice(fromBlocks, func(i, j int) bool { return fromBlocks[i] > fromBlocks[j] })
+	for _, fromBlock := range fromBlocks {
+		if fromBlock <= blockNumber.Uint64() {
+			return new(big.Int).Set(c.balances[account][fromBlock])
+		}
+	}
+	return big.NewInt(0)
+}
+
+// nonceAt counts the transactions sent by the address in the canonical blocks up to blockNumber
+func (c *Chain) nonceAt(account ethcommon.Address, blockNumber *big.Int) *big.Int {
+	nonce := new(big.Int)
+	for number, block := range c.canonical {
+		if number > blockNumber.Uint64() {
+			continue
+		}
+		for _, tx := range block.Transactions() {
+			if from, err := common.TxSender(tx); err == nil && from == account {
+				nonce.Add(nonce, big.NewInt(1))
+			}
+		}
+	}
+	return nonce
+}
+
+// NewBlock creates a block with the given transactions. Set parentHash to build different blocks with the same number.
+func NewBlock(number uint64, parentHash ethcommon.Hash, coinbase ethcommon.Address, extraData []byte, txs ...*types.Transaction) *types.Block {
+	header := &types.Header{
+		ParentHash: parentHash,
+		Number:     new(big.Int).SetUint64(number),
+		Coinbase:   coinbase,
+		GasLimit:   30_000_000,
+		Time:       1_700_000_000 + number*12,
+		Extra:      extraData,
+		BaseFee:    big.NewInt(7),
+	}
+	return types.NewBlock(header, &types.Body{Transactions: txs}, nil, trie.NewStackTrie(nil))
+}
+
+// NewTransfer returns a signed value transfer
+func NewTransfer(key *ecdsa.PrivateKey, nonce uint64, to ethcommon.Address, value *big.Int) *types.Transaction {
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   ChainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Gas:       21_000,
+		To:        &to,
+		Value:     value,
+	})
+	return signTx(key, tx)
+}
+
+// NewBlobTx returns a signed blob transaction with the given number of blobs
+func NewBlobTx(key *ecdsa.PrivateKey, nonce uint64, to ethcommon.Address, numBlobs int) *types.Transaction {
+	blobHashes := make([]ethcommon.Hash, numBlobs)
+	for i := range blobHashes {
+		blobHashes[i][0] = 0x01 // versioned hash
+		blobHashes[i][31] = byte(i)
+	}
+	tx := types.NewTx(&types.BlobTx{
+		ChainID:    uint256.MustFromBig(ChainID),
+		Nonce:      nonce,
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(10),
+		Gas:        21_000,
+		To:         to,
+		Value:      uint256.NewInt(0),
+		BlobFeeCap: uint256.NewInt(1),
+		BlobHashes: blobHashes,
+	})
+	return signTx(key, tx)
+}
+
+func signTx(key *ecdsa.PrivateKey, tx *types.Transaction) *types.Transaction {
+	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(ChainID), key)
+	if err != nil {
+		panic(err) // only with an invalid key
+	}
+	return signedTx
+}
@@ -0,0 +1,54 @@
+package mockrelay
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+
+	relaycommon "github.com/flashbots/mev-boost-relay/common"
+)
+
+//go:embed testdata/*.json
+var fixtures embed.FS
+
+// FixturePayloadsDelivered returns a small proposer_payload_delivered response (three slots)
+func FixturePayloadsDelivered() (payloads []relaycommon.BidTraceV2JSON, err error) {
+	err = loadFixture("testdata/proposer_payload_delivered.json", &payloads)
+	return payloads, err
+}
+
+// FixtureBlocksReceived returns a builder_blocks_received response for a single slot
+func FixtureBlocksReceived() (bids []relaycommon.BidTraceV2WithTimestampJSON, err error) {
+	err = loadFixture("testdata/builder_blocks_received.json", &bids)
+	return bids, err
+}
+
+func loadFixture(fn string, v any) error {
+	data, err := fixtures.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// GeneratePayloadsDelivered returns n delivered payloads, one per slot from firstSlot on (for pagination tests)
+func GeneratePayloadsDelivered(firstSlot uint64, n int) []relaycommon.BidTraceV2JSON {
+	payloads := make([]relaycommon.BidTraceV2JSON, n)
+	for i := range payloads {
+		slot := firstSlot + uint64(i) //nolint:gosec
+		payloads[i] = relaycommon.BidTraceV2JSON{
+			Slot:                 slot,
+			ParentHash:           fmt.Sprintf("0x%064x", slot-1),
+			BlockHash:            fmt.Sprintf("0x%064x", slot),
+			BuilderPubkey:        fmt.Sprintf("0x%096x", i%3),
+			ProposerPubkey:       fmt.Sprintf("0x%096x", slot),
+			ProposerFeeRecipient: fmt.Sprintf("0x%040x", slot),
+			GasLimit:             30_000_000,
+			GasUsed:              15_000_000,
+			Value:                fmt.Sprint(1_000_000_000_000_000 + slot),
+			NumTx:                100,
+			BlockNumber:          slot + 1_000_000,
+		}
+	}
+	return payloads
+}
@@ -0,0 +1,71 @@
+package mockrelay
+
+import (
+	"fmt"
+	"strings"
+
+	builderapideneb "github.com/attestantio/go-builder-client/api/deneb"
+	builderspec "github.com/attestantio/go-builder-client/spec"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	boosttypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/holiman/uint256"
+)
+
+// GetHeaderBid describes a getHeader bid to serve (signed by the relay key)
+type GetHeaderBid struct {
+	Slot           uint64
+	ParentHash     phase0.Hash32
+	ProposerPubkey string
+	BlockHash      phase0.Hash32
+	BlockNumber    uint64
+	Value          uint64 // in wei
+	NumBlobs       int
+}
+
+// AddGetHeaderBid signs a deneb bid with the relay key, and serves it for the slot, parent hash and proposer pubkey
+func (m *MockRelay) AddGetHeaderBid(b GetHeaderBid) (*builderspec.VersionedSignedBuilderBid, error) {
+	message := &builderapideneb.BuilderBid{
+		Header: &deneb.ExecutionPayloadHeader{
+			BlockNumber:   b.BlockNumber,
+			BlockHash:     b.BlockHash,
+			ParentHash:    b.ParentHash,
+			GasLimit:      30_000_000,
+			BaseFeePerGas: uint256.NewInt(7),
+			ExtraData:     []byte("mockrelay"),
+		},
+		BlobKZGCommitments: make([]deneb.KZGCommitment, b.NumBlobs),
+		Value:              uint256.NewInt(b.Value),
+		Pubkey:             phase0.BLSPubKey(m.publicKey),
+	}
+	signature, err := boosttypes.SignMessage(message, boosttypes.DomainBuilder, m.secretKey)
+	if err != nil {
+		return nil, err
+	}
+	bid := &builderspec.VersionedSignedBuilderBid{
+		Version: consensusspec.DataVersionDeneb,
+		Deneb:   &builderapideneb.SignedBuilderBid{Message: message, Signature: phase0.BLSSignature(signature)},
+	}
+
+	key := strings.ToLower(fmt.Sprintf("%d/%s/%s", b.Slot, b.ParentHash.String(), b.ProposerPubkey))
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.getHeaderBids[key] = bid
+	return bid, nil
+}
+
+func marshalBidSSZ(bid *builderspec.VersionedSignedBuilderBid) ([]byte, error) {
+	switch bid.Version { //nolint:exhaustive
+	case consensusspec.DataVersionBellatrix:
+		return bid.Bellatrix.MarshalSSZ()
+	case consensusspec.DataVersionCapella:
+		return bid.Capella.MarshalSSZ()
+	case consensusspec.DataVersionDeneb:
+		return bid.Deneb.MarshalSSZ()
+	case consensusspec.DataVersionElectra:
+		return bid.Electra.MarshalSSZ()
+	default:
+		return nil, fmt.Errorf("unsupported bid version: %s", bid.Version) //nolint:err113
+	}
+}
@@ -0,0 +1,370 @@
+// Package mockrelay provides a local relay server for tests. It serves the data API (proposer_payload_delivered with
+// cursor pagination and slot filter, builder_blocks_received), getHeader, the builder status endpoint and the ultrasound top-bid
+// websocket from fixtures, and can inject errors and rate-limiting.
+package mockrelay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	builderspec "github.com/attestantio/go-builder-client/spec"
+	"github.com/flashbots/go-boost-utils/bls"
+	boosttypes "github.com/flashbots/go-boost-utils/types"
+	relaycommon "github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/relayscan/common"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	PathPayloadDelivered = "/relay/v1/data/bidtraces/proposer_payload_delivered"
+	PathBlocksReceived   = "/relay/v1/data/bidtraces/builder_blocks_received"
+	PathGetHeader        = "/eth/v1/builder/header/"
+	PathTopBidStream     = "/ws/v1/top_bid"
+	PathStatus           = "/eth/v1/builder/status"
+
+	// maxPageLimit is the maximum limit for proposer_payload_delivered (like the real relays)
+	maxPageLimit = 200
+)
+
+// injectedError is returned for the next requests to a path (instead of the normal response)
+type injectedError struct {
+	statusCode int
+	body       string
+	remaining  int
+}
+
+// MockRelay is a relay server for tests (start with New, stop with Close)
+type MockRelay struct {
+	srv *httptest.Server
+
+	secretKey *bls.SecretKey
+	publicKey boosttypes.PublicKey
+
+	lock              sync.Mutex
+	payloadsDelivered []relaycommon.BidTraceV2JSON                         // sorted by slot, descending
+	blocksReceived    map[uint64][]relaycommon.BidTraceV2WithTimestampJSON // map[slot]bids
+	getHeaderBids     map[string]*builderspec.VersionedSignedBuilderBid    // map[slot/parentHash/proposerPubkey]bid
+	getHeaderSSZ      bool                                                 // serve getHeader as SSZ instead of JSON
+	topBids           []common.UltrasoundStreamBid                         // sent to every websocket client
+	injectedErrors    map[string]*injectedError                            // map[path prefix]error
+	requests          map[string]int                                       // map[path prefix]count
+
+	// Deviations from the relay-specs, like some real relays
+	pageLimit    uint64 // maximum limit for proposer_payload_delivered (larger limits are rejected)
+	ignoreCursor bool   // ignore the cursor of proposer_payload_delivered
+}
+
+// New starts a mock relay with a new BLS key (used to sign the getHeader bids)
+func New() (*MockRelay, error) {
+	sk, pk, err := bls.GenerateNewKeypair()
+	if err != nil {
+		return nil, err
+	}
+	relay := &MockRelay{
+		secretKey:      sk,
+		blocksReceived: make(map[uint64][]relaycommon.BidTraceV2WithTimestampJSON),
+		getHeaderBids:  make(map[string]*builderspec.VersionedSignedBuilderBid),
+		injectedErrors: make(map[string]*injectedError),
+		requests:       make(map[string]int),
+		pageLimit:      maxPageLimit,
+	}
+	err = relay.publicKey.FromSlice(bls.PublicKeyToBytes(pk))
+	if err != nil {
+		return nil, err
+	}
+	relay.srv = httptest.NewServer(relay.handler())
+	return relay, nil
+}
+
+// Close stops the server
+func (m *MockRelay) Close() {
+	m.srv.Close()
+}
+
+// URL returns the relay URL including the relay pubkey (i.e. http://0x...@127.0.0.1:1234)
+func (m *MockRelay) URL() string {
+	return strings.Replace(m.srv.URL, "http://", "http://"+m.publicKey.String()+"@", 1)
+}
+
+// RelayEntry returns the relay entry for the URL
+func (m *MockRelay) RelayEntry() common.RelayEntry {
+	return common.MustNewRelayEntry(m.URL(), true)
+}
+
+// TopBidStreamURL returns the websocket URL of the top-bid stream
+func (m *MockRelay) TopBidStreamURL() string {
+	return strings.Replace(m.srv.URL, "http://", "ws://", 1) + PathTopBidStream
+}
+
+// PublicKey is the key the getHeader bids are signed with
+func (m *MockRelay) PublicKey() boosttypes.PublicKey {
+	return m.publicKey
+}
+
+// SetPayloadsDelivered sets the delivered payloads (served with cursor pagination)
+func (m *MockRelay) SetPayloadsDelivered(payloads []relaycommon.BidTraceV2JSON) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.payloadsDelivered = append([]relaycommon.BidTraceV2JSON{}, payloads...)
+	sort.SliceStable(m.payloadsDelivered, func(i, j int) bool { return m.payloadsDelivered[i].Slot > m.payloadsDelivered[j].Slot })
+}
+
+// SetBlocksReceived sets the builder submissions, which are served by slot
+func (m *MockRelay) SetBlocksReceived(bids []relaycommon.BidTraceV2WithTimestampJSON) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.blocksReceived = make(map[uint64][]relaycommon.BidTraceV2WithTimestampJSON)
+	for _, bid := range bids {
+		m.blocksReceived[bid.Slot] = append(m.blocksReceived[bid.Slot], bid)
+	}
+}
+
+// AddBlocksReceived adds builder submissions (i.e. new bids arriving during a slot)
+func (m *MockRelay) AddBlocksReceived(bids ...relaycommon.BidTraceV2WithTimestampJSON) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, bid := range bids {
+		m.blocksReceived[bid.Slot] = append(m.blocksReceived[bid.Slot], bid)
+	}
+}
+
+// SetTopBids sets the bids that are sent to each websocket client after connecting
+func (m *MockRelay) SetTopBids(bids []common.UltrasoundStreamBid) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.topBids = append([]common.UltrasoundStreamBid{}, bids...)
+}
+
+// SetGetHeaderSSZ switches getHeader responses between SSZ and JSON (default)
+func (m *MockRelay) SetGetHeaderSSZ(ssz bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.getHeaderSSZ = ssz
+}
+
+// SetPageLimit sets the maximum limit for proposer_payload_delivered (200 by default, some relays only allow 100)
+func (m *MockRelay) SetPageLimit(limit uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.pageLimit = limit
+}
+
+// SetIgnoreCursor makes proposer_payload_delivered ignore the cursor (always returns the latest payloads)
+func (m *MockRelay) SetIgnoreCursor(ignore bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.ignoreCursor = ignore
+}
+
+// InjectError makes the next n requests to paths starting with pathPrefix fail with the given status code and body.
+// Use n < 0 for all further requests.
+func (m *MockRelay) InjectError(pathPrefix string, statusCode int, body string, n int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.injectedErrors[pathPrefix] = &injectedError{statusCode: statusCode, body: body, remaining: n}
+}
+
+// InjectRateLimit makes the next n requests to paths starting with pathPrefix fail with a 429
+func (m *MockRelay) InjectRateLimit(pathPrefix string, n int) {
+	m.InjectError(pathPrefix, http.StatusTooManyRequests, `{"code":429,"message":"too many requests"}`, n)
+}
+
+// ClearErrors removes all injected errors
+func (m *MockRelay) ClearErrors() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.injectedErrors = make(map[string]*injectedError)
+}
+
+// NumRequests returns the number of requests to one of the Path* endpoints
+func (m *MockRelay) NumRequests(path string) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.requests[path]
+}
+
+func (m *MockRelay) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathPayloadDelivered, m.handlePayloadDelivered)
+	mux.HandleFunc(PathBlocksReceived, m.handleBlocksReceived)
+	mux.HandleFunc(PathGetHeader, m.handleGetHeader)
+	mux.HandleFunc(PathTopBidStream, m.handleTopBidStream)
+	mux.HandleFunc(PathStatus, m.handleStatus)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.countRequestAndCheckErrors(w, r) {
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// countRequestAndCheckErrors counts the request, and writes an injected error if there is one (returns true then)
+func (m *MockRelay) countRequestAndCheckErrors(w http.ResponseWriter, r *http.Request) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, path := range []string{PathPayloadDelivered, PathBlocksReceived, PathGetHeader, PathTopBidStream, PathStatus} {
+		if strings.HasPrefix(r.URL.Path, path) {
+			m.requests[path]++
+		}
+	}
+
+	for prefix, injected := range m.injectedErrors {
+		if !strings.HasPrefix(r.URL.Path, prefix) || injected.remaining == 0 {
+			continue
+		}
+		if injected.remaining > 0 {
+			injected.remaining--
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(injected.statusCode)
+		_, _ = w.Write([]byte(injected.body))
+		return true
+	}
+	return false
+}
+
+func (m *MockRelay) handlePayloadDelivered(w http.ResponseWriter, r *http.Request) {
+	m.lock.Lock()
+	pageLimit, ignoreCursor := m.pageLimit, m.ignoreCursor
+	m.lock.Unlock()
+
+	limit, err := queryUint(r, "limit", pageLimit)
+	if err != nil || limit > pageLimit {
+		writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	cursor, err := queryUint(r, "cursor", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+	if ignoreCursor {
+		cursor = 0
+	}
+	slot, err := queryUint(r, "slot", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid slot")
+		return
+	}
+
+	m.lock.Lock()
+	resp := []relaycommon.BidTraceV2JSON{}
+	for _, payload := range m.payloadsDelivered {
+		if cursor > 0 && payload.Slot > cursor {
+			continue
+		}
+		if slot > 0 && payload.Slot != slot {
+			continue
+		}
+		if uint64(len(resp)) >= limit {
+			break
+		}
+		resp = append(resp, payload)
+	}
+	m.lock.Unlock()
+
+	writeJSON(w, resp)
+}
+
+func (m *MockRelay) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *MockRelay) handleBlocksReceived(w http.ResponseWriter, r *http.Request) {
+	slot, err := queryUint(r, "slot", 0)
+	if err != nil || slot == 0 {
+		writeError(w, http.StatusBadRequest, "invalid slot")
+		return
+	}
+
+	m.lock.Lock()
+	resp := append([]relaycommon.BidTraceV2WithTimestampJSON{}, m.blocksReceived[slot]...)
+	m.lock.Unlock()
+
+	writeJSON(w, resp)
+}
+
+func (m *MockRelay) handleGetHeader(w http.ResponseWriter, r *http.Request) {
+	key := strings.ToLower(strings.TrimPrefix(r.URL.Path, PathGetHeader))
+
+	m.lock.Lock()
+	bid, found := m.getHeaderBids[key]
+	useSSZ := m.getHeaderSSZ
+	m.lock.Unlock()
+
+	if !found {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if !useSSZ {
+		writeJSON(w, bid)
+		return
+	}
+
+	body, err := marshalBidSSZ(bid)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Eth-Consensus-Version", bid.Version.String())
+	_, _ = w.Write(body)
+}
+
+func (m *MockRelay) handleTopBidStream(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close() //nolint:errcheck
+
+	m.lock.Lock()
+	bids := append([]common.UltrasoundStreamBid{}, m.topBids...)
+	m.lock.Unlock()
+
+	for _, bid := range bids {
+		msg, err := bid.MarshalSSZ()
+		if err != nil {
+			return
+		}
+		if err := conn.WriteMessage(websocket.BinaryMessage, msg); err != nil {
+			return
+		}
+	}
+
+	// keep the connection open until the client disconnects
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func queryUint(r *http.Request, key string, defaultValue uint64) (uint64, error) {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return defaultValue, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = fmt.Fprintf(w, `{"code":%d,"message":%q}`, code, msg)
+}
@@ -0,0 +1,125 @@
+package mockrelay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	relaycommon "github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/relayscan/common"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func getJSON(t *testing.T, url string, dst any) int {
+	t.Helper()
+	code, err := common.SendHTTPRequest(context.Background(), *http.DefaultClient, http.MethodGet, url, nil, dst)
+	if err != nil {
+		require.NotZero(t, code, err)
+	}
+	return code
+}
+
+func TestFixtures(t *testing.T) {
+	payloads, err := FixturePayloadsDelivered()
+	require.NoError(t, err)
+	require.Len(t, payloads, 3)
+	require.Equal(t, uint64(9590903), payloads[0].Slot)
+
+	bids, err := FixtureBlocksReceived()
+	require.NoError(t, err)
+	require.Len(t, bids, 4)
+	require.True(t, bids[0].OptimisticSubmission)
+}
+
+func TestPayloadDeliveredPagination(t *testing.T) {
+	relay, err := New()
+	require.NoError(t, err)
+	defer relay.Close()
+	relay.SetPayloadsDelivered(GeneratePayloadsDelivered(1000, 250))
+
+	var payloads []relaycommon.BidTraceV2JSON
+	code := getJSON(t, relay.srv.URL+PathPayloadDelivered+"?limit=100", &payloads)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, payloads, 100)
+	require.Equal(t, uint64(1249), payloads[0].Slot)
+	require.Equal(t, uint64(1150), payloads[99].Slot)
+
+	// the cursor is inclusive
+	code = getJSON(t, relay.srv.URL+PathPayloadDelivered+"?limit=200&cursor=1050", &payloads)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, payloads, 51)
+	require.Equal(t, uint64(1050), payloads[0].Slot)
+
+	code = getJSON(t, relay.srv.URL+PathPayloadDelivered+"?limit=500", &payloads)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, 3, relay.NumRequests(PathPayloadDelivered))
+}
+
+func TestBlocksReceived(t *testing.T) {
+	relay, err := New()
+	require.NoError(t, err)
+	defer relay.Close()
+	bids, err := FixtureBlocksReceived()
+	require.NoError(t, err)
+	relay.SetBlocksReceived(bids)
+
+	var resp []relaycommon.BidTraceV2WithTimestampJSON
+	code := getJSON(t, fmt.Sprintf("%s?slot=%d", relay.srv.URL+PathBlocksReceived, bids[0].Slot), &resp)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp, 4)
+
+	relay.AddBlocksReceived(bids[0])
+	code = getJSON(t, fmt.Sprintf("%s?slot=%d", relay.srv.URL+PathBlocksReceived, bids[0].Slot), &resp)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp, 5)
+
+	code = getJSON(t, relay.srv.URL+PathBlocksReceived+"?slot=1", &resp)
+	require.Equal(t, http.StatusOK, code)
+	require.Empty(t, resp)
+}
+
+func TestInjectErrors(t *testing.T) {
+	relay, err := New()
+	require.NoError(t, err)
+	defer relay.Close()
+	url := relay.srv.URL + PathBlocksReceived + "?slot=1"
+
+	relay.InjectRateLimit(PathBlocksReceived, 2)
+	var resp []relaycommon.BidTraceV2WithTimestampJSON
+	require.Equal(t, http.StatusTooManyRequests, getJSON(t, url, &resp))
+	require.Equal(t, http.StatusTooManyRequests, getJSON(t, url, &resp))
+	require.Equal(t, http.StatusOK, getJSON(t, url, &resp))
+
+	relay.InjectError(PathBlocksReceived, http.StatusInternalServerError, "oops", -1)
+	for range 3 {
+		require.Equal(t, http.StatusInternalServerError, getJSON(t, url, &resp))
+	}
+	relay.ClearErrors()
+	require.Equal(t, http.StatusOK, getJSON(t, url, &resp))
+	require.Equal(t, 7, relay.NumRequests(PathBlocksReceived))
+}
+
+func TestTopBidStream(t *testing.T) {
+	relay, err := New()
+	require.NoError(t, err)
+	defer relay.Close()
+
+	bids := []common.UltrasoundStreamBid{{Slot: 1, BlockNumber: 2}, {Slot: 3, BlockNumber: 4}}
+	relay.SetTopBids(bids)
+
+	conn, resp, err := websocket.DefaultDialer.Dial(relay.TopBidStreamURL(), nil)
+	require.NoError(t, err)
+	defer conn.Close()      //nolint:errcheck
+	defer resp.Body.Close() //nolint:errcheck
+
+	for _, expected := range bids {
+		_, msg, err := conn.ReadMessage()
+		require.NoError(t, err)
+		bid := new(common.UltrasoundStreamBid)
+		require.NoError(t, bid.UnmarshalSSZ(msg))
+		require.Equal(t, expected.Slot, bid.Slot)
+		require.Equal(t, expected.BlockNumber, bid.BlockNumber)
+	}
+}